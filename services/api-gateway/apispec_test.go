@@ -0,0 +1,41 @@
+package main
+
+import (
+	"slices"
+	"testing"
+
+	"ride-sharing/shared/apispec"
+)
+
+// The documents are generated from the contract and proto types, these tests check that they cover
+// the routes and RPCs the gateway serves.
+
+func TestAPISpecDocumentsEveryRoute(t *testing.T) {
+	paths := apispec.OpenAPI()["paths"].(map[string]any)
+
+	for route := range routePolicies {
+		if route == "/v1/" {
+			continue
+		}
+		if _, ok := paths[route]; !ok {
+			t.Errorf("route %s is not documented", route)
+		}
+	}
+}
+
+func TestAPISpecDocumentsEveryRPC(t *testing.T) {
+	documented := apispec.RESTMethods()
+
+	for method := range rpcPolicies {
+		if !slices.Contains(documented, method) {
+			t.Errorf("RPC %s has a policy but no REST route", method)
+		}
+	}
+
+	// An RPC exposed without a policy answers every call with a 403
+	for _, method := range documented {
+		if _, ok := rpcPolicies[method]; !ok {
+			t.Errorf("RPC %s is exposed under /v1/ without a policy", method)
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"ride-sharing/shared/contracts"
+	"ride-sharing/shared/validate"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// grpcHTTPStatus maps the gRPC codes returned by the services to HTTP statuses
+var grpcHTTPStatus = map[codes.Code]int{
+	codes.InvalidArgument:    http.StatusBadRequest,
+	codes.OutOfRange:         http.StatusBadRequest,
+	codes.Unauthenticated:    http.StatusUnauthorized,
+	codes.PermissionDenied:   http.StatusForbidden,
+	codes.NotFound:           http.StatusNotFound,
+	codes.AlreadyExists:      http.StatusConflict,
+	codes.Aborted:            http.StatusConflict,
+	codes.FailedPrecondition: http.StatusConflict,
+	codes.ResourceExhausted:  http.StatusTooManyRequests,
+	codes.Unimplemented:      http.StatusNotImplemented,
+	codes.Unavailable:        http.StatusServiceUnavailable,
+	codes.DeadlineExceeded:   http.StatusServiceUnavailable,
+	codes.Canceled:           http.StatusServiceUnavailable,
+}
+
+// httpErrorCodes are the generic error codes of the HTTP statuses
+var httpErrorCodes = map[int]string{
+	http.StatusBadRequest:          contracts.ErrCodeBadRequest,
+	http.StatusUnauthorized:        contracts.ErrCodeUnauthenticated,
+	http.StatusForbidden:           contracts.ErrCodeForbidden,
+	http.StatusNotFound:            contracts.ErrCodeNotFound,
+	http.StatusMethodNotAllowed:    contracts.ErrCodeMethodNotAllowed,
+	http.StatusConflict:            contracts.ErrCodeConflict,
+	http.StatusTooManyRequests:     contracts.ErrCodeRateLimited,
+	http.StatusNotImplemented:      contracts.ErrCodeNotImplemented,
+	http.StatusServiceUnavailable:  contracts.ErrCodeServiceUnavailable,
+	http.StatusInternalServerError: contracts.ErrCodeInternal,
+}
+
+func writeJSONError(w http.ResponseWriter, code int, message string) {
+	writeAPIError(w, code, errorCodeFor(code), message)
+}
+
+func writeAPIError(w http.ResponseWriter, httpStatus int, code string, message string, fields ...contracts.FieldError) {
+	response := contracts.APIResponse{
+		Error: &contracts.APIError{
+			Message: message,
+			Code:    code,
+			Fields:  fields,
+		},
+	}
+	writeJSON(w, httpStatus, response)
+}
+
+// writeValidationError answers with the field errors of the request
+func writeValidationError(w http.ResponseWriter, err error) {
+	var errs validate.Errors
+	if !errors.As(err, &errs) {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	writeAPIError(w, http.StatusBadRequest, contracts.ErrCodeValidationFailed, "invalid request", errs...)
+}
+
+// writeGRPCError translates the error of a service call. The reason of the error details, when the
+// service sent one, becomes the error code, otherwise the code is derived from the HTTP status.
+// The text of unexpected errors is not sent to the client.
+func writeGRPCError(w http.ResponseWriter, err error, message string) {
+	st := status.Convert(err)
+
+	httpStatus, ok := grpcHTTPStatus[st.Code()]
+	if !ok {
+		writeJSONError(w, http.StatusInternalServerError, message)
+		return
+	}
+
+	code := errorCodeFor(httpStatus)
+	var fields []contracts.FieldError
+	for _, detail := range st.Details() {
+		switch d := detail.(type) {
+		case *errdetails.ErrorInfo:
+			if d.Reason != "" {
+				code = d.Reason
+			}
+		case *errdetails.BadRequest:
+			for _, violation := range d.GetFieldViolations() {
+				fields = append(fields, contracts.FieldError{Field: violation.GetField(), Message: violation.GetDescription()})
+			}
+		}
+	}
+
+	if httpStatus == http.StatusServiceUnavailable {
+		writeAPIError(w, httpStatus, code, fmt.Sprintf("%s: service unavailable", message))
+		return
+	}
+
+	writeAPIError(w, httpStatus, code, fmt.Sprintf("%s: %s", message, st.Message()), fields...)
+}
+
+func errorCodeFor(httpStatus int) string {
+	if code, ok := httpErrorCodes[httpStatus]; ok {
+		return code
+	}
+	return contracts.ErrCodeInternal
+}
@@ -2,48 +2,42 @@ package grpc_clients
 
 import (
 	"os"
+	"ride-sharing/shared/grpcclient"
 	pb "ride-sharing/shared/proto/driver"
-	"ride-sharing/shared/tracing"
-	"strings"
 
 	"google.golang.org/grpc"
-	"google.golang.org/grpc/credentials/insecure"
 )
 
-type driverServiceClient struct {
+type DriverServiceClient struct {
 	Client pb.DriverServiceClient
 	conn   *grpc.ClientConn
 }
 
-func NewDriverServiceClient() (*driverServiceClient, error) {
+func NewDriverServiceClient() (*DriverServiceClient, error) {
 	driverServiceURL := os.Getenv("DRIVER_SERVICE_URL")
 	if driverServiceURL == "" {
 		driverServiceURL = "driver-service:8080"
 	}
 
-	dialOptions := append(
-		tracing.DialOptionsWithTracing(),
-		grpc.WithTransportCredentials(insecure.NewCredentials()),
-	)
-
-	if !strings.HasPrefix(driverServiceURL, "dns:///") {
-		driverServiceURL = "dns:///" + driverServiceURL
-	}
-
-	conn, err := grpc.NewClient(driverServiceURL, dialOptions...)
+	conn, err := grpcclient.NewConn(driverServiceURL, pb.DriverService_ServiceDesc.ServiceName)
 	if err != nil {
 		return nil, err
 	}
 
 	client := pb.NewDriverServiceClient(conn)
 
-	return &driverServiceClient{
+	return &DriverServiceClient{
 		Client: client,
 		conn:   conn,
 	}, nil
 }
 
-func (c *driverServiceClient) Close() {
+// Conn returns the connection shared by the clients of the service
+func (c *DriverServiceClient) Conn() *grpc.ClientConn {
+	return c.conn
+}
+
+func (c *DriverServiceClient) Close() {
 	if c.conn != nil {
 		if err := c.conn.Close(); err != nil {
 			return
@@ -0,0 +1,51 @@
+package grpc_clients
+
+import (
+	"net/url"
+	"os"
+	"ride-sharing/shared/grpcclient"
+	pb "ride-sharing/shared/proto/payment"
+
+	"google.golang.org/grpc"
+)
+
+type PaymentServiceClient struct {
+	Client pb.PaymentServiceClient
+	conn   *grpc.ClientConn
+}
+
+func NewPaymentServiceClient() (*PaymentServiceClient, error) {
+	// The service answers gRPC and the proxied Stripe webhooks on the same port
+	paymentServiceURL := os.Getenv("PAYMENT_SERVICE_URL")
+	if u, err := url.Parse(paymentServiceURL); err == nil && u.Host != "" {
+		paymentServiceURL = u.Host
+	}
+	if paymentServiceURL == "" {
+		paymentServiceURL = "payment-service:9004"
+	}
+
+	conn, err := grpcclient.NewConn(paymentServiceURL, pb.PaymentService_ServiceDesc.ServiceName)
+	if err != nil {
+		return nil, err
+	}
+
+	client := pb.NewPaymentServiceClient(conn)
+
+	return &PaymentServiceClient{
+		Client: client,
+		conn:   conn,
+	}, nil
+}
+
+// Conn returns the connection shared by the clients of the service
+func (c *PaymentServiceClient) Conn() *grpc.ClientConn {
+	return c.conn
+}
+
+func (c *PaymentServiceClient) Close() {
+	if c.conn != nil {
+		if err := c.conn.Close(); err != nil {
+			return
+		}
+	}
+}
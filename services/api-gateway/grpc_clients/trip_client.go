@@ -2,48 +2,42 @@ package grpc_clients
 
 import (
 	"os"
+	"ride-sharing/shared/grpcclient"
 	pb "ride-sharing/shared/proto/trip"
-	"ride-sharing/shared/tracing"
-	"strings"
 
 	"google.golang.org/grpc"
-	"google.golang.org/grpc/credentials/insecure"
 )
 
-type tripServiceClient struct {
+type TripServiceClient struct {
 	Client pb.TripServiceClient
 	conn   *grpc.ClientConn
 }
 
-func NewTripServiceClient() (*tripServiceClient, error) {
+func NewTripServiceClient() (*TripServiceClient, error) {
 	tripServiceURL := os.Getenv("TRIP_SERVICE_URL")
 	if tripServiceURL == "" {
 		tripServiceURL = "trip-service:8080"
 	}
 
-	dialOptions := append(
-		tracing.DialOptionsWithTracing(),
-		grpc.WithTransportCredentials(insecure.NewCredentials()),
-	)
-
-	if !strings.HasPrefix(tripServiceURL, "dns:///") {
-		tripServiceURL = "dns:///" + tripServiceURL
-	}
-
-	conn, err := grpc.NewClient(tripServiceURL, dialOptions...)
+	conn, err := grpcclient.NewConn(tripServiceURL, pb.TripService_ServiceDesc.ServiceName)
 	if err != nil {
 		return nil, err
 	}
 
 	client := pb.NewTripServiceClient(conn)
 
-	return &tripServiceClient{
+	return &TripServiceClient{
 		Client: client,
 		conn:   conn,
 	}, nil
 }
 
-func (c *tripServiceClient) Close() {
+// Conn returns the connection shared by the clients of the service
+func (c *TripServiceClient) Conn() *grpc.ClientConn {
+	return c.conn
+}
+
+func (c *TripServiceClient) Close() {
 	if c.conn != nil {
 		if err := c.conn.Close(); err != nil {
 			return
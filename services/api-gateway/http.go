@@ -3,22 +3,18 @@ package main
 import (
 	"encoding/json"
 	"fmt"
-	"io"
 	"log"
 	"net/http"
+	"net/http/httputil"
+	"net/url"
 	"ride-sharing/services/api-gateway/grpc_clients"
 	"ride-sharing/shared/contracts"
-	"ride-sharing/shared/env"
-	"ride-sharing/shared/messaging"
 	"ride-sharing/shared/tracing"
-
-	"github.com/stripe/stripe-go/v81"
-	"github.com/stripe/stripe-go/v81/webhook"
 )
 
 var tracer = tracing.GetTracer("api-gateway")
 
-func handleTripStart(w http.ResponseWriter, r *http.Request) {
+func handleTripStart(w http.ResponseWriter, r *http.Request, tripService *grpc_clients.TripServiceClient) {
 	ctx, span := tracer.Start(r.Context(), "handleTripStart")
 	defer span.End()
 
@@ -35,21 +31,27 @@ func handleTripStart(w http.ResponseWriter, r *http.Request) {
 
 	defer r.Body.Close()
 
-	// Why we need to create a new client for each connection:
-	// because if a service is down, we don't want to block the whole application
-	// so we create a new client for each connection
-	tripService, err := grpc_clients.NewTripServiceClient()
+	userID, err := authorizeUserID(r, reqBody.UserID)
 	if err != nil {
-		log.Fatal(err)
+		writeAPIError(w, http.StatusForbidden, contracts.ErrCodeUserMismatch, err.Error())
+		return
 	}
+	reqBody.UserID = userID
 
-	// Don't forget to close the client to avoid resource leaks!
-	defer tripService.Close()
+	if err := reqBody.validate(); err != nil {
+		writeValidationError(w, err)
+		return
+	}
+
+	if tripService == nil {
+		writeJSONError(w, http.StatusServiceUnavailable, "trip service is unavailable")
+		return
+	}
 
 	trip, err := tripService.Client.CreateTrip(ctx, reqBody.toProto())
 	if err != nil {
 		log.Printf("DEBUG: gRPC CreateTrip failed: %v", err)
-		writeJSONError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to start trip: %v", err))
+		writeGRPCError(w, err, "Failed to start trip")
 		return
 	}
 
@@ -58,7 +60,7 @@ func handleTripStart(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, http.StatusCreated, response)
 }
 
-func handleTripPreview(w http.ResponseWriter, r *http.Request) {
+func handleTripPreview(w http.ResponseWriter, r *http.Request, tripService *grpc_clients.TripServiceClient) {
 	ctx, span := tracer.Start(r.Context(), "handleTripPreview")
 	defer span.End()
 
@@ -75,27 +77,27 @@ func handleTripPreview(w http.ResponseWriter, r *http.Request) {
 
 	defer r.Body.Close()
 
-	// validation
-	if reqBody.UserID == "" {
-		writeJSONError(w, http.StatusBadRequest, "user ID is required")
+	userID, err := authorizeUserID(r, reqBody.UserID)
+	if err != nil {
+		writeAPIError(w, http.StatusForbidden, contracts.ErrCodeUserMismatch, err.Error())
 		return
 	}
+	reqBody.UserID = userID
 
-	// Why we need to create a new client for each connection:
-	// because if a service is down, we don't want to block the whole application
-	// so we create a new client for each connection
-	tripService, err := grpc_clients.NewTripServiceClient()
-	if err != nil {
-		log.Fatal(err)
+	if err := reqBody.validate(); err != nil {
+		writeValidationError(w, err)
+		return
 	}
 
-	// Don't forget to close the client to avoid resource leaks!
-	defer tripService.Close()
+	if tripService == nil {
+		writeJSONError(w, http.StatusServiceUnavailable, "trip service is unavailable")
+		return
+	}
 
 	tripPreview, err := tripService.Client.PreviewTrip(ctx, reqBody.toProto())
 	if err != nil {
 		log.Printf("DEBUG: gRPC PreviewTrip failed: %v", err)
-		writeJSONError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to preview trip: %v", err))
+		writeGRPCError(w, err, "Failed to preview trip")
 		return
 	}
 
@@ -104,86 +106,37 @@ func handleTripPreview(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, http.StatusCreated, response)
 }
 
-func handleStripeWebhook(w http.ResponseWriter, r *http.Request, rb *messaging.RabbitMQ) {
-	ctx, span := tracer.Start(r.Context(), "handleStripeWebhook")
-	defer span.End()
-
-	body, err := io.ReadAll(r.Body)
+// newStripeWebhookProxy forwards the Stripe webhooks untouched to payment-service, which verifies
+// their signature against the raw body
+func newStripeWebhookProxy(paymentServiceURL string) (http.Handler, error) {
+	target, err := url.Parse(paymentServiceURL)
 	if err != nil {
-		http.Error(w, "Failed to read request body", http.StatusInternalServerError)
-		return
+		return nil, fmt.Errorf("invalid payment service URL: %v", err)
 	}
-	defer r.Body.Close()
 
-	webhookKey := env.GetString("STRIPE_WEBHOOK_KEY", "")
-	if webhookKey == "" {
-		log.Printf("Webhook key is required")
-		return
+	proxy := httputil.NewSingleHostReverseProxy(target)
+	proxy.ErrorHandler = func(w http.ResponseWriter, r *http.Request, err error) {
+		log.Printf("Error proxying the Stripe webhook: %v", err)
+		writeJSONError(w, http.StatusBadGateway, "payment service unavailable")
 	}
 
-	event, err := webhook.ConstructEventWithOptions(
-		body,
-		r.Header.Get("Stripe-Signature"),
-		webhookKey,
-		webhook.ConstructEventOptions{
-			IgnoreAPIVersionMismatch: true,
-		},
-	)
+	return proxy, nil
+}
+
+// handleAPISpec serves a document of shared/apispec, it is encoded once as it never changes
+func handleAPISpec(doc any) http.HandlerFunc {
+	body, err := json.Marshal(doc)
 	if err != nil {
-		log.Printf("Error verifying webhook signature: %v", err)
-		http.Error(w, "Invalid signature", http.StatusBadRequest)
-		return
+		log.Fatalf("Failed to encode the API spec: %v", err)
 	}
 
-	log.Printf("Received Stripe event: %v", event)
-
-	switch event.Type {
-	case "checkout.session.completed":
-		var session stripe.CheckoutSession
-
-		err := json.Unmarshal(event.Data.Raw, &session)
-		if err != nil {
-			log.Printf("Error parsing webhook JSON: %v", err)
-			http.Error(w, "Invalid payload", http.StatusBadRequest)
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			writeJSONError(w, http.StatusMethodNotAllowed, "method not allowed")
 			return
 		}
 
-		payload := messaging.PaymentStatusUpdateData{
-			TripID:   session.Metadata["trip_id"],
-			UserID:   session.Metadata["user_id"],
-			DriverID: session.Metadata["driver_id"],
-		}
-
-		payloadBytes, err := json.Marshal(payload)
-		if err != nil {
-			log.Printf("Error marshalling payload: %v", err)
-			http.Error(w, "Failed to marshal payload", http.StatusInternalServerError)
-			return
-		}
-
-		message := contracts.AmqpMessage{
-			OwnerID: session.Metadata["user_id"],
-			Data:    payloadBytes,
-		}
-
-		if err := rb.PublishMessage(
-			ctx,
-			contracts.PaymentEventSuccess,
-			message,
-		); err != nil {
-			log.Printf("Error publishing payment event: %v", err)
-			http.Error(w, "Failed to publish payment event", http.StatusInternalServerError)
-			return
-		}
-	}
-}
-
-func writeJSONError(w http.ResponseWriter, code int, message string) {
-	response := contracts.APIResponse{
-		Error: &contracts.APIError{
-			Message: message,
-			Code:    fmt.Sprintf("%d", code),
-		},
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(body)
 	}
-	writeJSON(w, code, response)
 }
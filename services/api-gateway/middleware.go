@@ -1,12 +1,22 @@
 package main
 
-import "net/http"
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+
+	"ride-sharing/shared/auth"
+)
+
+var errUserMismatch = errors.New("the user ID does not match the authenticated user")
 
 func enableCORS(handler http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Access-Control-Allow-Origin", "*")
 		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
 		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+		w.Header().Set("Access-Control-Expose-Headers", "Retry-After, X-RateLimit-Limit, X-RateLimit-Remaining, X-RateLimit-Reset")
 
 		// allow preflight requests from the browser API
 		if r.Method == "OPTIONS" {
@@ -16,4 +26,54 @@ func enableCORS(handler http.HandlerFunc) http.HandlerFunc {
 
 		handler(w, r)
 	}
+}
+
+// authenticate verifies the JWT of the request and puts the identity it carries on the request context.
+// The token is read from the Authorization header, or from the token query parameter on the websockets
+// and the event stream since browsers can't set headers on them. Elsewhere the URL would leak the token
+// to the logs and the Referer headers. A nil verifier disables the authentication.
+func authenticate(verifier *auth.Verifier, handler http.HandlerFunc) http.HandlerFunc {
+	if verifier == nil {
+		return handler
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if token == "" && queryTokenAllowed(r.URL.Path) {
+			token = r.URL.Query().Get("token")
+		}
+
+		identity, err := verifier.Verify(token)
+		if err != nil {
+			log.Printf("Rejected request to %s: %v", r.URL.Path, err)
+			writeJSONError(w, http.StatusUnauthorized, "invalid or missing token")
+			return
+		}
+
+		handler(w, r.WithContext(auth.NewContext(r.Context(), identity)))
+	}
+}
+
+// queryTokenAllowed reports whether the route takes the token from the query, see authenticate
+func queryTokenAllowed(path string) bool {
+	return strings.HasPrefix(path, "/ws/") || path == "/events/riders"
+}
+
+// authorizeUserID checks the user ID sent by the client against the authenticated user.
+// An empty user ID defaults to the authenticated user. Without authentication the user ID is trusted.
+func authorizeUserID(r *http.Request, userID string) (string, error) {
+	identity, ok := auth.FromContext(r.Context())
+	if !ok {
+		return userID, nil
+	}
+
+	if userID == "" {
+		return identity.UserID, nil
+	}
+
+	if !identity.CanActAs(userID) {
+		return "", errUserMismatch
+	}
+
+	return userID, nil
 }
\ No newline at end of file
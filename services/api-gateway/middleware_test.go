@@ -0,0 +1,133 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"ride-sharing/shared/auth"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const testSecret = "test-secret"
+
+// testToken signs a HS256 token of the user with the role
+func testToken(t *testing.T, userID string, role auth.Role) string {
+	t.Helper()
+
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, auth.Claims{
+		Role: role,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   userID,
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+		},
+	}).SignedString([]byte(testSecret))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+func TestAuthenticate(t *testing.T) {
+	verifier, err := auth.NewVerifier(&auth.Config{HMACSecret: testSecret})
+	if err != nil {
+		t.Fatal(err)
+	}
+	token := testToken(t, "rider-1", auth.RoleRider)
+
+	tests := []struct {
+		name   string
+		target string
+		header string
+		want   int
+	}{
+		{"header", "/trip/start", "Bearer " + token, http.StatusOK},
+		{"header on a websocket", "/ws/riders", "Bearer " + token, http.StatusOK},
+		{"query on the rider websocket", "/ws/riders?token=" + token, "", http.StatusOK},
+		{"query on the driver websocket", "/ws/drivers?token=" + token, "", http.StatusOK},
+		{"query on the event stream", "/events/riders?token=" + token, "", http.StatusOK},
+		{"query on a route", "/trip/start?token=" + token, "", http.StatusUnauthorized},
+		{"query on a REST route", "/v1/trips/active?token=" + token, "", http.StatusUnauthorized},
+		{"invalid header", "/trip/start", "Bearer not-a-jwt", http.StatusUnauthorized},
+		{"missing", "/ws/riders", "", http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var identity auth.Identity
+			handler := authenticate(verifier, func(w http.ResponseWriter, r *http.Request) {
+				identity, _ = auth.FromContext(r.Context())
+			})
+
+			r := httptest.NewRequest(http.MethodGet, tt.target, nil)
+			if tt.header != "" {
+				r.Header.Set("Authorization", tt.header)
+			}
+			w := httptest.NewRecorder()
+			handler(w, r)
+
+			if w.Code != tt.want {
+				t.Fatalf("got %d, want %d", w.Code, tt.want)
+			}
+			if tt.want == http.StatusOK && identity != (auth.Identity{UserID: "rider-1", Role: auth.RoleRider}) {
+				t.Errorf("got identity %+v, want rider-1 as rider", identity)
+			}
+		})
+	}
+}
+
+func TestAuthenticateWithoutVerifier(t *testing.T) {
+	called := false
+	handler := authenticate(nil, func(w http.ResponseWriter, r *http.Request) {
+		called = true
+		if _, ok := auth.FromContext(r.Context()); ok {
+			t.Error("got an identity without authentication")
+		}
+	})
+
+	handler(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/trip/start", nil))
+	if !called {
+		t.Fatal("the request was refused without authentication")
+	}
+}
+
+func TestAuthorize(t *testing.T) {
+	tests := []struct {
+		name  string
+		route string
+		role  auth.Role // Empty when the authentication is disabled
+		want  int
+	}{
+		{"rider starts a trip", "/trip/start", auth.RoleRider, http.StatusOK},
+		{"admin starts a trip", "/trip/start", auth.RoleAdmin, http.StatusOK},
+		{"driver starts a trip", "/trip/start", auth.RoleDriver, http.StatusForbidden},
+		{"rider on the rider websocket", "/ws/riders", auth.RoleRider, http.StatusOK},
+		{"driver on the rider websocket", "/ws/riders", auth.RoleDriver, http.StatusForbidden},
+		{"driver on the driver websocket", "/ws/drivers", auth.RoleDriver, http.StatusOK},
+		{"rider on the driver websocket", "/ws/drivers", auth.RoleRider, http.StatusForbidden},
+		{"admin on the driver websocket", "/ws/drivers", auth.RoleAdmin, http.StatusForbidden},
+		{"driver on the event stream", "/events/riders", auth.RoleDriver, http.StatusForbidden},
+		{"rider on a REST route", "/v1/", auth.RoleRider, http.StatusOK},
+		{"admin on a route without policy", "/unknown", auth.RoleAdmin, http.StatusForbidden},
+		{"without authentication", "/ws/drivers", "", http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := authorize(tt.route, func(w http.ResponseWriter, r *http.Request) {})
+
+			r := httptest.NewRequest(http.MethodGet, tt.route, nil)
+			if tt.role != "" {
+				r = r.WithContext(auth.NewContext(r.Context(), auth.Identity{UserID: "user-1", Role: tt.role}))
+			}
+			w := httptest.NewRecorder()
+			handler(w, r)
+
+			if w.Code != tt.want {
+				t.Errorf("got %d, want %d", w.Code, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"slices"
+
+	"ride-sharing/shared/auth"
+	"ride-sharing/shared/contracts"
+	pbp "ride-sharing/shared/proto/payment"
+	pb "ride-sharing/shared/proto/trip"
+)
+
+// routePolicies lists the roles allowed on every authenticated route of the gateway.
+// A route missing from this table is denied to everyone.
+var routePolicies = map[string][]auth.Role{
+	"/trip/preview":  {auth.RoleRider, auth.RoleAdmin},
+	"/trip/start":    {auth.RoleRider, auth.RoleAdmin},
+	"/ws/riders":     {auth.RoleRider},
+	"/events/riders": {auth.RoleRider},
+	"/ws/drivers":    {auth.RoleDriver},
+	// The roles of the REST routes are checked per RPC, see rpcPolicies
+	"/v1/": {auth.RoleRider, auth.RoleDriver, auth.RoleAdmin},
+}
+
+// rpcPolicies lists the roles allowed to call every RPC exposed under /v1/.
+// An RPC missing from this table is denied to everyone.
+var rpcPolicies = map[string][]auth.Role{
+	pb.TripService_PreviewTrip_FullMethodName:             {auth.RoleRider, auth.RoleAdmin},
+	pb.TripService_CreateTrip_FullMethodName:              {auth.RoleRider, auth.RoleAdmin},
+	pb.TripService_GetChatMessages_FullMethodName:         {auth.RoleRider, auth.RoleDriver, auth.RoleAdmin},
+	pb.TripService_ListQuickReplies_FullMethodName:        {auth.RoleRider, auth.RoleDriver},
+	pb.TripService_SplitFare_FullMethodName:               {auth.RoleRider, auth.RoleAdmin},
+	pb.TripService_CancelTrip_FullMethodName:              {auth.RoleRider, auth.RoleAdmin},
+	pb.TripService_GetActiveTrip_FullMethodName:           {auth.RoleRider, auth.RoleDriver, auth.RoleAdmin},
+	pbp.PaymentService_RefundPayment_FullMethodName:       {auth.RoleAdmin},
+	pbp.PaymentService_GetDriverEarnings_FullMethodName:   {auth.RoleDriver, auth.RoleAdmin},
+	pbp.PaymentService_CreateSetupIntent_FullMethodName:   {auth.RoleRider},
+	pbp.PaymentService_GetPaymentMethod_FullMethodName:    {auth.RoleRider, auth.RoleAdmin},
+	pbp.PaymentService_DeletePaymentMethod_FullMethodName: {auth.RoleRider, auth.RoleAdmin},
+	pbp.PaymentService_AddTip_FullMethodName:              {auth.RoleRider},
+}
+
+// wsMessagePolicies lists the roles allowed to send every websocket message type.
+// A message type missing from this table is dropped.
+var wsMessagePolicies = map[string][]auth.Role{
+	contracts.DriverCmdLocation:    {auth.RoleDriver},
+	contracts.DriverCmdTripAccept:  {auth.RoleDriver},
+	contracts.DriverCmdTripDecline: {auth.RoleDriver},
+	contracts.ChatCmdSend:          {auth.RoleRider, auth.RoleDriver},
+	contracts.ChatCmdReceipt:       {auth.RoleRider, auth.RoleDriver},
+	contracts.PaymentCmdAddTip:     {auth.RoleRider},
+	contracts.TripCmdSplitAccept:   {auth.RoleRider},
+	contracts.TripCmdSplitDecline:  {auth.RoleRider},
+}
+
+// authorize checks the role of the authenticated user against the policy of the route.
+// Without authentication every route is allowed.
+func authorize(route string, handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		identity, ok := auth.FromContext(r.Context())
+		if ok && !slices.Contains(routePolicies[route], identity.Role) {
+			log.Printf("Denied %s to user %s with role %s", route, identity.UserID, identity.Role)
+			writeJSONError(w, http.StatusForbidden, "forbidden")
+			return
+		}
+
+		handler(w, r)
+	}
+}
+
+// adminOnly reports whether a policy allows the admins only, or nobody.
+// Without authentication there is no admin, such a policy is denied.
+func adminOnly(roles []auth.Role) bool {
+	return !slices.ContainsFunc(roles, func(role auth.Role) bool { return role != auth.RoleAdmin })
+}
+
+// wsMessageAllowed checks the role of the authenticated user against the policy of the message type.
+// Without authentication only the known message types are allowed.
+func wsMessageAllowed(ctx context.Context, messageType string) bool {
+	roles, known := wsMessagePolicies[messageType]
+	if !known {
+		return false
+	}
+
+	identity, ok := auth.FromContext(ctx)
+	return !ok || slices.Contains(roles, identity.Role)
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"ride-sharing/shared/auth"
+	"ride-sharing/shared/env"
+	"ride-sharing/shared/ratelimit"
+)
+
+// routeLimit holds the limits of a route per authenticated user and per client IP
+type routeLimit struct {
+	User ratelimit.Limit
+	IP   ratelimit.Limit
+}
+
+// routeLimits are the rate limits of the gateway routes, per minute. A trip preview calls OSRM
+// and saves a fare per package, so it gets the tightest limit.
+var routeLimits = map[string]routeLimit{
+	"/trip/preview": {
+		User: ratelimit.PerMinute(env.GetInt("RATE_LIMIT_TRIP_PREVIEW_USER", 20), 5),
+		IP:   ratelimit.PerMinute(env.GetInt("RATE_LIMIT_TRIP_PREVIEW_IP", 60), 10),
+	},
+	"/trip/start": {
+		User: ratelimit.PerMinute(env.GetInt("RATE_LIMIT_TRIP_START_USER", 10), 3),
+		IP:   ratelimit.PerMinute(env.GetInt("RATE_LIMIT_TRIP_START_IP", 30), 10),
+	},
+	"/ws/riders": {
+		User: ratelimit.PerMinute(env.GetInt("RATE_LIMIT_WS_USER", 30), 10),
+		IP:   ratelimit.PerMinute(env.GetInt("RATE_LIMIT_WS_IP", 120), 20),
+	},
+	"/events/riders": {
+		User: ratelimit.PerMinute(env.GetInt("RATE_LIMIT_WS_USER", 30), 10),
+		IP:   ratelimit.PerMinute(env.GetInt("RATE_LIMIT_WS_IP", 120), 20),
+	},
+	"/ws/drivers": {
+		User: ratelimit.PerMinute(env.GetInt("RATE_LIMIT_WS_USER", 30), 10),
+		IP:   ratelimit.PerMinute(env.GetInt("RATE_LIMIT_WS_IP", 120), 20),
+	},
+	"/v1/": {
+		User: ratelimit.PerMinute(env.GetInt("RATE_LIMIT_REST_USER", 30), 10),
+		IP:   ratelimit.PerMinute(env.GetInt("RATE_LIMIT_REST_IP", 60), 10),
+	},
+}
+
+// proxyHops is the number of trusted proxies in front of the gateway, each of them appends the address
+// it got the request from to X-Forwarded-For. The client IP is the proxyHops-th address from the right,
+// the addresses on its left are sent by the client. With no proxy the client IP is the remote address.
+var proxyHops = env.GetInt("RATE_LIMIT_PROXY_HOPS", 0)
+
+// bucketLimit is a bucket of the rate limiter and its limit
+type bucketLimit struct {
+	key   string
+	limit ratelimit.Limit
+}
+
+// rateLimit limits the requests to the route per client IP and, for the authenticated users, per user
+// as well: a stolen token used from many IPs and many users behind one IP are both limited. It must run
+// after authenticate to know the user. Routes without limits are not limited.
+//
+// The X-RateLimit headers describe the token bucket closest to its limit: X-RateLimit-Limit is the size
+// of the bucket, the burst, X-RateLimit-Remaining the tokens left in it and X-RateLimit-Reset the seconds
+// until it is full again. The bucket is refilled at the rate of routeLimits.
+func rateLimit(store ratelimit.Store, route string, handler http.HandlerFunc) http.HandlerFunc {
+	limits, ok := routeLimits[route]
+	if !ok {
+		return handler
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		buckets := []bucketLimit{{key: route + "|ip:" + clientIP(r, proxyHops), limit: limits.IP}}
+		if identity, ok := auth.FromContext(r.Context()); ok {
+			buckets = append(buckets, bucketLimit{key: route + "|user:" + identity.UserID, limit: limits.User})
+		}
+
+		// A refused request takes no token from the buckets after the one refusing it
+		var result *ratelimit.Result
+		for _, b := range buckets {
+			taken, err := store.Take(r.Context(), b.key, b.limit)
+			if err != nil {
+				// Don't take the gateway down with the rate limiter store
+				log.Printf("Rate limiter failed, allowing the request: %v", err)
+				continue
+			}
+			if result == nil || tighter(taken, *result) {
+				result = &taken
+			}
+			if !taken.Allowed {
+				break
+			}
+		}
+
+		if result == nil {
+			handler(w, r)
+			return
+		}
+
+		w.Header().Set("X-RateLimit-Limit", strconv.Itoa(result.Limit))
+		w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(result.Remaining))
+		w.Header().Set("X-RateLimit-Reset", strconv.Itoa(ceilSeconds(result.ResetAfter)))
+
+		if !result.Allowed {
+			w.Header().Set("Retry-After", strconv.Itoa(ceilSeconds(result.RetryAfter)))
+			writeJSONError(w, http.StatusTooManyRequests, fmt.Sprintf("rate limit exceeded, retry in %ds", ceilSeconds(result.RetryAfter)))
+			return
+		}
+
+		handler(w, r)
+	}
+}
+
+// tighter reports whether the bucket of a is closer to its limit than the one of b, the headers
+// describe the tightest bucket
+func tighter(a, b ratelimit.Result) bool {
+	if a.Allowed != b.Allowed {
+		return !a.Allowed
+	}
+	if !a.Allowed {
+		return a.RetryAfter > b.RetryAfter
+	}
+	return a.Remaining < b.Remaining
+}
+
+// clientIP returns the address the hops-th proxy got the request from, or the remote address without
+// proxy. A request that went through fewer proxies gets the leftmost address, set by a proxy as well.
+func clientIP(r *http.Request, hops int) string {
+	if hops > 0 {
+		var addresses []string
+		for _, header := range r.Header.Values("X-Forwarded-For") {
+			for _, address := range strings.Split(header, ",") {
+				if address = strings.TrimSpace(address); address != "" {
+					addresses = append(addresses, address)
+				}
+			}
+		}
+		if len(addresses) > 0 {
+			return addresses[max(len(addresses)-hops, 0)]
+		}
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"ride-sharing/shared/auth"
+	"ride-sharing/shared/ratelimit"
+)
+
+// requestAs sends a request to the handler from the IP, authenticated as the user unless it is empty
+func requestAs(handler http.HandlerFunc, ip, userID string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(http.MethodPost, "/trip/start", nil)
+	r.RemoteAddr = ip + ":1234"
+	if userID != "" {
+		r = r.WithContext(auth.NewContext(r.Context(), auth.Identity{UserID: userID, Role: auth.RoleRider}))
+	}
+
+	w := httptest.NewRecorder()
+	handler(w, r)
+	return w
+}
+
+func TestRateLimitAppliesBothLimitsToTheUsers(t *testing.T) {
+	limits := routeLimits["/trip/start"]
+	handler := rateLimit(ratelimit.NewInmemStore(), "/trip/start", func(w http.ResponseWriter, r *http.Request) {})
+
+	// A user changing IP runs out of their own bucket
+	for i := 0; i < limits.User.Burst; i++ {
+		if w := requestAs(handler, "10.0.0."+strconv.Itoa(i+1), "rider-1"); w.Code != http.StatusOK {
+			t.Fatalf("request %d got %d, want 200", i, w.Code)
+		}
+	}
+	if w := requestAs(handler, "10.0.1.1", "rider-1"); w.Code != http.StatusTooManyRequests {
+		t.Fatalf("got %d, want the user to be limited", w.Code)
+	}
+
+	// Many users behind the same IP run out of the bucket of the IP
+	for i := 0; i < limits.IP.Burst; i++ {
+		if w := requestAs(handler, "10.0.2.1", "rider-"+strconv.Itoa(i+2)); w.Code != http.StatusOK {
+			t.Fatalf("request %d got %d, want 200", i, w.Code)
+		}
+	}
+	w := requestAs(handler, "10.0.2.1", "rider-100")
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("got %d, want the IP to be limited", w.Code)
+	}
+	if w.Header().Get("Retry-After") == "" {
+		t.Error("the refused request has no Retry-After header")
+	}
+}
+
+func TestRateLimitRefusedRequestsTakeNoOtherToken(t *testing.T) {
+	limits := routeLimits["/trip/start"]
+	handler := rateLimit(ratelimit.NewInmemStore(), "/trip/start", func(w http.ResponseWriter, r *http.Request) {})
+
+	// Other users drain the bucket of the IP
+	for i := 0; i < limits.IP.Burst; i++ {
+		requestAs(handler, "10.0.0.1", "rider-"+strconv.Itoa(i+2))
+	}
+
+	// The requests of rider-1 refused by the IP bucket leave the bucket of rider-1 alone
+	for i := 0; i < limits.User.Burst; i++ {
+		if w := requestAs(handler, "10.0.0.1", "rider-1"); w.Code != http.StatusTooManyRequests {
+			t.Fatalf("request %d got %d, want the IP to be limited", i, w.Code)
+		}
+	}
+	if w := requestAs(handler, "10.0.0.2", "rider-1"); w.Code != http.StatusOK {
+		t.Fatalf("got %d, want rider-1 to have tokens left", w.Code)
+	}
+}
+
+func TestClientIP(t *testing.T) {
+	tests := []struct {
+		name      string
+		forwarded []string
+		hops      int
+		want      string
+	}{
+		{name: "no proxy", forwarded: []string{"203.0.113.9"}, hops: 0, want: "192.0.2.1"},
+		{name: "one proxy", forwarded: []string{"203.0.113.9"}, hops: 1, want: "203.0.113.9"},
+		{name: "spoofed by the client", forwarded: []string{"1.2.3.4, 203.0.113.9"}, hops: 1, want: "203.0.113.9"},
+		{name: "two proxies", forwarded: []string{"1.2.3.4, 203.0.113.9, 10.0.0.5"}, hops: 2, want: "203.0.113.9"},
+		{name: "one header per proxy", forwarded: []string{"1.2.3.4", "203.0.113.9", "10.0.0.5"}, hops: 2, want: "203.0.113.9"},
+		{name: "fewer addresses than proxies", forwarded: []string{"203.0.113.9"}, hops: 2, want: "203.0.113.9"},
+		{name: "no header", hops: 1, want: "192.0.2.1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/trip/start", nil)
+			r.RemoteAddr = "192.0.2.1:1234"
+			for _, forwarded := range tt.forwarded {
+				r.Header.Add("X-Forwarded-For", forwarded)
+			}
+
+			if got := clientIP(r, tt.hops); got != tt.want {
+				t.Errorf("got %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"path"
+	"slices"
+	"strings"
+
+	"ride-sharing/services/api-gateway/grpc_clients"
+	"ride-sharing/shared/auth"
+	pbp "ride-sharing/shared/proto/payment"
+	pb "ride-sharing/shared/proto/trip"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// newRESTMux exposes the RPCs annotated with google.api.http in the protos under /v1/.
+// The responses and errors use the same envelope as the hand-written routes.
+func newRESTMux(ctx context.Context, tripService *grpc_clients.TripServiceClient, paymentService *grpc_clients.PaymentServiceClient) (*runtime.ServeMux, error) {
+	mux := runtime.NewServeMux(
+		runtime.WithMarshalerOption(runtime.MIMEWildcard, &runtime.JSONPb{
+			MarshalOptions: protojson.MarshalOptions{UseProtoNames: true},
+			// Reject the unknown fields instead of silently dropping them
+			UnmarshalOptions: protojson.UnmarshalOptions{DiscardUnknown: false},
+		}),
+		// The identity is only taken from the token, never from the forwarded headers
+		runtime.WithIncomingHeaderMatcher(func(string) (string, bool) { return "", false }),
+		runtime.WithForwardResponseRewriter(func(_ context.Context, response proto.Message) (any, error) {
+			return map[string]proto.Message{"data": response}, nil
+		}),
+		runtime.WithErrorHandler(handleRESTError),
+		runtime.WithRoutingErrorHandler(func(_ context.Context, _ *runtime.ServeMux, _ runtime.Marshaler, w http.ResponseWriter, _ *http.Request, httpStatus int) {
+			writeJSONError(w, httpStatus, strings.ToLower(http.StatusText(httpStatus)))
+		}),
+	)
+
+	// A service without client is left unregistered, its routes answer with a 404
+	if tripService != nil {
+		client := pb.NewTripServiceClient(authorizedConn{tripService.Conn()})
+		if err := pb.RegisterTripServiceHandlerClient(ctx, mux, client); err != nil {
+			return nil, err
+		}
+	}
+
+	if paymentService != nil {
+		client := pbp.NewPaymentServiceClient(authorizedConn{paymentService.Conn()})
+		if err := pbp.RegisterPaymentServiceHandlerClient(ctx, mux, client); err != nil {
+			return nil, err
+		}
+	}
+
+	return mux, nil
+}
+
+func handleRESTError(ctx context.Context, _ *runtime.ServeMux, _ runtime.Marshaler, w http.ResponseWriter, _ *http.Request, err error) {
+	// Errors raised by the gateway itself, e.g. an unsupported content type
+	var httpErr *runtime.HTTPStatusError
+	if errors.As(err, &httpErr) {
+		writeJSONError(w, httpErr.HTTPStatus, httpErr.Err.Error())
+		return
+	}
+
+	method, _ := runtime.RPCMethod(ctx)
+	writeGRPCError(w, err, "failed to call "+path.Base(method))
+}
+
+// authorizedConn checks the role of the authenticated user against rpcPolicies before calling the service.
+// Without authentication every RPC is allowed like the hand-written routes, except the admin only ones such as
+// RefundPayment or an RPC missing from the table.
+type authorizedConn struct {
+	grpc.ClientConnInterface
+}
+
+func (c authorizedConn) Invoke(ctx context.Context, method string, args, reply any, opts ...grpc.CallOption) error {
+	identity, ok := auth.FromContext(ctx)
+	if ok && !slices.Contains(rpcPolicies[method], identity.Role) {
+		log.Printf("Denied %s to user %s with role %s", method, identity.UserID, identity.Role)
+		return status.Error(codes.PermissionDenied, "forbidden")
+	}
+
+	if !ok && adminOnly(rpcPolicies[method]) {
+		log.Printf("Denied %s without authentication", method)
+		return status.Error(codes.PermissionDenied, "forbidden")
+	}
+
+	return c.ClientConnInterface.Invoke(ctx, method, args, reply, opts...)
+}
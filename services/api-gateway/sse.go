@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"strconv"
+
+	"ride-sharing/services/api-gateway/grpc_clients"
+	"ride-sharing/shared/contracts"
+	"ride-sharing/shared/messaging"
+)
+
+// handleRidersEvents streams the rider messages as Server-Sent Events, for the networks that block
+// the websocket upgrades. The stream carries the same messages as /ws/riders.
+func handleRidersEvents(w http.ResponseWriter, r *http.Request, router *messaging.UserRouter, tripService *grpc_clients.TripServiceClient) {
+	if r.Method != http.MethodGet {
+		writeJSONError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	userID, err := authorizeUserID(r, r.URL.Query().Get("userID"))
+	if err != nil {
+		writeAPIError(w, http.StatusForbidden, contracts.ErrCodeUserMismatch, err.Error())
+		return
+	}
+
+	if userID == "" {
+		writeJSONError(w, http.StatusBadRequest, "userID is required")
+		return
+	}
+
+	lastSeq, err := parseLastEventID(r)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, "invalid Last-Event-ID")
+		return
+	}
+
+	conn, err := messaging.NewSSEConn(w)
+	if err != nil {
+		log.Printf("Event stream failed: %v", err)
+		return
+	}
+	defer conn.Close()
+
+	if err := router.Connect(r.Context(), userID, conn, lastSeq); err != nil {
+		log.Printf("Failed to connect user %s: %v", userID, err)
+		return
+	}
+	defer router.Disconnect(context.Background(), userID, conn)
+
+	if lastSeq != nil {
+		sendTripSnapshot(r.Context(), tripService, userID)
+	}
+
+	// The client only listens, the stream lasts until it goes away or the gateway closes it
+	select {
+	case <-r.Context().Done():
+	case <-conn.Done():
+	}
+}
+
+// parseLastEventID reads the Last-Event-ID header sent by EventSource when it reconnects, the event ids
+// being the sequence numbers of the messages. ?lastSeq= is accepted as well, like on the websockets.
+func parseLastEventID(r *http.Request) (*uint64, error) {
+	value := r.Header.Get("Last-Event-ID")
+	if value == "" {
+		return parseLastSeq(r)
+	}
+
+	lastSeq, err := strconv.ParseUint(value, 10, 64)
+	if err != nil {
+		return nil, err
+	}
+
+	return &lastSeq, nil
+}
@@ -1,14 +1,23 @@
 package main
 
 import (
+	"ride-sharing/shared/contracts"
 	pb "ride-sharing/shared/proto/trip"
-	"ride-sharing/shared/types"
+	"ride-sharing/shared/validate"
 )
 
+// tripLimits are the distance and service area limits of the trips
+var tripLimits = validate.NewDefaultLimits()
+
 type previewTripRequest struct {
-	UserID      string           `json:"userID"`
-	Pickup      types.Coordinate `json:"pickup"`
-	Destination types.Coordinate `json:"destination"`
+	contracts.PreviewTripRequest
+}
+
+func (p *previewTripRequest) validate() error {
+	var errs validate.Errors
+	errs.Required("userID", p.UserID)
+	errs.Trip(&p.Pickup, &p.Destination, tripLimits)
+	return errs.Err()
 }
 
 func (p *previewTripRequest) toProto() *pb.PreviewTripRequest {
@@ -26,8 +35,14 @@ func (p *previewTripRequest) toProto() *pb.PreviewTripRequest {
 }
 
 type startTripRequest struct {
-	RideFareID string `json:"rideFareID"`
-	UserID     string `json:"userID"`
+	contracts.StartTripRequest
+}
+
+func (c *startTripRequest) validate() error {
+	var errs validate.Errors
+	errs.ObjectID("rideFareID", c.RideFareID)
+	errs.Required("userID", c.UserID)
+	return errs.Err()
 }
 
 func (c *startTripRequest) toProto() *pb.CreateTripRequest {
@@ -1,20 +1,69 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
+	"fmt"
 	"log"
 	"net/http"
 	"ride-sharing/services/api-gateway/grpc_clients"
 	"ride-sharing/shared/contracts"
+	"ride-sharing/shared/env"
 	"ride-sharing/shared/messaging"
 	"ride-sharing/shared/proto/driver"
+	pb "ride-sharing/shared/proto/trip"
+	"ride-sharing/shared/validate"
+	"strconv"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
+const driverUnregisterTimeout = 5 * time.Second
+
 var (
-	connManager = messaging.NewConnectionManager()
+	connManager = messaging.NewConnectionManager(connectionConfig())
+
+	// Queues whose messages are pushed to the riders and drivers websockets
+	notificationQueues = []string{
+		messaging.NotifyDriverNoDriversFoundQueue,
+		messaging.NotifyDriverAssignQueue,
+		messaging.NotifyDriverRejectedQueue,
+		messaging.NotifyPaymentSessionCreatedQueue,
+		messaging.NotifyTripCreatedQueue,
+		messaging.DriverCmdTripRequestQueue,
+		messaging.NotifyChatQueue,
+		messaging.NotifyPaymentTipQueue,
+		messaging.NotifyPaymentChargedQueue,
+		messaging.NotifyPaymentStatusQueue,
+		messaging.NotifyPaymentHoldQueue,
+		messaging.NotifySplitQueue,
+		messaging.NotifyPaymentSplitFailedQueue,
+	}
 )
 
-func handleRidersWebSocket(w http.ResponseWriter, r *http.Request, rb *messaging.RabbitMQ) {
+// startNotificationConsumers starts a single consumer per notification queue for this gateway instance.
+// Each message is routed to the connection of its owner, whichever instance holds it.
+func startNotificationConsumers(rb messaging.Broker, router *messaging.UserRouter) error {
+	for _, q := range notificationQueues {
+		consumer := messaging.NewQueueConsumer(rb, router, q)
+
+		if err := consumer.Start(); err != nil {
+			return fmt.Errorf("failed to start consumer for queue %s: %w", q, err)
+		}
+	}
+
+	return nil
+}
+
+func handleRidersWebSocket(w http.ResponseWriter, r *http.Request, rb messaging.Broker, router *messaging.UserRouter, tripService *grpc_clients.TripServiceClient) {
+	userID, err := authorizeUserID(r, r.URL.Query().Get("userID"))
+	if err != nil {
+		writeAPIError(w, http.StatusForbidden, contracts.ErrCodeUserMismatch, err.Error())
+		return
+	}
+
 	conn, err := connManager.Upgrade(w, r)
 	if err != nil {
 		log.Printf("WebSocket upgrade failed: %v", err)
@@ -23,32 +72,30 @@ func handleRidersWebSocket(w http.ResponseWriter, r *http.Request, rb *messaging
 
 	defer conn.Close()
 
-	userID := r.URL.Query().Get("userID")
 	if userID == "" {
 		log.Println("No user ID provided")
 		return
 	}
 
-	// Add connection to manager
-	connManager.Add(userID, conn)
-	defer connManager.Remove(userID)
-
-	// Initialize queue consumers
-	queues := []string{
-		messaging.NotifyDriverNoDriversFoundQueue,
-		messaging.NotifyDriverAssignQueue,
-		messaging.NotifyPaymentSessionCreatedQueue,
-		messaging.NotifyTripCreatedQueue, // Added this queue
+	lastSeq, err := parseLastSeq(r)
+	if err != nil {
+		log.Printf("Invalid lastSeq: %v", err)
+		return
 	}
 
-	for _, q := range queues {
-		consumer := messaging.NewQueueConsumer(rb, connManager, q)
+	// Add connection to manager and route the user messages to this instance
+	if err := router.Connect(r.Context(), userID, conn, lastSeq); err != nil {
+		log.Printf("Failed to connect user %s: %v", userID, err)
+		return
+	}
+	defer router.Disconnect(context.Background(), userID, conn)
 
-		if err := consumer.Start(); err != nil {
-			log.Printf("Failed to start consumer for queue: %s: err: %v", q, err)
-		}
+	if lastSeq != nil {
+		sendTripSnapshot(r.Context(), tripService, userID)
 	}
 
+	ctx := r.Context()
+
 	for {
 		_, message, err := conn.ReadMessage()
 		if err != nil {
@@ -56,11 +103,47 @@ func handleRidersWebSocket(w http.ResponseWriter, r *http.Request, rb *messaging
 			break
 		}
 
-		log.Printf("Received message: %s", message)
+		var riderMsg contracts.WSDriverMessage
+		if err := json.Unmarshal(message, &riderMsg); err != nil {
+			log.Printf("Error unmarshaling rider message: %v", err)
+			continue
+		}
+
+		if !wsMessageAllowed(ctx, riderMsg.Type) {
+			log.Printf("Dropped message %s from user %s: not allowed", riderMsg.Type, userID)
+			continue
+		}
+
+		switch riderMsg.Type {
+		case contracts.ChatCmdSend, contracts.ChatCmdReceipt, contracts.PaymentCmdAddTip, contracts.TripCmdSplitAccept, contracts.TripCmdSplitDecline:
+			publishClientCommand(ctx, rb, userID, riderMsg)
+		default:
+			log.Printf("Unknown message type: %s", riderMsg.Type)
+		}
 	}
 }
 
-func handleDriversWebSocket(w http.ResponseWriter, r *http.Request, rb *messaging.RabbitMQ) {
+func handleDriversWebSocket(w http.ResponseWriter, r *http.Request, rb messaging.Broker, router *messaging.UserRouter, driverService *grpc_clients.DriverServiceClient, tripService *grpc_clients.TripServiceClient) {
+	// A driver can't be registered without the driver service, don't accept the connection
+	if driverService == nil {
+		writeJSONError(w, http.StatusServiceUnavailable, "driver service is unavailable")
+		return
+	}
+
+	packageSlug := r.URL.Query().Get("packageSlug")
+	var errs validate.Errors
+	errs.PackageSlug("packageSlug", packageSlug)
+	if err := errs.Err(); err != nil {
+		writeValidationError(w, err)
+		return
+	}
+
+	userID, err := authorizeUserID(r, r.URL.Query().Get("userID"))
+	if err != nil {
+		writeAPIError(w, http.StatusForbidden, contracts.ErrCodeUserMismatch, err.Error())
+		return
+	}
+
 	conn, err := connManager.Upgrade(w, r)
 	if err != nil {
 		log.Printf("WebSocket upgrade failed: %v", err)
@@ -69,38 +152,39 @@ func handleDriversWebSocket(w http.ResponseWriter, r *http.Request, rb *messagin
 
 	defer conn.Close()
 
-	userID := r.URL.Query().Get("userID")
 	if userID == "" {
 		log.Println("No user ID provided")
 		return
 	}
 
-	packageSlug := r.URL.Query().Get("packageSlug")
-	if packageSlug == "" {
-		log.Println("No package slug provided")
+	lastSeq, err := parseLastSeq(r)
+	if err != nil {
+		log.Printf("Invalid lastSeq: %v", err)
 		return
 	}
 
-	// Add connection to manager
-	connManager.Add(userID, conn)
+	// Add connection to manager and route the user messages to this instance
+	if err := router.Connect(r.Context(), userID, conn, lastSeq); err != nil {
+		log.Printf("Failed to connect user %s: %v", userID, err)
+		return
+	}
 
 	ctx := r.Context()
 
-	driverService, err := grpc_clients.NewDriverServiceClient()
-	if err != nil {
-		log.Fatal(err)
-	}
-
-	// Closing connections
+	// Closing connections. The read loop ends as soon as the driver misses a heartbeat,
+	// the request context may be done by then but still carries the identity of the driver.
 	defer func() {
-		connManager.Remove(userID)
+		router.Disconnect(context.Background(), userID, conn)
+
+		unregisterCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), driverUnregisterTimeout)
+		defer cancel()
 
-		driverService.Client.UnregisterDriver(ctx, &driver.RegisterDriverRequest{
+		if _, err := driverService.Client.UnregisterDriver(unregisterCtx, &driver.RegisterDriverRequest{
 			DriverID:    userID,
 			PackageSlug: packageSlug,
-		})
-
-		driverService.Close()
+		}); err != nil {
+			log.Printf("Error unregistering driver %s: %v", userID, err)
+		}
 
 		log.Println("Driver unregistered: ", userID)
 	}()
@@ -122,17 +206,8 @@ func handleDriversWebSocket(w http.ResponseWriter, r *http.Request, rb *messagin
 		return
 	}
 
-	// Initialize queue consumers
-	queues := []string{
-		messaging.DriverCmdTripRequestQueue,
-	}
-
-	for _, q := range queues {
-		consumer := messaging.NewQueueConsumer(rb, connManager, q)
-
-		if err := consumer.Start(); err != nil {
-			log.Printf("Failed to start consumer for queue: %s: err: %v", q, err)
-		}
+	if lastSeq != nil {
+		sendTripSnapshot(ctx, tripService, userID)
 	}
 
 	for {
@@ -153,21 +228,88 @@ func handleDriversWebSocket(w http.ResponseWriter, r *http.Request, rb *messagin
 			continue
 		}
 
+		if !wsMessageAllowed(ctx, driverMsg.Type) {
+			log.Printf("Dropped message %s from user %s: not allowed", driverMsg.Type, userID)
+			continue
+		}
+
 		// Handle the different message type
 		switch driverMsg.Type {
 		case contracts.DriverCmdLocation:
 			// Handle driver location update in the future
 			continue
-		case contracts.DriverCmdTripAccept, contracts.DriverCmdTripDecline:
-			// Forward the message to RabbitMQ
-			if err := rb.PublishMessage(ctx, driverMsg.Type, contracts.AmqpMessage{
-				OwnerID: userID,
-				Data:    driverMsg.Data,
-			}); err != nil {
-				log.Printf("Error publishing message to RabbitMQ: %v", err)
-			}
+		case contracts.DriverCmdTripAccept, contracts.DriverCmdTripDecline, contracts.ChatCmdSend, contracts.ChatCmdReceipt:
+			publishClientCommand(ctx, rb, userID, contracts.WSDriverMessage{Type: driverMsg.Type, Data: driverMsg.Data})
 		default:
 			log.Printf("Unknown message type: %s", driverMsg.Type)
 		}
 	}
 }
+
+// publishClientCommand forwards a command of a websocket client to RabbitMQ.
+// The owner is the connected user, the services must not trust the ids in the payload.
+func publishClientCommand(ctx context.Context, rb messaging.Broker, userID string, message contracts.WSDriverMessage) {
+	if err := rb.PublishMessage(ctx, message.Type, contracts.AmqpMessage{
+		OwnerID: userID,
+		Data:    message.Data,
+	}); err != nil {
+		log.Printf("Error publishing message to RabbitMQ: %v", err)
+	}
+}
+
+// connectionConfig reads the websocket keepalive settings, the durations are in seconds
+func connectionConfig() messaging.ConnectionConfig {
+	cfg := messaging.DefaultConnectionConfig()
+
+	cfg.PingInterval = time.Duration(env.GetInt("WS_PING_INTERVAL", int(cfg.PingInterval/time.Second))) * time.Second
+	cfg.PongWait = time.Duration(env.GetInt("WS_IDLE_TIMEOUT", int(cfg.PongWait/time.Second))) * time.Second
+	cfg.WriteWait = time.Duration(env.GetInt("WS_WRITE_TIMEOUT", int(cfg.WriteWait/time.Second))) * time.Second
+	cfg.SendQueueSize = env.GetInt("WS_SEND_QUEUE_SIZE", cfg.SendQueueSize)
+
+	// A ping has to be sent before the client is considered idle
+	if cfg.PingInterval >= cfg.PongWait {
+		cfg.PingInterval = cfg.PongWait * 9 / 10
+	}
+
+	return cfg
+}
+
+// parseLastSeq reads the ?lastSeq= of a client resuming its session.
+// It returns nil for a new session.
+func parseLastSeq(r *http.Request) (*uint64, error) {
+	value := r.URL.Query().Get("lastSeq")
+	if value == "" {
+		return nil, nil
+	}
+
+	lastSeq, err := strconv.ParseUint(value, 10, 64)
+	if err != nil {
+		return nil, err
+	}
+
+	return &lastSeq, nil
+}
+
+// sendTripSnapshot sends the active trip of a reconnecting user, or no data if there is none.
+func sendTripSnapshot(ctx context.Context, tripService *grpc_clients.TripServiceClient, userID string) {
+	if tripService == nil {
+		return
+	}
+
+	var trip *pb.Trip
+	res, err := tripService.Client.GetActiveTrip(ctx, &pb.GetActiveTripRequest{UserID: userID})
+	switch {
+	case err == nil:
+		trip = res.Trip
+	case status.Code(err) != codes.NotFound:
+		log.Printf("Failed to get the active trip of user %s: %v", userID, err)
+		return
+	}
+
+	if err := connManager.SendMessage(userID, contracts.WSMessage{
+		Type: contracts.TripEventSnapshot,
+		Data: trip,
+	}); err != nil {
+		log.Printf("Failed to send the trip snapshot to user %s: %v", userID, err)
+	}
+}
@@ -2,7 +2,9 @@ package main
 
 import (
 	"context"
+	"ride-sharing/shared/auth"
 	pb "ride-sharing/shared/proto/driver"
+	"ride-sharing/shared/validate"
 
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
@@ -24,6 +26,17 @@ func NewGrpcHandler(s *grpc.Server, service *Service) {
 }
 
 func (h *driverGrpcHandler) RegisterDriver(ctx context.Context, req *pb.RegisterDriverRequest) (*pb.RegisterDriverResponse, error) {
+	if err := authorizeDriver(ctx, req.GetDriverID()); err != nil {
+		return nil, err
+	}
+
+	var errs validate.Errors
+	errs.Required("driverID", req.GetDriverID())
+	errs.PackageSlug("packageSlug", req.GetPackageSlug())
+	if err := errs.Err(); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
 	driver, err := h.service.RegisterDriver(req.GetDriverID(), req.GetPackageSlug())
 	if err != nil {
 		return nil, status.Errorf(codes.Internal, "failed to register driver")
@@ -35,6 +48,10 @@ func (h *driverGrpcHandler) RegisterDriver(ctx context.Context, req *pb.Register
 }
 
 func (h *driverGrpcHandler) UnregisterDriver(ctx context.Context, req *pb.RegisterDriverRequest) (*pb.RegisterDriverResponse, error) {
+	if err := authorizeDriver(ctx, req.GetDriverID()); err != nil {
+		return nil, err
+	}
+
 	h.service.UnregisterDriver(req.GetDriverID())
 
 	return &pb.RegisterDriverResponse{
@@ -43,3 +60,29 @@ func (h *driverGrpcHandler) UnregisterDriver(ctx context.Context, req *pb.Regist
 		},
 	}, nil
 }
+
+func (h *driverGrpcHandler) GetDriver(ctx context.Context, req *pb.GetDriverRequest) (*pb.GetDriverResponse, error) {
+	if req.GetDriverID() == "" {
+		return nil, status.Error(codes.InvalidArgument, "driverID is required")
+	}
+
+	driver := h.service.GetDriver(req.GetDriverID())
+	if driver == nil {
+		return nil, status.Errorf(codes.NotFound, "driver %s is not registered", req.GetDriverID())
+	}
+
+	return &pb.GetDriverResponse{
+		Driver: driver,
+	}, nil
+}
+
+// authorizeDriver rejects the requests made on behalf of another driver.
+// Requests without identity come from internal callers or from a gateway running without authentication.
+func authorizeDriver(ctx context.Context, driverID string) error {
+	identity, ok := auth.FromContext(ctx)
+	if !ok || identity.CanActAs(driverID) {
+		return nil
+	}
+
+	return status.Errorf(codes.PermissionDenied, "user %s can't act on behalf of driver %s", identity.UserID, driverID)
+}
@@ -6,16 +6,17 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"ride-sharing/shared/auth"
 	"ride-sharing/shared/env"
 	"ride-sharing/shared/messaging"
 	"ride-sharing/shared/tracing"
 	"strings"
 	"syscall"
+	"time"
 
 	grpcserver "google.golang.org/grpc"
-
-	"golang.org/x/net/http2"
-	"golang.org/x/net/http2/h2c"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
 )
 
 var GrpcAddr = env.GetString("GRPC_ADDR", ":9092")
@@ -49,16 +50,20 @@ func main() {
 	svc := NewService()
 
 	// RabbitMQ connection
-	rabbitmq, err := messaging.NewRabbitMQ(rabbitMqURI)
+	rabbitmq, err := messaging.NewBroker(rabbitMqURI)
 	if err != nil {
 		log.Fatal(err)
 	}
 	defer rabbitmq.Close()
 
 	// Initialize the gRPC server
-	grpcServer := grpcserver.NewServer(tracing.WithTracingInterceptors()...)
+	grpcServer := grpcserver.NewServer(append(tracing.WithTracingInterceptors(), auth.WithIdentityInterceptors()...)...)
 	NewGrpcHandler(grpcServer, svc)
 
+	// Report the serving status to the health checking gRPC clients
+	healthServer := health.NewServer()
+	healthpb.RegisterHealthServer(grpcServer, healthServer)
+
 	consumer := NewTripConsumer(rabbitmq, svc)
 	go func() {
 		if err := consumer.Listen(); err != nil {
@@ -82,17 +87,24 @@ func main() {
 		w.Write([]byte("Driver Service is Healthy"))
 	})
 
-	h2Handler := h2c.NewHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if r.ProtoMajor == 2 && strings.HasPrefix(r.Header.Get("Content-Type"), "application/grpc") {
 			grpcServer.ServeHTTP(w, r)
 		} else {
 			mux.ServeHTTP(w, r)
 		}
-	}), &http2.Server{})
+	})
+
+	// net/http serves HTTP/2 without TLS itself, unlike h2c it tracks the connections and Shutdown
+	// waits for the running gRPC calls
+	protocols := new(http.Protocols)
+	protocols.SetHTTP1(true)
+	protocols.SetUnencryptedHTTP2(true)
 
 	server := &http.Server{
-		Addr:    ":" + port,
-		Handler: h2Handler,
+		Addr:      ":" + port,
+		Handler:   handler,
+		Protocols: protocols,
 	}
 
 	go func() {
@@ -106,5 +118,15 @@ func main() {
 	// wait for the shutdown signal
 	<-ctx.Done()
 	log.Println("Shutting down the server...")
-	grpcServer.GracefulStop()
+	// Clients stop sending new calls to this instance while the running ones complete
+	healthServer.Shutdown()
+
+	// The gRPC calls are served through the HTTP server, GracefulStop can't drain them
+	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer shutdownCancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		log.Printf("Could not stop the server gracefully: %v", err)
+		server.Close()
+	}
+	grpcServer.Stop()
 }
@@ -72,6 +72,19 @@ func (s *Service) RegisterDriver(driverId string, packageSlug string) (*pb.Drive
 	return driver, nil
 }
 
+// GetDriver returns the registered driver, or nil
+func (s *Service) GetDriver(driverId string) *pb.Driver {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, driver := range s.drivers {
+		if driver.Driver.Id == driverId {
+			return driver.Driver
+		}
+	}
+	return nil
+}
+
 func (s *Service) UnregisterDriver(driverId string) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
@@ -12,11 +12,11 @@ import (
 )
 
 type tripConsumer struct {
-	rabbitmq *messaging.RabbitMQ
+	rabbitmq messaging.Broker
 	service  *Service
 }
 
-func NewTripConsumer(rabbitmq *messaging.RabbitMQ, service *Service) *tripConsumer {
+func NewTripConsumer(rabbitmq messaging.Broker, service *Service) *tripConsumer {
 	return &tripConsumer{
 		rabbitmq: rabbitmq,
 		service:  service,
@@ -56,9 +56,15 @@ func (c *tripConsumer) handleFindAndNotifyDrivers(ctx context.Context, payload m
 	log.Printf("Found suitable drivers %v", len(suitableIDs))
 
 	if len(suitableIDs) == 0 {
-		// Notify the driver that no drivers are available
+		marshalledEvent, err := json.Marshal(payload)
+		if err != nil {
+			return err
+		}
+
+		// Notify the rider that no drivers are available, the trip service cancels the trip
 		if err := c.rabbitmq.PublishMessage(ctx, contracts.TripEventNoDriversFound, contracts.AmqpMessage{
 			OwnerID: payload.Trip.UserID,
+			Data:    marshalledEvent,
 		}); err != nil {
 			log.Printf("Failed to publish message to exchange: %v", err)
 			return err
@@ -77,8 +83,8 @@ func (c *tripConsumer) handleFindAndNotifyDrivers(ctx context.Context, payload m
 		return err
 	}
 
-	// Notify the driver about a potential trip
-	if err := c.rabbitmq.PublishMessage(ctx, contracts.DriverCmdTripRequest, contracts.AmqpMessage{
+	// Offer the trip to the driver, the trip service records the offer and sends them the trip request
+	if err := c.rabbitmq.PublishMessage(ctx, contracts.TripCmdOffer, contracts.AmqpMessage{
 		OwnerID: suitableDriverID,
 		Data:    marshalledEvent,
 	}); err != nil {
@@ -8,18 +8,27 @@ import (
 	"os/signal"
 	"syscall"
 
+	"ride-sharing/services/payment-service/internal/domain"
 	"ride-sharing/services/payment-service/internal/events"
+	"ride-sharing/services/payment-service/internal/infrastructure/fake"
+	"ride-sharing/services/payment-service/internal/infrastructure/grpc"
+	"ride-sharing/services/payment-service/internal/infrastructure/repository"
 	"ride-sharing/services/payment-service/internal/infrastructure/stripe"
+	"ride-sharing/services/payment-service/internal/infrastructure/webhook"
 	"ride-sharing/services/payment-service/internal/service"
 	"ride-sharing/services/payment-service/pkg/types"
+	"ride-sharing/shared/auth"
+	"ride-sharing/shared/db"
 	"ride-sharing/shared/env"
 	"ride-sharing/shared/messaging"
 	"ride-sharing/shared/tracing"
+	"ride-sharing/shared/validate"
 	"strings"
+	"time"
 
-	"golang.org/x/net/http2"
-	"golang.org/x/net/http2/h2c"
 	grpcserver "google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
 )
 
 var GrpcAddr = env.GetString("GRPC_ADDR", ":9004")
@@ -55,30 +64,87 @@ func main() {
 
 	// Stripe config
 	stripeCfg := &types.PaymentConfig{
-		StripeSecretKey: env.GetString("STRIPE_SECRET_KEY", ""),
-		SuccessURL:      env.GetString("STRIPE_SUCCESS_URL", appURL+"?payment=success"),
-		CancelURL:       env.GetString("STRIPE_CANCEL_URL", appURL+"?payment=cancel"),
-		UseStripeAPI:    env.GetBool("USE_STRIPE_API", false), // Toggle this to true to use real Stripe API
+		StripeSecretKey:     env.GetString("STRIPE_SECRET_KEY", ""),
+		StripeWebhookSecret: env.GetString("STRIPE_WEBHOOK_KEY", ""),
+		SuccessURL:          env.GetString("STRIPE_SUCCESS_URL", appURL+"?payment=success"),
+		CancelURL:           env.GetString("STRIPE_CANCEL_URL", appURL+"?payment=cancel"),
+		UseStripeAPI:        env.GetBool("USE_STRIPE_API", false), // Toggle this to true to use real Stripe API
 	}
 
-	if stripeCfg.StripeSecretKey == "" {
-		// Log warning instead of fatal if we are mocking
-		if stripeCfg.UseStripeAPI {
+	// Payment processor, the scripted fake unless the Stripe API is enabled
+	var paymentProcessor domain.PaymentProcessor
+	if stripeCfg.UseStripeAPI {
+		if stripeCfg.StripeSecretKey == "" {
 			log.Fatalf("STRIPE_SECRET_KEY is not set")
-			return
-		} else {
-			log.Printf("STRIPE_SECRET_KEY is not set (running in mock mode)")
 		}
+		paymentProcessor = stripe.NewStripeClient(stripeCfg)
+	} else {
+		script, err := fake.ParseScript(env.GetString("FAKE_PAYMENT_SCRIPT", "success"))
+		if err != nil {
+			log.Fatalf("Invalid FAKE_PAYMENT_SCRIPT: %v", err)
+		}
+
+		webhookURL := env.GetString("FAKE_PAYMENT_WEBHOOK_URL", "")
+		if webhookURL != "" && stripeCfg.StripeWebhookSecret == "" {
+			log.Fatalf("FAKE_PAYMENT_WEBHOOK_URL is set but STRIPE_WEBHOOK_KEY is not, the webhooks can't be signed")
+		}
+
+		log.Printf("USE_STRIPE_API is not set (using the fake payment processor)")
+		paymentProcessor = fake.NewFakeProcessor(script, webhookURL, stripeCfg.StripeWebhookSecret)
 	}
 
-	// Stripe processor
-	paymentProcessor := stripe.NewStripeClient(stripeCfg)
+	// Payment records, kept in memory when MongoDB is not configured
+	var paymentRepo domain.PaymentRepository
+	var ledgerRepo domain.LedgerRepository
+	var methodRepo domain.PaymentMethodRepository
+	mongoCfg := db.NewMongoDefaultConfig()
+	if mongoCfg.URI != "" {
+		mongoClient, err := db.NewMongoClient(ctx, mongoCfg)
+		if err != nil {
+			log.Fatalf("Failed to initialize MongoDB, err: %v", err)
+		}
+		defer mongoClient.Disconnect(ctx)
 
-	// Service
-	svc := service.NewPaymentService(paymentProcessor)
+		repo := repository.NewMongoRepository(db.GetDatabase(mongoClient, mongoCfg))
+		paymentRepo, ledgerRepo, methodRepo = repo, repo, repo
+	} else {
+		log.Printf("MONGODB_URI is not set (keeping the payments in memory)")
+		repo := repository.NewInmemRepository()
+		paymentRepo, ledgerRepo, methodRepo = repo, repo, repo
+	}
+
+	// Commission of the platform, in percent of the fares, e.g. COMMISSION_PERCENT_LUXURY=25
+	commission := types.CommissionRates{
+		Default:  env.GetInt("COMMISSION_PERCENT", 20),
+		Packages: map[string]int{},
+	}
+	for _, slug := range validate.PackageSlugs {
+		if percent := env.GetInt("COMMISSION_PERCENT_"+strings.ToUpper(slug), -1); percent >= 0 {
+			commission.Packages[slug] = percent
+		}
+	}
+
+	// Services, the drivers are paid out by a fake provider until a real one is integrated
+	ledgerSvc := service.NewLedgerService(ledgerRepo, fake.NewFakePayoutProvider(), commission)
+	tipLimits := types.TipLimits{
+		Min: int64(env.GetInt("TIP_MIN_CENTS", 100)),
+		Max: int64(env.GetInt("TIP_MAX_CENTS", 10000)),
+	}
+	// Holds placed on the saved cards at the trip requests, e.g. HOLD_BUFFER_PERCENT=20 holds 120% of the fare
+	holdCfg := types.HoldConfig{
+		BufferPercent: env.GetInt("HOLD_BUFFER_PERCENT", 20),
+		RequireCard:   requireCard(),
+	}
+	svc := service.NewPaymentService(paymentProcessor, paymentRepo, methodRepo, ledgerSvc, tipLimits, holdCfg)
+
+	if interval := env.GetInt("PAYOUT_INTERVAL_MINUTES", 24*60); interval > 0 {
+		go runPayouts(ctx, ledgerSvc, time.Duration(interval)*time.Minute)
+	} else {
+		log.Printf("PAYOUT_INTERVAL_MINUTES is 0 (the drivers are not paid out)")
+	}
 
 	// RabbitMQ connection
-	rabbitmq, err := messaging.NewRabbitMQ(rabbitMqURI)
+	rabbitmq, err := messaging.NewBroker(rabbitMqURI)
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -90,8 +156,31 @@ func main() {
 	tripConsumer := events.NewTripConsumer(rabbitmq, svc)
 	go tripConsumer.Listen()
 
-	// Initialize gRPC server (even if we mostly use RabbitMQ, it's good for consistency)
-	grpcServer := grpcserver.NewServer(tracing.WithTracingInterceptors()...)
+	// Holds of the fares, placed when the riders request a trip
+	holdConsumer := events.NewHoldConsumer(rabbitmq, svc)
+	go holdConsumer.Listen()
+
+	// Tips sent from the rider websockets
+	tipConsumer := events.NewTipConsumer(rabbitmq, svc)
+	go tipConsumer.Listen()
+
+	// Stripe webhooks, proxied by the gateway
+	var webhookParser domain.WebhookParser
+	if stripeCfg.StripeWebhookSecret != "" {
+		webhookParser = stripe.NewWebhookParser(stripeCfg.StripeWebhookSecret)
+	} else {
+		log.Printf("STRIPE_WEBHOOK_KEY is not set (the Stripe webhooks will be rejected)")
+	}
+	publisher := events.NewPaymentEventPublisher(rabbitmq)
+	webhookHandler := webhook.NewHandler(webhookParser, svc, publisher)
+
+	// Initialize gRPC server
+	grpcServer := grpcserver.NewServer(append(tracing.WithTracingInterceptors(), auth.WithIdentityInterceptors()...)...)
+	grpc.NewGRPCHandler(grpcServer, svc, ledgerSvc, publisher)
+
+	// Report the serving status to the health checking gRPC clients
+	healthServer := health.NewServer()
+	healthpb.RegisterHealthServer(grpcServer, healthServer)
 
 	// Combine gRPC and HTTP Health Check on the same port
 	port := os.Getenv("PORT")
@@ -100,22 +189,30 @@ func main() {
 	}
 
 	mux := http.NewServeMux()
+	mux.Handle("/webhook/stripe", tracing.WrapHandlerFunc(webhookHandler.HandleStripeWebhook, "/webhook/stripe"))
 	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
 		w.Write([]byte("Payment Service is Healthy"))
 	})
 
-	h2Handler := h2c.NewHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if r.ProtoMajor == 2 && strings.HasPrefix(r.Header.Get("Content-Type"), "application/grpc") {
 			grpcServer.ServeHTTP(w, r)
 		} else {
 			mux.ServeHTTP(w, r)
 		}
-	}), &http2.Server{})
+	})
+
+	// net/http serves HTTP/2 without TLS itself, unlike h2c it tracks the connections and Shutdown
+	// waits for the running gRPC calls
+	protocols := new(http.Protocols)
+	protocols.SetHTTP1(true)
+	protocols.SetUnencryptedHTTP2(true)
 
 	server := &http.Server{
-		Addr:    ":" + port,
-		Handler: h2Handler,
+		Addr:      ":" + port,
+		Handler:   handler,
+		Protocols: protocols,
 	}
 
 	go func() {
@@ -129,4 +226,51 @@ func main() {
 	// Wait for shutdown signal
 	<-ctx.Done()
 	log.Println("Shutting down payment service...")
+	// Clients stop sending new calls to this instance while the running ones complete
+	healthServer.Shutdown()
+
+	// The gRPC calls are served through the HTTP server, GracefulStop can't drain them
+	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer shutdownCancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		log.Printf("Could not stop the server gracefully: %v", err)
+		server.Close()
+	}
+	grpcServer.Stop()
+}
+
+// runPayouts pays the unpaid balances of the drivers out every interval, until ctx is done
+// requireCard tells whether the riders need a saved card to request a trip. HOLD_CARD_OPTIONAL=true lets
+// the riders without one request trips without any hold and pay at checkout, it is refused outside of
+// an explicit ENVIRONMENT=development.
+func requireCard() bool {
+	if !env.GetBool("HOLD_CARD_OPTIONAL", false) {
+		return true
+	}
+
+	if environment := env.GetString("ENVIRONMENT", ""); environment != "development" {
+		log.Fatalf("HOLD_CARD_OPTIONAL is only allowed with ENVIRONMENT=development, not %q", environment)
+	}
+
+	log.Println("WARNING: HOLD_CARD_OPTIONAL is set, the riders without a saved card request trips without any hold on their fare")
+	return false
+}
+
+func runPayouts(ctx context.Context, ledger domain.LedgerService, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			payouts, err := ledger.RunPayouts(ctx)
+			if err != nil {
+				log.Printf("Failed to run the payouts: %v", err)
+				continue
+			}
+			log.Printf("Ran %d payouts", len(payouts))
+		}
+	}
 }
@@ -2,14 +2,172 @@ package domain
 
 import (
 	"context"
+	"errors"
+	"time"
 
 	"ride-sharing/services/payment-service/pkg/types"
 )
 
+var (
+	// ErrPaymentNotFound is returned when no payment matches the ID or the Stripe objects
+	ErrPaymentNotFound = errors.New("payment not found")
+	// ErrInvalidWebhook is returned for the webhook payloads that are not signed by Stripe
+	ErrInvalidWebhook = errors.New("invalid webhook")
+	// ErrDuplicateEvent is returned for a Stripe event already processed, Stripe retries the deliveries
+	ErrDuplicateEvent = errors.New("event already processed")
+	// ErrStaleEvent is returned for an event that would move the payment back in its lifecycle,
+	// Stripe doesn't guarantee the order of the deliveries
+	ErrStaleEvent = errors.New("event is older than the payment status")
+	// ErrNotRefundable is returned for a payment that was not paid, or is already fully refunded
+	ErrNotRefundable = errors.New("payment is not refundable")
+	// ErrInvalidRefund is returned for a refund amount above what remains of the payment
+	ErrInvalidRefund = errors.New("invalid refund amount")
+	// ErrTripNotPaid is returned for a tip on a trip whose fare was not paid
+	ErrTripNotPaid = errors.New("trip is not paid")
+	// ErrNotTripRider is returned when the user tipping is not a rider who paid the fare of the trip
+	ErrNotTripRider = errors.New("user is not the rider of the trip")
+	// ErrInvalidTip is returned for a tip out of the limits, or without ID
+	ErrInvalidTip = errors.New("invalid tip")
+	// ErrPaymentMethodNotFound is returned when the rider has no saved card
+	ErrPaymentMethodNotFound = errors.New("payment method not found")
+	// ErrAuthenticationRequired is returned when the bank asks the rider to authenticate an off-session charge
+	ErrAuthenticationRequired = errors.New("card requires authentication")
+	// ErrCardDeclined is returned when an off-session charge is declined
+	ErrCardDeclined = errors.New("card declined")
+	// ErrHoldNotCapturable is returned when the hold of a fare expired or was released before its capture
+	ErrHoldNotCapturable = errors.New("hold can no longer be captured")
+	// ErrEntriesSettled is returned when another payout settled some of the ledger entries first
+	ErrEntriesSettled = errors.New("ledger entries already settled")
+	// ErrPaymentConflict is returned when the payment was updated since it was read
+	ErrPaymentConflict = errors.New("payment was updated concurrently")
+)
+
 type Service interface {
-	CreatePaymentSession(ctx context.Context, tripID, userID, driverID string, amount int64, currency string) (*types.PaymentIntent, error)
+	// CreatePaymentSession creates the fare payment of a trip, followed by the charge of the part of the
+	// fare above the hold of the rider when there is one
+	CreatePaymentSession(ctx context.Context, tripID, userID, driverID, packageSlug string, amount int64, currency string) ([]*types.PaymentIntent, error)
+	// CreateSplitPayments creates a fare payment per share of a split fare, the shares of the riders
+	// other than ownerID remember who requested the trip
+	CreateSplitPayments(ctx context.Context, tripID, ownerID, driverID, packageSlug string, shares []types.FareShare, currency string) ([]*types.PaymentIntent, error)
+	// HandlePaymentEvent records the outcome reported by Stripe on the payment it refers to. The payment
+	// is returned unchanged when it already has the status of the event.
+	HandlePaymentEvent(ctx context.Context, event *types.PaymentEvent) (*types.Payment, error)
+	// RecordPaymentEvent marks the Stripe event as processed, its deliveries are ignored from now on
+	RecordPaymentEvent(ctx context.Context, event *types.PaymentEvent) error
+	GetPayment(ctx context.Context, id string) (*types.Payment, error)
+	// ListPayments returns the payments the user paid or is paid and/or of the trip, newest first
+	ListPayments(ctx context.Context, userID, tripID string) ([]*types.Payment, error)
+	// RefundPayment refunds the amount of the payment, what remains of it when amount is 0
+	RefundPayment(ctx context.Context, paymentID string, amount int64, reason, requestedBy string) (*types.Payment, *types.Refund, error)
+	// AddTip creates the pending payment of a tip of the rider for the driver of a paid trip. The tipID
+	// chosen by the client makes it idempotent: the tip created with it already is returned again.
+	AddTip(ctx context.Context, tripID, userID, tipID string, amount int64) (*types.Payment, error)
+	// CreateSetupIntent starts saving a card for the rider, creating their Stripe customer the first time
+	CreateSetupIntent(ctx context.Context, userID string) (*types.SetupIntent, error)
+	// SavePaymentMethod saves the card of a succeeded setup intent, it replaces the card the rider had
+	SavePaymentMethod(ctx context.Context, event *types.PaymentEvent) (*types.PaymentMethod, error)
+	GetPaymentMethod(ctx context.Context, userID string) (*types.PaymentMethod, error)
+	DeletePaymentMethod(ctx context.Context, userID string) error
+	// AuthorizeHold holds the quoted fare of a trip, plus a buffer, on the saved card of the rider. The
+	// fare payment is authorized, or failed with ErrCardDeclined or ErrAuthenticationRequired when the
+	// bank refused the hold. It returns nil when the rider has no saved card and the holds don't
+	// require one, ErrPaymentMethodNotFound when they do.
+	AuthorizeHold(ctx context.Context, tripID, userID, packageSlug string, amount int64, currency string) (*types.Payment, error)
+	// ReleaseHold cancels the hold of a trip that won't be paid, the fare payment is cancelled. It
+	// returns nil when the trip has no hold, or when it was captured.
+	ReleaseHold(ctx context.Context, tripID string) (*types.Payment, error)
+}
+
+// LedgerService keeps the double-entry ledger of the payments and pays the drivers their balance
+type LedgerService interface {
+	// RecordCharge splits a paid payment between the commission and the driver, recording it again is a no-op
+	RecordCharge(ctx context.Context, payment *types.Payment) error
+	// RecordRefund takes the refund back from the driver and the commission in the proportions of the charge
+	RecordRefund(ctx context.Context, payment *types.Payment, refund *types.Refund) error
+	GetDriverEarnings(ctx context.Context, driverID string, from, to time.Time) (*types.DriverEarnings, error)
+	// RunPayouts pays every driver with a positive balance out, a failed payout is retried by the next run
+	RunPayouts(ctx context.Context) ([]*types.Payout, error)
 }
 
 type PaymentProcessor interface {
 	CreatePaymentSession(ctx context.Context, amount int64, currency string, metadata map[string]string) (string, error)
+	// Refund refunds the amount of the payment intent paymentRef and returns the ID of the refund at the
+	// processor. refundID is the idempotency key, refunding again with the same ID returns the same refund.
+	Refund(ctx context.Context, paymentRef string, amount int64, reason, refundID string) (string, error)
+	// CreateCustomer creates the customer the cards of the user are saved to and returns its ID
+	CreateCustomer(ctx context.Context, userID string) (string, error)
+	// CreateSetupIntent creates the setup intent saving a card for off-session payments of the customer
+	CreateSetupIntent(ctx context.Context, customerID string, metadata map[string]string) (*types.SetupIntent, error)
+	GetCard(ctx context.Context, paymentMethodID string) (*types.Card, error)
+	DetachPaymentMethod(ctx context.Context, paymentMethodID string) error
+	// ChargeOffSession charges the saved card of the customer and returns the payment intent with its
+	// status, pending while the bank processes it. It returns ErrAuthenticationRequired or ErrCardDeclined
+	// when the customer has to pay by themselves. The reference is the ID of the payment, a retry with
+	// the same reference doesn't charge the card twice.
+	ChargeOffSession(ctx context.Context, customerID, paymentMethodID string, amount int64, currency string, metadata map[string]string, reference string) (string, types.PaymentStatus, error)
+	// AuthorizeHold holds the amount on the saved card of the customer without charging it and returns
+	// the payment intent. It fails like ChargeOffSession, the reference has the same use.
+	AuthorizeHold(ctx context.Context, customerID, paymentMethodID string, amount int64, currency string, metadata map[string]string, reference string) (string, error)
+	// CaptureHold charges the amount, at most the one held, and releases the rest. It returns
+	// ErrHoldNotCapturable when the hold expired or was released.
+	CaptureHold(ctx context.Context, paymentIntentID string, amount int64) (types.PaymentStatus, error)
+	ReleaseHold(ctx context.Context, paymentIntentID string) error
+}
+
+// PayoutProvider sends the money to the drivers
+type PayoutProvider interface {
+	// Payout transfers the amount to the driver and returns the ID of the transfer. The reference is
+	// the ID of the payout, the provider can use it to ignore a retry.
+	Payout(ctx context.Context, driverID string, amount int64, currency, reference string) (string, error)
+}
+
+// WebhookParser verifies the signature of the Stripe webhooks and extracts the payment outcome.
+// It returns a nil event for the types of events that don't change a payment.
+type WebhookParser interface {
+	ParseEvent(payload []byte, signature string) (*types.PaymentEvent, error)
+}
+
+// PaymentRepository persists a payment for every checkout session
+type PaymentRepository interface {
+	SavePayment(ctx context.Context, payment *types.Payment) error
+	// GetPaymentByID, GetPaymentBySessionID and GetPaymentByIntentID return nil when there is no such payment
+	GetPaymentByID(ctx context.Context, id string) (*types.Payment, error)
+	GetPaymentBySessionID(ctx context.Context, sessionID string) (*types.Payment, error)
+	GetPaymentByIntentID(ctx context.Context, paymentIntentID string) (*types.Payment, error)
+	// ListPayments filters on the non-empty userID, the rider or the driver, and tripID, newest first
+	ListPayments(ctx context.Context, userID, tripID string) ([]*types.Payment, error)
+	// UpdatePayment saves the status, the driver, the amount, the checkout session, the payment intent, the
+	// saved card and the update time of the payment
+	UpdatePayment(ctx context.Context, payment *types.Payment) error
+	// UpdateRefunds saves the status, the payment intent, the refunds and the update time of the payment,
+	// unless it was updated after unmodifiedSince. It returns ErrPaymentConflict then.
+	UpdateRefunds(ctx context.Context, payment *types.Payment, unmodifiedSince time.Time) error
+	IsEventProcessed(ctx context.Context, eventID string) (bool, error)
+	// SaveProcessedEvent records the ID of a Stripe event, saving it again is not an error
+	SaveProcessedEvent(ctx context.Context, eventID, eventType string, at time.Time) error
+}
+
+// PaymentMethodRepository persists the Stripe customer and the saved card of every rider
+type PaymentMethodRepository interface {
+	// GetPaymentMethod returns nil when the user has no Stripe customer
+	GetPaymentMethod(ctx context.Context, userID string) (*types.PaymentMethod, error)
+	// SavePaymentMethod creates or replaces the payment method of the user
+	SavePaymentMethod(ctx context.Context, method *types.PaymentMethod) error
+}
+
+// LedgerRepository persists the ledger entries and the payouts
+type LedgerRepository interface {
+	// SaveEntries inserts the entries of a transaction, the ones already saved are skipped
+	SaveEntries(ctx context.Context, entries []types.LedgerEntry) error
+	// ListPaymentEntries returns the entries of the payment, oldest first
+	ListPaymentEntries(ctx context.Context, paymentID string) ([]types.LedgerEntry, error)
+	// ListDriverEntries returns the entries of the trips and payouts of the driver created in [from, to), oldest first
+	ListDriverEntries(ctx context.Context, driverID string, from, to time.Time) ([]types.LedgerEntry, error)
+	// ListUnpaidEntries returns the entries of the driver accounts not settled by a payout, of every driver when driverID is empty
+	ListUnpaidEntries(ctx context.Context, driverID string) ([]types.LedgerEntry, error)
+	// SettleEntries sets the payout of the unpaid entries, none of them is settled when one was already
+	SettleEntries(ctx context.Context, entryIDs []string, payoutID string) error
+	// UnsettleEntries releases the entries of a failed payout
+	UnsettleEntries(ctx context.Context, payoutID string) error
+	SavePayout(ctx context.Context, payout *types.Payout) error
 }
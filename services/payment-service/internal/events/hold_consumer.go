@@ -0,0 +1,74 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+
+	"ride-sharing/services/payment-service/internal/domain"
+	"ride-sharing/shared/contracts"
+	"ride-sharing/shared/messaging"
+
+	"github.com/rabbitmq/amqp091-go"
+)
+
+// HoldConsumer releases the holds of the trips that won't be paid. The holds are placed synchronously,
+// with the AuthorizeHold RPC, when the riders request the trips.
+type HoldConsumer struct {
+	rabbitmq  messaging.Broker
+	service   domain.Service
+	publisher *PaymentEventPublisher
+}
+
+func NewHoldConsumer(rabbitmq messaging.Broker, service domain.Service) *HoldConsumer {
+	return &HoldConsumer{
+		rabbitmq:  rabbitmq,
+		service:   service,
+		publisher: NewPaymentEventPublisher(rabbitmq),
+	}
+}
+
+func (c *HoldConsumer) Listen() error {
+	return c.rabbitmq.ConsumeMessages(messaging.PaymentCmdHoldQueue, func(ctx context.Context, msg amqp091.Delivery) error {
+		var message contracts.AmqpMessage
+		if err := json.Unmarshal(msg.Body, &message); err != nil {
+			log.Printf("Failed to unmarshal message: %v", err)
+			return err
+		}
+
+		var payload messaging.PaymentHoldCmdData
+		if err := json.Unmarshal(message.Data, &payload); err != nil {
+			log.Printf("Failed to unmarshal payload: %v", err)
+			return err
+		}
+
+		switch msg.RoutingKey {
+		case contracts.PaymentCmdReleaseHold:
+			return c.handleReleaseHold(ctx, payload)
+		}
+
+		log.Printf("unknown hold command: %s", msg.RoutingKey)
+		return nil
+	})
+}
+
+func (c *HoldConsumer) handleReleaseHold(ctx context.Context, payload messaging.PaymentHoldCmdData) error {
+	payment, err := c.service.ReleaseHold(ctx, payload.TripID)
+	if err != nil {
+		log.Printf("Failed to release the hold of trip %s: %v", payload.TripID, err)
+		return err
+	}
+
+	if payment == nil {
+		log.Printf("Trip %s has no hold to release", payload.TripID)
+		return nil
+	}
+
+	log.Printf("Released the hold of trip %s", payload.TripID)
+	return c.publisher.PublishHold(ctx, contracts.PaymentEventHoldReleased, payment.UserID, messaging.PaymentHoldData{
+		TripID:    payment.TripID,
+		PaymentID: payment.ID,
+		Amount:    payment.HeldAmount,
+		Currency:  payment.Currency,
+	})
+}
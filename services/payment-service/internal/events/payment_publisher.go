@@ -0,0 +1,137 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"ride-sharing/services/payment-service/pkg/types"
+	"ride-sharing/shared/contracts"
+	"ride-sharing/shared/messaging"
+)
+
+// statusEvents are the routing keys of the payment events, by status of the payment.
+// The refunds are published with PublishRefund.
+var statusEvents = map[types.PaymentStatus]string{
+	types.PaymentStatusSuccess:   contracts.PaymentEventSuccess,
+	types.PaymentStatusFailed:    contracts.PaymentEventFailed,
+	types.PaymentStatusCancelled: contracts.PaymentEventCancelled,
+	types.PaymentStatusDisputed:  contracts.PaymentEventDisputed,
+}
+
+type PaymentEventPublisher struct {
+	rabbitmq messaging.Broker
+}
+
+func NewPaymentEventPublisher(rabbitmq messaging.Broker) *PaymentEventPublisher {
+	return &PaymentEventPublisher{
+		rabbitmq: rabbitmq,
+	}
+}
+
+// PublishPaymentStatus notifies the other services that the payment of the trip reached the status
+func (p *PaymentEventPublisher) PublishPaymentStatus(ctx context.Context, status types.PaymentStatus, payload messaging.PaymentStatusUpdateData) error {
+	routingKey, ok := statusEvents[status]
+	if !ok {
+		return fmt.Errorf("no payment event for status %s", status)
+	}
+
+	payloadBytes, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal payload: %v", err)
+	}
+
+	return p.rabbitmq.PublishMessage(ctx, routingKey, contracts.AmqpMessage{
+		OwnerID: payload.UserID,
+		Data:    payloadBytes,
+	})
+}
+
+// PublishCharged tells the rider that their saved card was charged
+func (p *PaymentEventPublisher) PublishCharged(ctx context.Context, userID string, payload messaging.PaymentChargedData) error {
+	payloadBytes, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal payload: %v", err)
+	}
+
+	return p.rabbitmq.PublishMessage(ctx, contracts.PaymentEventCharged, contracts.AmqpMessage{
+		OwnerID: userID,
+		Data:    payloadBytes,
+	})
+}
+
+// PublishHold publishes the outcome of a hold, event is one of the payment.event.hold_* routing keys.
+// The rider owns the message.
+func (p *PaymentEventPublisher) PublishHold(ctx context.Context, event, userID string, payload messaging.PaymentHoldData) error {
+	payloadBytes, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal payload: %v", err)
+	}
+
+	return p.rabbitmq.PublishMessage(ctx, event, contracts.AmqpMessage{
+		OwnerID: userID,
+		Data:    payloadBytes,
+	})
+}
+
+// PublishRefund notifies the other services that the payment was refunded by amount
+func (p *PaymentEventPublisher) PublishRefund(ctx context.Context, payment *types.Payment, amount int64, reason string) error {
+	payloadBytes, err := json.Marshal(messaging.PaymentRefundedData{
+		TripID:         payment.TripID,
+		UserID:         payment.UserID,
+		DriverID:       payment.DriverID,
+		PaymentID:      payment.ID,
+		Amount:         amount,
+		RefundedAmount: payment.RefundedAmount,
+		Currency:       payment.Currency,
+		Reason:         reason,
+		FullyRefunded:  payment.Status == types.PaymentStatusRefunded,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal payload: %v", err)
+	}
+
+	return p.rabbitmq.PublishMessage(ctx, contracts.PaymentEventRefunded, contracts.AmqpMessage{
+		OwnerID: payment.UserID,
+		Data:    payloadBytes,
+	})
+}
+
+// PublishTipReceived notifies the driver that the rider paid a tip
+func (p *PaymentEventPublisher) PublishTipReceived(ctx context.Context, payment *types.Payment) error {
+	payloadBytes, err := json.Marshal(messaging.PaymentTipReceivedData{
+		TripID:    payment.TripID,
+		PaymentID: payment.ID,
+		Amount:    payment.Amount,
+		Currency:  payment.Currency,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal payload: %v", err)
+	}
+
+	return p.rabbitmq.PublishMessage(ctx, contracts.PaymentEventTipReceived, contracts.AmqpMessage{
+		OwnerID: payment.DriverID,
+		Data:    payloadBytes,
+	})
+}
+
+// PublishSplitFailed tells the rider who requested the trip that another rider didn't pay their share
+// of the fare
+func (p *PaymentEventPublisher) PublishSplitFailed(ctx context.Context, payment *types.Payment) error {
+	payloadBytes, err := json.Marshal(messaging.PaymentSplitFailedData{
+		TripID:    payment.TripID,
+		PaymentID: payment.ID,
+		UserID:    payment.UserID,
+		Amount:    payment.Amount,
+		Currency:  payment.Currency,
+		Status:    string(payment.Status),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal payload: %v", err)
+	}
+
+	return p.rabbitmq.PublishMessage(ctx, contracts.PaymentEventSplitFailed, contracts.AmqpMessage{
+		OwnerID: payment.TripOwnerID,
+		Data:    payloadBytes,
+	})
+}
@@ -0,0 +1,89 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+
+	"ride-sharing/services/payment-service/internal/domain"
+	"ride-sharing/shared/contracts"
+	"ride-sharing/shared/messaging"
+
+	"github.com/rabbitmq/amqp091-go"
+)
+
+// TipConsumer creates the payments of the tips sent from the rider websockets. The owner of the
+// commands is the rider authenticated by the gateway.
+type TipConsumer struct {
+	rabbitmq  messaging.Broker
+	service   domain.Service
+	publisher *PaymentEventPublisher
+}
+
+func NewTipConsumer(rabbitmq messaging.Broker, service domain.Service) *TipConsumer {
+	return &TipConsumer{
+		rabbitmq:  rabbitmq,
+		service:   service,
+		publisher: NewPaymentEventPublisher(rabbitmq),
+	}
+}
+
+func (c *TipConsumer) Listen() error {
+	return c.rabbitmq.ConsumeMessages(messaging.PaymentCmdAddTipQueue, func(ctx context.Context, msg amqp091.Delivery) error {
+		var message contracts.AmqpMessage
+		if err := json.Unmarshal(msg.Body, &message); err != nil {
+			log.Printf("Failed to unmarshal message: %v", err)
+			return err
+		}
+
+		var payload messaging.AddTipData
+		if err := json.Unmarshal(message.Data, &payload); err != nil {
+			log.Printf("Failed to unmarshal payload: %v", err)
+			return err
+		}
+
+		tip, err := c.service.AddTip(ctx, payload.TripID, message.OwnerID, payload.TipID, payload.Amount)
+		// A rejected tip won't succeed on a retry
+		if errors.Is(err, domain.ErrTripNotPaid) || errors.Is(err, domain.ErrNotTripRider) || errors.Is(err, domain.ErrInvalidTip) {
+			log.Printf("Rejected the tip of user %s: %v", message.OwnerID, err)
+			return nil
+		}
+		if err != nil {
+			log.Printf("Failed to add tip: %v", err)
+			return err
+		}
+
+		// A redelivery gets the same tip back and notifies the rider again, the card is charged once
+		if tip.OffSession() {
+			err := c.publisher.PublishCharged(ctx, tip.UserID, messaging.PaymentChargedData{
+				TripID:    tip.TripID,
+				PaymentID: tip.ID,
+				Amount:    tip.Amount,
+				Currency:  tip.Currency,
+				Tip:       true,
+			})
+			if err != nil {
+				log.Printf("Failed to publish the charge of tip %s: %v", tip.ID, err)
+			}
+			return nil
+		}
+
+		// The rider pays the tip with its own checkout session
+		payloadBytes, err := json.Marshal(messaging.PaymentEventSessionCreatedData{
+			TripID:    tip.TripID,
+			SessionID: tip.StripeSessionID,
+			Amount:    float64(tip.Amount) / 100.0,
+			Currency:  tip.Currency,
+			Tip:       true,
+		})
+		if err != nil {
+			return err
+		}
+
+		return c.rabbitmq.PublishMessage(ctx, contracts.PaymentEventSessionCreated, contracts.AmqpMessage{
+			OwnerID: tip.UserID,
+			Data:    payloadBytes,
+		})
+	})
+}
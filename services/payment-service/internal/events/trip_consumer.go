@@ -6,6 +6,7 @@ import (
 	"log"
 
 	"ride-sharing/services/payment-service/internal/domain"
+	"ride-sharing/services/payment-service/pkg/types"
 	"ride-sharing/shared/contracts"
 	"ride-sharing/shared/messaging"
 
@@ -13,14 +14,16 @@ import (
 )
 
 type TripConsumer struct {
-	rabbitmq *messaging.RabbitMQ
-	service  domain.Service
+	rabbitmq  messaging.Broker
+	service   domain.Service
+	publisher *PaymentEventPublisher
 }
 
-func NewTripConsumer(rabbitmq *messaging.RabbitMQ, service domain.Service) *TripConsumer {
+func NewTripConsumer(rabbitmq messaging.Broker, service domain.Service) *TripConsumer {
 	return &TripConsumer{
-		rabbitmq: rabbitmq,
-		service:  service,
+		rabbitmq:  rabbitmq,
+		service:   service,
+		publisher: NewPaymentEventPublisher(rabbitmq),
 	}
 }
 
@@ -53,11 +56,16 @@ func (c *TripConsumer) Listen() error {
 func (c *TripConsumer) handleTripAccepted(ctx context.Context, payload messaging.PaymentTripResponseData) error {
 	log.Printf("Handling trip accepted by driver: %s", payload.TripID)
 
-	paymentSession, err := c.service.CreatePaymentSession(
+	if len(payload.Shares) > 0 {
+		return c.handleSplitFare(ctx, payload)
+	}
+
+	paymentSessions, err := c.service.CreatePaymentSession(
 		ctx,
 		payload.TripID,
 		payload.UserID,
 		payload.DriverID,
+		payload.PackageSlug,
 		int64(payload.Amount),
 		payload.Currency,
 	)
@@ -66,11 +74,64 @@ func (c *TripConsumer) handleTripAccepted(ctx context.Context, payload messaging
 		return err
 	}
 
+	for _, paymentSession := range paymentSessions {
+		if err := c.publishPaymentSession(ctx, paymentSession); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// handleSplitFare creates the payment of every share of the fare, each rider is told about their own
+func (c *TripConsumer) handleSplitFare(ctx context.Context, payload messaging.PaymentTripResponseData) error {
+	shares := make([]types.FareShare, len(payload.Shares))
+	for i, share := range payload.Shares {
+		shares[i] = types.FareShare{UserID: share.UserID, Amount: share.Amount}
+	}
+
+	paymentSessions, err := c.service.CreateSplitPayments(
+		ctx,
+		payload.TripID,
+		payload.UserID,
+		payload.DriverID,
+		payload.PackageSlug,
+		shares,
+		payload.Currency,
+	)
+	if err != nil {
+		log.Printf("Failed to create the split payments: %v", err)
+		return err
+	}
+
+	for _, paymentSession := range paymentSessions {
+		if err := c.publishPaymentSession(ctx, paymentSession); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// publishPaymentSession tells the rider of the payment to pay in the checkout session, or that their
+// saved card was charged
+func (c *TripConsumer) publishPaymentSession(ctx context.Context, paymentSession *types.PaymentIntent) error {
+	// Nothing to pay in a checkout, the saved card of the rider was charged
+	if paymentSession.OffSession {
+		log.Printf("Charged the saved card of user %s for trip %s", paymentSession.UserID, paymentSession.TripID)
+		return c.publisher.PublishCharged(ctx, paymentSession.UserID, messaging.PaymentChargedData{
+			TripID:    paymentSession.TripID,
+			PaymentID: paymentSession.ID,
+			Amount:    paymentSession.Amount,
+			Currency:  paymentSession.Currency,
+		})
+	}
+
 	log.Printf("Payment session created: %s", paymentSession.StripeSessionID)
 
 	// Publish payment session created event
 	paymentPayload := messaging.PaymentEventSessionCreatedData{
-		TripID:    payload.TripID,
+		TripID:    paymentSession.TripID,
 		SessionID: paymentSession.StripeSessionID,
 		Amount:    float64(paymentSession.Amount) / 100.0, // Convert from cents to dollars
 		Currency:  paymentSession.Currency,
@@ -84,7 +145,7 @@ func (c *TripConsumer) handleTripAccepted(ctx context.Context, payload messaging
 
 	if err := c.rabbitmq.PublishMessage(ctx, contracts.PaymentEventSessionCreated,
 		contracts.AmqpMessage{
-			OwnerID: payload.UserID,
+			OwnerID: paymentSession.UserID,
 			Data:    payloadBytes,
 		},
 	); err != nil {
@@ -92,6 +153,6 @@ func (c *TripConsumer) handleTripAccepted(ctx context.Context, payload messaging
 		return err
 	}
 
-	log.Printf("Published payment session created event for trip: %s", payload.TripID)
+	log.Printf("Published payment session created event for trip: %s", paymentSession.TripID)
 	return nil
 }
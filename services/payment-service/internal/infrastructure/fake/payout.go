@@ -0,0 +1,22 @@
+package fake
+
+import (
+	"context"
+	"log"
+
+	"ride-sharing/services/payment-service/internal/domain"
+
+	"github.com/google/uuid"
+)
+
+// fakePayoutProvider accepts every payout without moving any money
+type fakePayoutProvider struct{}
+
+func NewFakePayoutProvider() domain.PayoutProvider {
+	return &fakePayoutProvider{}
+}
+
+func (p *fakePayoutProvider) Payout(ctx context.Context, driverID string, amount int64, currency, reference string) (string, error) {
+	log.Printf("Fake payout %s: %d %s to driver %s", reference, amount, currency, driverID)
+	return "po_fake_" + uuid.NewString(), nil
+}
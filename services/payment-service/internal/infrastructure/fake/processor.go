@@ -0,0 +1,364 @@
+package fake
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"ride-sharing/services/payment-service/internal/domain"
+	"ride-sharing/services/payment-service/pkg/types"
+
+	"github.com/google/uuid"
+	"github.com/stripe/stripe-go/v81"
+	"github.com/stripe/stripe-go/v81/webhook"
+)
+
+// SessionPrefix starts the IDs of the fake checkout sessions, the web app skips the Stripe checkout for them
+const SessionPrefix = "cs_test_fake_"
+
+// SetupIntentPrefix starts the IDs of the fake setup intents, they succeed without Stripe.js
+const SetupIntentPrefix = "seti_fake_"
+
+// minWebhookDelay leaves the service the time to save the payment of the session before its webhook
+const minWebhookDelay = time.Second
+
+// Outcome is what happens to a checkout session created by the fake processor
+type Outcome string
+
+const (
+	OutcomeSuccess Outcome = "success" // The rider pays, checkout.session.completed
+	OutcomeDecline Outcome = "decline" // The card is declined, payment_intent.payment_failed
+	OutcomeExpire  Outcome = "expire"  // The rider never pays, checkout.session.expired
+	OutcomePending Outcome = "pending" // No webhook, the payment stays pending
+	OutcomeError   Outcome = "error"   // The session can't be created
+	// The saved card needs authentication, the off-session charge falls back to a checkout session.
+	// A checkout session succeeds.
+	OutcomeAuthenticate Outcome = "authenticate"
+)
+
+// outcomeEvents are the webhook events sent for the outcomes
+var outcomeEvents = map[Outcome]stripe.EventType{
+	OutcomeSuccess: stripe.EventTypeCheckoutSessionCompleted,
+	OutcomeDecline: stripe.EventTypePaymentIntentPaymentFailed,
+	OutcomeExpire:  stripe.EventTypeCheckoutSessionExpired,
+
+	OutcomeAuthenticate: stripe.EventTypeCheckoutSessionCompleted,
+}
+
+// Step is the outcome of one checkout session, its webhook is sent after Delay
+type Step struct {
+	Outcome Outcome
+	Delay   time.Duration
+}
+
+// ParseScript parses a comma separated list of outcomes with an optional delay,
+// e.g. "success,decline:5s,error"
+func ParseScript(script string) ([]Step, error) {
+	var steps []Step
+	for _, part := range strings.Split(script, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		name, delay, hasDelay := strings.Cut(part, ":")
+		step := Step{Outcome: Outcome(name)}
+		if _, ok := outcomeEvents[step.Outcome]; !ok && step.Outcome != OutcomePending && step.Outcome != OutcomeError {
+			return nil, fmt.Errorf("unknown outcome %q", name)
+		}
+		if hasDelay {
+			d, err := time.ParseDuration(delay)
+			if err != nil {
+				return nil, fmt.Errorf("invalid delay of %q: %v", part, err)
+			}
+			step.Delay = d
+		}
+		steps = append(steps, step)
+	}
+
+	return steps, nil
+}
+
+// fakeProcessor creates checkout sessions and charges the saved cards without Stripe. The sessions,
+// the off-session charges and the holds follow the steps of the script in turn, the last one is
+// repeated once the script is over. Their webhooks are signed with the webhook secret and posted to
+// webhookURL, when it is set. The setup intents always succeed.
+type fakeProcessor struct {
+	webhookURL    string
+	webhookSecret string
+	client        *http.Client
+
+	mu      sync.Mutex
+	script  []Step
+	next    int
+	holds   map[string]*hold  // Holds not released, by payment intent
+	refunds map[string]string // IDs of the refunds, by idempotency key
+}
+
+// hold is a fare held on a saved card, its step tells what happens when it is captured
+type hold struct {
+	step     Step
+	metadata map[string]string
+	captured bool
+}
+
+func NewFakeProcessor(script []Step, webhookURL, webhookSecret string) domain.PaymentProcessor {
+	if len(script) == 0 {
+		script = []Step{{Outcome: OutcomeSuccess}}
+	}
+
+	return &fakeProcessor{
+		webhookURL:    webhookURL,
+		webhookSecret: webhookSecret,
+		client:        &http.Client{Timeout: 5 * time.Second},
+		script:        script,
+		holds:         map[string]*hold{},
+		refunds:       map[string]string{},
+	}
+}
+
+func (p *fakeProcessor) CreatePaymentSession(ctx context.Context, amount int64, currency string, metadata map[string]string) (string, error) {
+	step := p.nextStep()
+	if step.Outcome == OutcomeError {
+		return "", fmt.Errorf("fake processor: session of %d %s rejected by the script", amount, currency)
+	}
+
+	sessionID := SessionPrefix + uuid.NewString()
+	eventType, ok := outcomeEvents[step.Outcome]
+	if !ok || p.webhookURL == "" {
+		return sessionID, nil
+	}
+
+	object := sessionObject(eventType, sessionID, "pi_fake_"+uuid.NewString(), metadata)
+	p.sendWebhookLater(eventType, sessionID, object, step.Delay)
+
+	return sessionID, nil
+}
+
+// Refund refunds once per refund ID, like the idempotency keys of Stripe
+func (p *fakeProcessor) Refund(ctx context.Context, paymentRef string, amount int64, reason, refundID string) (string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if id, ok := p.refunds[refundID]; ok {
+		return id, nil
+	}
+	id := "re_fake_" + uuid.NewString()
+	p.refunds[refundID] = id
+	return id, nil
+}
+
+func (p *fakeProcessor) CreateCustomer(ctx context.Context, userID string) (string, error) {
+	return "cus_fake_" + uuid.NewString(), nil
+}
+
+// CreateSetupIntent creates a setup intent that succeeds as if the rider confirmed it with a test card
+func (p *fakeProcessor) CreateSetupIntent(ctx context.Context, customerID string, metadata map[string]string) (*types.SetupIntent, error) {
+	setupIntent := &types.SetupIntent{
+		ID:         SetupIntentPrefix + uuid.NewString(),
+		CustomerID: customerID,
+	}
+	setupIntent.ClientSecret = setupIntent.ID + "_secret_fake"
+
+	if p.webhookURL != "" {
+		object := map[string]any{
+			"id":             setupIntent.ID,
+			"object":         "setup_intent",
+			"customer":       customerID,
+			"payment_method": "pm_fake_" + uuid.NewString(),
+			"metadata":       metadata,
+		}
+		p.sendWebhookLater(stripe.EventTypeSetupIntentSucceeded, setupIntent.ID, object, 0)
+	}
+
+	return setupIntent, nil
+}
+
+func (p *fakeProcessor) GetCard(ctx context.Context, paymentMethodID string) (*types.Card, error) {
+	return &types.Card{
+		Brand:    "visa",
+		Last4:    "4242",
+		ExpMonth: 12,
+		ExpYear:  int64(time.Now().Year() + 3),
+	}, nil
+}
+
+func (p *fakeProcessor) DetachPaymentMethod(ctx context.Context, paymentMethodID string) error {
+	return nil
+}
+
+// ChargeOffSession follows the next step of the script: decline and authenticate fail the charge,
+// pending and expire leave it processing without webhook
+func (p *fakeProcessor) ChargeOffSession(ctx context.Context, customerID, paymentMethodID string, amount int64, currency string, metadata map[string]string, reference string) (string, types.PaymentStatus, error) {
+	step := p.nextStep()
+	intentID := "pi_fake_" + uuid.NewString()
+
+	switch step.Outcome {
+	case OutcomeError:
+		return "", "", fmt.Errorf("fake processor: charge of %d %s rejected by the script", amount, currency)
+	case OutcomeDecline:
+		return "", "", fmt.Errorf("%w: fake processor declined payment %s", domain.ErrCardDeclined, reference)
+	case OutcomeAuthenticate:
+		return "", "", fmt.Errorf("%w: fake processor asks to authenticate payment %s", domain.ErrAuthenticationRequired, reference)
+	case OutcomePending, OutcomeExpire:
+		return intentID, types.PaymentStatusPending, nil
+	}
+
+	if p.webhookURL != "" {
+		object := sessionObject(stripe.EventTypePaymentIntentSucceeded, "", intentID, metadata)
+		p.sendWebhookLater(stripe.EventTypePaymentIntentSucceeded, intentID, object, step.Delay)
+	}
+
+	return intentID, types.PaymentStatusSuccess, nil
+}
+
+// AuthorizeHold follows the next step of the script: decline and authenticate refuse the hold. The
+// other steps hold the amount, expire makes the capture fail and pending leaves it processing.
+func (p *fakeProcessor) AuthorizeHold(ctx context.Context, customerID, paymentMethodID string, amount int64, currency string, metadata map[string]string, reference string) (string, error) {
+	step := p.nextStep()
+
+	switch step.Outcome {
+	case OutcomeError:
+		return "", fmt.Errorf("fake processor: hold of %d %s rejected by the script", amount, currency)
+	case OutcomeDecline:
+		return "", fmt.Errorf("%w: fake processor declined the hold of payment %s", domain.ErrCardDeclined, reference)
+	case OutcomeAuthenticate:
+		return "", fmt.Errorf("%w: fake processor asks to authenticate the hold of payment %s", domain.ErrAuthenticationRequired, reference)
+	}
+
+	intentID := "pi_fake_" + uuid.NewString()
+
+	p.mu.Lock()
+	p.holds[intentID] = &hold{step: step, metadata: metadata}
+	p.mu.Unlock()
+
+	return intentID, nil
+}
+
+// CaptureHold captures a hold of this processor, the holds are lost when the service restarts.
+// Capturing a hold again succeeds without webhook.
+func (p *fakeProcessor) CaptureHold(ctx context.Context, paymentIntentID string, amount int64) (types.PaymentStatus, error) {
+	p.mu.Lock()
+	h, ok := p.holds[paymentIntentID]
+	captured := ok && h.captured
+	if ok {
+		h.captured = true
+	}
+	p.mu.Unlock()
+
+	switch {
+	case captured:
+		return types.PaymentStatusSuccess, nil
+	case !ok || h.step.Outcome == OutcomeExpire:
+		return "", fmt.Errorf("%w: fake processor has no hold %s", domain.ErrHoldNotCapturable, paymentIntentID)
+	case h.step.Outcome == OutcomePending:
+		return types.PaymentStatusPending, nil
+	}
+
+	if p.webhookURL != "" {
+		object := sessionObject(stripe.EventTypePaymentIntentSucceeded, "", paymentIntentID, h.metadata)
+		p.sendWebhookLater(stripe.EventTypePaymentIntentSucceeded, paymentIntentID, object, h.step.Delay)
+	}
+
+	return types.PaymentStatusSuccess, nil
+}
+
+func (p *fakeProcessor) ReleaseHold(ctx context.Context, paymentIntentID string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	delete(p.holds, paymentIntentID)
+	return nil
+}
+
+func (p *fakeProcessor) nextStep() Step {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	step := p.script[p.next]
+	if p.next < len(p.script)-1 {
+		p.next++
+	}
+	return step
+}
+
+// sessionObject is the Stripe object of the event, with the fields the webhook parser reads
+func sessionObject(eventType stripe.EventType, sessionID, intentID string, metadata map[string]string) map[string]any {
+	switch eventType {
+	case stripe.EventTypePaymentIntentPaymentFailed, stripe.EventTypePaymentIntentSucceeded:
+		return map[string]any{
+			"id":       intentID,
+			"object":   "payment_intent",
+			"metadata": metadata,
+		}
+	case stripe.EventTypeCheckoutSessionCompleted:
+		return map[string]any{
+			"id":             sessionID,
+			"object":         "checkout.session",
+			"payment_intent": intentID,
+			"metadata":       metadata,
+		}
+	default:
+		return map[string]any{
+			"id":       sessionID,
+			"object":   "checkout.session",
+			"metadata": metadata,
+		}
+	}
+}
+
+// sendWebhookLater posts the webhook of the object after the delay
+func (p *fakeProcessor) sendWebhookLater(eventType stripe.EventType, objectID string, object map[string]any, delay time.Duration) {
+	time.AfterFunc(max(delay, minWebhookDelay), func() {
+		if err := p.sendWebhook(eventType, object); err != nil {
+			log.Printf("Fake processor: failed to send %s of %s: %v", eventType, objectID, err)
+		}
+	})
+}
+
+func (p *fakeProcessor) sendWebhook(eventType stripe.EventType, object map[string]any) error {
+	payload, err := json.Marshal(map[string]any{
+		"id":          "evt_fake_" + uuid.NewString(),
+		"object":      "event",
+		"type":        eventType,
+		"api_version": stripe.APIVersion,
+		"created":     time.Now().Unix(),
+		"data":        map[string]any{"object": object},
+	})
+	if err != nil {
+		return err
+	}
+
+	signed := webhook.GenerateTestSignedPayload(&webhook.UnsignedPayload{
+		Payload:   payload,
+		Secret:    p.webhookSecret,
+		Timestamp: time.Now(),
+	})
+
+	req, err := http.NewRequest(http.MethodPost, p.webhookURL, bytes.NewReader(signed.Payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Stripe-Signature", signed.Header)
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("webhook answered %d: %s", resp.StatusCode, bytes.TrimSpace(body))
+	}
+
+	return nil
+}
@@ -0,0 +1,343 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"ride-sharing/services/payment-service/internal/domain"
+	"ride-sharing/services/payment-service/internal/events"
+	"ride-sharing/services/payment-service/pkg/types"
+	"ride-sharing/shared/auth"
+	"ride-sharing/shared/contracts"
+	"ride-sharing/shared/messaging"
+	pb "ride-sharing/shared/proto/payment"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type gRPCHandler struct {
+	pb.UnimplementedPaymentServiceServer
+
+	service   domain.Service
+	ledger    domain.LedgerService
+	publisher *events.PaymentEventPublisher
+}
+
+func NewGRPCHandler(server *grpc.Server, service domain.Service, ledger domain.LedgerService, publisher *events.PaymentEventPublisher) *gRPCHandler {
+	handler := &gRPCHandler{
+		service:   service,
+		ledger:    ledger,
+		publisher: publisher,
+	}
+
+	pb.RegisterPaymentServiceServer(server, handler)
+	return handler
+}
+
+func (h *gRPCHandler) GetPayment(ctx context.Context, req *pb.GetPaymentRequest) (*pb.GetPaymentResponse, error) {
+	if req.GetPaymentID() == "" {
+		return nil, status.Error(codes.InvalidArgument, "paymentID is required")
+	}
+
+	payment, err := h.service.GetPayment(ctx, req.GetPaymentID())
+	if errors.Is(err, domain.ErrPaymentNotFound) {
+		return nil, status.Error(codes.NotFound, err.Error())
+	}
+	if err != nil {
+		log.Printf("Failed to get payment: %v", err)
+		return nil, status.Error(codes.Internal, "failed to get the payment")
+	}
+
+	// The payment is disclosed to the rider and the driver of the trip only
+	if identity, ok := auth.FromContext(ctx); ok && !identity.CanActAs(payment.UserID) && identity.UserID != payment.DriverID {
+		return nil, status.Errorf(codes.NotFound, "%v: %s", domain.ErrPaymentNotFound, req.GetPaymentID())
+	}
+
+	return &pb.GetPaymentResponse{
+		Payment: payment.ToProto(),
+	}, nil
+}
+
+func (h *gRPCHandler) ListPayments(ctx context.Context, req *pb.ListPaymentsRequest) (*pb.ListPaymentsResponse, error) {
+	userID := req.GetUserID()
+
+	identity, ok := auth.FromContext(ctx)
+	if ok && identity.Role != auth.RoleAdmin {
+		if userID != "" && userID != identity.UserID {
+			return nil, status.Errorf(codes.PermissionDenied, "user %s cannot list the payments of user %s", identity.UserID, userID)
+		}
+		userID = identity.UserID
+	}
+
+	if userID == "" && req.GetTripID() == "" {
+		return nil, status.Error(codes.InvalidArgument, "userID or tripID is required")
+	}
+
+	payments, err := h.service.ListPayments(ctx, userID, req.GetTripID())
+	if err != nil {
+		log.Printf("Failed to list payments: %v", err)
+		return nil, status.Error(codes.Internal, "failed to list the payments")
+	}
+
+	return &pb.ListPaymentsResponse{
+		Payments: toPaymentsProto(payments),
+	}, nil
+}
+
+func (h *gRPCHandler) RefundPayment(ctx context.Context, req *pb.RefundPaymentRequest) (*pb.RefundPaymentResponse, error) {
+	// Requests without identity come from internal callers
+	identity, ok := auth.FromContext(ctx)
+	if ok && identity.Role != auth.RoleAdmin {
+		return nil, status.Errorf(codes.PermissionDenied, "user %s cannot refund payments", identity.UserID)
+	}
+
+	if req.GetPaymentID() == "" {
+		return nil, status.Error(codes.InvalidArgument, "paymentID is required")
+	}
+
+	payment, refund, err := h.service.RefundPayment(ctx, req.GetPaymentID(), req.GetAmount(), req.GetReason(), identity.UserID)
+	switch {
+	case errors.Is(err, domain.ErrPaymentNotFound):
+		return nil, status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, domain.ErrNotRefundable):
+		return nil, status.Error(codes.FailedPrecondition, err.Error())
+	case errors.Is(err, domain.ErrInvalidRefund):
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, domain.ErrPaymentConflict):
+		return nil, status.Error(codes.Aborted, err.Error())
+	case err != nil:
+		log.Printf("Failed to refund payment: %v", err)
+		return nil, status.Error(codes.Internal, "failed to refund the payment")
+	}
+
+	// The refund is done, a failure to notify the trip service doesn't undo it
+	if err := h.publisher.PublishRefund(ctx, payment, refund.Amount, refund.Reason); err != nil {
+		log.Printf("Failed to publish the refund %s of payment %s: %v", refund.ID, payment.ID, err)
+	}
+
+	return &pb.RefundPaymentResponse{
+		Payment: payment.ToProto(),
+		Refund:  refund.ToProto(),
+	}, nil
+}
+
+func (h *gRPCHandler) AddTip(ctx context.Context, req *pb.AddTipRequest) (*pb.AddTipResponse, error) {
+	userID := req.GetUserID()
+	if identity, ok := auth.FromContext(ctx); ok {
+		if userID == "" {
+			userID = identity.UserID
+		}
+		if !identity.CanActAs(userID) {
+			return nil, status.Errorf(codes.PermissionDenied, "user %s cannot tip for user %s", identity.UserID, userID)
+		}
+	}
+
+	if userID == "" || req.GetTripID() == "" || req.GetTipID() == "" {
+		return nil, status.Error(codes.InvalidArgument, "userID, tripID and tipID are required")
+	}
+
+	tip, err := h.service.AddTip(ctx, req.GetTripID(), userID, req.GetTipID(), req.GetAmount())
+	switch {
+	case errors.Is(err, domain.ErrTripNotPaid):
+		return nil, status.Error(codes.FailedPrecondition, err.Error())
+	case errors.Is(err, domain.ErrNotTripRider):
+		return nil, status.Error(codes.PermissionDenied, err.Error())
+	case errors.Is(err, domain.ErrInvalidTip):
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	case err != nil:
+		log.Printf("Failed to add tip: %v", err)
+		return nil, status.Error(codes.Internal, "failed to add the tip")
+	}
+
+	return &pb.AddTipResponse{
+		Payment: tip.ToProto(),
+	}, nil
+}
+
+// AuthorizeHold holds the fare of the trip the rider is requesting. A hold refused by the bank is
+// answered with its reason, not an error, and the rider is told on the websocket as well.
+func (h *gRPCHandler) AuthorizeHold(ctx context.Context, req *pb.AuthorizeHoldRequest) (*pb.AuthorizeHoldResponse, error) {
+	userID := req.GetUserID()
+	if identity, ok := auth.FromContext(ctx); ok && !identity.CanActAs(userID) {
+		return nil, status.Errorf(codes.PermissionDenied, "user %s cannot request a trip for user %s", identity.UserID, userID)
+	}
+
+	if userID == "" || req.GetTripID() == "" || req.GetAmount() <= 0 {
+		return nil, status.Error(codes.InvalidArgument, "userID, tripID and a positive amount are required")
+	}
+
+	payment, err := h.service.AuthorizeHold(ctx, req.GetTripID(), userID, req.GetPackageSlug(), req.GetAmount(), req.GetCurrency())
+
+	// A refused hold won't succeed on a retry, the trip is not dispatched
+	var reason string
+	switch {
+	case errors.Is(err, domain.ErrPaymentMethodNotFound):
+		reason = messaging.HoldFailedPaymentMethodRequired
+	case errors.Is(err, domain.ErrAuthenticationRequired):
+		reason = messaging.HoldFailedAuthenticationRequired
+	case errors.Is(err, domain.ErrCardDeclined):
+		reason = messaging.HoldFailedCardDeclined
+	case err != nil:
+		log.Printf("Failed to hold the fare of trip %s: %v", req.GetTripID(), err)
+		return nil, status.Error(codes.Internal, "failed to hold the fare")
+	}
+
+	res := &pb.AuthorizeHoldResponse{FailureReason: reason}
+	if payment != nil {
+		res.Payment = payment.ToProto()
+	}
+
+	if reason == "" {
+		if payment != nil {
+			log.Printf("Held %d %s on the card of user %s for trip %s", payment.HeldAmount, payment.Currency, userID, req.GetTripID())
+		}
+		return res, nil
+	}
+
+	log.Printf("Refused the trip %s of user %s: %v", req.GetTripID(), userID, err)
+	failed := messaging.PaymentHoldData{
+		TripID:   req.GetTripID(),
+		Currency: req.GetCurrency(),
+		Reason:   reason,
+	}
+	if payment != nil {
+		failed.PaymentID = payment.ID
+		failed.Amount = payment.HeldAmount
+	}
+	if err := h.publisher.PublishHold(ctx, contracts.PaymentEventHoldFailed, userID, failed); err != nil {
+		log.Printf("Failed to notify user %s of the refused hold of trip %s: %v", userID, req.GetTripID(), err)
+	}
+
+	return res, nil
+}
+
+func (h *gRPCHandler) CreateSetupIntent(ctx context.Context, req *pb.CreateSetupIntentRequest) (*pb.CreateSetupIntentResponse, error) {
+	userID, err := paymentMethodOwner(ctx, req.GetUserID())
+	if err != nil {
+		return nil, err
+	}
+
+	setupIntent, err := h.service.CreateSetupIntent(ctx, userID)
+	if err != nil {
+		log.Printf("Failed to create setup intent: %v", err)
+		return nil, status.Error(codes.Internal, "failed to create the setup intent")
+	}
+
+	return &pb.CreateSetupIntentResponse{
+		SetupIntentID: setupIntent.ID,
+		ClientSecret:  setupIntent.ClientSecret,
+	}, nil
+}
+
+func (h *gRPCHandler) GetPaymentMethod(ctx context.Context, req *pb.GetPaymentMethodRequest) (*pb.GetPaymentMethodResponse, error) {
+	userID, err := paymentMethodOwner(ctx, req.GetUserID())
+	if err != nil {
+		return nil, err
+	}
+
+	method, err := h.service.GetPaymentMethod(ctx, userID)
+	if errors.Is(err, domain.ErrPaymentMethodNotFound) {
+		return nil, status.Error(codes.NotFound, err.Error())
+	}
+	if err != nil {
+		log.Printf("Failed to get payment method: %v", err)
+		return nil, status.Error(codes.Internal, "failed to get the payment method")
+	}
+
+	return &pb.GetPaymentMethodResponse{
+		PaymentMethod: method.ToProto(),
+	}, nil
+}
+
+func (h *gRPCHandler) DeletePaymentMethod(ctx context.Context, req *pb.DeletePaymentMethodRequest) (*pb.DeletePaymentMethodResponse, error) {
+	userID, err := paymentMethodOwner(ctx, req.GetUserID())
+	if err != nil {
+		return nil, err
+	}
+
+	err = h.service.DeletePaymentMethod(ctx, userID)
+	if errors.Is(err, domain.ErrPaymentMethodNotFound) {
+		return nil, status.Error(codes.NotFound, err.Error())
+	}
+	if err != nil {
+		log.Printf("Failed to delete payment method: %v", err)
+		return nil, status.Error(codes.Internal, "failed to delete the payment method")
+	}
+
+	return &pb.DeletePaymentMethodResponse{}, nil
+}
+
+// paymentMethodOwner returns the user whose payment method is requested, the caller by default.
+// Only the user and the admins manage it.
+func paymentMethodOwner(ctx context.Context, userID string) (string, error) {
+	if identity, ok := auth.FromContext(ctx); ok {
+		if userID == "" {
+			userID = identity.UserID
+		}
+		if !identity.CanActAs(userID) {
+			return "", status.Errorf(codes.PermissionDenied, "user %s cannot manage the payment method of user %s", identity.UserID, userID)
+		}
+	}
+
+	if userID == "" {
+		return "", status.Error(codes.InvalidArgument, "userID is required")
+	}
+
+	return userID, nil
+}
+
+// earningsPeriod is the period of the earnings when the request doesn't set it
+const earningsPeriod = 7 * 24 * time.Hour
+
+func (h *gRPCHandler) GetDriverEarnings(ctx context.Context, req *pb.GetDriverEarningsRequest) (*pb.GetDriverEarningsResponse, error) {
+	driverID := req.GetDriverID()
+	if driverID == "" {
+		return nil, status.Error(codes.InvalidArgument, "driverID is required")
+	}
+
+	if identity, ok := auth.FromContext(ctx); ok && !identity.CanActAs(driverID) {
+		return nil, status.Errorf(codes.PermissionDenied, "user %s cannot read the earnings of driver %s", identity.UserID, driverID)
+	}
+
+	to, err := parseTime(req.GetTo(), time.Now())
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid to: %v", err)
+	}
+	from, err := parseTime(req.GetFrom(), to.Add(-earningsPeriod))
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid from: %v", err)
+	}
+	if !from.Before(to) {
+		return nil, status.Error(codes.InvalidArgument, "from must be before to")
+	}
+
+	earnings, err := h.ledger.GetDriverEarnings(ctx, driverID, from, to)
+	if err != nil {
+		log.Printf("Failed to get driver earnings: %v", err)
+		return nil, status.Error(codes.Internal, "failed to get the earnings")
+	}
+
+	return &pb.GetDriverEarningsResponse{
+		Earnings: earnings.ToProto(),
+	}, nil
+}
+
+// parseTime parses an RFC 3339 timestamp, fallback when it is empty
+func parseTime(value string, fallback time.Time) (time.Time, error) {
+	if value == "" {
+		return fallback, nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
+
+func toPaymentsProto(payments []*types.Payment) []*pb.Payment {
+	protoPayments := make([]*pb.Payment, len(payments))
+	for i, p := range payments {
+		protoPayments[i] = p.ToProto()
+	}
+	return protoPayments
+}
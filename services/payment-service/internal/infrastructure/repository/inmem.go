@@ -0,0 +1,275 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"ride-sharing/services/payment-service/internal/domain"
+	"ride-sharing/services/payment-service/pkg/types"
+)
+
+type inmemRepository struct {
+	mu       sync.RWMutex
+	payments map[string]*types.Payment
+	events   map[string]time.Time // Stripe event ID -> processing time
+	entries  []*types.LedgerEntry // In insertion order
+	entryIDs map[string]bool
+	payouts  map[string]*types.Payout
+	methods  map[string]*types.PaymentMethod // User ID -> payment method
+}
+
+func NewInmemRepository() *inmemRepository {
+	return &inmemRepository{
+		payments: make(map[string]*types.Payment),
+		events:   make(map[string]time.Time),
+		entryIDs: make(map[string]bool),
+		payouts:  make(map[string]*types.Payout),
+		methods:  make(map[string]*types.PaymentMethod),
+	}
+}
+
+func (r *inmemRepository) SavePayment(ctx context.Context, payment *types.Payment) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored := *payment
+	r.payments[payment.ID] = &stored
+	return nil
+}
+
+func (r *inmemRepository) GetPaymentByID(ctx context.Context, id string) (*types.Payment, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	payment, ok := r.payments[id]
+	if !ok {
+		return nil, nil
+	}
+	found := *payment
+	return &found, nil
+}
+
+func (r *inmemRepository) GetPaymentBySessionID(ctx context.Context, sessionID string) (*types.Payment, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, payment := range r.payments {
+		if payment.StripeSessionID == sessionID {
+			found := *payment
+			return &found, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *inmemRepository) GetPaymentByIntentID(ctx context.Context, paymentIntentID string) (*types.Payment, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, payment := range r.payments {
+		if payment.PaymentIntentID == paymentIntentID {
+			found := *payment
+			return &found, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *inmemRepository) ListPayments(ctx context.Context, userID, tripID string) ([]*types.Payment, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var payments []*types.Payment
+	for _, payment := range r.payments {
+		if (userID != "" && payment.UserID != userID && payment.DriverID != userID) || (tripID != "" && payment.TripID != tripID) {
+			continue
+		}
+		found := *payment
+		payments = append(payments, &found)
+	}
+
+	slices.SortFunc(payments, func(a, b *types.Payment) int {
+		return b.CreatedAt.Compare(a.CreatedAt)
+	})
+	return payments, nil
+}
+
+func (r *inmemRepository) UpdatePayment(ctx context.Context, payment *types.Payment) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, ok := r.payments[payment.ID]
+	if !ok {
+		return fmt.Errorf("%w: %s", domain.ErrPaymentNotFound, payment.ID)
+	}
+
+	stored.Status = payment.Status
+	stored.DriverID = payment.DriverID
+	stored.Amount = payment.Amount
+	stored.StripeSessionID = payment.StripeSessionID
+	stored.PaymentIntentID = payment.PaymentIntentID
+	stored.PaymentMethodID = payment.PaymentMethodID
+	stored.UpdatedAt = payment.UpdatedAt
+	return nil
+}
+
+func (r *inmemRepository) UpdateRefunds(ctx context.Context, payment *types.Payment, unmodifiedSince time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, ok := r.payments[payment.ID]
+	if !ok {
+		return fmt.Errorf("%w: %s", domain.ErrPaymentNotFound, payment.ID)
+	}
+	if !stored.UpdatedAt.Equal(unmodifiedSince) {
+		return fmt.Errorf("%w: %s", domain.ErrPaymentConflict, payment.ID)
+	}
+
+	stored.Status = payment.Status
+	stored.PaymentIntentID = payment.PaymentIntentID
+	stored.RefundedAmount = payment.RefundedAmount
+	stored.Refunds = slices.Clone(payment.Refunds)
+	stored.UpdatedAt = payment.UpdatedAt
+	return nil
+}
+
+func (r *inmemRepository) IsEventProcessed(ctx context.Context, eventID string) (bool, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	_, ok := r.events[eventID]
+	return ok, nil
+}
+
+func (r *inmemRepository) SaveProcessedEvent(ctx context.Context, eventID, eventType string, at time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.events[eventID]; !ok {
+		r.events[eventID] = at
+	}
+	return nil
+}
+
+func (r *inmemRepository) SaveEntries(ctx context.Context, entries []types.LedgerEntry) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, entry := range entries {
+		if r.entryIDs[entry.ID] {
+			continue
+		}
+		stored := entry
+		r.entries = append(r.entries, &stored)
+		r.entryIDs[entry.ID] = true
+	}
+	return nil
+}
+
+func (r *inmemRepository) ListPaymentEntries(ctx context.Context, paymentID string) ([]types.LedgerEntry, error) {
+	return r.listEntries(func(entry *types.LedgerEntry) bool {
+		return entry.PaymentID == paymentID
+	}), nil
+}
+
+func (r *inmemRepository) ListDriverEntries(ctx context.Context, driverID string, from, to time.Time) ([]types.LedgerEntry, error) {
+	return r.listEntries(func(entry *types.LedgerEntry) bool {
+		return entry.DriverID == driverID && !entry.CreatedAt.Before(from) && entry.CreatedAt.Before(to)
+	}), nil
+}
+
+func (r *inmemRepository) ListUnpaidEntries(ctx context.Context, driverID string) ([]types.LedgerEntry, error) {
+	return r.listEntries(func(entry *types.LedgerEntry) bool {
+		return unpaidEntry(entry) && (driverID == "" || entry.Account == types.DriverAccount(driverID))
+	}), nil
+}
+
+func (r *inmemRepository) SettleEntries(ctx context.Context, entryIDs []string, payoutID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	ids := make(map[string]bool, len(entryIDs))
+	for _, id := range entryIDs {
+		ids[id] = true
+	}
+
+	var settled []*types.LedgerEntry
+	for _, entry := range r.entries {
+		if !ids[entry.ID] {
+			continue
+		}
+		if !unpaidEntry(entry) {
+			return fmt.Errorf("%w: %s", domain.ErrEntriesSettled, entry.ID)
+		}
+		settled = append(settled, entry)
+	}
+
+	for _, entry := range settled {
+		entry.PayoutID = payoutID
+	}
+	return nil
+}
+
+func (r *inmemRepository) UnsettleEntries(ctx context.Context, payoutID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, entry := range r.entries {
+		if entry.PayoutID == payoutID && entry.Kind != types.EntryPayout {
+			entry.PayoutID = ""
+		}
+	}
+	return nil
+}
+
+func (r *inmemRepository) SavePayout(ctx context.Context, payout *types.Payout) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored := *payout
+	r.payouts[payout.ID] = &stored
+	return nil
+}
+
+func (r *inmemRepository) GetPaymentMethod(ctx context.Context, userID string) (*types.PaymentMethod, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	method, ok := r.methods[userID]
+	if !ok {
+		return nil, nil
+	}
+	found := *method
+	return &found, nil
+}
+
+func (r *inmemRepository) SavePaymentMethod(ctx context.Context, method *types.PaymentMethod) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored := *method
+	r.methods[method.UserID] = &stored
+	return nil
+}
+
+func (r *inmemRepository) listEntries(match func(entry *types.LedgerEntry) bool) []types.LedgerEntry {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	entries := []types.LedgerEntry{}
+	for _, entry := range r.entries {
+		if match(entry) {
+			entries = append(entries, *entry)
+		}
+	}
+	return entries
+}
+
+// unpaidEntry reports whether the entry is owed to a driver and not settled by a payout yet
+func unpaidEntry(entry *types.LedgerEntry) bool {
+	return strings.HasPrefix(entry.Account, types.DriverAccount("")) && entry.PayoutID == ""
+}
@@ -0,0 +1,272 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"time"
+
+	"ride-sharing/services/payment-service/internal/domain"
+	"ride-sharing/services/payment-service/pkg/types"
+	"ride-sharing/shared/db"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type mongoRepository struct {
+	db *mongo.Database
+}
+
+func NewMongoRepository(db *mongo.Database) *mongoRepository {
+	return &mongoRepository{db: db}
+}
+
+func (r *mongoRepository) SavePayment(ctx context.Context, payment *types.Payment) error {
+	_, err := r.db.Collection(db.PaymentsCollection).InsertOne(ctx, payment)
+	return err
+}
+
+func (r *mongoRepository) GetPaymentByID(ctx context.Context, id string) (*types.Payment, error) {
+	return r.findOne(ctx, bson.M{"_id": id})
+}
+
+func (r *mongoRepository) GetPaymentBySessionID(ctx context.Context, sessionID string) (*types.Payment, error) {
+	return r.findOne(ctx, bson.M{"stripeSessionID": sessionID})
+}
+
+func (r *mongoRepository) GetPaymentByIntentID(ctx context.Context, paymentIntentID string) (*types.Payment, error) {
+	return r.findOne(ctx, bson.M{"paymentIntentID": paymentIntentID})
+}
+
+func (r *mongoRepository) ListPayments(ctx context.Context, userID, tripID string) ([]*types.Payment, error) {
+	filter := bson.M{}
+	if userID != "" {
+		filter["$or"] = bson.A{bson.M{"userID": userID}, bson.M{"driverID": userID}}
+	}
+	if tripID != "" {
+		filter["tripID"] = tripID
+	}
+
+	cursor, err := r.db.Collection(db.PaymentsCollection).Find(ctx, filter, options.Find().SetSort(bson.D{{Key: "createdAt", Value: -1}}))
+	if err != nil {
+		return nil, err
+	}
+
+	payments := []*types.Payment{}
+	if err := cursor.All(ctx, &payments); err != nil {
+		return nil, err
+	}
+
+	return payments, nil
+}
+
+func (r *mongoRepository) UpdatePayment(ctx context.Context, payment *types.Payment) error {
+	result, err := r.db.Collection(db.PaymentsCollection).UpdateOne(ctx,
+		bson.M{"_id": payment.ID},
+		bson.M{"$set": bson.M{
+			"status":          payment.Status,
+			"driverID":        payment.DriverID,
+			"amount":          payment.Amount,
+			"stripeSessionID": payment.StripeSessionID,
+			"paymentIntentID": payment.PaymentIntentID,
+			"paymentMethodID": payment.PaymentMethodID,
+			"updatedAt":       payment.UpdatedAt,
+		}},
+	)
+	if err != nil {
+		return err
+	}
+
+	if result.MatchedCount == 0 {
+		return fmt.Errorf("%w: %s", domain.ErrPaymentNotFound, payment.ID)
+	}
+
+	return nil
+}
+
+func (r *mongoRepository) UpdateRefunds(ctx context.Context, payment *types.Payment, unmodifiedSince time.Time) error {
+	result, err := r.db.Collection(db.PaymentsCollection).UpdateOne(ctx,
+		bson.M{"_id": payment.ID, "updatedAt": unmodifiedSince},
+		bson.M{"$set": bson.M{
+			"status":          payment.Status,
+			"paymentIntentID": payment.PaymentIntentID,
+			"refundedAmount":  payment.RefundedAmount,
+			"refunds":         payment.Refunds,
+			"updatedAt":       payment.UpdatedAt,
+		}},
+	)
+	if err != nil {
+		return err
+	}
+
+	if result.MatchedCount == 0 {
+		return fmt.Errorf("%w: %s", domain.ErrPaymentConflict, payment.ID)
+	}
+
+	return nil
+}
+
+func (r *mongoRepository) IsEventProcessed(ctx context.Context, eventID string) (bool, error) {
+	count, err := r.db.Collection(db.StripeEventsCollection).CountDocuments(ctx, bson.M{"_id": eventID})
+	if err != nil {
+		return false, err
+	}
+
+	return count > 0, nil
+}
+
+func (r *mongoRepository) SaveProcessedEvent(ctx context.Context, eventID, eventType string, at time.Time) error {
+	_, err := r.db.Collection(db.StripeEventsCollection).InsertOne(ctx, bson.M{
+		"_id":         eventID,
+		"type":        eventType,
+		"processedAt": at,
+	})
+	// A concurrent delivery of the same event saved it first
+	if mongo.IsDuplicateKeyError(err) {
+		return nil
+	}
+
+	return err
+}
+
+func (r *mongoRepository) SaveEntries(ctx context.Context, entries []types.LedgerEntry) error {
+	docs := make([]any, len(entries))
+	for i, entry := range entries {
+		docs[i] = entry
+	}
+
+	// Unordered, the entries of a transaction recorded again are rejected as duplicates one by one
+	_, err := r.db.Collection(db.LedgerCollection).InsertMany(ctx, docs, options.InsertMany().SetOrdered(false))
+	if err != nil && !onlyDuplicateKeys(err) {
+		return err
+	}
+
+	return nil
+}
+
+func (r *mongoRepository) ListPaymentEntries(ctx context.Context, paymentID string) ([]types.LedgerEntry, error) {
+	return r.findEntries(ctx, bson.M{"paymentID": paymentID})
+}
+
+func (r *mongoRepository) ListDriverEntries(ctx context.Context, driverID string, from, to time.Time) ([]types.LedgerEntry, error) {
+	return r.findEntries(ctx, bson.M{
+		"driverID":  driverID,
+		"createdAt": bson.M{"$gte": from, "$lt": to},
+	})
+}
+
+func (r *mongoRepository) ListUnpaidEntries(ctx context.Context, driverID string) ([]types.LedgerEntry, error) {
+	filter := bson.M{
+		"account":  bson.M{"$regex": "^" + regexp.QuoteMeta(types.DriverAccount(""))},
+		"payoutID": bson.M{"$exists": false},
+	}
+	if driverID != "" {
+		filter["account"] = types.DriverAccount(driverID)
+	}
+
+	return r.findEntries(ctx, filter)
+}
+
+func (r *mongoRepository) SettleEntries(ctx context.Context, entryIDs []string, payoutID string) error {
+	result, err := r.db.Collection(db.LedgerCollection).UpdateMany(ctx,
+		bson.M{"_id": bson.M{"$in": entryIDs}, "payoutID": bson.M{"$exists": false}},
+		bson.M{"$set": bson.M{"payoutID": payoutID}},
+	)
+	if err != nil {
+		return err
+	}
+
+	// Another payout settled some of the entries in the meantime, the other ones are released
+	if result.ModifiedCount != int64(len(entryIDs)) {
+		if err := r.UnsettleEntries(ctx, payoutID); err != nil {
+			return err
+		}
+		return fmt.Errorf("%w: %d of %d entries", domain.ErrEntriesSettled, int64(len(entryIDs))-result.ModifiedCount, len(entryIDs))
+	}
+
+	return nil
+}
+
+func (r *mongoRepository) UnsettleEntries(ctx context.Context, payoutID string) error {
+	_, err := r.db.Collection(db.LedgerCollection).UpdateMany(ctx,
+		bson.M{"payoutID": payoutID, "kind": bson.M{"$ne": types.EntryPayout}},
+		bson.M{"$unset": bson.M{"payoutID": ""}},
+	)
+	return err
+}
+
+func (r *mongoRepository) SavePayout(ctx context.Context, payout *types.Payout) error {
+	_, err := r.db.Collection(db.PayoutsCollection).InsertOne(ctx, payout)
+	return err
+}
+
+func (r *mongoRepository) GetPaymentMethod(ctx context.Context, userID string) (*types.PaymentMethod, error) {
+	result := r.db.Collection(db.PaymentMethodsCollection).FindOne(ctx, bson.M{"_id": userID})
+	if errors.Is(result.Err(), mongo.ErrNoDocuments) {
+		return nil, nil
+	}
+	if result.Err() != nil {
+		return nil, result.Err()
+	}
+
+	var method types.PaymentMethod
+	if err := result.Decode(&method); err != nil {
+		return nil, err
+	}
+
+	return &method, nil
+}
+
+func (r *mongoRepository) SavePaymentMethod(ctx context.Context, method *types.PaymentMethod) error {
+	_, err := r.db.Collection(db.PaymentMethodsCollection).ReplaceOne(ctx, bson.M{"_id": method.UserID}, method, options.Replace().SetUpsert(true))
+	return err
+}
+
+func (r *mongoRepository) findEntries(ctx context.Context, filter bson.M) ([]types.LedgerEntry, error) {
+	cursor, err := r.db.Collection(db.LedgerCollection).Find(ctx, filter, options.Find().SetSort(bson.D{{Key: "createdAt", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+
+	entries := []types.LedgerEntry{}
+	if err := cursor.All(ctx, &entries); err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+// onlyDuplicateKeys reports whether every write error of a bulk insert is a duplicate key
+func onlyDuplicateKeys(err error) bool {
+	var bulkErr mongo.BulkWriteException
+	if !errors.As(err, &bulkErr) || bulkErr.WriteConcernError != nil {
+		return false
+	}
+
+	for _, writeErr := range bulkErr.WriteErrors {
+		if !mongo.IsDuplicateKeyError(writeErr) {
+			return false
+		}
+	}
+	return true
+}
+
+func (r *mongoRepository) findOne(ctx context.Context, filter bson.M) (*types.Payment, error) {
+	result := r.db.Collection(db.PaymentsCollection).FindOne(ctx, filter)
+	if errors.Is(result.Err(), mongo.ErrNoDocuments) {
+		return nil, nil
+	}
+	if result.Err() != nil {
+		return nil, result.Err()
+	}
+
+	var payment types.Payment
+	if err := result.Decode(&payment); err != nil {
+		return nil, err
+	}
+
+	return &payment, nil
+}
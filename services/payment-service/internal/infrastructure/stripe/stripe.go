@@ -2,15 +2,25 @@ package stripe
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"ride-sharing/services/payment-service/internal/domain"
 	"ride-sharing/services/payment-service/pkg/types"
+	"slices"
 	"time"
 
 	"github.com/stripe/stripe-go/v81"
 	"github.com/stripe/stripe-go/v81/checkout/session"
+	"github.com/stripe/stripe-go/v81/customer"
+	"github.com/stripe/stripe-go/v81/paymentintent"
+	"github.com/stripe/stripe-go/v81/paymentmethod"
+	"github.com/stripe/stripe-go/v81/refund"
+	"github.com/stripe/stripe-go/v81/setupintent"
 )
 
+// requestTimeout bounds the calls to the Stripe API, an unreachable Stripe is reported as an error
+const requestTimeout = 10 * time.Second
+
 type stripeClient struct {
 	config *types.PaymentConfig
 }
@@ -24,15 +34,14 @@ func NewStripeClient(config *types.PaymentConfig) domain.PaymentProcessor {
 }
 
 func (s *stripeClient) CreatePaymentSession(ctx context.Context, amount int64, currency string, metadata map[string]string) (string, error) {
-	if !s.config.UseStripeAPI {
-		// Return mock session immediately if API usage is disabled
-		return "cs_test_mock_session_" + fmt.Sprintf("%d", time.Now().Unix()), nil
-	}
-
 	params := &stripe.CheckoutSessionParams{
 		SuccessURL: stripe.String(s.config.SuccessURL),
 		CancelURL:  stripe.String(s.config.CancelURL),
 		Metadata:   metadata,
+		// The payment intent events and its charges refer to the trip as well
+		PaymentIntentData: &stripe.CheckoutSessionPaymentIntentDataParams{
+			Metadata: metadata,
+		},
 		LineItems: []*stripe.CheckoutSessionLineItemParams{
 			{
 				PriceData: &stripe.CheckoutSessionLineItemPriceDataParams{
@@ -48,33 +57,264 @@ func (s *stripeClient) CreatePaymentSession(ctx context.Context, amount int64, c
 		Mode: stripe.String(string(stripe.CheckoutSessionModePayment)),
 	}
 
-	// Use a channel to handle timeout since we can't easily set HTTP client timeout in this version of stripe-go
-	type sessionResult struct {
-		id  string
-		err error
+	ctx, cancel := context.WithTimeout(ctx, requestTimeout)
+	defer cancel()
+	params.Context = ctx
+
+	result, err := session.New(params)
+	if err != nil {
+		return "", fmt.Errorf("failed to create the checkout session: %v", err)
 	}
 
-	resultCh := make(chan sessionResult, 1)
+	return result.ID, nil
+}
 
-	go func() {
-		result, err := session.New(params)
-		if err != nil {
-			resultCh <- sessionResult{err: err}
-			return
-		}
-		resultCh <- sessionResult{id: result.ID}
-	}()
+// refundReasons are the reasons Stripe knows, any other one is kept in the metadata of the refund
+var refundReasons = []stripe.RefundReason{
+	stripe.RefundReasonDuplicate,
+	stripe.RefundReasonFraudulent,
+	stripe.RefundReasonRequestedByCustomer,
+}
+
+func (s *stripeClient) Refund(ctx context.Context, paymentRef string, amount int64, reason, refundID string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, requestTimeout)
+	defer cancel()
 
-	select {
-	case res := <-resultCh:
-		if res.err != nil {
-			// Fallback for development/offline mode
-			fmt.Printf("Error creating Stripe session (likely due to network restrictions): %v. Returning MOCK session.\n", res.err)
-			return "cs_test_mock_session_" + fmt.Sprintf("%d", time.Now().Unix()), nil
-		}
-		return res.id, nil
-	case <-time.After(2 * time.Second):
-		fmt.Println("Stripe session creation timed out (likely due to network restrictions). Returning MOCK session.")
-		return "cs_test_mock_session_" + fmt.Sprintf("%d", time.Now().Unix()), nil
+	params := &stripe.RefundParams{
+		PaymentIntent: stripe.String(paymentRef),
+		Amount:        stripe.Int64(amount),
 	}
+	params.Context = ctx
+	params.SetIdempotencyKey("refund-" + refundID)
+	params.AddMetadata("refund_id", refundID)
+
+	if slices.Contains(refundReasons, stripe.RefundReason(reason)) {
+		params.Reason = stripe.String(reason)
+	} else if reason != "" {
+		params.AddMetadata("reason", reason)
+	}
+
+	result, err := refund.New(params)
+	if err != nil {
+		return "", fmt.Errorf("failed to refund %s: %v", paymentRef, err)
+	}
+
+	return result.ID, nil
+}
+
+func (s *stripeClient) CreateCustomer(ctx context.Context, userID string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, requestTimeout)
+	defer cancel()
+
+	params := &stripe.CustomerParams{}
+	params.Context = ctx
+	params.AddMetadata("user_id", userID)
+
+	result, err := customer.New(params)
+	if err != nil {
+		return "", fmt.Errorf("failed to create the customer of user %s: %v", userID, err)
+	}
+
+	return result.ID, nil
+}
+
+func (s *stripeClient) CreateSetupIntent(ctx context.Context, customerID string, metadata map[string]string) (*types.SetupIntent, error) {
+	ctx, cancel := context.WithTimeout(ctx, requestTimeout)
+	defer cancel()
+
+	params := &stripe.SetupIntentParams{
+		Customer:           stripe.String(customerID),
+		PaymentMethodTypes: stripe.StringSlice([]string{string(stripe.PaymentMethodTypeCard)}),
+		Usage:              stripe.String(string(stripe.SetupIntentUsageOffSession)),
+		Metadata:           metadata,
+	}
+	params.Context = ctx
+
+	result, err := setupintent.New(params)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create the setup intent: %v", err)
+	}
+
+	return &types.SetupIntent{
+		ID:           result.ID,
+		ClientSecret: result.ClientSecret,
+		CustomerID:   customerID,
+	}, nil
+}
+
+func (s *stripeClient) GetCard(ctx context.Context, paymentMethodID string) (*types.Card, error) {
+	ctx, cancel := context.WithTimeout(ctx, requestTimeout)
+	defer cancel()
+
+	params := &stripe.PaymentMethodParams{}
+	params.Context = ctx
+
+	result, err := paymentmethod.Get(paymentMethodID, params)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get payment method %s: %v", paymentMethodID, err)
+	}
+	if result.Card == nil {
+		return nil, fmt.Errorf("payment method %s is a %s, not a card", paymentMethodID, result.Type)
+	}
+
+	return &types.Card{
+		Brand:    string(result.Card.Brand),
+		Last4:    result.Card.Last4,
+		ExpMonth: result.Card.ExpMonth,
+		ExpYear:  result.Card.ExpYear,
+	}, nil
+}
+
+func (s *stripeClient) DetachPaymentMethod(ctx context.Context, paymentMethodID string) error {
+	ctx, cancel := context.WithTimeout(ctx, requestTimeout)
+	defer cancel()
+
+	params := &stripe.PaymentMethodDetachParams{}
+	params.Context = ctx
+
+	if _, err := paymentmethod.Detach(paymentMethodID, params); err != nil {
+		return fmt.Errorf("failed to detach payment method %s: %v", paymentMethodID, err)
+	}
+
+	return nil
+}
+
+func (s *stripeClient) ChargeOffSession(ctx context.Context, customerID, paymentMethodID string, amount int64, currency string, metadata map[string]string, reference string) (string, types.PaymentStatus, error) {
+	ctx, cancel := context.WithTimeout(ctx, requestTimeout)
+	defer cancel()
+
+	params := &stripe.PaymentIntentParams{
+		Amount:        stripe.Int64(amount),
+		Currency:      stripe.String(currency),
+		Customer:      stripe.String(customerID),
+		PaymentMethod: stripe.String(paymentMethodID),
+		OffSession:    stripe.Bool(true),
+		Confirm:       stripe.Bool(true),
+		Metadata:      metadata,
+	}
+	params.Context = ctx
+	params.SetIdempotencyKey("charge-" + reference)
+
+	result, err := paymentintent.New(params)
+
+	// The bank answers with a card error when the rider has to act
+	var stripeErr *stripe.Error
+	if errors.As(err, &stripeErr) && stripeErr.Code == stripe.ErrorCodeAuthenticationRequired {
+		return "", "", fmt.Errorf("%w: %s", domain.ErrAuthenticationRequired, stripeErr.Msg)
+	}
+	if errors.As(err, &stripeErr) && stripeErr.Type == stripe.ErrorTypeCard {
+		return "", "", fmt.Errorf("%w: %s", domain.ErrCardDeclined, stripeErr.Msg)
+	}
+	if err != nil {
+		return "", "", fmt.Errorf("failed to charge payment method %s: %v", paymentMethodID, err)
+	}
+
+	switch result.Status {
+	case stripe.PaymentIntentStatusSucceeded:
+		return result.ID, types.PaymentStatusSuccess, nil
+	case stripe.PaymentIntentStatusProcessing:
+		return result.ID, types.PaymentStatusPending, nil
+	case stripe.PaymentIntentStatusRequiresAction:
+		return "", "", fmt.Errorf("%w: payment intent %s", domain.ErrAuthenticationRequired, result.ID)
+	default:
+		return "", "", fmt.Errorf("%w: payment intent %s is %s", domain.ErrCardDeclined, result.ID, result.Status)
+	}
+}
+
+func (s *stripeClient) AuthorizeHold(ctx context.Context, customerID, paymentMethodID string, amount int64, currency string, metadata map[string]string, reference string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, requestTimeout)
+	defer cancel()
+
+	params := &stripe.PaymentIntentParams{
+		Amount:        stripe.Int64(amount),
+		Currency:      stripe.String(currency),
+		Customer:      stripe.String(customerID),
+		PaymentMethod: stripe.String(paymentMethodID),
+		CaptureMethod: stripe.String(string(stripe.PaymentIntentCaptureMethodManual)),
+		OffSession:    stripe.Bool(true),
+		Confirm:       stripe.Bool(true),
+		Metadata:      metadata,
+	}
+	params.Context = ctx
+	params.SetIdempotencyKey("hold-" + reference)
+
+	result, err := paymentintent.New(params)
+
+	var stripeErr *stripe.Error
+	if errors.As(err, &stripeErr) && stripeErr.Code == stripe.ErrorCodeAuthenticationRequired {
+		return "", fmt.Errorf("%w: %s", domain.ErrAuthenticationRequired, stripeErr.Msg)
+	}
+	if errors.As(err, &stripeErr) && stripeErr.Type == stripe.ErrorTypeCard {
+		return "", fmt.Errorf("%w: %s", domain.ErrCardDeclined, stripeErr.Msg)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to hold on payment method %s: %v", paymentMethodID, err)
+	}
+
+	switch result.Status {
+	case stripe.PaymentIntentStatusRequiresCapture:
+		return result.ID, nil
+	case stripe.PaymentIntentStatusRequiresAction:
+		return "", fmt.Errorf("%w: payment intent %s", domain.ErrAuthenticationRequired, result.ID)
+	default:
+		return "", fmt.Errorf("%w: payment intent %s is %s", domain.ErrCardDeclined, result.ID, result.Status)
+	}
+}
+
+// CaptureHold looks the payment intent up first, a capture that already went through is not an error
+func (s *stripeClient) CaptureHold(ctx context.Context, paymentIntentID string, amount int64) (types.PaymentStatus, error) {
+	ctx, cancel := context.WithTimeout(ctx, requestTimeout)
+	defer cancel()
+
+	getParams := &stripe.PaymentIntentParams{}
+	getParams.Context = ctx
+
+	intent, err := paymentintent.Get(paymentIntentID, getParams)
+	if err != nil {
+		return "", fmt.Errorf("failed to get payment intent %s: %v", paymentIntentID, err)
+	}
+
+	switch intent.Status {
+	case stripe.PaymentIntentStatusSucceeded:
+		return types.PaymentStatusSuccess, nil
+	case stripe.PaymentIntentStatusProcessing:
+		return types.PaymentStatusPending, nil
+	case stripe.PaymentIntentStatusRequiresCapture:
+	default:
+		// Stripe cancels the holds not captured within 7 days
+		return "", fmt.Errorf("%w: payment intent %s is %s", domain.ErrHoldNotCapturable, paymentIntentID, intent.Status)
+	}
+
+	params := &stripe.PaymentIntentCaptureParams{
+		AmountToCapture: stripe.Int64(amount),
+	}
+	params.Context = ctx
+	params.SetIdempotencyKey("capture-" + paymentIntentID)
+
+	result, err := paymentintent.Capture(paymentIntentID, params)
+	if err != nil {
+		return "", fmt.Errorf("failed to capture payment intent %s: %v", paymentIntentID, err)
+	}
+
+	if result.Status == stripe.PaymentIntentStatusSucceeded {
+		return types.PaymentStatusSuccess, nil
+	}
+	return types.PaymentStatusPending, nil
+}
+
+func (s *stripeClient) ReleaseHold(ctx context.Context, paymentIntentID string) error {
+	ctx, cancel := context.WithTimeout(ctx, requestTimeout)
+	defer cancel()
+
+	params := &stripe.PaymentIntentCancelParams{
+		CancellationReason: stripe.String(string(stripe.PaymentIntentCancellationReasonAbandoned)),
+	}
+	params.Context = ctx
+	params.SetIdempotencyKey("release-" + paymentIntentID)
+
+	if _, err := paymentintent.Cancel(paymentIntentID, params); err != nil {
+		return fmt.Errorf("failed to release payment intent %s: %v", paymentIntentID, err)
+	}
+
+	return nil
 }
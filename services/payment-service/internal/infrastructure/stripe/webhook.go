@@ -0,0 +1,147 @@
+package stripe
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"ride-sharing/services/payment-service/internal/domain"
+	"ride-sharing/services/payment-service/pkg/types"
+
+	"github.com/stripe/stripe-go/v81"
+	"github.com/stripe/stripe-go/v81/webhook"
+)
+
+// webhookStatuses are the events that change a payment, with the status they move it to
+var webhookStatuses = map[stripe.EventType]types.PaymentStatus{
+	stripe.EventTypeCheckoutSessionCompleted:          types.PaymentStatusSuccess,
+	stripe.EventTypeCheckoutSessionAsyncPaymentFailed: types.PaymentStatusFailed,
+	stripe.EventTypeCheckoutSessionExpired:            types.PaymentStatusCancelled,
+	stripe.EventTypePaymentIntentPaymentFailed:        types.PaymentStatusFailed,
+	stripe.EventTypePaymentIntentSucceeded:            types.PaymentStatusSuccess,
+	stripe.EventTypeChargeRefunded:                    types.PaymentStatusRefunded,
+	stripe.EventTypeChargeDisputeCreated:              types.PaymentStatusDisputed,
+}
+
+type webhookParser struct {
+	secret string
+}
+
+func NewWebhookParser(secret string) domain.WebhookParser {
+	return &webhookParser{
+		secret: secret,
+	}
+}
+
+func (p *webhookParser) ParseEvent(payload []byte, signature string) (*types.PaymentEvent, error) {
+	event, err := webhook.ConstructEventWithOptions(payload, signature, p.secret, webhook.ConstructEventOptions{
+		IgnoreAPIVersionMismatch: true,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", domain.ErrInvalidWebhook, err)
+	}
+
+	if event.Type == stripe.EventTypeSetupIntentSucceeded {
+		return parseSetupIntent(event)
+	}
+
+	status, ok := webhookStatuses[event.Type]
+	if !ok {
+		return nil, nil
+	}
+
+	paymentEvent := &types.PaymentEvent{
+		ID:     event.ID,
+		Type:   string(event.Type),
+		Status: status,
+	}
+
+	var metadata map[string]string
+
+	switch event.Type {
+	case stripe.EventTypeCheckoutSessionCompleted, stripe.EventTypeCheckoutSessionAsyncPaymentFailed, stripe.EventTypeCheckoutSessionExpired:
+		var session stripe.CheckoutSession
+		if err := json.Unmarshal(event.Data.Raw, &session); err != nil {
+			return nil, fmt.Errorf("failed to parse the checkout session: %v", err)
+		}
+		paymentEvent.SessionID = session.ID
+		paymentEvent.PaymentIntentID = paymentIntentID(session.PaymentIntent)
+		metadata = session.Metadata
+
+	case stripe.EventTypePaymentIntentPaymentFailed, stripe.EventTypePaymentIntentSucceeded:
+		var intent stripe.PaymentIntent
+		if err := json.Unmarshal(event.Data.Raw, &intent); err != nil {
+			return nil, fmt.Errorf("failed to parse the payment intent: %v", err)
+		}
+		// The checkout sessions report their success, and a failed off-session charge already fell
+		// back to a checkout session
+		offSession := intent.Metadata["off_session"] == "true"
+		if (event.Type == stripe.EventTypePaymentIntentSucceeded) != offSession {
+			return nil, nil
+		}
+		paymentEvent.PaymentIntentID = intent.ID
+		metadata = intent.Metadata
+
+	case stripe.EventTypeChargeRefunded:
+		var charge stripe.Charge
+		if err := json.Unmarshal(event.Data.Raw, &charge); err != nil {
+			return nil, fmt.Errorf("failed to parse the charge: %v", err)
+		}
+		// A partial refund leaves the payment as it is
+		if !charge.Refunded {
+			return nil, nil
+		}
+		paymentEvent.PaymentIntentID = paymentIntentID(charge.PaymentIntent)
+		if charge.Refunds != nil {
+			for _, refund := range charge.Refunds.Data {
+				paymentEvent.ProcessorRefundIDs = append(paymentEvent.ProcessorRefundIDs, refund.ID)
+			}
+		}
+		metadata = charge.Metadata
+
+	case stripe.EventTypeChargeDisputeCreated:
+		var dispute stripe.Dispute
+		if err := json.Unmarshal(event.Data.Raw, &dispute); err != nil {
+			return nil, fmt.Errorf("failed to parse the dispute: %v", err)
+		}
+		paymentEvent.PaymentIntentID = paymentIntentID(dispute.PaymentIntent)
+		metadata = dispute.Metadata
+	}
+
+	paymentEvent.PaymentID = metadata["payment_id"]
+	paymentEvent.TripID = metadata["trip_id"]
+	paymentEvent.UserID = metadata["user_id"]
+	paymentEvent.DriverID = metadata["driver_id"]
+
+	return paymentEvent, nil
+}
+
+// parseSetupIntent returns the card saved by a succeeded setup intent, for the user of its metadata
+func parseSetupIntent(event stripe.Event) (*types.PaymentEvent, error) {
+	var intent stripe.SetupIntent
+	if err := json.Unmarshal(event.Data.Raw, &intent); err != nil {
+		return nil, fmt.Errorf("failed to parse the setup intent: %v", err)
+	}
+
+	paymentEvent := &types.PaymentEvent{
+		ID:            event.ID,
+		Type:          string(event.Type),
+		SetupIntentID: intent.ID,
+		UserID:        intent.Metadata["user_id"],
+	}
+	if intent.Customer != nil {
+		paymentEvent.CustomerID = intent.Customer.ID
+	}
+	if intent.PaymentMethod != nil {
+		paymentEvent.PaymentMethodID = intent.PaymentMethod.ID
+	}
+
+	return paymentEvent, nil
+}
+
+// paymentIntentID returns the ID of an expandable payment intent, which is often not expanded
+func paymentIntentID(intent *stripe.PaymentIntent) string {
+	if intent == nil {
+		return ""
+	}
+	return intent.ID
+}
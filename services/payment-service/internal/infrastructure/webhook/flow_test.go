@@ -0,0 +1,315 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"ride-sharing/services/payment-service/internal/domain"
+	"ride-sharing/services/payment-service/pkg/types"
+	"ride-sharing/shared/contracts"
+	"ride-sharing/shared/messaging"
+
+	stripeapi "github.com/stripe/stripe-go/v81"
+)
+
+// The payment paths run offline against the fake processor, which posts its signed webhooks to the
+// handler like Stripe does.
+
+// waitForStatus waits for a webhook of the fake processor to move the payment to the status
+func (s *testPaymentService) waitForStatus(t *testing.T, paymentID string, want types.PaymentStatus) *types.Payment {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		payment, err := s.service.GetPayment(context.Background(), paymentID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if payment.Status == want {
+			return payment
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for payment %s, it is %s", want, payment.Status)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+// saveCard saves a card for the rider through the setup intent of the fake processor
+func (s *testPaymentService) saveCard(t *testing.T, userID string) {
+	t.Helper()
+
+	ctx := context.Background()
+	if _, err := s.service.CreateSetupIntent(ctx, userID); err != nil {
+		t.Fatal(err)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		_, err := s.service.GetPaymentMethod(ctx, userID)
+		if err == nil {
+			return
+		}
+		if !errors.Is(err, domain.ErrPaymentMethodNotFound) {
+			t.Fatal(err)
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for the card to be saved")
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+func TestFakeProcessorSessionPaidThenRefunded(t *testing.T) {
+	ctx := context.Background()
+	s := startTestPaymentService(t, "success")
+	succeeded := s.consume(t, messaging.NotifyPaymentSuccessQueue)
+
+	intent := s.createSession(t, "trip-1")
+	expectMessage(t, succeeded, contracts.PaymentEventSuccess)
+
+	payment := s.waitForStatus(t, intent.ID, types.PaymentStatusSuccess)
+	if payment.PaymentIntentID == "" {
+		t.Fatal("the completed session didn't record its payment intent")
+	}
+
+	earnings, err := s.ledger.GetDriverEarnings(ctx, "driver-1", time.Now().Add(-time.Hour), time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if earnings.Fares != 2500 || earnings.Commission != 500 || earnings.Balance != 2000 {
+		t.Errorf("got fares %d, commission %d, balance %d, want 2500, 500, 2000", earnings.Fares, earnings.Commission, earnings.Balance)
+	}
+
+	payment, refund, err := s.service.RefundPayment(ctx, intent.ID, 0, "the driver never came", "admin-1")
+	if err != nil {
+		t.Fatalf("failed to refund the payment: %v", err)
+	}
+	if payment.Status != types.PaymentStatusRefunded || refund.Amount != 2500 || refund.ProcessorRefundID == "" {
+		t.Errorf("got payment %s refunded by %d with %q, want refunded by 2500", payment.Status, refund.Amount, refund.ProcessorRefundID)
+	}
+
+	earnings, err = s.ledger.GetDriverEarnings(ctx, "driver-1", time.Now().Add(-time.Hour), time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if earnings.Refunds != 2000 || earnings.Balance != 0 {
+		t.Errorf("got refunds %d, balance %d, want 2000, 0", earnings.Refunds, earnings.Balance)
+	}
+
+	// Nothing is left to refund
+	if _, _, err := s.service.RefundPayment(ctx, intent.ID, 0, "again", "admin-1"); err == nil {
+		t.Error("refunded the payment twice")
+	}
+}
+
+func TestFakeProcessorSessionDeclined(t *testing.T) {
+	s := startTestPaymentService(t, "decline")
+	succeeded := s.consume(t, messaging.NotifyPaymentSuccessQueue)
+
+	intent := s.createSession(t, "trip-1")
+	s.waitForStatus(t, intent.ID, types.PaymentStatusFailed)
+	expectNoMessage(t, succeeded)
+}
+
+func TestFakeProcessorTipIsIdempotent(t *testing.T) {
+	ctx := context.Background()
+	s := startTestPaymentService(t, "success")
+	tips := s.consume(t, messaging.NotifyPaymentTipQueue)
+
+	fare := s.createSession(t, "trip-1")
+	s.waitForStatus(t, fare.ID, types.PaymentStatusSuccess)
+
+	tip, err := s.service.AddTip(ctx, "trip-1", "rider-1", "tip-1", 300)
+	if err != nil {
+		t.Fatalf("failed to add the tip: %v", err)
+	}
+
+	// The client retries the tip with the same ID
+	retried, err := s.service.AddTip(ctx, "trip-1", "rider-1", "tip-1", 300)
+	if err != nil {
+		t.Fatalf("failed to retry the tip: %v", err)
+	}
+	if retried.ID != tip.ID || retried.StripeSessionID != tip.StripeSessionID {
+		t.Errorf("got tip %s of session %s, want tip %s of session %s", retried.ID, retried.StripeSessionID, tip.ID, tip.StripeSessionID)
+	}
+
+	expectMessage(t, tips, contracts.PaymentEventTipReceived)
+	s.waitForStatus(t, tip.ID, types.PaymentStatusSuccess)
+	expectNoMessage(t, tips)
+}
+
+func TestFakeProcessorChargesTheFareAboveTheHold(t *testing.T) {
+	ctx := context.Background()
+	s := startTestPaymentService(t, "success")
+	s.saveCard(t, "rider-1")
+
+	// 2000 quoted, 2400 held with the buffer of 20%
+	hold, err := s.service.AuthorizeHold(ctx, "trip-1", "rider-1", "sedan", 2000, "usd")
+	if err != nil {
+		t.Fatalf("failed to hold the fare: %v", err)
+	}
+	if hold.Status != types.PaymentStatusAuthorized || hold.HeldAmount != 2400 {
+		t.Fatalf("got hold %s of %d, want authorized for 2400", hold.Status, hold.HeldAmount)
+	}
+
+	intents, err := s.service.CreatePaymentSession(ctx, "trip-1", "rider-1", "driver-1", "sedan", 3000, "usd")
+	if err != nil {
+		t.Fatalf("failed to charge the fare: %v", err)
+	}
+	if len(intents) != 2 || intents[0].ID != hold.ID || intents[0].Amount != 2400 || intents[1].Amount != 600 {
+		t.Fatalf("got %+v, want the hold captured for 2400 and a charge of 600", intents)
+	}
+
+	// A redelivered command charges nothing more
+	again, err := s.service.CreatePaymentSession(ctx, "trip-1", "rider-1", "driver-1", "sedan", 3000, "usd")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(again) != 2 || again[0].ID != intents[0].ID || again[1].ID != intents[1].ID {
+		t.Errorf("got %+v, want the same payments", again)
+	}
+
+	remainder := s.waitForStatus(t, intents[1].ID, types.PaymentStatusSuccess)
+	if remainder.HoldPaymentID != hold.ID {
+		t.Errorf("got remainder of hold %q, want %s", remainder.HoldPaymentID, hold.ID)
+	}
+	s.waitForStatus(t, hold.ID, types.PaymentStatusSuccess)
+}
+
+func TestFakeProcessorHoldRequiresACard(t *testing.T) {
+	s := startTestPaymentService(t, "success")
+
+	hold, err := s.service.AuthorizeHold(context.Background(), "trip-1", "rider-1", "sedan", 2000, "usd")
+	if !errors.Is(err, domain.ErrPaymentMethodNotFound) {
+		t.Fatalf("got hold %+v (%v), want the rider without a card to be refused", hold, err)
+	}
+}
+
+// chargeRefunded is the charge.refunded event of a fully refunded charge listing its refunds
+func chargeRefunded(payment *types.Payment, refundIDs ...string) map[string]any {
+	refunds := []map[string]any{}
+	for _, id := range refundIDs {
+		refunds = append(refunds, map[string]any{"id": id, "object": "refund"})
+	}
+
+	return map[string]any{
+		"id":             "ch_" + payment.ID,
+		"object":         "charge",
+		"refunded":       true,
+		"payment_intent": payment.PaymentIntentID,
+		"refunds":        map[string]any{"object": "list", "data": refunds},
+		"metadata":       map[string]string{"payment_id": payment.ID, "trip_id": payment.TripID},
+	}
+}
+
+func TestFakeProcessorRefundIsRecordedOnce(t *testing.T) {
+	ctx := context.Background()
+	s := startTestPaymentService(t, "success")
+
+	fare := s.createSession(t, "trip-1")
+	s.waitForStatus(t, fare.ID, types.PaymentStatusSuccess)
+
+	payment, refund, err := s.service.RefundPayment(ctx, fare.ID, 1000, "late", "admin-1")
+	if err != nil {
+		t.Fatalf("failed to refund the payment: %v", err)
+	}
+
+	// The rest is refunded from the Stripe dashboard, the webhook lists both refunds
+	if code := s.deliver(t, testWebhookSecret, time.Now(), "evt_1", stripeapi.EventTypeChargeRefunded, chargeRefunded(payment, refund.ProcessorRefundID, "re_dashboard")); code != http.StatusOK {
+		t.Fatalf("got %d, want 200", code)
+	}
+
+	payment = s.expectStatus(t, fare.ID, types.PaymentStatusRefunded)
+	if len(payment.Refunds) != 2 || payment.RefundedAmount != 2500 {
+		t.Fatalf("got %d refunds of %d, want 2 of 2500", len(payment.Refunds), payment.RefundedAmount)
+	}
+	if got := payment.Refunds[1]; got.Amount != 1500 || got.ProcessorRefundID != "re_dashboard" {
+		t.Errorf("got refund %+v, want the 1500 of re_dashboard", got)
+	}
+
+	// Stripe sends the event again
+	s.deliver(t, testWebhookSecret, time.Now(), "evt_2", stripeapi.EventTypeChargeRefunded, chargeRefunded(payment, refund.ProcessorRefundID, "re_dashboard"))
+
+	earnings, err := s.ledger.GetDriverEarnings(ctx, "driver-1", time.Now().Add(-time.Hour), time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if earnings.Refunds != 2000 || earnings.Balance != 0 {
+		t.Errorf("got refunds %d, balance %d, want 2000, 0", earnings.Refunds, earnings.Balance)
+	}
+}
+
+func TestFakeProcessorRefundedWebhookOfARecordedRefund(t *testing.T) {
+	ctx := context.Background()
+	s := startTestPaymentService(t, "success")
+
+	fare := s.createSession(t, "trip-1")
+	s.waitForStatus(t, fare.ID, types.PaymentStatusSuccess)
+
+	payment, refund, err := s.service.RefundPayment(ctx, fare.ID, 0, "the driver never came", "admin-1")
+	if err != nil {
+		t.Fatalf("failed to refund the payment: %v", err)
+	}
+
+	if code := s.deliver(t, testWebhookSecret, time.Now(), "evt_1", stripeapi.EventTypeChargeRefunded, chargeRefunded(payment, refund.ProcessorRefundID)); code != http.StatusOK {
+		t.Fatalf("got %d, want 200", code)
+	}
+
+	payment = s.expectStatus(t, fare.ID, types.PaymentStatusRefunded)
+	if len(payment.Refunds) != 1 {
+		t.Errorf("got %d refunds, want 1", len(payment.Refunds))
+	}
+
+	earnings, err := s.ledger.GetDriverEarnings(ctx, "driver-1", time.Now().Add(-time.Hour), time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if earnings.Refunds != 2000 {
+		t.Errorf("got refunds %d, want 2000", earnings.Refunds)
+	}
+}
+
+func TestRefundPaymentConcurrently(t *testing.T) {
+	ctx := context.Background()
+	s := startTestPaymentService(t, "success")
+
+	fare := s.createSession(t, "trip-1")
+	s.waitForStatus(t, fare.ID, types.PaymentStatusSuccess)
+
+	// Five admins refund 1000 of the 2500 at once, only two refunds fit
+	var wg sync.WaitGroup
+	errs := make(chan error, 5)
+	for range 5 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, _, err := s.service.RefundPayment(ctx, fare.ID, 1000, "late", "admin-1")
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	refunded := 0
+	for err := range errs {
+		switch {
+		case err == nil:
+			refunded++
+		case !errors.Is(err, domain.ErrInvalidRefund) && !errors.Is(err, domain.ErrPaymentConflict):
+			t.Errorf("got %v, want an invalid refund", err)
+		}
+	}
+	if refunded != 2 {
+		t.Errorf("got %d refunds, want 2", refunded)
+	}
+
+	payment := s.expectStatus(t, fare.ID, types.PaymentStatusPartiallyRefunded)
+	if payment.RefundedAmount != 2000 || len(payment.Refunds) != 2 {
+		t.Errorf("got %d refunds of %d, want 2 of 2000", len(payment.Refunds), payment.RefundedAmount)
+	}
+}
@@ -0,0 +1,223 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+
+	"ride-sharing/services/payment-service/internal/domain"
+	"ride-sharing/services/payment-service/internal/events"
+	"ride-sharing/services/payment-service/pkg/types"
+	"ride-sharing/shared/messaging"
+)
+
+// maxBodyBytes bounds the payloads read, the Stripe events are a few kilobytes
+const maxBodyBytes = 256 << 10
+
+// Handler receives the Stripe webhooks, proxied by the gateway. Stripe retries the deliveries
+// answered with an error, the events that can't be processed are acknowledged. An event is recorded
+// once handled, so that its redeliveries are ignored.
+type Handler struct {
+	parser    domain.WebhookParser
+	service   domain.Service
+	publisher *events.PaymentEventPublisher
+}
+
+// NewHandler creates the webhook handler, parser is nil when no webhook secret is configured
+func NewHandler(parser domain.WebhookParser, service domain.Service, publisher *events.PaymentEventPublisher) *Handler {
+	return &Handler{
+		parser:    parser,
+		service:   service,
+		publisher: publisher,
+	}
+}
+
+func (h *Handler) HandleStripeWebhook(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if h.parser == nil {
+		log.Printf("Rejected a Stripe webhook: STRIPE_WEBHOOK_KEY is not set")
+		http.Error(w, "webhook is not configured", http.StatusServiceUnavailable)
+		return
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxBodyBytes))
+	if err != nil {
+		http.Error(w, "failed to read request body", http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	event, err := h.parser.ParseEvent(body, r.Header.Get("Stripe-Signature"))
+	if errors.Is(err, domain.ErrInvalidWebhook) {
+		log.Printf("Error verifying webhook signature: %v", err)
+		http.Error(w, "invalid signature", http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		log.Printf("Error parsing webhook: %v", err)
+		http.Error(w, "invalid payload", http.StatusBadRequest)
+		return
+	}
+
+	// Not an event we handle
+	if event == nil {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	if event.SetupIntentID != "" {
+		h.saveCard(w, r, event)
+		return
+	}
+
+	ctx := r.Context()
+
+	payload := messaging.PaymentStatusUpdateData{
+		TripID:    event.TripID,
+		UserID:    event.UserID,
+		DriverID:  event.DriverID,
+		SessionID: event.SessionID,
+	}
+
+	payment, err := h.service.HandlePaymentEvent(ctx, event)
+	switch {
+	case errors.Is(err, domain.ErrDuplicateEvent):
+		log.Printf("Ignored Stripe event %s: %v", event.ID, err)
+		w.WriteHeader(http.StatusOK)
+		return
+	case errors.Is(err, domain.ErrStaleEvent):
+		log.Printf("Ignored Stripe event %s: %v", event.ID, err)
+		h.recordEvent(ctx, event)
+		w.WriteHeader(http.StatusOK)
+		return
+	case errors.Is(err, domain.ErrPaymentNotFound):
+		// The session may predate the payment records, the metadata still tells the trip
+		if event.TripID == "" {
+			log.Printf("Ignored Stripe event %s: %v", event.ID, err)
+			h.recordEvent(ctx, event)
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		log.Printf("No payment recorded for trip %s, publishing the %s event from the metadata", event.TripID, event.Type)
+	case err != nil:
+		log.Printf("Failed to handle Stripe event %s: %v", event.ID, err)
+		http.Error(w, "failed to handle the event", http.StatusInternalServerError)
+		return
+	default:
+		payload = messaging.PaymentStatusUpdateData{
+			TripID:    payment.TripID,
+			UserID:    payment.UserID,
+			DriverID:  payment.DriverID,
+			SessionID: payment.StripeSessionID,
+		}
+	}
+
+	// The tips don't change the trip, only the driver is told about them
+	if payment != nil && payment.PaymentType() == types.PaymentTypeTip {
+		h.publishTip(w, r, event, payment)
+		return
+	}
+
+	if event.Status == types.PaymentStatusRefunded {
+		h.publishRefund(w, r, event, payment)
+		return
+	}
+
+	if err := h.publisher.PublishPaymentStatus(ctx, event.Status, payload); err != nil {
+		log.Printf("Error publishing payment event: %v", err)
+		http.Error(w, "failed to publish payment event", http.StatusInternalServerError)
+		return
+	}
+
+	// The rider who requested the trip is told when another rider doesn't pay their share
+	if payment != nil && payment.TripOwnerID != "" && (payment.Status == types.PaymentStatusFailed || payment.Status == types.PaymentStatusCancelled) {
+		if err := h.publisher.PublishSplitFailed(ctx, payment); err != nil {
+			log.Printf("Error publishing payment event: %v", err)
+			http.Error(w, "failed to publish payment event", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	h.recordEvent(ctx, event)
+	w.WriteHeader(http.StatusOK)
+}
+
+// publishRefund publishes the refund recorded for a charge.refunded event. Without payment there
+// is no amount to report, the event is only recorded.
+func (h *Handler) publishRefund(w http.ResponseWriter, r *http.Request, event *types.PaymentEvent, payment *types.Payment) {
+	ctx := r.Context()
+
+	if payment == nil || len(payment.Refunds) == 0 {
+		log.Printf("Ignored Stripe event %s: no payment recorded for trip %s", event.ID, event.TripID)
+		h.recordEvent(ctx, event)
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	refund := payment.Refunds[len(payment.Refunds)-1]
+	if err := h.publisher.PublishRefund(ctx, payment, refund.Amount, refund.Reason); err != nil {
+		log.Printf("Error publishing payment event: %v", err)
+		http.Error(w, "failed to publish payment event", http.StatusInternalServerError)
+		return
+	}
+
+	h.recordEvent(ctx, event)
+	w.WriteHeader(http.StatusOK)
+}
+
+// publishTip notifies the driver of a paid tip, the other outcomes of a tip are only recorded
+func (h *Handler) publishTip(w http.ResponseWriter, r *http.Request, event *types.PaymentEvent, payment *types.Payment) {
+	ctx := r.Context()
+
+	if event.Status == types.PaymentStatusSuccess {
+		if err := h.publisher.PublishTipReceived(ctx, payment); err != nil {
+			log.Printf("Error publishing payment event: %v", err)
+			http.Error(w, "failed to publish payment event", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	h.recordEvent(ctx, event)
+	w.WriteHeader(http.StatusOK)
+}
+
+// saveCard saves the card of a succeeded setup intent, saving it again is a no-op
+func (h *Handler) saveCard(w http.ResponseWriter, r *http.Request, event *types.PaymentEvent) {
+	ctx := r.Context()
+
+	method, err := h.service.SavePaymentMethod(ctx, event)
+	if errors.Is(err, domain.ErrDuplicateEvent) {
+		log.Printf("Ignored Stripe event %s: %v", event.ID, err)
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	if errors.Is(err, domain.ErrPaymentMethodNotFound) {
+		log.Printf("Ignored Stripe event %s: %v", event.ID, err)
+		h.recordEvent(ctx, event)
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to handle Stripe event %s: %v", event.ID, err)
+		http.Error(w, "failed to handle the event", http.StatusInternalServerError)
+		return
+	}
+
+	log.Printf("Saved payment method %s of user %s", method.PaymentMethodID, method.UserID)
+	h.recordEvent(ctx, event)
+	w.WriteHeader(http.StatusOK)
+}
+
+// recordEvent marks the event as processed. Failing to do so only means that a redelivery is
+// published again, which the consumers of the payment events tolerate.
+func (h *Handler) recordEvent(ctx context.Context, event *types.PaymentEvent) {
+	if err := h.service.RecordPaymentEvent(ctx, event); err != nil {
+		log.Printf("Failed to record Stripe event %s: %v", event.ID, err)
+	}
+}
@@ -0,0 +1,308 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"ride-sharing/services/payment-service/internal/domain"
+	"ride-sharing/services/payment-service/internal/events"
+	"ride-sharing/services/payment-service/internal/infrastructure/fake"
+	"ride-sharing/services/payment-service/internal/infrastructure/repository"
+	"ride-sharing/services/payment-service/internal/infrastructure/stripe"
+	"ride-sharing/services/payment-service/internal/service"
+	"ride-sharing/services/payment-service/pkg/types"
+	"ride-sharing/shared/contracts"
+	"ride-sharing/shared/messaging"
+
+	"github.com/rabbitmq/amqp091-go"
+	stripeapi "github.com/stripe/stripe-go/v81"
+	"github.com/stripe/stripe-go/v81/webhook"
+)
+
+const testWebhookSecret = "whsec_test"
+
+type testPaymentService struct {
+	handler *Handler
+	service domain.Service
+	ledger  domain.LedgerService
+	broker  *messaging.InmemBroker
+}
+
+// startTestPaymentService wires the payment service on a new broker like cmd/main.go, the fake
+// processor follows the script and posts its webhooks to the handler
+func startTestPaymentService(t *testing.T, script string) *testPaymentService {
+	t.Helper()
+
+	steps, err := fake.ParseScript(script)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := &testPaymentService{broker: messaging.NewInmemBroker()}
+	t.Cleanup(func() { s.broker.Close() })
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.handler.HandleStripeWebhook(w, r)
+	}))
+	t.Cleanup(server.Close)
+
+	repo := repository.NewInmemRepository()
+	s.ledger = service.NewLedgerService(repo, fake.NewFakePayoutProvider(), types.CommissionRates{Default: 20})
+	processor := fake.NewFakeProcessor(steps, server.URL, testWebhookSecret)
+	s.service = service.NewPaymentService(processor, repo, repo, s.ledger, types.TipLimits{Min: 100, Max: 10000}, types.HoldConfig{BufferPercent: 20, RequireCard: true})
+	s.handler = NewHandler(stripe.NewWebhookParser(testWebhookSecret), s.service, events.NewPaymentEventPublisher(s.broker))
+
+	return s
+}
+
+// consume collects the messages routed to the queue
+func (s *testPaymentService) consume(t *testing.T, queue string) <-chan amqp091.Delivery {
+	t.Helper()
+
+	deliveries := make(chan amqp091.Delivery, 16)
+	err := s.broker.ConsumeMessages(queue, func(ctx context.Context, msg amqp091.Delivery) error {
+		deliveries <- msg
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("failed to consume %s: %v", queue, err)
+	}
+	return deliveries
+}
+
+// createSession creates the checkout session of the fare of a trip, the rider has no saved card
+func (s *testPaymentService) createSession(t *testing.T, tripID string) *types.PaymentIntent {
+	t.Helper()
+
+	intents, err := s.service.CreatePaymentSession(context.Background(), tripID, "rider-1", "driver-1", "sedan", 2500, "usd")
+	if err != nil {
+		t.Fatalf("failed to create the payment session: %v", err)
+	}
+	if len(intents) != 1 || intents[0].StripeSessionID == "" {
+		t.Fatalf("got %+v, want one checkout session", intents)
+	}
+	return intents[0]
+}
+
+// deliver posts the event signed with secret at the time, like Stripe does, and returns the status
+// of the answer
+func (s *testPaymentService) deliver(t *testing.T, secret string, at time.Time, eventID string, eventType stripeapi.EventType, object map[string]any) int {
+	t.Helper()
+
+	payload, err := json.Marshal(map[string]any{
+		"id":          eventID,
+		"object":      "event",
+		"type":        eventType,
+		"api_version": stripeapi.APIVersion,
+		"created":     at.Unix(),
+		"data":        map[string]any{"object": object},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	signed := webhook.GenerateTestSignedPayload(&webhook.UnsignedPayload{
+		Payload:   payload,
+		Secret:    secret,
+		Timestamp: at,
+	})
+
+	r := httptest.NewRequest(http.MethodPost, "/webhook/stripe", bytes.NewReader(signed.Payload))
+	r.Header.Set("Stripe-Signature", signed.Header)
+	w := httptest.NewRecorder()
+	s.handler.HandleStripeWebhook(w, r)
+	return w.Code
+}
+
+// expectStatus checks the status of the payment
+func (s *testPaymentService) expectStatus(t *testing.T, paymentID string, want types.PaymentStatus) *types.Payment {
+	t.Helper()
+
+	payment, err := s.service.GetPayment(context.Background(), paymentID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if payment.Status != want {
+		t.Fatalf("got payment %s, want %s", payment.Status, want)
+	}
+	return payment
+}
+
+// expectMessage waits for the next message of the queue, the fake processor sends its webhooks after a second
+func expectMessage(t *testing.T, deliveries <-chan amqp091.Delivery, routingKey string) {
+	t.Helper()
+
+	select {
+	case msg := <-deliveries:
+		if msg.RoutingKey != routingKey {
+			t.Fatalf("got message %s, want %s", msg.RoutingKey, routingKey)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("timed out waiting for message %s", routingKey)
+	}
+}
+
+// expectNoMessage checks that nothing is routed to the queue for a while
+func expectNoMessage(t *testing.T, deliveries <-chan amqp091.Delivery) {
+	t.Helper()
+
+	select {
+	case msg := <-deliveries:
+		t.Fatalf("got unexpected message %s", msg.RoutingKey)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func sessionCompleted(intent *types.PaymentIntent, paymentIntentID string) map[string]any {
+	return map[string]any{
+		"id":             intent.StripeSessionID,
+		"object":         "checkout.session",
+		"payment_intent": paymentIntentID,
+		"metadata":       map[string]string{"payment_id": intent.ID, "trip_id": intent.TripID},
+	}
+}
+
+func TestWebhookRejectsInvalidSignatures(t *testing.T) {
+	// The sessions stay pending, the events are sent by the test
+	s := startTestPaymentService(t, "pending")
+	intent := s.createSession(t, "trip-1")
+
+	tests := []struct {
+		name   string
+		secret string
+		at     time.Time
+	}{
+		{name: "another secret", secret: "whsec_other", at: time.Now()},
+		{name: "replayed after the tolerance", secret: testWebhookSecret, at: time.Now().Add(-time.Hour)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if code := s.deliver(t, tt.secret, tt.at, "evt_"+tt.name, stripeapi.EventTypeCheckoutSessionCompleted, sessionCompleted(intent, "pi_1")); code != http.StatusBadRequest {
+				t.Errorf("got %d, want 400", code)
+			}
+			s.expectStatus(t, intent.ID, types.PaymentStatusPending)
+		})
+	}
+
+	t.Run("no signature", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodPost, "/webhook/stripe", bytes.NewReader([]byte(`{"id": "evt_1", "type": "checkout.session.completed"}`)))
+		w := httptest.NewRecorder()
+		s.handler.HandleStripeWebhook(w, r)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("got %d, want 400", w.Code)
+		}
+	})
+}
+
+func TestWebhookIgnoresReplayedEvents(t *testing.T) {
+	s := startTestPaymentService(t, "pending")
+	succeeded := s.consume(t, messaging.NotifyPaymentSuccessQueue)
+	intent := s.createSession(t, "trip-1")
+
+	if code := s.deliver(t, testWebhookSecret, time.Now(), "evt_1", stripeapi.EventTypeCheckoutSessionCompleted, sessionCompleted(intent, "pi_1")); code != http.StatusOK {
+		t.Fatalf("got %d, want 200", code)
+	}
+	expectMessage(t, succeeded, contracts.PaymentEventSuccess)
+	s.expectStatus(t, intent.ID, types.PaymentStatusSuccess)
+
+	// Stripe retries the event, signed again
+	if code := s.deliver(t, testWebhookSecret, time.Now(), "evt_1", stripeapi.EventTypeCheckoutSessionCompleted, sessionCompleted(intent, "pi_1")); code != http.StatusOK {
+		t.Fatalf("got %d, want 200", code)
+	}
+	expectNoMessage(t, succeeded)
+}
+
+func TestWebhookIgnoresOutOfOrderEvents(t *testing.T) {
+	s := startTestPaymentService(t, "pending")
+	succeeded := s.consume(t, messaging.NotifyPaymentSuccessQueue)
+	refunded := s.consume(t, messaging.NotifyPaymentRefundedQueue)
+
+	t.Run("failure after the success", func(t *testing.T) {
+		intent := s.createSession(t, "trip-1")
+
+		s.deliver(t, testWebhookSecret, time.Now(), "evt_1", stripeapi.EventTypeCheckoutSessionCompleted, sessionCompleted(intent, "pi_1"))
+		expectMessage(t, succeeded, contracts.PaymentEventSuccess)
+
+		late := []struct {
+			eventType stripeapi.EventType
+			object    map[string]any
+		}{
+			{stripeapi.EventTypePaymentIntentPaymentFailed, map[string]any{"id": "pi_1", "object": "payment_intent"}},
+			{stripeapi.EventTypeCheckoutSessionExpired, map[string]any{"id": intent.StripeSessionID, "object": "checkout.session"}},
+		}
+		for _, event := range late {
+			if code := s.deliver(t, testWebhookSecret, time.Now(), "evt_"+string(event.eventType), event.eventType, event.object); code != http.StatusOK {
+				t.Fatalf("%s got %d, want 200", event.eventType, code)
+			}
+			s.expectStatus(t, intent.ID, types.PaymentStatusSuccess)
+		}
+	})
+
+	t.Run("success after the refund", func(t *testing.T) {
+		intent := s.createSession(t, "trip-2")
+
+		// The refund records the charge it refunds, the success reported late changes nothing
+		refund := map[string]any{
+			"id":             "ch_2",
+			"object":         "charge",
+			"refunded":       true,
+			"payment_intent": "pi_2",
+			"metadata":       map[string]string{"payment_id": intent.ID, "trip_id": intent.TripID},
+		}
+		if code := s.deliver(t, testWebhookSecret, time.Now(), "evt_2", stripeapi.EventTypeChargeRefunded, refund); code != http.StatusOK {
+			t.Fatalf("got %d, want 200", code)
+		}
+		expectMessage(t, refunded, contracts.PaymentEventRefunded)
+		s.expectStatus(t, intent.ID, types.PaymentStatusRefunded)
+
+		if code := s.deliver(t, testWebhookSecret, time.Now(), "evt_3", stripeapi.EventTypeCheckoutSessionCompleted, sessionCompleted(intent, "pi_2")); code != http.StatusOK {
+			t.Fatalf("got %d, want 200", code)
+		}
+		expectNoMessage(t, succeeded)
+		s.expectStatus(t, intent.ID, types.PaymentStatusRefunded)
+	})
+}
+
+func TestWebhookPublishesThePaymentsNotMade(t *testing.T) {
+	s := startTestPaymentService(t, "pending")
+	notified := s.consume(t, messaging.NotifyPaymentStatusQueue)
+	disputes := s.consume(t, messaging.TripPaymentDisputedQueue)
+
+	failed := s.createSession(t, "trip-1")
+	if code := s.deliver(t, testWebhookSecret, time.Now(), "evt_1", stripeapi.EventTypeCheckoutSessionAsyncPaymentFailed, sessionCompleted(failed, "pi_1")); code != http.StatusOK {
+		t.Fatalf("got %d, want 200", code)
+	}
+	expectMessage(t, notified, contracts.PaymentEventFailed)
+	s.expectStatus(t, failed.ID, types.PaymentStatusFailed)
+
+	expired := s.createSession(t, "trip-2")
+	if code := s.deliver(t, testWebhookSecret, time.Now(), "evt_2", stripeapi.EventTypeCheckoutSessionExpired, sessionCompleted(expired, "")); code != http.StatusOK {
+		t.Fatalf("got %d, want 200", code)
+	}
+	expectMessage(t, notified, contracts.PaymentEventCancelled)
+	s.expectStatus(t, expired.ID, types.PaymentStatusCancelled)
+
+	// Only the disputes change the trip
+	expectNoMessage(t, disputes)
+
+	disputed := s.createSession(t, "trip-3")
+	s.deliver(t, testWebhookSecret, time.Now(), "evt_3", stripeapi.EventTypeCheckoutSessionCompleted, sessionCompleted(disputed, "pi_3"))
+	dispute := map[string]any{
+		"id":             "dp_3",
+		"object":         "dispute",
+		"payment_intent": "pi_3",
+		"metadata":       map[string]string{"payment_id": disputed.ID, "trip_id": disputed.TripID},
+	}
+	if code := s.deliver(t, testWebhookSecret, time.Now(), "evt_4", stripeapi.EventTypeChargeDisputeCreated, dispute); code != http.StatusOK {
+		t.Fatalf("got %d, want 200", code)
+	}
+	expectMessage(t, notified, contracts.PaymentEventDisputed)
+	expectMessage(t, disputes, contracts.PaymentEventDisputed)
+	s.expectStatus(t, disputed.ID, types.PaymentStatusDisputed)
+}
@@ -0,0 +1,221 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"ride-sharing/services/payment-service/internal/domain"
+	"ride-sharing/services/payment-service/pkg/types"
+
+	"github.com/google/uuid"
+)
+
+// AuthorizeHold holds the quoted fare on the saved card of the rider before the trip is dispatched,
+// the rider can't walk away without paying
+func (s *paymentService) AuthorizeHold(ctx context.Context, tripID, userID, packageSlug string, amount int64, currency string) (*types.Payment, error) {
+	// A retried call gets the hold of the trip back
+	payment, err := s.tripFare(ctx, tripID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if payment != nil {
+		switch {
+		case payment.Status == types.PaymentStatusFailed:
+			return payment, fmt.Errorf("%w: the hold of payment %s was refused", domain.ErrCardDeclined, payment.ID)
+		case payment.Status != types.PaymentStatusPending || payment.PaymentIntentID != "":
+			return payment, nil
+		}
+
+		// The hold failed before the processor answered, it is retried with the same reference
+		method, err := s.methods.GetPaymentMethod(ctx, payment.UserID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get the payment method of user %s: %w", payment.UserID, err)
+		}
+		if method == nil {
+			return nil, fmt.Errorf("%w: user %s", domain.ErrPaymentMethodNotFound, payment.UserID)
+		}
+
+		return payment, s.authorizeHold(ctx, payment, method.CustomerID)
+	}
+
+	method, err := s.methods.GetPaymentMethod(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the payment method of user %s: %w", userID, err)
+	}
+
+	if !method.Saved() {
+		if s.holds.RequireCard {
+			return nil, fmt.Errorf("%w: user %s has no saved card to hold the fare of trip %s", domain.ErrPaymentMethodNotFound, userID, tripID)
+		}
+		return nil, nil
+	}
+
+	now := time.Now()
+	payment = &types.Payment{
+		ID:              uuid.New().String(),
+		TripID:          tripID,
+		UserID:          userID,
+		Type:            types.PaymentTypeFare,
+		PackageSlug:     packageSlug,
+		Amount:          amount,
+		HeldAmount:      s.holds.HoldAmount(amount),
+		Currency:        currency,
+		Status:          types.PaymentStatusPending,
+		PaymentMethodID: method.PaymentMethodID,
+		CreatedAt:       now,
+		UpdatedAt:       now,
+	}
+
+	// Saved before the hold, a retry finds the payment and reuses its reference
+	if err := s.repo.SavePayment(ctx, payment); err != nil {
+		return nil, fmt.Errorf("failed to save payment: %w", err)
+	}
+
+	return payment, s.authorizeHold(ctx, payment, method.CustomerID)
+}
+
+// authorizeHold places the hold of the saved payment. A hold refused by the bank fails the payment,
+// the error tells why.
+func (s *paymentService) authorizeHold(ctx context.Context, payment *types.Payment, customerID string) error {
+	metadata := paymentMetadata(payment)
+	metadata["off_session"] = "true"
+
+	intentID, err := s.paymentProcessor.AuthorizeHold(ctx, customerID, payment.PaymentMethodID, payment.HeldAmount, payment.Currency, metadata, payment.ID)
+	refused := errors.Is(err, domain.ErrAuthenticationRequired) || errors.Is(err, domain.ErrCardDeclined)
+	if err != nil && !refused {
+		return fmt.Errorf("failed to hold payment %s: %w", payment.ID, err)
+	}
+
+	payment.Status = types.PaymentStatusAuthorized
+	payment.PaymentIntentID = intentID
+	if refused {
+		log.Printf("The hold of payment %s was refused: %v", payment.ID, err)
+		payment.Status = types.PaymentStatusFailed
+	}
+	payment.UpdatedAt = time.Now()
+
+	if updateErr := s.repo.UpdatePayment(ctx, payment); updateErr != nil {
+		return fmt.Errorf("failed to update payment: %w", updateErr)
+	}
+
+	return err
+}
+
+// captureHold charges the final fare from the hold, it can't take more than what was held: farePayment
+// charges the rest with chargeRemainder. A hold that expired falls back to a checkout session of the fare.
+func (s *paymentService) captureHold(ctx context.Context, payment *types.Payment, amount int64) error {
+	payment.Amount = min(amount, payment.HeldAmount)
+	payment.UpdatedAt = time.Now()
+
+	// Saved before the capture, the webhook of the captured payment intent may come first
+	if err := s.repo.UpdatePayment(ctx, payment); err != nil {
+		return fmt.Errorf("failed to update payment: %w", err)
+	}
+
+	status, err := s.paymentProcessor.CaptureHold(ctx, payment.PaymentIntentID, payment.Amount)
+	if errors.Is(err, domain.ErrHoldNotCapturable) {
+		log.Printf("Falling back to a checkout session for payment %s: %v", payment.ID, err)
+		payment.Amount = amount
+		payment.Status = types.PaymentStatusPending
+		payment.PaymentIntentID = ""
+		return s.checkoutFallback(ctx, payment)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to capture the hold of payment %s: %w", payment.ID, err)
+	}
+
+	payment.Status = status
+	payment.UpdatedAt = time.Now()
+
+	if err := s.repo.UpdatePayment(ctx, payment); err != nil {
+		return fmt.Errorf("failed to update payment: %w", err)
+	}
+
+	if payment.Status == types.PaymentStatusSuccess {
+		if err := s.ledger.RecordCharge(ctx, payment); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// chargeRemainder charges the part of the fare above the hold of the payment to the saved card of the
+// rider, or creates its checkout session. A redelivered command gets the charge back.
+func (s *paymentService) chargeRemainder(ctx context.Context, hold *types.Payment, amount int64) (*types.Payment, error) {
+	id := remainderPaymentID(hold.ID)
+	remainder, err := s.repo.GetPaymentByID(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get payment %s: %w", id, err)
+	}
+
+	if remainder != nil {
+		return remainder, s.retryCharge(ctx, remainder)
+	}
+
+	log.Printf("The fare of payment %s is above its hold, charging the remaining %d", hold.ID, amount)
+
+	now := time.Now()
+	remainder = &types.Payment{
+		ID:            id,
+		TripID:        hold.TripID,
+		UserID:        hold.UserID,
+		DriverID:      hold.DriverID,
+		Type:          types.PaymentTypeFare,
+		PackageSlug:   hold.PackageSlug,
+		Amount:        amount,
+		Currency:      hold.Currency,
+		Status:        types.PaymentStatusPending,
+		TripOwnerID:   hold.TripOwnerID,
+		HoldPaymentID: hold.ID,
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	}
+
+	if err := s.createPayment(ctx, remainder); err != nil {
+		return nil, err
+	}
+
+	return remainder, nil
+}
+
+// remainderPaymentID derives the ID of the charge of the remainder from the payment of the hold, it is
+// the idempotency key of the charge as well
+func remainderPaymentID(holdPaymentID string) string {
+	return uuid.NewSHA1(uuid.NameSpaceURL, []byte("remainder:"+holdPaymentID)).String()
+}
+
+// ReleaseHold cancels the hold of a trip that no driver accepted
+func (s *paymentService) ReleaseHold(ctx context.Context, tripID string) (*types.Payment, error) {
+	payment, err := s.tripFare(ctx, tripID, "")
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case payment == nil || payment.HeldAmount == 0:
+		return nil, nil
+	case payment.Status == types.PaymentStatusCancelled:
+		// A redelivered command, the hold was released already
+		return payment, nil
+	case payment.Status != types.PaymentStatusAuthorized:
+		return nil, nil
+	}
+
+	if err := s.paymentProcessor.ReleaseHold(ctx, payment.PaymentIntentID); err != nil {
+		return nil, fmt.Errorf("failed to release the hold of payment %s: %w", payment.ID, err)
+	}
+
+	payment.Status = types.PaymentStatusCancelled
+	payment.UpdatedAt = time.Now()
+
+	if err := s.repo.UpdatePayment(ctx, payment); err != nil {
+		return nil, fmt.Errorf("failed to update payment: %w", err)
+	}
+
+	return payment, nil
+}
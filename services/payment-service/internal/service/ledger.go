@@ -0,0 +1,279 @@
+package service
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"time"
+
+	"ride-sharing/services/payment-service/internal/domain"
+	"ride-sharing/services/payment-service/pkg/types"
+
+	"github.com/google/uuid"
+)
+
+type ledgerService struct {
+	repo       domain.LedgerRepository
+	payouts    domain.PayoutProvider
+	commission types.CommissionRates
+}
+
+// NewLedgerService creates the service keeping the ledger of the payments and paying the drivers out
+func NewLedgerService(repo domain.LedgerRepository, payouts domain.PayoutProvider, commission types.CommissionRates) domain.LedgerService {
+	return &ledgerService{
+		repo:       repo,
+		payouts:    payouts,
+		commission: commission,
+	}
+}
+
+func (s *ledgerService) RecordCharge(ctx context.Context, payment *types.Payment) error {
+	// The transaction ID is derived from the payment, a redelivered webhook doesn't charge twice
+	tx := newTransaction(chargeTransactionID(payment), payment)
+
+	if payment.PaymentType() == types.PaymentTypeTip {
+		// The whole tip goes to the driver
+		tx.add(types.AccountCash, types.EntryTip, payment.Amount)
+		tx.add(types.DriverAccount(payment.DriverID), types.EntryTip, -payment.Amount)
+	} else {
+		commission := payment.Amount * int64(s.commission.Percent(payment.PackageSlug)) / 100
+		tx.add(types.AccountCash, types.EntryCharge, payment.Amount)
+		tx.add(types.AccountCommission, types.EntryCommission, -commission)
+		tx.add(types.DriverAccount(payment.DriverID), types.EntryEarning, -(payment.Amount - commission))
+	}
+
+	if err := s.repo.SaveEntries(ctx, tx.entries); err != nil {
+		return fmt.Errorf("failed to record the charge of payment %s: %w", payment.ID, err)
+	}
+
+	return nil
+}
+
+func (s *ledgerService) RecordRefund(ctx context.Context, payment *types.Payment, refund *types.Refund) error {
+	entries, err := s.repo.ListPaymentEntries(ctx, payment.ID)
+	if err != nil {
+		return fmt.Errorf("failed to get the entries of payment %s: %w", payment.ID, err)
+	}
+
+	var charged, earned int64
+	for _, entry := range entries {
+		if entry.TransactionID != chargeTransactionID(payment) {
+			continue
+		}
+		switch entry.Account {
+		case types.AccountCash:
+			charged += entry.Amount
+		case types.DriverAccount(payment.DriverID):
+			earned -= entry.Amount
+		}
+	}
+
+	if charged == 0 {
+		return fmt.Errorf("the charge of payment %s is not in the ledger", payment.ID)
+	}
+
+	driverShare := refund.Amount * earned / charged
+
+	tx := newTransaction("refund:"+refund.ID, payment)
+	tx.add(types.AccountCash, types.EntryRefund, -refund.Amount)
+	tx.add(types.AccountCommission, types.EntryRefund, refund.Amount-driverShare)
+	tx.add(types.DriverAccount(payment.DriverID), types.EntryRefund, driverShare)
+
+	if err := s.repo.SaveEntries(ctx, tx.entries); err != nil {
+		return fmt.Errorf("failed to record refund %s of payment %s: %w", refund.ID, payment.ID, err)
+	}
+
+	return nil
+}
+
+func (s *ledgerService) GetDriverEarnings(ctx context.Context, driverID string, from, to time.Time) (*types.DriverEarnings, error) {
+	entries, err := s.repo.ListDriverEntries(ctx, driverID, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list the entries of driver %s: %w", driverID, err)
+	}
+
+	unpaid, err := s.repo.ListUnpaidEntries(ctx, driverID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list the unpaid entries of driver %s: %w", driverID, err)
+	}
+
+	earnings := &types.DriverEarnings{
+		DriverID: driverID,
+		From:     from,
+		To:       to,
+		Entries:  []types.LedgerEntry{},
+	}
+
+	trips := map[string]bool{}
+	account := types.DriverAccount(driverID)
+	for _, entry := range entries {
+		earnings.Currency = entry.Currency
+
+		switch {
+		case entry.Kind == types.EntryCharge:
+			earnings.Fares += entry.Amount
+			trips[entry.TripID] = true
+		case entry.Kind == types.EntryCommission:
+			earnings.Commission -= entry.Amount
+		case entry.Account != account:
+			// The cash and commission sides of the refunds and payouts
+			continue
+		case entry.Kind == types.EntryEarning:
+			earnings.Earnings -= entry.Amount
+		case entry.Kind == types.EntryTip:
+			earnings.Tips -= entry.Amount
+		case entry.Kind == types.EntryRefund:
+			earnings.Refunds += entry.Amount
+		case entry.Kind == types.EntryPayout:
+			earnings.PaidOut += entry.Amount
+		}
+
+		if entry.Account == account {
+			earnings.Entries = append(earnings.Entries, entry)
+		}
+	}
+	earnings.Trips = len(trips)
+
+	for _, entry := range unpaid {
+		earnings.Balance -= entry.Amount
+	}
+
+	return earnings, nil
+}
+
+func (s *ledgerService) RunPayouts(ctx context.Context) ([]*types.Payout, error) {
+	entries, err := s.repo.ListUnpaidEntries(ctx, "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list the unpaid entries: %w", err)
+	}
+
+	// The balance of every driver, per currency
+	type account struct {
+		driverID string
+		currency string
+	}
+	balances := map[account]int64{}
+	entryIDs := map[account][]string{}
+	for _, entry := range entries {
+		key := account{driverID: entry.DriverID, currency: entry.Currency}
+		balances[key] -= entry.Amount
+		entryIDs[key] = append(entryIDs[key], entry.ID)
+	}
+
+	accounts := make([]account, 0, len(balances))
+	for key := range balances {
+		accounts = append(accounts, key)
+	}
+	slices.SortFunc(accounts, func(a, b account) int {
+		return cmp.Or(cmp.Compare(a.driverID, b.driverID), cmp.Compare(a.currency, b.currency))
+	})
+
+	batchID := uuid.New().String()
+	payouts := []*types.Payout{}
+	for _, key := range accounts {
+		// A negative balance, after a refund, is taken from the next earnings
+		if balances[key] <= 0 {
+			continue
+		}
+
+		payout, err := s.payout(ctx, batchID, key.driverID, key.currency, balances[key], entryIDs[key])
+		if errors.Is(err, domain.ErrEntriesSettled) {
+			log.Printf("Skipped the payout of driver %s: %v", key.driverID, err)
+			continue
+		}
+		if err != nil {
+			return payouts, err
+		}
+		payouts = append(payouts, payout)
+	}
+
+	return payouts, nil
+}
+
+// payout settles the entries before paying them out, so that concurrent runs don't pay them twice
+func (s *ledgerService) payout(ctx context.Context, batchID, driverID, currency string, amount int64, entryIDs []string) (*types.Payout, error) {
+	payout := &types.Payout{
+		ID:        uuid.New().String(),
+		BatchID:   batchID,
+		DriverID:  driverID,
+		Amount:    amount,
+		Currency:  currency,
+		Entries:   len(entryIDs),
+		CreatedAt: time.Now(),
+	}
+
+	if err := s.repo.SettleEntries(ctx, entryIDs, payout.ID); err != nil {
+		return nil, fmt.Errorf("failed to settle the entries of driver %s: %w", driverID, err)
+	}
+
+	providerPayoutID, err := s.payouts.Payout(ctx, driverID, amount, currency, payout.ID)
+	if err != nil {
+		payout.Status = types.PayoutStatusFailed
+		payout.Error = err.Error()
+		if err := s.repo.UnsettleEntries(ctx, payout.ID); err != nil {
+			return nil, fmt.Errorf("failed to release the entries of payout %s: %w", payout.ID, err)
+		}
+	} else {
+		payout.Status = types.PayoutStatusPaid
+		payout.ProviderPayoutID = providerPayoutID
+
+		tx := &transaction{id: "payout:" + payout.ID, currency: currency, driverID: driverID, payoutID: payout.ID, createdAt: payout.CreatedAt}
+		tx.add(types.DriverAccount(driverID), types.EntryPayout, amount)
+		tx.add(types.AccountCash, types.EntryPayout, -amount)
+		if err := s.repo.SaveEntries(ctx, tx.entries); err != nil {
+			return nil, fmt.Errorf("failed to record payout %s: %w", payout.ID, err)
+		}
+	}
+
+	if err := s.repo.SavePayout(ctx, payout); err != nil {
+		return nil, fmt.Errorf("failed to save payout %s: %w", payout.ID, err)
+	}
+
+	return payout, nil
+}
+
+func chargeTransactionID(payment *types.Payment) string {
+	return "charge:" + payment.ID
+}
+
+// transaction builds the entries of a ledger transaction, their IDs are derived from its ID
+type transaction struct {
+	id        string
+	currency  string
+	driverID  string
+	tripID    string
+	paymentID string
+	payoutID  string
+	createdAt time.Time
+	entries   []types.LedgerEntry
+}
+
+func newTransaction(id string, payment *types.Payment) *transaction {
+	return &transaction{
+		id:        id,
+		currency:  payment.Currency,
+		driverID:  payment.DriverID,
+		tripID:    payment.TripID,
+		paymentID: payment.ID,
+		createdAt: time.Now(),
+	}
+}
+
+func (t *transaction) add(account string, kind types.EntryKind, amount int64) {
+	t.entries = append(t.entries, types.LedgerEntry{
+		ID:            t.id + "/" + account,
+		TransactionID: t.id,
+		Account:       account,
+		Kind:          kind,
+		Amount:        amount,
+		Currency:      t.currency,
+		DriverID:      t.driverID,
+		TripID:        t.tripID,
+		PaymentID:     t.paymentID,
+		PayoutID:      t.payoutID,
+		CreatedAt:     t.createdAt,
+	})
+}
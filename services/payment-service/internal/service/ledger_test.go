@@ -0,0 +1,267 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"ride-sharing/services/payment-service/internal/domain"
+	"ride-sharing/services/payment-service/internal/infrastructure/repository"
+	"ride-sharing/services/payment-service/pkg/types"
+)
+
+var testCommission = types.CommissionRates{Default: 20, Packages: map[string]int{"luxury": 15}}
+
+// countingPayouts pays the drivers out and remembers the payouts
+type countingPayouts struct {
+	mutex   sync.Mutex
+	amounts map[string]int64 // driverID -> amount paid out
+	count   int
+}
+
+func (p *countingPayouts) Payout(ctx context.Context, driverID string, amount int64, currency, reference string) (string, error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if p.amounts == nil {
+		p.amounts = map[string]int64{}
+	}
+	p.amounts[driverID] += amount
+	p.count++
+	return "po_" + reference, nil
+}
+
+// racingLedger lets the payout runs list the unpaid entries all together before any of them settles them
+type racingLedger struct {
+	domain.LedgerRepository
+	listed sync.WaitGroup
+}
+
+func (r *racingLedger) ListUnpaidEntries(ctx context.Context, driverID string) ([]types.LedgerEntry, error) {
+	entries, err := r.LedgerRepository.ListUnpaidEntries(ctx, driverID)
+	r.listed.Done()
+	r.listed.Wait()
+	return entries, err
+}
+
+func testPayment(id string, packageSlug string, amount int64) *types.Payment {
+	return &types.Payment{
+		ID:          id,
+		TripID:      "trip-" + id,
+		UserID:      "rider-1",
+		DriverID:    "driver-1",
+		PackageSlug: packageSlug,
+		Amount:      amount,
+		Currency:    "usd",
+		Status:      types.PaymentStatusSuccess,
+	}
+}
+
+// accountAmounts sums the entries of the transaction by account, and checks that they balance
+func accountAmounts(t *testing.T, repo domain.LedgerRepository, paymentID, transactionID string) map[string]int64 {
+	t.Helper()
+
+	entries, err := repo.ListPaymentEntries(context.Background(), paymentID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	amounts := map[string]int64{}
+	var sum int64
+	for _, entry := range entries {
+		if entry.TransactionID != transactionID {
+			continue
+		}
+		amounts[entry.Account] += entry.Amount
+		sum += entry.Amount
+	}
+	if sum != 0 {
+		t.Errorf("the entries of %s sum to %d, want 0", transactionID, sum)
+	}
+	return amounts
+}
+
+func TestRecordChargeSplitsTheCommission(t *testing.T) {
+	tip := testPayment("tip", "", 500)
+	tip.Type = types.PaymentTypeTip
+
+	tests := []struct {
+		name           string
+		payment        *types.Payment
+		wantCommission int64
+		wantDriver     int64
+	}{
+		{"default rate", testPayment("sedan", "sedan", 2500), 500, 2000},
+		{"rate of the package, rounded down", testPayment("luxury", "luxury", 3333), 499, 2834},
+		{"tip", tip, 0, 500},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			repo := repository.NewInmemRepository()
+			ledger := NewLedgerService(repo, &countingPayouts{}, testCommission)
+
+			// A redelivered webhook records the charge again
+			for range 2 {
+				if err := ledger.RecordCharge(ctx, tt.payment); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			amounts := accountAmounts(t, repo, tt.payment.ID, chargeTransactionID(tt.payment))
+			if amounts[types.AccountCash] != tt.payment.Amount {
+				t.Errorf("got %d cash, want %d", amounts[types.AccountCash], tt.payment.Amount)
+			}
+			if -amounts[types.AccountCommission] != tt.wantCommission {
+				t.Errorf("got a commission of %d, want %d", -amounts[types.AccountCommission], tt.wantCommission)
+			}
+			if -amounts[types.DriverAccount("driver-1")] != tt.wantDriver {
+				t.Errorf("got %d for the driver, want %d", -amounts[types.DriverAccount("driver-1")], tt.wantDriver)
+			}
+		})
+	}
+}
+
+func TestRecordRefundTakesTheShareOfTheDriver(t *testing.T) {
+	tests := []struct {
+		name       string
+		amount     int64
+		wantDriver int64 // amount * earned / charged, rounded down
+	}{
+		{"partial refund", 1000, 850},          // 1000 * 2834 / 3333 = 850.28
+		{"rounded down", 1, 0},                 // 0.85
+		{"full refund", 3333, 2834},            // What the driver earned
+		{"refund of the commission", 499, 424}, // 424.3
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			repo := repository.NewInmemRepository()
+			ledger := NewLedgerService(repo, &countingPayouts{}, testCommission)
+
+			payment := testPayment("luxury", "luxury", 3333)
+			if err := ledger.RecordCharge(ctx, payment); err != nil {
+				t.Fatal(err)
+			}
+
+			refund := &types.Refund{ID: "refund-1", Amount: tt.amount}
+			if err := ledger.RecordRefund(ctx, payment, refund); err != nil {
+				t.Fatal(err)
+			}
+
+			amounts := accountAmounts(t, repo, payment.ID, "refund:"+refund.ID)
+			if -amounts[types.AccountCash] != tt.amount {
+				t.Errorf("got %d cash refunded, want %d", -amounts[types.AccountCash], tt.amount)
+			}
+			if amounts[types.DriverAccount("driver-1")] != tt.wantDriver {
+				t.Errorf("got %d taken from the driver, want %d", amounts[types.DriverAccount("driver-1")], tt.wantDriver)
+			}
+			if amounts[types.AccountCommission] != tt.amount-tt.wantDriver {
+				t.Errorf("got %d taken from the commission, want %d", amounts[types.AccountCommission], tt.amount-tt.wantDriver)
+			}
+		})
+	}
+}
+
+func TestRecordRefundRequiresTheCharge(t *testing.T) {
+	ledger := NewLedgerService(repository.NewInmemRepository(), &countingPayouts{}, testCommission)
+
+	err := ledger.RecordRefund(context.Background(), testPayment("sedan", "sedan", 2500), &types.Refund{ID: "refund-1", Amount: 100})
+	if err == nil {
+		t.Fatal("recorded the refund of a charge missing from the ledger")
+	}
+}
+
+func TestRunPayoutsCarriesANegativeBalance(t *testing.T) {
+	ctx := context.Background()
+	repo := repository.NewInmemRepository()
+	payouts := &countingPayouts{}
+	ledger := NewLedgerService(repo, payouts, testCommission)
+
+	// The driver earns 800 and is paid out
+	first := testPayment("first", "sedan", 1000)
+	if err := ledger.RecordCharge(ctx, first); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ledger.RunPayouts(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	// The fare is refunded after the payout, the driver owes their 800 back
+	if err := ledger.RecordRefund(ctx, first, &types.Refund{ID: "refund-1", Amount: 1000}); err != nil {
+		t.Fatal(err)
+	}
+	paid, err := ledger.RunPayouts(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(paid) != 0 {
+		t.Fatalf("got payouts %+v of a negative balance, want none", paid)
+	}
+
+	// The next earnings pay the debt first
+	if err := ledger.RecordCharge(ctx, testPayment("second", "sedan", 2500)); err != nil {
+		t.Fatal(err)
+	}
+	paid, err = ledger.RunPayouts(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(paid) != 1 || paid[0].Amount != 1200 || paid[0].Status != types.PayoutStatusPaid {
+		t.Fatalf("got payouts %+v, want 2000 earned minus the 800 owed", paid)
+	}
+	if payouts.amounts["driver-1"] != 800+1200 {
+		t.Errorf("got %d paid out, want 2000", payouts.amounts["driver-1"])
+	}
+
+	earnings, err := ledger.GetDriverEarnings(ctx, "driver-1", first.CreatedAt, paid[0].CreatedAt.Add(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if earnings.Balance != 0 {
+		t.Errorf("got a balance of %d, want 0", earnings.Balance)
+	}
+}
+
+func TestRunPayoutsConcurrently(t *testing.T) {
+	ctx := context.Background()
+	repo := &racingLedger{LedgerRepository: repository.NewInmemRepository()}
+	payouts := &countingPayouts{}
+	ledger := NewLedgerService(repo, payouts, testCommission)
+
+	if err := ledger.RecordCharge(ctx, testPayment("sedan", "sedan", 2500)); err != nil {
+		t.Fatal(err)
+	}
+
+	// Both runs see the same unpaid entries, only the first one to settle them pays them out
+	const runs = 2
+	repo.listed.Add(runs)
+	results := make(chan []*types.Payout, runs)
+	var wg sync.WaitGroup
+	for range runs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			paid, err := ledger.RunPayouts(ctx)
+			if err != nil {
+				t.Error(err)
+			}
+			results <- paid
+		}()
+	}
+	wg.Wait()
+	close(results)
+
+	var paid []*types.Payout
+	for result := range results {
+		paid = append(paid, result...)
+	}
+	if len(paid) != 1 || paid[0].Amount != 2000 {
+		t.Fatalf("got payouts %+v, want a single payout of 2000", paid)
+	}
+	if payouts.count != 1 {
+		t.Errorf("the driver was paid out %d times, want once", payouts.count)
+	}
+}
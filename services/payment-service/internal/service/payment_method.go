@@ -0,0 +1,142 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"ride-sharing/services/payment-service/internal/domain"
+	"ride-sharing/services/payment-service/pkg/types"
+)
+
+// CreateSetupIntent creates the setup intent the rider confirms with their card
+func (s *paymentService) CreateSetupIntent(ctx context.Context, userID string) (*types.SetupIntent, error) {
+	method, err := s.methods.GetPaymentMethod(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the payment method of user %s: %w", userID, err)
+	}
+
+	// The customer is kept with the rider, the cards they save later are attached to it
+	if method == nil {
+		customerID, err := s.paymentProcessor.CreateCustomer(ctx, userID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create the customer of user %s: %w", userID, err)
+		}
+
+		now := time.Now()
+		method = &types.PaymentMethod{
+			UserID:     userID,
+			CustomerID: customerID,
+			CreatedAt:  now,
+			UpdatedAt:  now,
+		}
+		if err := s.methods.SavePaymentMethod(ctx, method); err != nil {
+			return nil, fmt.Errorf("failed to save the customer of user %s: %w", userID, err)
+		}
+	}
+
+	setupIntent, err := s.paymentProcessor.CreateSetupIntent(ctx, method.CustomerID, map[string]string{
+		"user_id": userID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create the setup intent of user %s: %w", userID, err)
+	}
+
+	return setupIntent, nil
+}
+
+// SavePaymentMethod saves the card of the setup intent, the previous card is detached from the customer
+func (s *paymentService) SavePaymentMethod(ctx context.Context, event *types.PaymentEvent) (*types.PaymentMethod, error) {
+	// An older setup intent redelivered after a newer one would bring the replaced card back
+	processed, err := s.repo.IsEventProcessed(ctx, event.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check event: %w", err)
+	}
+	if processed {
+		return nil, fmt.Errorf("%w: %s", domain.ErrDuplicateEvent, event.ID)
+	}
+
+	if event.UserID == "" || event.PaymentMethodID == "" {
+		return nil, fmt.Errorf("%w: setup intent %s has no user or card", domain.ErrPaymentMethodNotFound, event.SetupIntentID)
+	}
+
+	method, err := s.methods.GetPaymentMethod(ctx, event.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the payment method of user %s: %w", event.UserID, err)
+	}
+
+	now := time.Now()
+	if method == nil {
+		method = &types.PaymentMethod{
+			UserID:    event.UserID,
+			CreatedAt: now,
+		}
+	}
+
+	// A redelivery of the webhook
+	if method.PaymentMethodID == event.PaymentMethodID {
+		return method, nil
+	}
+
+	card, err := s.paymentProcessor.GetCard(ctx, event.PaymentMethodID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the card of payment method %s: %w", event.PaymentMethodID, err)
+	}
+
+	previous := method.PaymentMethodID
+	if event.CustomerID != "" {
+		method.CustomerID = event.CustomerID
+	}
+	method.PaymentMethodID = event.PaymentMethodID
+	method.Card = card
+	method.UpdatedAt = now
+
+	if err := s.methods.SavePaymentMethod(ctx, method); err != nil {
+		return nil, fmt.Errorf("failed to save the payment method of user %s: %w", event.UserID, err)
+	}
+
+	if previous != "" {
+		if err := s.paymentProcessor.DetachPaymentMethod(ctx, previous); err != nil {
+			log.Printf("Failed to detach the replaced payment method %s of user %s: %v", previous, event.UserID, err)
+		}
+	}
+
+	return method, nil
+}
+
+// GetPaymentMethod returns the saved card of the rider
+func (s *paymentService) GetPaymentMethod(ctx context.Context, userID string) (*types.PaymentMethod, error) {
+	method, err := s.methods.GetPaymentMethod(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the payment method of user %s: %w", userID, err)
+	}
+
+	if !method.Saved() {
+		return nil, fmt.Errorf("%w: user %s", domain.ErrPaymentMethodNotFound, userID)
+	}
+
+	return method, nil
+}
+
+// DeletePaymentMethod detaches the saved card of the rider, their customer is kept
+func (s *paymentService) DeletePaymentMethod(ctx context.Context, userID string) error {
+	method, err := s.GetPaymentMethod(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	if err := s.paymentProcessor.DetachPaymentMethod(ctx, method.PaymentMethodID); err != nil {
+		return fmt.Errorf("failed to detach payment method %s: %w", method.PaymentMethodID, err)
+	}
+
+	method.PaymentMethodID = ""
+	method.Card = nil
+	method.UpdatedAt = time.Now()
+
+	if err := s.methods.SavePaymentMethod(ctx, method); err != nil {
+		return fmt.Errorf("failed to save the payment method of user %s: %w", userID, err)
+	}
+
+	return nil
+}
@@ -2,7 +2,10 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"log"
+	"slices"
 	"time"
 
 	"ride-sharing/services/payment-service/internal/domain"
@@ -11,47 +14,680 @@ import (
 	"github.com/google/uuid"
 )
 
+// maxRefundAttempts bounds the saves of the refunds of a payment updated concurrently
+const maxRefundAttempts = 5
+
 type paymentService struct {
 	paymentProcessor domain.PaymentProcessor
+	repo             domain.PaymentRepository
+	methods          domain.PaymentMethodRepository
+	ledger           domain.LedgerService
+	tips             types.TipLimits
+	holds            types.HoldConfig
 }
 
 // NewPaymentService creates a new instance of the payment service
-func NewPaymentService(paymentProcessor domain.PaymentProcessor) domain.Service {
+func NewPaymentService(paymentProcessor domain.PaymentProcessor, repo domain.PaymentRepository, methods domain.PaymentMethodRepository, ledger domain.LedgerService, tips types.TipLimits, holds types.HoldConfig) domain.Service {
 	return &paymentService{
 		paymentProcessor: paymentProcessor,
+		repo:             repo,
+		methods:          methods,
+		ledger:           ledger,
+		tips:             tips,
+		holds:            holds,
 	}
 }
 
-// CreatePaymentSession creates a new payment session for a trip
+// CreatePaymentSession captures the fare of a trip from its hold, charges it to the saved card of the
+// rider, or creates its checkout session
 func (s *paymentService) CreatePaymentSession(
 	ctx context.Context,
 	tripID string,
 	userID string,
 	driverID string,
+	packageSlug string,
+	amount int64,
+	currency string,
+) ([]*types.PaymentIntent, error) {
+	payments, err := s.farePayment(ctx, tripID, userID, userID, driverID, packageSlug, amount, currency)
+	if err != nil {
+		return nil, err
+	}
+
+	intents := make([]*types.PaymentIntent, len(payments))
+	for i, payment := range payments {
+		intents[i] = toPaymentIntent(payment)
+	}
+
+	return intents, nil
+}
+
+// CreateSplitPayments creates the payment of every share of a split fare, the way CreatePaymentSession
+// does for a whole fare. The hold of the rider who requested the trip pays their share.
+func (s *paymentService) CreateSplitPayments(
+	ctx context.Context,
+	tripID string,
+	ownerID string,
+	driverID string,
+	packageSlug string,
+	shares []types.FareShare,
+	currency string,
+) ([]*types.PaymentIntent, error) {
+	var intents []*types.PaymentIntent
+	for _, share := range shares {
+		payments, err := s.farePayment(ctx, tripID, share.UserID, ownerID, driverID, packageSlug, share.Amount, currency)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create the payment of the share of user %s: %w", share.UserID, err)
+		}
+		for _, payment := range payments {
+			intents = append(intents, toPaymentIntent(payment))
+		}
+	}
+
+	return intents, nil
+}
+
+// farePayment creates the fare payment of the rider for a trip requested by ownerID, or gets it back.
+// It is followed by the charge of the remainder when the fare is above the hold of the rider.
+func (s *paymentService) farePayment(
+	ctx context.Context,
+	tripID string,
+	userID string,
+	ownerID string,
+	driverID string,
+	packageSlug string,
 	amount int64,
 	currency string,
-) (*types.PaymentIntent, error) {
-	metadata := map[string]string{
-		"trip_id":   tripID,
-		"user_id":   userID,
-		"driver_id": driverID,
+) ([]*types.Payment, error) {
+	// A redelivered command gets the fare of the trip back, the card is not charged twice
+	payment, err := s.tripFare(ctx, tripID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case payment == nil:
+		now := time.Now()
+		payment = &types.Payment{
+			ID:          uuid.New().String(),
+			TripID:      tripID,
+			UserID:      userID,
+			DriverID:    driverID,
+			Type:        types.PaymentTypeFare,
+			PackageSlug: packageSlug,
+			Amount:      amount,
+			Currency:    currency,
+			Status:      types.PaymentStatusPending,
+			CreatedAt:   now,
+			UpdatedAt:   now,
+		}
+		if ownerID != userID {
+			payment.TripOwnerID = ownerID
+		}
+
+		if err := s.createPayment(ctx, payment); err != nil {
+			return nil, err
+		}
+	case payment.Status == types.PaymentStatusAuthorized:
+		// The fare was held when the rider requested the trip
+		payment.DriverID = driverID
+		if err := s.captureHold(ctx, payment, amount); err != nil {
+			return nil, err
+		}
+	default:
+		if err := s.retryCharge(ctx, payment); err != nil {
+			return nil, err
+		}
+	}
+
+	// The capture can't take more than the hold, the rest of the fare is charged on its own
+	if payment.HeldAmount == 0 || amount <= payment.Amount {
+		return []*types.Payment{payment}, nil
+	}
+
+	remainder, err := s.chargeRemainder(ctx, payment, amount-payment.Amount)
+	if err != nil {
+		return nil, err
+	}
+
+	return []*types.Payment{payment, remainder}, nil
+}
+
+// retryCharge charges again an off-session payment whose charge failed before the processor answered,
+// with the same reference. The other payments are left as is.
+func (s *paymentService) retryCharge(ctx context.Context, payment *types.Payment) error {
+	if !payment.OffSession() || payment.PaymentIntentID != "" || payment.Status != types.PaymentStatusPending {
+		return nil
+	}
+
+	method, err := s.methods.GetPaymentMethod(ctx, payment.UserID)
+	if err != nil {
+		return fmt.Errorf("failed to get the payment method of user %s: %w", payment.UserID, err)
+	}
+	if method == nil {
+		return fmt.Errorf("%w: user %s", domain.ErrPaymentMethodNotFound, payment.UserID)
+	}
+
+	return s.chargeOffSession(ctx, payment, method.CustomerID)
+}
+
+func toPaymentIntent(payment *types.Payment) *types.PaymentIntent {
+	return &types.PaymentIntent{
+		ID:              payment.ID,
+		TripID:          payment.TripID,
+		UserID:          payment.UserID,
+		DriverID:        payment.DriverID,
+		Amount:          payment.Amount,
+		Currency:        payment.Currency,
+		StripeSessionID: payment.StripeSessionID,
+		OffSession:      payment.OffSession(),
+		CreatedAt:       payment.CreatedAt,
+	}
+}
+
+// tripFare returns the latest fare payment of the rider for the trip, of any rider when userID is
+// empty, nil when there is none. The charges of the remainders of the holds are skipped.
+func (s *paymentService) tripFare(ctx context.Context, tripID, userID string) (*types.Payment, error) {
+	payments, err := s.repo.ListPayments(ctx, "", tripID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list the payments of trip %s: %w", tripID, err)
+	}
+
+	for _, payment := range payments {
+		if payment.PaymentType() != types.PaymentTypeFare || payment.HoldPaymentID != "" || (userID != "" && payment.UserID != userID) {
+			continue
+		}
+		return payment, nil
+	}
+
+	return nil, nil
+}
+
+// AddTip creates the payment of a tip, credited to the driver in full once paid
+func (s *paymentService) AddTip(ctx context.Context, tripID, userID, tipID string, amount int64) (*types.Payment, error) {
+	if tipID == "" {
+		return nil, fmt.Errorf("%w: the tip needs an ID", domain.ErrInvalidTip)
+	}
+
+	payments, err := s.repo.ListPayments(ctx, "", tripID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list the payments of trip %s: %w", tripID, err)
+	}
+
+	// A retried tip gets its payment back, the card is not charged twice
+	id := tipPaymentID(tripID, userID, tipID)
+	if i := slices.IndexFunc(payments, func(p *types.Payment) bool { return p.ID == id }); i >= 0 {
+		if err := s.retryCharge(ctx, payments[i]); err != nil {
+			return nil, err
+		}
+		return payments[i], nil
+	}
+
+	// The riders who split the fare can tip as well, each of them paid a fare payment
+	var fare, paidFare *types.Payment
+	var tipped int64
+	for _, payment := range payments {
+		switch {
+		case payment.PaymentType() == types.PaymentTypeTip && payment.Paid():
+			tipped += payment.Amount - payment.RefundedAmount
+		case payment.PaymentType() == types.PaymentTypeFare && payment.Paid():
+			if paidFare == nil {
+				paidFare = payment
+			}
+			if payment.UserID == userID && fare == nil {
+				fare = payment
+			}
+		}
+	}
+
+	if paidFare == nil {
+		return nil, fmt.Errorf("%w: %s", domain.ErrTripNotPaid, tripID)
+	}
+
+	if fare == nil {
+		return nil, fmt.Errorf("%w: user %s, trip %s", domain.ErrNotTripRider, userID, tripID)
+	}
+
+	if amount < s.tips.Min || tipped+amount > s.tips.Max {
+		return nil, fmt.Errorf("%w: %d, the tips of a trip are between %d and %d %s, %d were paid", domain.ErrInvalidTip, amount, s.tips.Min, s.tips.Max, fare.Currency, tipped)
+	}
+
+	now := time.Now()
+	tip := &types.Payment{
+		ID:          id,
+		TripID:      tripID,
+		UserID:      userID,
+		DriverID:    fare.DriverID,
+		Type:        types.PaymentTypeTip,
+		PackageSlug: fare.PackageSlug,
+		Amount:      amount,
+		Currency:    fare.Currency,
+		Status:      types.PaymentStatusPending,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+
+	if err := s.createPayment(ctx, tip); err != nil {
+		return nil, err
+	}
+
+	return tip, nil
+}
+
+// tipPaymentID derives the ID of the payment of a tip from the ID chosen by the client. It is the
+// idempotency key of the off-session charge of the tip as well.
+func tipPaymentID(tripID, userID, tipID string) string {
+	return uuid.NewSHA1(uuid.NameSpaceURL, []byte("tip:"+tripID+":"+userID+":"+tipID)).String()
+}
+
+// createPayment charges the payment to the saved card of the rider, or creates its checkout session
+// when they have none. The payment is saved as pending.
+func (s *paymentService) createPayment(ctx context.Context, payment *types.Payment) error {
+	method, err := s.methods.GetPaymentMethod(ctx, payment.UserID)
+	if err != nil {
+		return fmt.Errorf("failed to get the payment method of user %s: %w", payment.UserID, err)
+	}
+
+	if method.Saved() {
+		// Saved before the charge, a retry finds the payment and reuses its reference
+		payment.PaymentMethodID = method.PaymentMethodID
+		if err := s.repo.SavePayment(ctx, payment); err != nil {
+			return fmt.Errorf("failed to save payment: %w", err)
+		}
+
+		return s.chargeOffSession(ctx, payment, method.CustomerID)
+	}
+
+	sessionID, err := s.paymentProcessor.CreatePaymentSession(ctx, payment.Amount, payment.Currency, paymentMetadata(payment))
+	if err != nil {
+		return fmt.Errorf("failed to create payment session: %w", err)
+	}
+	payment.StripeSessionID = sessionID
+
+	if err := s.repo.SavePayment(ctx, payment); err != nil {
+		return fmt.Errorf("failed to save payment: %w", err)
+	}
+
+	return nil
+}
+
+// chargeOffSession charges the saved payment to the card of the rider. When the bank needs the rider,
+// to authenticate or because it declined the card, the payment falls back to a checkout session.
+func (s *paymentService) chargeOffSession(ctx context.Context, payment *types.Payment, customerID string) error {
+	metadata := paymentMetadata(payment)
+	metadata["off_session"] = "true"
+
+	intentID, status, err := s.paymentProcessor.ChargeOffSession(ctx, customerID, payment.PaymentMethodID, payment.Amount, payment.Currency, metadata, payment.ID)
+	if errors.Is(err, domain.ErrAuthenticationRequired) || errors.Is(err, domain.ErrCardDeclined) {
+		log.Printf("Falling back to a checkout session for payment %s: %v", payment.ID, err)
+		return s.checkoutFallback(ctx, payment)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to charge payment %s off-session: %w", payment.ID, err)
+	}
+
+	payment.PaymentIntentID = intentID
+	payment.Status = status
+	payment.UpdatedAt = time.Now()
+
+	if err := s.repo.UpdatePayment(ctx, payment); err != nil {
+		return fmt.Errorf("failed to update payment: %w", err)
+	}
+
+	// The webhook of the payment intent confirms it later, recording the charge again is a no-op
+	if payment.Status == types.PaymentStatusSuccess {
+		if err := s.ledger.RecordCharge(ctx, payment); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// checkoutFallback creates the checkout session of a payment the saved card of the rider couldn't pay
+func (s *paymentService) checkoutFallback(ctx context.Context, payment *types.Payment) error {
+	sessionID, err := s.paymentProcessor.CreatePaymentSession(ctx, payment.Amount, payment.Currency, paymentMetadata(payment))
+	if err != nil {
+		return fmt.Errorf("failed to create payment session: %w", err)
+	}
+	payment.StripeSessionID = sessionID
+	payment.PaymentMethodID = ""
+	payment.UpdatedAt = time.Now()
+
+	if err := s.repo.UpdatePayment(ctx, payment); err != nil {
+		return fmt.Errorf("failed to update payment: %w", err)
+	}
+	return nil
+}
+
+// paymentMetadata identifies the payment in the Stripe objects and their events
+func paymentMetadata(payment *types.Payment) map[string]string {
+	return map[string]string{
+		"payment_id":   payment.ID,
+		"payment_type": string(payment.Type),
+		"trip_id":      payment.TripID,
+		"user_id":      payment.UserID,
+		"driver_id":    payment.DriverID,
+	}
+}
+
+// HandlePaymentEvent records the outcome reported by Stripe on the payment it refers to
+func (s *paymentService) HandlePaymentEvent(ctx context.Context, event *types.PaymentEvent) (*types.Payment, error) {
+	processed, err := s.repo.IsEventProcessed(ctx, event.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check event: %w", err)
+	}
+
+	if processed {
+		return nil, fmt.Errorf("%w: %s", domain.ErrDuplicateEvent, event.ID)
+	}
+
+	payment, err := s.findPayment(ctx, event)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get payment: %w", err)
+	}
+
+	if payment == nil {
+		return nil, fmt.Errorf("%w: %s event %s", domain.ErrPaymentNotFound, event.Type, event.ID)
+	}
+
+	// A redelivery after a failure to publish, the event is published again. A refund made with
+	// RefundPayment was published already, Stripe then reports it with the webhook as well.
+	if payment.Status == event.Status && event.Status != types.PaymentStatusRefunded {
+		// The charge may have failed to reach the ledger the first time
+		if payment.Status == types.PaymentStatusSuccess {
+			if err := s.ledger.RecordCharge(ctx, payment); err != nil {
+				return nil, err
+			}
+		}
+		return payment, nil
+	}
+
+	if !payment.Status.CanMoveTo(event.Status) {
+		return payment, fmt.Errorf("%w: %s event %s on a %s payment", domain.ErrStaleEvent, event.Type, event.ID, payment.Status)
+	}
+
+	if event.Status == types.PaymentStatusRefunded {
+		return s.handleRefundEvent(ctx, payment.ID, event)
+	}
+
+	payment.Status = event.Status
+	if event.PaymentIntentID != "" {
+		payment.PaymentIntentID = event.PaymentIntentID
+	}
+	payment.UpdatedAt = time.Now()
+
+	if err := s.repo.UpdatePayment(ctx, payment); err != nil {
+		return nil, fmt.Errorf("failed to update payment: %w", err)
+	}
+
+	if payment.Status == types.PaymentStatusSuccess {
+		if err := s.ledger.RecordCharge(ctx, payment); err != nil {
+			return nil, err
+		}
+	}
+
+	return payment, nil
+}
+
+// handleRefundEvent records the refund of a charge.refunded event. The refunds made with RefundPayment
+// are recorded before Stripe is called, what the payment has left was refunded from the Stripe dashboard.
+func (s *paymentService) handleRefundEvent(ctx context.Context, paymentID string, event *types.PaymentEvent) (*types.Payment, error) {
+	var refund *types.Refund
+	payment, err := s.updateRefunds(ctx, paymentID, func(payment *types.Payment) error {
+		refund = nil
+		if !payment.Status.CanMoveTo(types.PaymentStatusRefunded) {
+			return fmt.Errorf("%w: %s event %s on a %s payment", domain.ErrStaleEvent, event.Type, event.ID, payment.Status)
+		}
+
+		// Stripe lists the refunds of the charge, the ones recorded already are not taken twice
+		unknown := unrecordedRefunds(payment, event.ProcessorRefundIDs)
+		if remaining := payment.Amount - payment.RefundedAmount; remaining > 0 && (len(event.ProcessorRefundIDs) == 0 || len(unknown) > 0) {
+			refund = &types.Refund{
+				ID:          uuid.New().String(),
+				Amount:      remaining,
+				Reason:      "refunded with Stripe",
+				RequestedBy: "stripe",
+				CreatedAt:   time.Now(),
+			}
+			if len(unknown) > 0 {
+				refund.ProcessorRefundID = unknown[len(unknown)-1]
+			}
+			payment.Refunds = append(payment.Refunds, *refund)
+			payment.RefundedAmount = payment.Amount
+		}
+
+		payment.Status = types.PaymentStatusRefunded
+		if event.PaymentIntentID != "" {
+			payment.PaymentIntentID = event.PaymentIntentID
+		}
+		return nil
+	})
+	if err != nil {
+		return payment, err
+	}
+
+	// The success event may still be on its way, the refund needs the charge in the ledger
+	if err := s.ledger.RecordCharge(ctx, payment); err != nil {
+		return nil, err
+	}
+	if refund != nil {
+		if err := s.ledger.RecordRefund(ctx, payment, refund); err != nil {
+			return nil, err
+		}
+	}
+
+	return payment, nil
+}
+
+// unrecordedRefunds returns the processor refunds the payment has no record of
+func unrecordedRefunds(payment *types.Payment, processorRefundIDs []string) []string {
+	var unknown []string
+	for _, id := range processorRefundIDs {
+		if !slices.ContainsFunc(payment.Refunds, func(refund types.Refund) bool { return refund.ProcessorRefundID == id }) {
+			unknown = append(unknown, id)
+		}
+	}
+	return unknown
+}
+
+// RecordPaymentEvent marks the Stripe event as processed
+func (s *paymentService) RecordPaymentEvent(ctx context.Context, event *types.PaymentEvent) error {
+	if err := s.repo.SaveProcessedEvent(ctx, event.ID, event.Type, time.Now()); err != nil {
+		return fmt.Errorf("failed to save event: %w", err)
+	}
+
+	return nil
+}
+
+// GetPayment returns the payment with this ID
+func (s *paymentService) GetPayment(ctx context.Context, id string) (*types.Payment, error) {
+	payment, err := s.repo.GetPaymentByID(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get payment: %w", err)
+	}
+
+	if payment == nil {
+		return nil, fmt.Errorf("%w: %s", domain.ErrPaymentNotFound, id)
+	}
+
+	return payment, nil
+}
+
+// ListPayments returns the payments of the user and/or the trip
+func (s *paymentService) ListPayments(ctx context.Context, userID, tripID string) ([]*types.Payment, error) {
+	payments, err := s.repo.ListPayments(ctx, userID, tripID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list payments: %w", err)
+	}
+
+	return payments, nil
+}
+
+// RefundPayment refunds the payment at the processor and records the refund against it. The refund is
+// recorded before the processor is called, so that concurrent refunds can't take more than what is left
+// of the payment and the webhook of the refund finds it recorded. The ID of the refund is the idempotency
+// key of the processor, a retried call doesn't refund twice.
+func (s *paymentService) RefundPayment(ctx context.Context, paymentID string, amount int64, reason, requestedBy string) (*types.Payment, *types.Refund, error) {
+	refund := types.Refund{
+		ID:          uuid.New().String(),
+		Reason:      reason,
+		RequestedBy: requestedBy,
+		CreatedAt:   time.Now(),
+	}
+
+	payment, err := s.updateRefunds(ctx, paymentID, func(payment *types.Payment) error {
+		refundable := payment.RefundableAmount()
+		if refundable <= 0 {
+			return fmt.Errorf("%w: payment %s is %s", domain.ErrNotRefundable, paymentID, payment.Status)
+		}
+
+		// The payment intent is only known from the webhook of the completed session
+		if payment.PaymentIntentID == "" {
+			return fmt.Errorf("%w: payment %s has no payment intent", domain.ErrNotRefundable, paymentID)
+		}
+
+		refund.Amount = amount
+		if amount == 0 {
+			refund.Amount = refundable
+		}
+		if refund.Amount < 0 || refund.Amount > refundable {
+			return fmt.Errorf("%w: %d, %d %s can be refunded", domain.ErrInvalidRefund, refund.Amount, refundable, payment.Currency)
+		}
+
+		payment.Refunds = append(payment.Refunds, refund)
+		payment.RefundedAmount += refund.Amount
+		payment.Status = refundedStatus(payment)
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	processorRefundID, err := s.paymentProcessor.Refund(ctx, payment.PaymentIntentID, refund.Amount, reason, refund.ID)
+	if err != nil {
+		// Nothing was refunded, the amount can be refunded again
+		if _, cancelErr := s.updateRefunds(ctx, paymentID, func(payment *types.Payment) error {
+			removeRefund(payment, refund.ID)
+			return nil
+		}); cancelErr != nil {
+			log.Printf("Failed to cancel refund %s of payment %s: %v", refund.ID, paymentID, cancelErr)
+		}
+		return nil, nil, fmt.Errorf("failed to refund payment: %w", err)
+	}
+
+	refund.ProcessorRefundID = processorRefundID
+	payment, err = s.updateRefunds(ctx, paymentID, func(payment *types.Payment) error {
+		for i := range payment.Refunds {
+			if payment.Refunds[i].ID == refund.ID {
+				payment.Refunds[i].ProcessorRefundID = processorRefundID
+			}
+		}
+		return nil
+	})
+	// The money is already refunded and the amount recorded, only the ID of the refund is missing
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to save refund %s of payment %s: %w", processorRefundID, paymentID, err)
+	}
+
+	if err := s.ledger.RecordRefund(ctx, payment, &refund); err != nil {
+		return nil, nil, err
+	}
+
+	return payment, &refund, nil
+}
+
+// updateRefunds changes the refunds of the payment and saves them, the change is made again on the
+// payment read again when it was updated in the meantime
+func (s *paymentService) updateRefunds(ctx context.Context, paymentID string, change func(payment *types.Payment) error) (*types.Payment, error) {
+	for attempt := 1; ; attempt++ {
+		payment, err := s.GetPayment(ctx, paymentID)
+		if err != nil {
+			return nil, err
+		}
+
+		unmodifiedSince := payment.UpdatedAt
+		if err := change(payment); err != nil {
+			return payment, err
+		}
+
+		// The update time tells the versions apart, at the millisecond MongoDB keeps
+		payment.UpdatedAt = time.Now().Truncate(time.Millisecond)
+		if !payment.UpdatedAt.After(unmodifiedSince) {
+			payment.UpdatedAt = unmodifiedSince.Add(time.Millisecond)
+		}
+
+		err = s.repo.UpdateRefunds(ctx, payment, unmodifiedSince)
+		if errors.Is(err, domain.ErrPaymentConflict) && attempt < maxRefundAttempts {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to save the refunds of payment %s: %w", paymentID, err)
+		}
+
+		return payment, nil
+	}
+}
+
+// refundedStatus is the status of a paid payment after its refunds changed
+func refundedStatus(payment *types.Payment) types.PaymentStatus {
+	switch {
+	case payment.RefundedAmount <= 0:
+		return types.PaymentStatusSuccess
+	case payment.RefundedAmount >= payment.Amount:
+		return types.PaymentStatusRefunded
+	default:
+		return types.PaymentStatusPartiallyRefunded
+	}
+}
+
+// removeRefund drops a refund the processor didn't make
+func removeRefund(payment *types.Payment, refundID string) {
+	i := slices.IndexFunc(payment.Refunds, func(refund types.Refund) bool { return refund.ID == refundID })
+	if i < 0 {
+		return
+	}
+
+	payment.RefundedAmount -= payment.Refunds[i].Amount
+	payment.Refunds = slices.Delete(payment.Refunds, i, i+1)
+	if payment.Status == types.PaymentStatusPartiallyRefunded || payment.Status == types.PaymentStatusRefunded {
+		payment.Status = refundedStatus(payment)
+	}
+}
+
+// findPayment looks the payment up by checkout session, then by payment intent, then by the ID in
+// the metadata. The payment intent events of the older sessions only carry the trip in their metadata.
+func (s *paymentService) findPayment(ctx context.Context, event *types.PaymentEvent) (*types.Payment, error) {
+	if event.SessionID != "" {
+		return s.repo.GetPaymentBySessionID(ctx, event.SessionID)
+	}
+
+	if event.PaymentIntentID != "" {
+		payment, err := s.repo.GetPaymentByIntentID(ctx, event.PaymentIntentID)
+		if err != nil || payment != nil {
+			return payment, err
+		}
+	}
+
+	if event.PaymentID != "" {
+		return s.repo.GetPaymentByID(ctx, event.PaymentID)
+	}
+
+	if event.TripID == "" {
+		return nil, nil
 	}
 
-	sessionID, err := s.paymentProcessor.CreatePaymentSession(ctx, amount, currency, metadata)
+	payments, err := s.repo.ListPayments(ctx, "", event.TripID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create payment session: %w", err)
+		return nil, err
 	}
 
-	paymentIntent := &types.PaymentIntent{
-		ID:              uuid.New().String(),
-		TripID:          tripID,
-		UserID:          userID,
-		DriverID:        driverID,
-		Amount:          amount,
-		Currency:        currency,
-		StripeSessionID: sessionID,
-		CreatedAt:       time.Now(),
+	// The latest fare of the trip, the tips always have their ID in the metadata
+	for _, payment := range payments {
+		if payment.PaymentType() == types.PaymentTypeFare {
+			return payment, nil
+		}
 	}
 
-	return paymentIntent, nil
+	return nil, nil
 }
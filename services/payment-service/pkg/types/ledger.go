@@ -0,0 +1,140 @@
+package types
+
+import (
+	"time"
+
+	pb "ride-sharing/shared/proto/payment"
+)
+
+// Accounts of the ledger. Every driver has their own account, see DriverAccount.
+const (
+	AccountCash       = "cash"       // Money collected from the riders and held by the processor
+	AccountCommission = "commission" // Revenue of the platform
+)
+
+// DriverAccount is the account of what the platform owes to the driver
+func DriverAccount(driverID string) string {
+	return "driver:" + driverID
+}
+
+type EntryKind string
+
+const (
+	EntryCharge     EntryKind = "charge"
+	EntryCommission EntryKind = "commission"
+	EntryEarning    EntryKind = "earning"
+	EntryTip        EntryKind = "tip"
+	EntryRefund     EntryKind = "refund"
+	EntryPayout     EntryKind = "payout"
+)
+
+// LedgerEntry is one side of a ledger transaction. Amount is positive for a debit and negative
+// for a credit, the entries of a transaction sum to zero.
+type LedgerEntry struct {
+	ID            string    `json:"id" bson:"_id"`
+	TransactionID string    `json:"transaction_id" bson:"transactionID"`
+	Account       string    `json:"account" bson:"account"`
+	Kind          EntryKind `json:"kind" bson:"kind"`
+	Amount        int64     `json:"amount" bson:"amount"` // In cents
+	Currency      string    `json:"currency" bson:"currency"`
+	DriverID      string    `json:"driver_id,omitempty" bson:"driverID,omitempty"` // Driver of the trip or of the payout
+	TripID        string    `json:"trip_id,omitempty" bson:"tripID,omitempty"`
+	PaymentID     string    `json:"payment_id,omitempty" bson:"paymentID,omitempty"`
+	PayoutID      string    `json:"payout_id,omitempty" bson:"payoutID,omitempty"` // Payout that settled the driver entry
+	CreatedAt     time.Time `json:"created_at" bson:"createdAt"`
+}
+
+// ToProto returns the entry as seen by the driver, credits are positive
+func (e *LedgerEntry) ToProto() *pb.LedgerEntry {
+	return &pb.LedgerEntry{
+		Id:        e.ID,
+		TripID:    e.TripID,
+		PaymentID: e.PaymentID,
+		Kind:      string(e.Kind),
+		Amount:    -e.Amount,
+		Currency:  e.Currency,
+		PayoutID:  e.PayoutID,
+		CreatedAt: e.CreatedAt.UTC().Format(time.RFC3339),
+	}
+}
+
+// DriverEarnings sums up the ledger entries of the trips and payouts of a driver over [From, To).
+// The amounts are in cents.
+type DriverEarnings struct {
+	DriverID   string
+	Currency   string
+	From       time.Time
+	To         time.Time
+	Trips      int
+	Fares      int64
+	Commission int64
+	Earnings   int64 // Fares minus commission
+	Tips       int64
+	Refunds    int64 // Share of the refunds taken back from the driver
+	PaidOut    int64
+	Balance    int64         // Unpaid balance of the driver, whatever the period
+	Entries    []LedgerEntry // Entries of the account of the driver
+}
+
+// Net is what the driver earned over the period
+func (e *DriverEarnings) Net() int64 {
+	return e.Earnings + e.Tips - e.Refunds
+}
+
+func (e *DriverEarnings) ToProto() *pb.DriverEarnings {
+	entries := make([]*pb.LedgerEntry, len(e.Entries))
+	for i := range e.Entries {
+		entries[i] = e.Entries[i].ToProto()
+	}
+
+	return &pb.DriverEarnings{
+		DriverID:   e.DriverID,
+		Currency:   e.Currency,
+		From:       e.From.UTC().Format(time.RFC3339),
+		To:         e.To.UTC().Format(time.RFC3339),
+		Trips:      int32(e.Trips),
+		Fares:      e.Fares,
+		Commission: e.Commission,
+		Earnings:   e.Earnings,
+		Tips:       e.Tips,
+		Refunds:    e.Refunds,
+		Net:        e.Net(),
+		PaidOut:    e.PaidOut,
+		Balance:    e.Balance,
+		Entries:    entries,
+	}
+}
+
+type PayoutStatus string
+
+const (
+	PayoutStatusPaid   PayoutStatus = "paid"
+	PayoutStatusFailed PayoutStatus = "failed"
+)
+
+// Payout pays the unpaid balance of a driver out, the payouts of a run of the job share their batch
+type Payout struct {
+	ID               string       `json:"id" bson:"_id"`
+	BatchID          string       `json:"batch_id" bson:"batchID"`
+	DriverID         string       `json:"driver_id" bson:"driverID"`
+	Amount           int64        `json:"amount" bson:"amount"` // In cents
+	Currency         string       `json:"currency" bson:"currency"`
+	Status           PayoutStatus `json:"status" bson:"status"`
+	ProviderPayoutID string       `json:"provider_payout_id,omitempty" bson:"providerPayoutID,omitempty"`
+	Error            string       `json:"error,omitempty" bson:"error,omitempty"`
+	Entries          int          `json:"entries" bson:"entries"` // Number of ledger entries settled
+	CreatedAt        time.Time    `json:"created_at" bson:"createdAt"`
+}
+
+// CommissionRates are the percentages of the fares kept by the platform
+type CommissionRates struct {
+	Default  int
+	Packages map[string]int // Package slug -> percentage
+}
+
+func (c CommissionRates) Percent(packageSlug string) int {
+	if percent, ok := c.Packages[packageSlug]; ok {
+		return percent
+	}
+	return c.Default
+}
@@ -0,0 +1,53 @@
+package types
+
+import (
+	"time"
+
+	pb "ride-sharing/shared/proto/payment"
+)
+
+// PaymentMethod is the card a rider saved with a setup intent, their payments are charged to it
+// off-session. The Stripe customer of the rider is kept once created, with or without a card.
+type PaymentMethod struct {
+	UserID          string    `json:"user_id" bson:"_id"`
+	CustomerID      string    `json:"customer_id" bson:"customerID"`
+	PaymentMethodID string    `json:"payment_method_id,omitempty" bson:"paymentMethodID,omitempty"`
+	Card            *Card     `json:"card,omitempty" bson:"card,omitempty"`
+	CreatedAt       time.Time `json:"created_at" bson:"createdAt"`
+	UpdatedAt       time.Time `json:"updated_at" bson:"updatedAt"`
+}
+
+// Saved reports whether the rider has a card to charge off-session
+func (m *PaymentMethod) Saved() bool {
+	return m != nil && m.PaymentMethodID != ""
+}
+
+func (m *PaymentMethod) ToProto() *pb.PaymentMethod {
+	method := &pb.PaymentMethod{
+		UserID:          m.UserID,
+		PaymentMethodID: m.PaymentMethodID,
+		UpdatedAt:       m.UpdatedAt.UTC().Format(time.RFC3339),
+	}
+	if m.Card != nil {
+		method.Brand = m.Card.Brand
+		method.Last4 = m.Card.Last4
+		method.ExpMonth = int32(m.Card.ExpMonth)
+		method.ExpYear = int32(m.Card.ExpYear)
+	}
+	return method
+}
+
+// Card holds the details of a saved card shown to the rider, the card number stays with Stripe
+type Card struct {
+	Brand    string `json:"brand" bson:"brand"`
+	Last4    string `json:"last4" bson:"last4"`
+	ExpMonth int64  `json:"exp_month" bson:"expMonth"`
+	ExpYear  int64  `json:"exp_year" bson:"expYear"`
+}
+
+// SetupIntent is confirmed by the client with Stripe.js to save a card for the customer
+type SetupIntent struct {
+	ID           string
+	ClientSecret string
+	CustomerID   string
+}
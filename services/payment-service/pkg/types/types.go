@@ -1,28 +1,158 @@
 package types
 
-import "time"
+import (
+	"slices"
+	"time"
+
+	pb "ride-sharing/shared/proto/payment"
+)
 
 // PaymentStatus represents the current status of a payment
 type PaymentStatus string
 
 const (
-	PaymentStatusPending   PaymentStatus = "pending"
-	PaymentStatusSuccess   PaymentStatus = "success"
-	PaymentStatusFailed    PaymentStatus = "failed"
-	PaymentStatusCancelled PaymentStatus = "cancelled"
+	// PaymentStatusAuthorized is a fare held on the saved card of the rider, captured once a driver accepted the trip
+	PaymentStatusAuthorized PaymentStatus = "authorized"
+	PaymentStatusPending    PaymentStatus = "pending"
+	PaymentStatusSuccess    PaymentStatus = "success"
+	PaymentStatusFailed     PaymentStatus = "failed"
+	PaymentStatusCancelled  PaymentStatus = "cancelled"
+	PaymentStatusRefunded   PaymentStatus = "refunded"
+	PaymentStatusDisputed   PaymentStatus = "disputed"
+	// PaymentStatusPartiallyRefunded is a paid payment refunded for a part of its amount
+	PaymentStatusPartiallyRefunded PaymentStatus = "partially_refunded"
 )
 
+// PaymentType tells what a payment pays for, the payments saved before the tips have none and are fares
+type PaymentType string
+
+const (
+	PaymentTypeFare PaymentType = "fare"
+	PaymentTypeTip  PaymentType = "tip"
+)
+
+// nextStatuses are the statuses a payment can move to. A failed payment can still succeed, the rider
+// may retry in the checkout, but nothing moves a payment back. A released hold is cancelled.
+var nextStatuses = map[PaymentStatus][]PaymentStatus{
+	PaymentStatusAuthorized:        {PaymentStatusCancelled, PaymentStatusSuccess},
+	PaymentStatusPending:           {PaymentStatusFailed, PaymentStatusCancelled, PaymentStatusSuccess, PaymentStatusDisputed, PaymentStatusRefunded},
+	PaymentStatusFailed:            {PaymentStatusCancelled, PaymentStatusSuccess, PaymentStatusDisputed, PaymentStatusRefunded},
+	PaymentStatusSuccess:           {PaymentStatusDisputed, PaymentStatusPartiallyRefunded, PaymentStatusRefunded},
+	PaymentStatusPartiallyRefunded: {PaymentStatusDisputed, PaymentStatusRefunded},
+	PaymentStatusDisputed:          {PaymentStatusRefunded},
+}
+
+// CanMoveTo reports whether a payment in this status can move forward to next
+func (s PaymentStatus) CanMoveTo(next PaymentStatus) bool {
+	return slices.Contains(nextStatuses[s], next)
+}
+
 // Payment represents a payment transaction
 type Payment struct {
-	ID              string        `json:"id"`
-	TripID          string        `json:"trip_id"`
-	UserID          string        `json:"user_id"`
-	Amount          int64         `json:"amount"`   // Amount in cents
-	Currency        string        `json:"currency"` // e.g., "usd"
-	Status          PaymentStatus `json:"status"`
-	StripeSessionID string        `json:"stripe_session_id"`
-	CreatedAt       time.Time     `json:"created_at"`
-	UpdatedAt       time.Time     `json:"updated_at"`
+	ID              string        `json:"id" bson:"_id"`
+	TripID          string        `json:"trip_id" bson:"tripID"`
+	UserID          string        `json:"user_id" bson:"userID"`
+	DriverID        string        `json:"driver_id" bson:"driverID"`
+	Type            PaymentType   `json:"type,omitempty" bson:"type,omitempty"`
+	PackageSlug     string        `json:"package_slug,omitempty" bson:"packageSlug,omitempty"` // Sets the commission of the platform
+	Amount          int64         `json:"amount" bson:"amount"`                                // Amount in cents
+	Currency        string        `json:"currency" bson:"currency"`                            // e.g., "usd"
+	Status          PaymentStatus `json:"status" bson:"status"`
+	StripeSessionID string        `json:"stripe_session_id" bson:"stripeSessionID"`
+	PaymentIntentID string        `json:"payment_intent_id,omitempty" bson:"paymentIntentID,omitempty"` // Known once the session completed
+	PaymentMethodID string        `json:"payment_method_id,omitempty" bson:"paymentMethodID,omitempty"` // Saved card charged off-session, unset for a checkout session
+	HeldAmount      int64         `json:"held_amount,omitempty" bson:"heldAmount,omitempty"`            // Held on the saved card at the trip request, in cents
+	TripOwnerID     string        `json:"trip_owner_id,omitempty" bson:"tripOwnerID,omitempty"`         // Rider who requested the trip, set on the shares of a split fare paid by the other riders
+	HoldPaymentID   string        `json:"hold_payment_id,omitempty" bson:"holdPaymentID,omitempty"`     // Set on the charge of the part of the fare above the hold of that payment
+	RefundedAmount  int64         `json:"refunded_amount" bson:"refundedAmount"`                        // Sum of the refunds in cents
+	Refunds         []Refund      `json:"refunds,omitempty" bson:"refunds,omitempty"`
+	CreatedAt       time.Time     `json:"created_at" bson:"createdAt"`
+	UpdatedAt       time.Time     `json:"updated_at" bson:"updatedAt"`
+}
+
+func (p *Payment) ToProto() *pb.Payment {
+	return &pb.Payment{
+		Id:              p.ID,
+		TripID:          p.TripID,
+		UserID:          p.UserID,
+		DriverID:        p.DriverID,
+		Type:            string(p.PaymentType()),
+		PackageSlug:     p.PackageSlug,
+		Amount:          p.Amount,
+		Currency:        p.Currency,
+		Status:          string(p.Status),
+		StripeSessionID: p.StripeSessionID,
+		PaymentIntentID: p.PaymentIntentID,
+		OffSession:      p.OffSession(),
+		HeldAmount:      p.HeldAmount,
+		TripOwnerID:     p.TripOwnerID,
+		HoldPaymentID:   p.HoldPaymentID,
+		CreatedAt:       p.CreatedAt.UTC().Format(time.RFC3339),
+		UpdatedAt:       p.UpdatedAt.UTC().Format(time.RFC3339),
+		RefundedAmount:  p.RefundedAmount,
+		Refunds:         toRefundsProto(p.Refunds),
+	}
+}
+
+// PaymentType is the type of the payment, a fare unless it is a tip
+func (p *Payment) PaymentType() PaymentType {
+	if p.Type == "" {
+		return PaymentTypeFare
+	}
+	return p.Type
+}
+
+// OffSession reports whether the payment is charged to the saved card of the rider, without a checkout session
+func (p *Payment) OffSession() bool {
+	return p.PaymentMethodID != ""
+}
+
+// Paid reports whether the payment was paid and not fully refunded
+func (p *Payment) Paid() bool {
+	return p.Status == PaymentStatusSuccess || p.Status == PaymentStatusPartiallyRefunded
+}
+
+// RefundableAmount is the part of a paid payment that was not refunded yet
+func (p *Payment) RefundableAmount() int64 {
+	if !p.Paid() {
+		return 0
+	}
+	return p.Amount - p.RefundedAmount
+}
+
+// Refund is a refund of a payment, full or partial
+type Refund struct {
+	ID                string    `json:"id" bson:"id"`
+	Amount            int64     `json:"amount" bson:"amount"` // Amount in cents
+	Reason            string    `json:"reason" bson:"reason"`
+	RequestedBy       string    `json:"requested_by" bson:"requestedBy"`
+	ProcessorRefundID string    `json:"processor_refund_id" bson:"processorRefundID"`
+	CreatedAt         time.Time `json:"created_at" bson:"createdAt"`
+}
+
+func (r *Refund) ToProto() *pb.Refund {
+	return &pb.Refund{
+		Id:                r.ID,
+		Amount:            r.Amount,
+		Reason:            r.Reason,
+		RequestedBy:       r.RequestedBy,
+		ProcessorRefundID: r.ProcessorRefundID,
+		CreatedAt:         r.CreatedAt.UTC().Format(time.RFC3339),
+	}
+}
+
+func toRefundsProto(refunds []Refund) []*pb.Refund {
+	protoRefunds := make([]*pb.Refund, len(refunds))
+	for i := range refunds {
+		protoRefunds[i] = refunds[i].ToProto()
+	}
+	return protoRefunds
+}
+
+// FareShare is the part of a split fare a rider pays, in cents
+type FareShare struct {
+	UserID string
+	Amount int64
 }
 
 // PaymentIntent represents the intent to collect a payment
@@ -33,10 +163,50 @@ type PaymentIntent struct {
 	DriverID        string    `json:"driver_id"`
 	Amount          int64     `json:"amount"`
 	Currency        string    `json:"currency"`
-	StripeSessionID string    `json:"stripe_session_id"`
+	StripeSessionID string    `json:"stripe_session_id"` // Unset when the saved card of the rider was charged
+	OffSession      bool      `json:"off_session"`
 	CreatedAt       time.Time `json:"created_at"`
 }
 
+// PaymentEvent is an outcome of a payment reported by the Stripe webhook. The IDs and the
+// metadata of the Stripe objects identify the payment, which of them are set depends on the event.
+// A succeeded setup intent has no payment, it sets SetupIntentID and the card saved for UserID.
+type PaymentEvent struct {
+	ID              string // ID of the Stripe event
+	Type            string // Type of the Stripe event
+	Status          PaymentStatus
+	PaymentID       string // From the metadata, unset for the sessions created before it was added
+	SessionID       string
+	PaymentIntentID string
+	TripID          string
+	UserID          string
+	DriverID        string
+	SetupIntentID   string
+	CustomerID      string
+	PaymentMethodID string
+	// ProcessorRefundIDs are the refunds of a refunded charge, when Stripe lists them
+	ProcessorRefundIDs []string
+}
+
+// TipLimits bound the tips of a trip, in cents. Max bounds the sum of the paid tips of the trip.
+type TipLimits struct {
+	Min int64
+	Max int64
+}
+
+// HoldConfig sets the holds placed on the saved cards when the riders request a trip. The fare is
+// held with a buffer of BufferPercent. RequireCard refuses the trips of the riders who saved no card,
+// without it they are let through with no hold and pay at checkout, in development only.
+type HoldConfig struct {
+	BufferPercent int
+	RequireCard   bool
+}
+
+// HoldAmount is the amount held for a quoted fare
+func (c HoldConfig) HoldAmount(fare int64) int64 {
+	return fare + fare*int64(c.BufferPercent)/100
+}
+
 // PaymentConfig holds the configuration for the payment service
 type PaymentConfig struct {
 	StripeSecretKey     string `json:"stripeSecretKey"`
@@ -0,0 +1,39 @@
+package types
+
+import "testing"
+
+func TestPaymentStatusCanMoveTo(t *testing.T) {
+	tests := []struct {
+		from, to PaymentStatus
+		want     bool
+	}{
+		{PaymentStatusPending, PaymentStatusSuccess, true},
+		{PaymentStatusPending, PaymentStatusFailed, true},
+		{PaymentStatusFailed, PaymentStatusSuccess, true}, // Paid at the second attempt in the checkout
+		{PaymentStatusAuthorized, PaymentStatusSuccess, true},
+		{PaymentStatusAuthorized, PaymentStatusCancelled, true},
+		{PaymentStatusSuccess, PaymentStatusPartiallyRefunded, true},
+		{PaymentStatusPartiallyRefunded, PaymentStatusRefunded, true},
+		{PaymentStatusDisputed, PaymentStatusRefunded, true},
+		// A refund reported before the success of the charge it refunds
+		{PaymentStatusPending, PaymentStatusRefunded, true},
+
+		// The events delivered late never move a payment back
+		{PaymentStatusSuccess, PaymentStatusFailed, false},
+		{PaymentStatusSuccess, PaymentStatusCancelled, false},
+		{PaymentStatusSuccess, PaymentStatusPending, false},
+		{PaymentStatusRefunded, PaymentStatusSuccess, false},
+		{PaymentStatusRefunded, PaymentStatusDisputed, false},
+		{PaymentStatusPartiallyRefunded, PaymentStatusSuccess, false},
+		{PaymentStatusDisputed, PaymentStatusSuccess, false},
+		{PaymentStatusCancelled, PaymentStatusSuccess, false},
+		{PaymentStatusAuthorized, PaymentStatusFailed, false},
+		{PaymentStatusSuccess, PaymentStatusSuccess, false},
+	}
+
+	for _, tt := range tests {
+		if got := tt.from.CanMoveTo(tt.to); got != tt.want {
+			t.Errorf("%s.CanMoveTo(%s) = %v, want %v", tt.from, tt.to, got, tt.want)
+		}
+	}
+}
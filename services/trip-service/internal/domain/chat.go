@@ -0,0 +1,174 @@
+package domain
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	pb "ride-sharing/shared/proto/trip"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	ChatStatusSent      = "sent"
+	ChatStatusDelivered = "delivered"
+	ChatStatusRead      = "read"
+)
+
+// MaxChatMessageLength is the max number of characters of a chat message
+const MaxChatMessageLength = 500
+
+// ChatTripStatuses are the statuses of a trip whose participants can chat: a driver is on the way
+// to the rider or driving them.
+var ChatTripStatuses = []string{TripStatusAccepted, TripStatusPayed}
+
+// chatStatusRank orders the statuses, a receipt never moves a message back
+var chatStatusRank = map[string]int{
+	ChatStatusSent:      1,
+	ChatStatusDelivered: 2,
+	ChatStatusRead:      3,
+}
+
+// ChatStatusAfter reports whether the status is later than the current one
+func ChatStatusAfter(status, current string) bool {
+	return chatStatusRank[status] > chatStatusRank[current]
+}
+
+type QuickReply struct {
+	ID   string
+	Text string
+}
+
+// riderQuickReplies and driverQuickReplies are the templates the participants can send in one tap
+var (
+	riderQuickReplies = []QuickReply{
+		{ID: "rider.on_my_way", Text: "I'm on my way"},
+		{ID: "rider.at_pickup", Text: "I'm at the pickup point"},
+		{ID: "rider.wait", Text: "Please wait a couple of minutes"},
+		{ID: "rider.call_me", Text: "Please call me when you arrive"},
+	}
+	driverQuickReplies = []QuickReply{
+		{ID: "driver.arrived", Text: "I've arrived"},
+		{ID: "driver.traffic", Text: "I'm stuck in traffic, I'll be there soon"},
+		{ID: "driver.cant_find", Text: "I can't find you, where are you?"},
+		{ID: "driver.ok", Text: "OK, no problem"},
+	}
+)
+
+// QuickReplies returns the templates of the participant, the rider or the driver of the trip
+func QuickReplies(trip *TripModel, userID string) []QuickReply {
+	if trip.UserID == userID {
+		return riderQuickReplies
+	}
+	return driverQuickReplies
+}
+
+// FindQuickReply returns the template of the participant with this ID
+func FindQuickReply(trip *TripModel, userID string, id string) (QuickReply, bool) {
+	replies := QuickReplies(trip, userID)
+	i := slices.IndexFunc(replies, func(r QuickReply) bool { return r.ID == id })
+	if i < 0 {
+		return QuickReply{}, false
+	}
+	return replies[i], true
+}
+
+func (r QuickReply) ToProto() *pb.QuickReply {
+	return &pb.QuickReply{
+		Id:   r.ID,
+		Text: r.Text,
+	}
+}
+
+// Participant returns the other participant of the trip, ok is false when userID is neither its rider nor its driver
+func (t *TripModel) Participant(userID string) (other string, ok bool) {
+	switch {
+	case userID == "":
+		return "", false
+	case userID == t.UserID:
+		if t.Driver == nil {
+			return "", true
+		}
+		return t.Driver.ID, true
+	case t.Driver != nil && userID == t.Driver.ID:
+		return t.UserID, true
+	default:
+		return "", false
+	}
+}
+
+// ChatOpen reports whether the participants can send messages
+func (t *TripModel) ChatOpen() bool {
+	return t.Driver != nil && slices.Contains(ChatTripStatuses, t.Status)
+}
+
+type ChatMessageModel struct {
+	ID              primitive.ObjectID `bson:"_id,omitempty"`
+	TripID          string             `bson:"tripID"`
+	SenderID        string             `bson:"senderID"`
+	RecipientID     string             `bson:"recipientID"`
+	Text            string             `bson:"text"`
+	TemplateID      string             `bson:"templateID,omitempty"`
+	ClientMessageID string             `bson:"clientMessageID,omitempty"`
+	Status          string             `bson:"status"`
+	SentAt          time.Time          `bson:"sentAt"`
+	DeliveredAt     *time.Time         `bson:"deliveredAt,omitempty"`
+	ReadAt          *time.Time         `bson:"readAt,omitempty"`
+}
+
+func (m *ChatMessageModel) ToProto() *pb.ChatMessage {
+	return &pb.ChatMessage{
+		Id:              m.ID.Hex(),
+		TripID:          m.TripID,
+		SenderID:        m.SenderID,
+		RecipientID:     m.RecipientID,
+		Text:            m.Text,
+		TemplateID:      m.TemplateID,
+		ClientMessageID: m.ClientMessageID,
+		Status:          m.Status,
+		SentAt:          formatTime(&m.SentAt),
+		DeliveredAt:     formatTime(m.DeliveredAt),
+		ReadAt:          formatTime(m.ReadAt),
+	}
+}
+
+func ToChatMessagesProto(messages []*ChatMessageModel) []*pb.ChatMessage {
+	protoMessages := make([]*pb.ChatMessage, len(messages))
+	for i, m := range messages {
+		protoMessages[i] = m.ToProto()
+	}
+	return protoMessages
+}
+
+func formatTime(t *time.Time) string {
+	if t == nil || t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+// ChatRepository persists the chat messages of the trips
+type ChatRepository interface {
+	SaveChatMessage(ctx context.Context, message *ChatMessageModel) error
+	// GetChatMessageByClientID returns the message the sender already sent with this client ID, or nil if there is none
+	GetChatMessageByClientID(ctx context.Context, tripID, senderID, clientMessageID string) (*ChatMessageModel, error)
+	// GetChatMessages returns the messages of the trip, oldest first
+	GetChatMessages(ctx context.Context, tripID string) ([]*ChatMessageModel, error)
+	// UpdateChatStatus moves the messages sent to the recipient to the status, if it is later than
+	// their current one, and returns the updated messages
+	UpdateChatStatus(ctx context.Context, tripID, recipientID string, messageIDs []string, status string, at time.Time) ([]*ChatMessageModel, error)
+}
+
+// ChatService handles the chat between the rider and the driver of a trip
+type ChatService interface {
+	// SendChatMessage saves the message of a participant of an ongoing trip. text is ignored when templateID is set.
+	// Sending again a clientMessageID returns the message already saved.
+	SendChatMessage(ctx context.Context, tripID, senderID, text, templateID, clientMessageID string) (*ChatMessageModel, error)
+	// RecordChatReceipt marks the messages received by the reader as delivered or read
+	RecordChatReceipt(ctx context.Context, tripID, readerID string, messageIDs []string, status string) ([]*ChatMessageModel, error)
+	// GetChatMessages returns the chat of the trip to one of its participants
+	GetChatMessages(ctx context.Context, tripID, userID string) ([]*ChatMessageModel, error)
+	// ListQuickReplies returns the templates a participant of the trip can send
+	ListQuickReplies(ctx context.Context, tripID, userID string) ([]QuickReply, error)
+}
@@ -0,0 +1,56 @@
+package domain
+
+import (
+	"fmt"
+
+	"ride-sharing/shared/contracts"
+)
+
+// ErrorKind classifies the domain errors, the transports map it to their own status codes
+type ErrorKind int
+
+const (
+	KindInvalidArgument ErrorKind = iota + 1
+	KindNotFound
+	KindForbidden
+	KindExpired
+	KindFailedPrecondition
+)
+
+// Error is an expected failure of the domain. Reason is a stable code from contracts for the clients.
+type Error struct {
+	Kind    ErrorKind
+	Reason  string
+	Message string
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+var (
+	ErrInvalidID          = &Error{Kind: KindInvalidArgument, Reason: contracts.ErrCodeInvalidID, Message: "invalid ID"}
+	ErrUserMismatch       = &Error{Kind: KindForbidden, Reason: contracts.ErrCodeUserMismatch, Message: "can't act on behalf of another user"}
+	ErrFareNotFound       = &Error{Kind: KindNotFound, Reason: contracts.ErrCodeFareNotFound, Message: "fare not found"}
+	ErrFareNotOwned       = &Error{Kind: KindForbidden, Reason: contracts.ErrCodeFareNotOwned, Message: "fare does not belong to the user"}
+	ErrFareExpired        = &Error{Kind: KindExpired, Reason: contracts.ErrCodeFareExpired, Message: "fare has expired, preview the trip again"}
+	ErrTripNotFound       = &Error{Kind: KindNotFound, Reason: contracts.ErrCodeTripNotFound, Message: "trip not found"}
+	ErrTripNotOffered     = &Error{Kind: KindForbidden, Reason: contracts.ErrCodeTripNotOffered, Message: "trip was not offered to the driver"}
+	ErrDriverNotFound     = &Error{Kind: KindNotFound, Reason: contracts.ErrCodeDriverNotFound, Message: "driver is not registered"}
+	ErrNoActiveTrip       = &Error{Kind: KindNotFound, Reason: contracts.ErrCodeNoActiveTrip, Message: "no active trip"}
+	ErrNotParticipant     = &Error{Kind: KindForbidden, Reason: contracts.ErrCodeNotParticipant, Message: "user is neither the rider nor the driver of the trip"}
+	ErrChatClosed         = &Error{Kind: KindFailedPrecondition, Reason: contracts.ErrCodeChatClosed, Message: "chat is only open while a driver is assigned to the trip"}
+	ErrUnknownReply       = &Error{Kind: KindInvalidArgument, Reason: contracts.ErrCodeUnknownReply, Message: "unknown quick reply"}
+	ErrInvalidChatMessage = &Error{Kind: KindInvalidArgument, Reason: contracts.ErrCodeInvalidChatMessage, Message: "invalid chat message"}
+	ErrNotTripOwner       = &Error{Kind: KindForbidden, Reason: contracts.ErrCodeNotTripOwner, Message: "user did not request the trip"}
+	ErrSplitClosed        = &Error{Kind: KindFailedPrecondition, Reason: contracts.ErrCodeSplitClosed, Message: "fare can only be split until a driver accepts the trip"}
+	ErrInvalidSplit       = &Error{Kind: KindInvalidArgument, Reason: contracts.ErrCodeInvalidSplit, Message: "invalid split"}
+	ErrNotInvited         = &Error{Kind: KindForbidden, Reason: contracts.ErrCodeNotInvited, Message: "user was not invited to split the fare"}
+	ErrHoldRefused        = &Error{Kind: KindFailedPrecondition, Reason: contracts.ErrCodeHoldRefused, Message: "fare could not be held on the card of the rider"}
+	ErrTripNotCancellable = &Error{Kind: KindFailedPrecondition, Reason: contracts.ErrCodeTripNotCancellable, Message: "trip can only be cancelled until a driver accepts it"}
+)
+
+// InvalidIDError wraps ErrInvalidID with the offending ID
+func InvalidIDError(id string) error {
+	return fmt.Errorf("%w: %q", ErrInvalidID, id)
+}
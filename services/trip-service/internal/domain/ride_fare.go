@@ -1,6 +1,8 @@
 package domain
 
 import (
+	"time"
+
 	"ride-sharing/services/trip-service/pkg/types"
 	pb "ride-sharing/shared/proto/trip"
 
@@ -13,6 +15,15 @@ type RideFareModel struct {
 	PackageSlug       string                 `bson:"packageSlug"` // ex: van, luxury, sedan
 	TotalPriceInCents float64                `bson:"totalPriceInCents"`
 	Route             *types.OsrmApiResponse `bson:"route"`
+	ExpiresAt         time.Time              `bson:"expiresAt"`
+}
+
+// RideFareTTL is how long a previewed fare can be used to start a trip
+const RideFareTTL = 15 * time.Minute
+
+// Expired reports whether the fare can no longer be used. Fares saved before they had an expiry never expire.
+func (r *RideFareModel) Expired(now time.Time) bool {
+	return !r.ExpiresAt.IsZero() && now.After(r.ExpiresAt)
 }
 
 func (r *RideFareModel) ToProto() *pb.RideFare {
@@ -0,0 +1,136 @@
+package domain
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	pb "ride-sharing/shared/proto/trip"
+)
+
+const (
+	// SplitModeEqual divides the fare between the rider and the riders who accepted
+	SplitModeEqual = "equal"
+	// SplitModeCustom asks every invited rider for their own share, the rider pays the rest
+	SplitModeCustom = "custom"
+
+	SplitStatusInvited  = "invited"
+	SplitStatusAccepted = "accepted"
+	SplitStatusDeclined = "declined"
+)
+
+// MaxSplitParticipants is the max number of riders invited to split a fare
+const MaxSplitParticipants = 4
+
+// SplitTripStatuses are the statuses of a trip whose fare can still be split: the shares are locked
+// once a driver accepts it.
+var SplitTripStatuses = []string{TripStatusAuthorizing, TripStatusPending}
+
+// TripSplit is the split of the fare of a trip between the rider who requested it and the riders they invited
+type TripSplit struct {
+	Mode         string              `bson:"mode"`
+	Participants []*SplitParticipant `bson:"participants"`
+	// Shares are locked when a driver accepts the trip, the rider who requested it included
+	Shares []*SplitShare `bson:"shares,omitempty"`
+}
+
+type SplitParticipant struct {
+	UserID      string     `bson:"userID"`
+	Share       int64      `bson:"share,omitempty"` // In cents, custom mode only
+	Status      string     `bson:"status"`
+	InvitedAt   time.Time  `bson:"invitedAt"`
+	RespondedAt *time.Time `bson:"respondedAt,omitempty"`
+}
+
+type SplitShare struct {
+	UserID string `bson:"userID"`
+	Amount int64  `bson:"amount"` // In cents
+	Paid   bool   `bson:"paid"`
+}
+
+// Participant returns the invitation of the rider, or nil if they were not invited
+func (s *TripSplit) Participant(userID string) *SplitParticipant {
+	i := slices.IndexFunc(s.Participants, func(p *SplitParticipant) bool { return p.UserID == userID })
+	if i < 0 {
+		return nil
+	}
+	return s.Participants[i]
+}
+
+// ComputeShares splits the fare between the owner and the participants who accepted. In equal mode
+// the cents left by the division go to the owner, in custom mode the owner pays what the others don't.
+func (s *TripSplit) ComputeShares(ownerID string, fare int64) []*SplitShare {
+	var accepted []*SplitParticipant
+	for _, p := range s.Participants {
+		if p.Status == SplitStatusAccepted {
+			accepted = append(accepted, p)
+		}
+	}
+
+	owner := &SplitShare{UserID: ownerID, Amount: fare}
+	shares := []*SplitShare{owner}
+	for _, p := range accepted {
+		amount := p.Share
+		if s.Mode == SplitModeEqual {
+			amount = fare / int64(len(accepted)+1)
+		}
+		owner.Amount -= amount
+		shares = append(shares, &SplitShare{UserID: p.UserID, Amount: amount})
+	}
+	return shares
+}
+
+// Paid reports whether every share of the fare was paid
+func (s *TripSplit) Paid() bool {
+	return !slices.ContainsFunc(s.Shares, func(share *SplitShare) bool { return !share.Paid })
+}
+
+func (s *TripSplit) ToProto() *pb.TripSplit {
+	if s == nil {
+		return nil
+	}
+
+	participants := make([]*pb.SplitParticipant, len(s.Participants))
+	for i, p := range s.Participants {
+		participants[i] = &pb.SplitParticipant{
+			UserID:      p.UserID,
+			Share:       p.Share,
+			Status:      p.Status,
+			InvitedAt:   formatTime(&p.InvitedAt),
+			RespondedAt: formatTime(p.RespondedAt),
+		}
+	}
+
+	shares := make([]*pb.SplitShare, len(s.Shares))
+	for i, share := range s.Shares {
+		shares[i] = &pb.SplitShare{
+			UserID: share.UserID,
+			Amount: share.Amount,
+			Paid:   share.Paid,
+		}
+	}
+
+	return &pb.TripSplit{
+		Mode:         s.Mode,
+		Participants: participants,
+		Shares:       shares,
+	}
+}
+
+// SplitInvite is a rider the owner of a trip invites to split its fare
+type SplitInvite struct {
+	UserID string
+	Share  int64 // In cents, custom mode only
+}
+
+// SplitService handles the split of the fare of a trip between several riders
+type SplitService interface {
+	// SplitFare invites riders to split the fare of a trip requested by ownerID. Inviting again replaces
+	// the invitations, the riders invited already keep their answer unless their share changed.
+	SplitFare(ctx context.Context, tripID, ownerID, mode string, invites []SplitInvite) (*TripModel, error)
+	// RespondToSplit records the answer of an invited rider. Answering again changes the answer.
+	RespondToSplit(ctx context.Context, tripID, userID string, accept bool) (*TripModel, error)
+	// LockSplitShares computes the shares of a split fare once a driver accepted the trip, they are kept
+	// as is when they were locked already. It returns nil for a trip whose fare was not split.
+	LockSplitShares(ctx context.Context, trip *TripModel) ([]*SplitShare, error)
+}
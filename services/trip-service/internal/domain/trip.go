@@ -3,14 +3,33 @@ package domain
 import (
 	"context"
 	"ride-sharing/shared/types"
+	"time"
 
 	tripTypes "ride-sharing/services/trip-service/pkg/types"
-	pbd "ride-sharing/shared/proto/driver"
 	pb "ride-sharing/shared/proto/trip"
 
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+const (
+	// TripStatusAuthorizing is a trip waiting for the hold of its fare on the card of the rider, it is
+	// offered to the drivers once the hold is authorized
+	TripStatusAuthorizing = "authorizing"
+	TripStatusPending     = "pending"
+	TripStatusAccepted    = "accepted"
+	TripStatusPayed       = "payed"
+	// TripStatusDisputed is a payed trip whose payment the rider disputes with their bank
+	TripStatusDisputed = "disputed"
+	// TripStatusHoldFailed is a trip never dispatched, the bank refused to hold its fare
+	TripStatusHoldFailed = "hold_failed"
+	// TripStatusCancelled is a trip no driver accepted, or the rider cancelled before a driver accepted
+	// it. The hold of its fare is released.
+	TripStatusCancelled = "cancelled"
+)
+
+// ActiveTripStatuses are the statuses of a trip that is still in progress
+var ActiveTripStatuses = []string{TripStatusAuthorizing, TripStatusPending, TripStatusAccepted}
+
 type TripDriver struct {
 	ID             string `bson:"id"`
 	Name           string `bson:"name"`
@@ -36,6 +55,32 @@ type TripModel struct {
 	Status   string             `bson:"status"`
 	RideFare *RideFareModel     `bson:"rideFare"`
 	Driver   *TripDriver        `bson:"driver"`
+	// OfferedDriverID is the driver the trip was last offered to, the only one allowed to accept or decline it
+	OfferedDriverID string      `bson:"offeredDriverID,omitempty"`
+	Refund          *TripRefund `bson:"refund,omitempty"`
+	Split           *TripSplit  `bson:"split,omitempty"`
+}
+
+// TripRefund sums up the refunds of the payment of the trip
+type TripRefund struct {
+	RefundedAmount int64     `bson:"refundedAmount"` // In cents
+	Currency       string    `bson:"currency"`
+	Reason         string    `bson:"reason"` // Reason of the last refund
+	Full           bool      `bson:"full"`
+	RefundedAt     time.Time `bson:"refundedAt"`
+}
+
+func (r *TripRefund) ToProto() *pb.TripRefund {
+	if r == nil {
+		return nil
+	}
+	return &pb.TripRefund{
+		RefundedAmount: r.RefundedAmount,
+		Currency:       r.Currency,
+		Reason:         r.Reason,
+		Full:           r.Full,
+		RefundedAt:     r.RefundedAt.UTC().Format(time.RFC3339),
+	}
 }
 
 func (t *TripModel) ToProto() *pb.Trip {
@@ -46,18 +91,47 @@ func (t *TripModel) ToProto() *pb.Trip {
 		Status:       t.Status,
 		Driver:       t.Driver.ToProto(),
 		Route:        t.RideFare.Route.ToProto(),
+		Refund:       t.Refund.ToProto(),
+		Split:        t.Split.ToProto(),
 	}
 }
 
+// DriverProfiles looks the drivers up in the driver service
+type DriverProfiles interface {
+	// GetDriver returns the profile of a registered driver, or ErrDriverNotFound
+	GetDriver(ctx context.Context, driverID string) (*TripDriver, error)
+}
+
+// FareHolds places the holds of the fares in the payment service
+type FareHolds interface {
+	// AuthorizeHold holds the fare of the trip on the saved card of the rider. A hold refused by the
+	// bank returns ErrHoldRefused with the reason.
+	AuthorizeHold(ctx context.Context, trip *TripModel) error
+}
+
 type TripRepository interface {
 	CreateTrip(ctx context.Context, trip *TripModel) (*TripModel, error)
 	SaveRideFare(ctx context.Context, f *RideFareModel) error
 	GetRideFareByID(ctx context.Context, id string) (*RideFareModel, error)
 	GetTripByID(ctx context.Context, id string) (*TripModel, error)
-	UpdateTrip(ctx context.Context, tripID string, status string, driver *pbd.Driver) error
+	// GetActiveTripByUserID returns the latest active trip of a rider or a driver, or nil if there is none
+	GetActiveTripByUserID(ctx context.Context, userID string) (*TripModel, error)
+	UpdateTrip(ctx context.Context, tripID string, status string) error
+	// CancelTrip cancels the trip if it is pending, or returns ErrTripNotCancellable
+	CancelTrip(ctx context.Context, tripID string) error
+	// AcceptTrip assigns the driver to the trip if it is pending and offered to them, or returns ErrTripNotOffered
+	AcceptTrip(ctx context.Context, tripID string, driver *TripDriver) error
+	SetOfferedDriver(ctx context.Context, tripID string, driverID string) error
+	SetTripRefund(ctx context.Context, tripID string, refund *TripRefund) error
+	SetTripSplit(ctx context.Context, tripID string, split *TripSplit) error
+	// MarkSplitSharePaid marks the share of the rider in the split fare of the trip as paid
+	MarkSplitSharePaid(ctx context.Context, tripID string, userID string) error
 }
 
 type TripService interface {
+	// CreateTrip saves the trip and holds its fare on the card of the rider, the trip is pending and can
+	// be offered to the drivers once the hold is authorized. When the hold is refused, with ErrHoldRefused,
+	// or the call fails, the trip is returned along with the error as hold_failed and never dispatched.
 	CreateTrip(ctx context.Context, fare *RideFareModel) (*TripModel, error)
 	GetRoute(ctx context.Context, pickup, destination *types.Coordinate, useOsrmApi bool) (*tripTypes.OsrmApiResponse, error)
 	EstimatePackagesPriceWithRoute(route *tripTypes.OsrmApiResponse) []*RideFareModel
@@ -69,5 +143,27 @@ type TripService interface {
 	) ([]*RideFareModel, error)
 	GetAndValidateFare(ctx context.Context, fareID, userID string) (*RideFareModel, error)
 	GetTripByID(ctx context.Context, id string) (*TripModel, error)
-	UpdateTrip(ctx context.Context, tripID string, status string, driver *pbd.Driver) error
+	GetActiveTrip(ctx context.Context, userID string) (*TripModel, error)
+	// AcceptTrip assigns the driver to the trip offered to them, with the profile of the driver service.
+	// A trip accepted by the driver already is returned as is, the accept commands may be redelivered.
+	AcceptTrip(ctx context.Context, tripID string, driverID string) (*TripModel, error)
+	// RecordDriverOffer remembers the driver a pending trip was offered to
+	RecordDriverOffer(ctx context.Context, tripID string, driverID string) error
+	// GetOfferedTrip returns the pending trip offered to the driver, or an error if it wasn't offered to them
+	GetOfferedTrip(ctx context.Context, tripID string, driverID string) (*TripModel, error)
+	// MarkTripPayed records the payment of the fare of an accepted trip by the rider, the trip moves to
+	// payed once every share of a split fare is paid. It reports false, without error, when the trip
+	// didn't move: the payment events may be redelivered.
+	MarkTripPayed(ctx context.Context, tripID string, userID string) (bool, error)
+	// MarkTripDisputed moves a payed trip to disputed. It reports false, without error, when the trip
+	// isn't payed: the payment events may be redelivered.
+	MarkTripDisputed(ctx context.Context, tripID string) (bool, error)
+	// CancelTrip cancels a pending trip no driver accepted, the hold of its fare can be released. It reports
+	// true as well for a trip already cancelled, and false for a trip in any other status.
+	CancelTrip(ctx context.Context, tripID string) (*TripModel, bool, error)
+	// CancelRiderTrip cancels the trip requested by the rider until a driver accepts it, a cancelled trip
+	// is returned as is. It returns ErrTripNotCancellable for a trip in any other status.
+	CancelRiderTrip(ctx context.Context, tripID string, userID string) (*TripModel, error)
+	// RecordTripRefund annotates the trip with the refunds of its payment
+	RecordTripRefund(ctx context.Context, tripID string, refund *TripRefund) error
 }
@@ -0,0 +1,55 @@
+package clients
+
+import (
+	"context"
+	"fmt"
+
+	"ride-sharing/services/trip-service/internal/domain"
+	"ride-sharing/shared/env"
+	"ride-sharing/shared/grpcclient"
+	pbd "ride-sharing/shared/proto/driver"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// DriverClient reads the profiles of the drivers from the driver service
+type DriverClient struct {
+	client pbd.DriverServiceClient
+	conn   *grpc.ClientConn
+}
+
+func NewDriverClient() (*DriverClient, error) {
+	conn, err := grpcclient.NewConn(env.GetString("DRIVER_SERVICE_URL", "driver-service:8080"), pbd.DriverService_ServiceDesc.ServiceName)
+	if err != nil {
+		return nil, err
+	}
+
+	return &DriverClient{
+		client: pbd.NewDriverServiceClient(conn),
+		conn:   conn,
+	}, nil
+}
+
+func (c *DriverClient) GetDriver(ctx context.Context, driverID string) (*domain.TripDriver, error) {
+	res, err := c.client.GetDriver(ctx, &pbd.GetDriverRequest{DriverID: driverID})
+	if status.Code(err) == codes.NotFound {
+		return nil, fmt.Errorf("%w: %s", domain.ErrDriverNotFound, driverID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get driver %s: %w", driverID, err)
+	}
+
+	driver := res.GetDriver()
+	return &domain.TripDriver{
+		ID:             driver.GetId(),
+		Name:           driver.GetName(),
+		ProfilePicture: driver.GetProfilePicture(),
+		CarPlate:       driver.GetCarPlate(),
+	}, nil
+}
+
+func (c *DriverClient) Close() error {
+	return c.conn.Close()
+}
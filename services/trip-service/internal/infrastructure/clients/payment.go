@@ -0,0 +1,61 @@
+package clients
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+
+	"ride-sharing/services/trip-service/internal/domain"
+	"ride-sharing/shared/env"
+	"ride-sharing/shared/grpcclient"
+	pbp "ride-sharing/shared/proto/payment"
+
+	"google.golang.org/grpc"
+)
+
+// PaymentClient holds the fares of the trips in the payment service
+type PaymentClient struct {
+	client pbp.PaymentServiceClient
+	conn   *grpc.ClientConn
+}
+
+func NewPaymentClient() (*PaymentClient, error) {
+	// The service answers gRPC and the proxied Stripe webhooks on the same port
+	target := env.GetString("PAYMENT_SERVICE_URL", "payment-service:9004")
+	if u, err := url.Parse(target); err == nil && u.Host != "" {
+		target = u.Host
+	}
+
+	conn, err := grpcclient.NewConn(target, pbp.PaymentService_ServiceDesc.ServiceName)
+	if err != nil {
+		return nil, err
+	}
+
+	return &PaymentClient{
+		client: pbp.NewPaymentServiceClient(conn),
+		conn:   conn,
+	}, nil
+}
+
+func (c *PaymentClient) AuthorizeHold(ctx context.Context, trip *domain.TripModel) error {
+	res, err := c.client.AuthorizeHold(ctx, &pbp.AuthorizeHoldRequest{
+		TripID:      trip.ID.Hex(),
+		UserID:      trip.UserID,
+		PackageSlug: trip.RideFare.PackageSlug,
+		Amount:      int64(trip.RideFare.TotalPriceInCents),
+		Currency:    "USD",
+	})
+	if err != nil {
+		return fmt.Errorf("failed to hold the fare of trip %s: %w", trip.ID.Hex(), err)
+	}
+
+	if reason := res.GetFailureReason(); reason != "" {
+		return fmt.Errorf("%w: %s", domain.ErrHoldRefused, reason)
+	}
+
+	return nil
+}
+
+func (c *PaymentClient) Close() error {
+	return c.conn.Close()
+}
@@ -0,0 +1,125 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+
+	"ride-sharing/services/trip-service/internal/domain"
+	"ride-sharing/shared/contracts"
+	"ride-sharing/shared/messaging"
+
+	"github.com/rabbitmq/amqp091-go"
+)
+
+// chatConsumer saves the chat messages of the trips and routes them to the other participant.
+// The owner of the commands is the user authenticated by the gateway.
+type chatConsumer struct {
+	rabbitmq messaging.Broker
+	service  domain.ChatService
+}
+
+func NewChatConsumer(rabbitmq messaging.Broker, service domain.ChatService) *chatConsumer {
+	return &chatConsumer{
+		rabbitmq: rabbitmq,
+		service:  service,
+	}
+}
+
+func (c *chatConsumer) Listen() error {
+	return c.rabbitmq.ConsumeMessages(messaging.ChatCmdQueue, func(ctx context.Context, msg amqp091.Delivery) error {
+		var message contracts.AmqpMessage
+		if err := json.Unmarshal(msg.Body, &message); err != nil {
+			log.Printf("Failed to unmarshal message: %v", err)
+			return err
+		}
+
+		var err error
+		switch msg.RoutingKey {
+		case contracts.ChatCmdSend:
+			err = c.handleSend(ctx, message)
+		case contracts.ChatCmdReceipt:
+			err = c.handleReceipt(ctx, message)
+		default:
+			log.Printf("Unknown chat command: %s", msg.RoutingKey)
+			return nil
+		}
+
+		// A rejected command won't succeed on a retry
+		var domainErr *domain.Error
+		if errors.As(err, &domainErr) {
+			log.Printf("Rejected %s from user %s: %v", msg.RoutingKey, message.OwnerID, err)
+			return nil
+		}
+
+		return err
+	})
+}
+
+func (c *chatConsumer) handleSend(ctx context.Context, message contracts.AmqpMessage) error {
+	var payload messaging.ChatSendData
+	if err := json.Unmarshal(message.Data, &payload); err != nil {
+		log.Printf("Failed to unmarshal payload: %v", err)
+		return err
+	}
+
+	chatMessage, err := c.service.SendChatMessage(ctx, payload.TripID, message.OwnerID, payload.Text, payload.TemplateID, payload.ClientMessageID)
+	if err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(chatMessage.ToProto())
+	if err != nil {
+		return err
+	}
+
+	// The message goes to the recipient, and back to the sender as the acknowledgement of the send
+	for _, userID := range []string{chatMessage.RecipientID, chatMessage.SenderID} {
+		if err := c.rabbitmq.PublishMessage(ctx, contracts.ChatEventMessage, contracts.AmqpMessage{
+			OwnerID: userID,
+			Data:    data,
+		}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (c *chatConsumer) handleReceipt(ctx context.Context, message contracts.AmqpMessage) error {
+	var payload messaging.ChatReceiptData
+	if err := json.Unmarshal(message.Data, &payload); err != nil {
+		log.Printf("Failed to unmarshal payload: %v", err)
+		return err
+	}
+
+	updated, err := c.service.RecordChatReceipt(ctx, payload.TripID, message.OwnerID, payload.MessageIDs, payload.Status)
+	if err != nil {
+		return err
+	}
+
+	if len(updated) == 0 {
+		return nil
+	}
+
+	// Every message of the receipt was sent by the other participant
+	receipt := messaging.ChatReceiptData{
+		TripID:     payload.TripID,
+		MessageIDs: make([]string, len(updated)),
+		Status:     payload.Status,
+	}
+	for i, m := range updated {
+		receipt.MessageIDs[i] = m.ID.Hex()
+	}
+
+	data, err := json.Marshal(receipt)
+	if err != nil {
+		return err
+	}
+
+	return c.rabbitmq.PublishMessage(ctx, contracts.ChatEventReceipt, contracts.AmqpMessage{
+		OwnerID: updated[0].SenderID,
+		Data:    data,
+	})
+}
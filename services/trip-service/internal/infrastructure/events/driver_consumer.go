@@ -3,25 +3,28 @@ package events
 import (
 	"context"
 	"encoding/json"
-	"fmt"
+	"errors"
 	"log"
 	"ride-sharing/services/trip-service/internal/domain"
 	"ride-sharing/shared/contracts"
 	"ride-sharing/shared/messaging"
-	pbd "ride-sharing/shared/proto/driver"
 
 	"github.com/rabbitmq/amqp091-go"
 )
 
 type driverConsumer struct {
-	rabbitmq *messaging.RabbitMQ
-	service  domain.TripService
+	rabbitmq  messaging.Broker
+	service   domain.TripService
+	splits    domain.SplitService
+	publisher *TripEventPublisher
 }
 
-func NewDriverConsumer(rabbitmq *messaging.RabbitMQ, service domain.TripService) *driverConsumer {
+func NewDriverConsumer(rabbitmq messaging.Broker, service domain.TripService, splits domain.SplitService, publisher *TripEventPublisher) *driverConsumer {
 	return &driverConsumer{
-		rabbitmq: rabbitmq,
-		service:  service,
+		rabbitmq:  rabbitmq,
+		service:   service,
+		splits:    splits,
+		publisher: publisher,
 	}
 }
 
@@ -41,29 +44,86 @@ func (c *driverConsumer) Listen() error {
 
 		log.Printf("driver response received message: %+v", payload)
 
+		// The owner is the driver authenticated by the gateway, the payload is whatever the client sent:
+		// only the ID of its driver is checked, the profile is read from the driver service
+		if payload.Driver == nil || payload.Driver.Id != message.OwnerID {
+			log.Printf("Ignoring the response of driver %s sent on behalf of another driver", message.OwnerID)
+			return c.publisher.PublishResponseRejected(ctx, message.OwnerID, payload.TripID, msg.RoutingKey, domain.ErrUserMismatch)
+		}
+
+		var err error
 		switch msg.RoutingKey {
 		case contracts.DriverCmdTripAccept:
-			if err := c.handleTripAccepted(ctx, payload.TripID, payload.Driver); err != nil {
-				log.Printf("Failed to handle the trip accept: %v", err)
-				return err
-			}
+			err = c.handleTripAccepted(ctx, payload.TripID, message.OwnerID)
 		case contracts.DriverCmdTripDecline:
-			if err := c.handleTripDeclined(ctx, payload.TripID, payload.RiderID); err != nil {
-				log.Printf("Failed to handle the trip decline: %v", err)
+			err = c.handleTripDeclined(ctx, payload.TripID, message.OwnerID)
+		default:
+			log.Printf("unknown trip event: %+v", payload)
+			return nil
+		}
+
+		// A trip that was not offered to the driver, or a driver who is not registered, won't succeed on a
+		// retry. The driver is told their response was refused.
+		var domainErr *domain.Error
+		if errors.As(err, &domainErr) {
+			log.Printf("Ignored the %s of driver %s: %v", msg.RoutingKey, message.OwnerID, err)
+			return c.publisher.PublishResponseRejected(ctx, message.OwnerID, payload.TripID, msg.RoutingKey, err)
+		}
+		if err != nil {
+			log.Printf("Failed to handle the %s of driver %s: %v", msg.RoutingKey, message.OwnerID, err)
+			return err
+		}
+
+		return nil
+	})
+}
+
+// ListenNoDriversFound cancels the trips no driver is available for and releases the hold of their fare
+func (c *driverConsumer) ListenNoDriversFound() error {
+	return c.rabbitmq.ConsumeMessages(messaging.TripNoDriversFoundQueue, func(ctx context.Context, msg amqp091.Delivery) error {
+		var message contracts.AmqpMessage
+		if err := json.Unmarshal(msg.Body, &message); err != nil {
+			log.Printf("Failed to unmarshal message: %v", err)
+			return err
+		}
+
+		var payload messaging.TripEventData
+		if len(message.Data) > 0 {
+			if err := json.Unmarshal(message.Data, &payload); err != nil {
+				log.Printf("Failed to unmarshal payload: %v", err)
 				return err
 			}
+		}
+
+		// The event had no data before it carried the trip
+		if payload.Trip == nil {
+			log.Printf("Ignored a no drivers found event without trip")
 			return nil
 		}
-		log.Printf("unknown trip event: %+v", payload)
 
-		return nil
+		trip, cancelled, err := c.service.CancelTrip(ctx, payload.Trip.Id)
+		if err != nil {
+			var domainErr *domain.Error
+			if errors.As(err, &domainErr) {
+				log.Printf("Ignored the no drivers found event of trip %s: %v", payload.Trip.Id, err)
+				return nil
+			}
+			return err
+		}
+
+		if !cancelled {
+			log.Printf("Ignored the no drivers found event of trip %s: the trip is %s", payload.Trip.Id, trip.Status)
+			return nil
+		}
+
+		return c.publisher.PublishReleaseHold(ctx, trip)
 	})
 }
 
-func (c *driverConsumer) handleTripDeclined(ctx context.Context, tripID, riderID string) error {
+func (c *driverConsumer) handleTripDeclined(ctx context.Context, tripID, driverID string) error {
 	// When a driver declines, we should try to find another driver
 
-	trip, err := c.service.GetTripByID(ctx, tripID)
+	trip, err := c.service.GetOfferedTrip(ctx, tripID, driverID)
 	if err != nil {
 		return err
 	}
@@ -79,7 +139,7 @@ func (c *driverConsumer) handleTripDeclined(ctx context.Context, tripID, riderID
 
 	if err := c.rabbitmq.PublishMessage(ctx, contracts.TripEventDriverNotInterested,
 		contracts.AmqpMessage{
-			OwnerID: riderID,
+			OwnerID: trip.UserID,
 			Data:    marshalledPayload,
 		},
 	); err != nil {
@@ -89,50 +149,51 @@ func (c *driverConsumer) handleTripDeclined(ctx context.Context, tripID, riderID
 	return nil
 }
 
-func (c *driverConsumer) handleTripAccepted(ctx context.Context, tripID string, driver *pbd.Driver) error {
-	// 1. Fetch the first
-	trip, err := c.service.GetTripByID(ctx, tripID)
+func (c *driverConsumer) handleTripAccepted(ctx context.Context, tripID string, driverID string) error {
+	// 1. Assign the driver, the trip must have been offered to them. A redelivered accept gets the
+	// trip back and publishes the events again.
+	trip, err := c.service.AcceptTrip(ctx, tripID, driverID)
 	if err != nil {
 		return err
 	}
 
-	if trip == nil {
-		return fmt.Errorf("Trip was not found %s", tripID)
+	// 2. Driver has been assigned -> publish this event to RB
+	marshalledTrip, err := json.Marshal(trip.ToProto())
+	if err != nil {
+		return err
 	}
 
-	// 2. Update the trip
-	if err := c.service.UpdateTrip(ctx, tripID, "accepted", driver); err != nil {
-		log.Printf("Failed to update the trip: %v", err)
+	// Notify the rider that a driver has been assigned
+	if err := c.rabbitmq.PublishMessage(ctx, contracts.TripEventDriverAssigned, contracts.AmqpMessage{
+		OwnerID: trip.UserID,
+		Data:    marshalledTrip,
+	}); err != nil {
 		return err
 	}
 
-	trip, err = c.service.GetTripByID(ctx, tripID)
+	// The riders who accepted to split the fare each pay their share from now on
+	shares, err := c.splits.LockSplitShares(ctx, trip)
 	if err != nil {
 		return err
 	}
 
-	// 3. Driver has been assigned -> publish this event to RB
-	marshalledTrip, err := json.Marshal(trip)
-	if err != nil {
-		return err
+	paymentPayload := messaging.PaymentTripResponseData{
+		TripID:      tripID,
+		UserID:      trip.UserID,
+		DriverID:    driverID,
+		PackageSlug: trip.RideFare.PackageSlug,
+		Amount:      trip.RideFare.TotalPriceInCents,
+		Currency:    "USD",
+	}
+	for _, share := range shares {
+		paymentPayload.Shares = append(paymentPayload.Shares, messaging.PaymentShare{UserID: share.UserID, Amount: share.Amount})
 	}
 
-	// Notify the rider that a driver has been assigned
-	if err := c.rabbitmq.PublishMessage(ctx, contracts.TripEventDriverAssigned, contracts.AmqpMessage{
-		OwnerID: trip.UserID,
-		Data:    marshalledTrip,
-	}); err != nil {
+	marshalledPayload, err := json.Marshal(paymentPayload)
+	if err != nil {
 		return err
 	}
 
-	marshalledPayload, err := json.Marshal(messaging.PaymentTripResponseData{
-		TripID:   tripID,
-		UserID:   trip.UserID,
-		DriverID: driver.Id,
-		Amount:   trip.RideFare.TotalPriceInCents,
-		Currency: "USD",
-	})
-
 	if err := c.rabbitmq.PublishMessage(ctx, contracts.PaymentCmdCreateSession,
 		contracts.AmqpMessage{
 			OwnerID: trip.UserID,
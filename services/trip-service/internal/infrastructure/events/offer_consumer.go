@@ -0,0 +1,67 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+
+	"ride-sharing/services/trip-service/internal/domain"
+	"ride-sharing/shared/contracts"
+	"ride-sharing/shared/messaging"
+
+	"github.com/rabbitmq/amqp091-go"
+)
+
+// offerConsumer records which driver each trip was offered to, so that only this driver can accept or
+// decline it. The trip request is sent to the driver once the offer is recorded.
+type offerConsumer struct {
+	rabbitmq messaging.Broker
+	service  domain.TripService
+}
+
+func NewOfferConsumer(rabbitmq messaging.Broker, service domain.TripService) *offerConsumer {
+	return &offerConsumer{
+		rabbitmq: rabbitmq,
+		service:  service,
+	}
+}
+
+func (c *offerConsumer) Listen() error {
+	return c.rabbitmq.ConsumeMessages(messaging.TripDriverOfferQueue, func(ctx context.Context, msg amqp091.Delivery) error {
+		var message contracts.AmqpMessage
+		if err := json.Unmarshal(msg.Body, &message); err != nil {
+			log.Printf("Failed to unmarshal message: %v", err)
+			return err
+		}
+
+		// The owner of a trip request is the driver it is sent to
+		var payload messaging.TripEventData
+		if err := json.Unmarshal(message.Data, &payload); err != nil {
+			log.Printf("Failed to unmarshal payload: %v", err)
+			return err
+		}
+
+		if payload.Trip == nil {
+			log.Printf("Ignoring a trip offer without trip for driver %s", message.OwnerID)
+			return nil
+		}
+
+		err := c.service.RecordDriverOffer(ctx, payload.Trip.Id, message.OwnerID)
+		// The trip is no longer pending, the driver is not asked
+		var domainErr *domain.Error
+		if errors.As(err, &domainErr) {
+			log.Printf("Ignored the offer of trip %s to driver %s: %v", payload.Trip.Id, message.OwnerID, err)
+			return nil
+		}
+		if err != nil {
+			log.Printf("Failed to record the offer of trip %s to driver %s: %v", payload.Trip.Id, message.OwnerID, err)
+			return err
+		}
+
+		return c.rabbitmq.PublishMessage(ctx, contracts.DriverCmdTripRequest, contracts.AmqpMessage{
+			OwnerID: message.OwnerID,
+			Data:    message.Data,
+		})
+	})
+}
@@ -3,7 +3,9 @@ package events
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"log"
+	"time"
 
 	"ride-sharing/services/trip-service/internal/domain"
 	"ride-sharing/shared/contracts"
@@ -13,14 +15,16 @@ import (
 )
 
 type paymentConsumer struct {
-	rabbitmq *messaging.RabbitMQ
-	service  domain.TripService
+	rabbitmq  messaging.Broker
+	service   domain.TripService
+	publisher *TripEventPublisher
 }
 
-func NewPaymentConsumer(rabbitmq *messaging.RabbitMQ, service domain.TripService) *paymentConsumer {
+func NewPaymentConsumer(rabbitmq messaging.Broker, service domain.TripService, publisher *TripEventPublisher) *paymentConsumer {
 	return &paymentConsumer{
-		rabbitmq: rabbitmq,
-		service:  service,
+		rabbitmq:  rabbitmq,
+		service:   service,
+		publisher: publisher,
 	}
 }
 
@@ -37,13 +41,91 @@ func (c *paymentConsumer) Listen() error {
 			return err
 		}
 
+		payed, err := c.service.MarkTripPayed(ctx, payload.TripID, payload.UserID)
+		if err != nil {
+			var domainErr *domain.Error
+			if errors.As(err, &domainErr) {
+				log.Printf("Ignored the payment of trip %s: %v", payload.TripID, err)
+				return nil
+			}
+			return err
+		}
+
+		if !payed {
+			log.Printf("Trip %s did not move to payed with the payment of user %s: the trip is not waiting for it, or for other shares of its fare", payload.TripID, payload.UserID)
+			return nil
+		}
+
 		log.Printf("Trip has been completed and payed.")
+		return nil
+	})
+}
+
+// ListenRefunds annotates the trips with the refunds of their payment
+func (c *paymentConsumer) ListenRefunds() error {
+	return c.rabbitmq.ConsumeMessages(messaging.NotifyPaymentRefundedQueue, func(ctx context.Context, msg amqp091.Delivery) error {
+		var message contracts.AmqpMessage
+		if err := json.Unmarshal(msg.Body, &message); err != nil {
+			log.Printf("Failed to unmarshal message: %v", err)
+			return err
+		}
+		var payload messaging.PaymentRefundedData
+		if err := json.Unmarshal(message.Data, &payload); err != nil {
+			log.Printf("Failed to unmarshal payload: %v", err)
+			return err
+		}
+
+		err := c.service.RecordTripRefund(ctx, payload.TripID, &domain.TripRefund{
+			RefundedAmount: payload.RefundedAmount,
+			Currency:       payload.Currency,
+			Reason:         payload.Reason,
+			Full:           payload.FullyRefunded,
+			RefundedAt:     time.Now(),
+		})
+		if err != nil {
+			var domainErr *domain.Error
+			if errors.As(err, &domainErr) {
+				log.Printf("Ignored the refund of trip %s: %v", payload.TripID, err)
+				return nil
+			}
+			return err
+		}
+
+		log.Printf("Recorded a refund of %d on trip %s", payload.Amount, payload.TripID)
+		return nil
+	})
+}
+
+// ListenDisputes moves the payed trips to disputed when the rider disputes their payment
+func (c *paymentConsumer) ListenDisputes() error {
+	return c.rabbitmq.ConsumeMessages(messaging.TripPaymentDisputedQueue, func(ctx context.Context, msg amqp091.Delivery) error {
+		var message contracts.AmqpMessage
+		if err := json.Unmarshal(msg.Body, &message); err != nil {
+			log.Printf("Failed to unmarshal message: %v", err)
+			return err
+		}
+		var payload messaging.PaymentStatusUpdateData
+		if err := json.Unmarshal(message.Data, &payload); err != nil {
+			log.Printf("Failed to unmarshal payload: %v", err)
+			return err
+		}
+
+		disputed, err := c.service.MarkTripDisputed(ctx, payload.TripID)
+		if err != nil {
+			var domainErr *domain.Error
+			if errors.As(err, &domainErr) {
+				log.Printf("Ignored the dispute of trip %s: %v", payload.TripID, err)
+				return nil
+			}
+			return err
+		}
+
+		if !disputed {
+			log.Printf("Trip %s did not move to disputed: the trip is not payed", payload.TripID)
+			return nil
+		}
 
-		return c.service.UpdateTrip(
-			ctx,
-			payload.TripID,
-			"payed",
-			nil,
-		)
+		log.Printf("The payment of trip %s is disputed by user %s", payload.TripID, payload.UserID)
+		return nil
 	})
 }
@@ -0,0 +1,69 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+
+	"ride-sharing/services/trip-service/internal/domain"
+	"ride-sharing/shared/contracts"
+	"ride-sharing/shared/messaging"
+
+	"github.com/rabbitmq/amqp091-go"
+)
+
+// splitConsumer records the answers of the riders invited to split a fare. The owner of the commands
+// is the invited rider, authenticated by the gateway.
+type splitConsumer struct {
+	rabbitmq  messaging.Broker
+	service   domain.SplitService
+	publisher *TripEventPublisher
+}
+
+func NewSplitConsumer(rabbitmq messaging.Broker, service domain.SplitService, publisher *TripEventPublisher) *splitConsumer {
+	return &splitConsumer{
+		rabbitmq:  rabbitmq,
+		service:   service,
+		publisher: publisher,
+	}
+}
+
+func (c *splitConsumer) Listen() error {
+	return c.rabbitmq.ConsumeMessages(messaging.TripCmdSplitQueue, func(ctx context.Context, msg amqp091.Delivery) error {
+		var message contracts.AmqpMessage
+		if err := json.Unmarshal(msg.Body, &message); err != nil {
+			log.Printf("Failed to unmarshal message: %v", err)
+			return err
+		}
+
+		var payload messaging.SplitResponseData
+		if err := json.Unmarshal(message.Data, &payload); err != nil {
+			log.Printf("Failed to unmarshal payload: %v", err)
+			return err
+		}
+
+		var accept bool
+		switch msg.RoutingKey {
+		case contracts.TripCmdSplitAccept:
+			accept = true
+		case contracts.TripCmdSplitDecline:
+		default:
+			log.Printf("Unknown split command: %s", msg.RoutingKey)
+			return nil
+		}
+
+		trip, err := c.service.RespondToSplit(ctx, payload.TripID, message.OwnerID, accept)
+		if err != nil {
+			// A rejected command won't succeed on a retry
+			var domainErr *domain.Error
+			if errors.As(err, &domainErr) {
+				log.Printf("Rejected %s from user %s: %v", msg.RoutingKey, message.OwnerID, err)
+				return nil
+			}
+			return err
+		}
+
+		return c.publisher.PublishSplitUpdated(ctx, trip)
+	})
+}
@@ -3,16 +3,17 @@ package events
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"ride-sharing/services/trip-service/internal/domain"
 	"ride-sharing/shared/contracts"
 	"ride-sharing/shared/messaging"
 )
 
 type TripEventPublisher struct {
-	rabbitmq *messaging.RabbitMQ
+	rabbitmq messaging.Broker
 }
 
-func NewTripEventPublisher(rabbitmq *messaging.RabbitMQ) *TripEventPublisher {
+func NewTripEventPublisher(rabbitmq messaging.Broker) *TripEventPublisher {
 	return &TripEventPublisher{
 		rabbitmq: rabbitmq,
 	}
@@ -33,3 +34,83 @@ func (p *TripEventPublisher) PublishTripCreated(ctx context.Context, trip *domai
 		Data:    tripEventJSON,
 	})
 }
+
+// PublishReleaseHold asks the payment service to release the hold of a trip that won't be paid
+func (p *TripEventPublisher) PublishReleaseHold(ctx context.Context, trip *domain.TripModel) error {
+	return p.publishHoldCmd(ctx, contracts.PaymentCmdReleaseHold, trip, messaging.PaymentHoldCmdData{
+		TripID: trip.ID.Hex(),
+		UserID: trip.UserID,
+	})
+}
+
+// PublishSplitInvited invites the rider to split the fare of the trip
+func (p *TripEventPublisher) PublishSplitInvited(ctx context.Context, trip *domain.TripModel, participant *domain.SplitParticipant) error {
+	payloadJSON, err := json.Marshal(messaging.SplitInvitationData{
+		TripID:   trip.ID.Hex(),
+		OwnerID:  trip.UserID,
+		Mode:     trip.Split.Mode,
+		Share:    participant.Share,
+		Fare:     int64(trip.RideFare.TotalPriceInCents),
+		Currency: "USD",
+	})
+	if err != nil {
+		return err
+	}
+
+	return p.rabbitmq.PublishMessage(ctx, contracts.TripEventSplitInvited, contracts.AmqpMessage{
+		OwnerID: participant.UserID,
+		Data:    payloadJSON,
+	})
+}
+
+// PublishSplitUpdated tells the rider who requested the trip that an invited rider answered
+func (p *TripEventPublisher) PublishSplitUpdated(ctx context.Context, trip *domain.TripModel) error {
+	payloadJSON, err := json.Marshal(messaging.TripEventData{
+		Trip: trip.ToProto(),
+	})
+	if err != nil {
+		return err
+	}
+
+	return p.rabbitmq.PublishMessage(ctx, contracts.TripEventSplitUpdated, contracts.AmqpMessage{
+		OwnerID: trip.UserID,
+		Data:    payloadJSON,
+	})
+}
+
+// PublishResponseRejected tells the driver that their response to the trip, the command, was refused
+// with the domain error
+func (p *TripEventPublisher) PublishResponseRejected(ctx context.Context, driverID, tripID, command string, err error) error {
+	payload := messaging.DriverResponseRejectedData{
+		TripID:  tripID,
+		Command: command,
+		Code:    contracts.ErrCodeInternal,
+		Message: err.Error(),
+	}
+	var domainErr *domain.Error
+	if errors.As(err, &domainErr) {
+		payload.Code = domainErr.Reason
+	}
+
+	payloadJSON, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	return p.rabbitmq.PublishMessage(ctx, contracts.TripEventResponseRejected, contracts.AmqpMessage{
+		OwnerID: driverID,
+		Data:    payloadJSON,
+	})
+}
+
+func (p *TripEventPublisher) publishHoldCmd(ctx context.Context, routingKey string, trip *domain.TripModel, payload messaging.PaymentHoldCmdData) error {
+	payloadJSON, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	return p.rabbitmq.PublishMessage(ctx, routingKey, contracts.AmqpMessage{
+		OwnerID: trip.UserID,
+		Data:    payloadJSON,
+	})
+}
@@ -0,0 +1,74 @@
+package grpc
+
+import (
+	"errors"
+	"fmt"
+	"log"
+
+	"ride-sharing/services/trip-service/internal/domain"
+	"ride-sharing/shared/contracts"
+	"ride-sharing/shared/validate"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// errorInfoDomain identifies the service in the details of the errors it returns
+const errorInfoDomain = "trip-service"
+
+var kindCodes = map[domain.ErrorKind]codes.Code{
+	domain.KindInvalidArgument:    codes.InvalidArgument,
+	domain.KindNotFound:           codes.NotFound,
+	domain.KindForbidden:          codes.PermissionDenied,
+	domain.KindExpired:            codes.FailedPrecondition,
+	domain.KindFailedPrecondition: codes.FailedPrecondition,
+}
+
+// toStatus converts the domain errors to a gRPC status with their reason in an ErrorInfo detail.
+// Any other error is internal, its text is only logged.
+func toStatus(err error, action string) error {
+	var domainErr *domain.Error
+	if !errors.As(err, &domainErr) {
+		log.Printf("Failed to %s: %v", action, err)
+		return status.Errorf(codes.Internal, "failed to %s", action)
+	}
+
+	st := status.New(kindCodes[domainErr.Kind], err.Error())
+	detailed, detailsErr := st.WithDetails(&errdetails.ErrorInfo{
+		Reason: domainErr.Reason,
+		Domain: errorInfoDomain,
+	})
+	if detailsErr != nil {
+		return st.Err()
+	}
+
+	return detailed.Err()
+}
+
+// validationStatus returns an InvalidArgument status with the field errors as BadRequest details
+func validationStatus(errs validate.Errors) error {
+	violations := make([]*errdetails.BadRequest_FieldViolation, len(errs))
+	for i, fe := range errs {
+		violations[i] = &errdetails.BadRequest_FieldViolation{
+			Field:       fe.Field,
+			Description: fe.Message,
+		}
+	}
+
+	st := status.New(codes.InvalidArgument, errs.Error())
+	detailed, err := st.WithDetails(
+		&errdetails.ErrorInfo{Reason: contracts.ErrCodeValidationFailed, Domain: errorInfoDomain},
+		&errdetails.BadRequest{FieldViolations: violations},
+	)
+	if err != nil {
+		return st.Err()
+	}
+
+	return detailed.Err()
+}
+
+// userMismatchError is returned when the authenticated user acts on behalf of another one
+func userMismatchError(actorID, userID string) error {
+	return toStatus(fmt.Errorf("%w: user %s for user %s", domain.ErrUserMismatch, actorID, userID), "authorize the user")
+}
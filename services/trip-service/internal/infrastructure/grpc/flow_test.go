@@ -0,0 +1,332 @@
+package grpc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"ride-sharing/services/trip-service/internal/domain"
+	"ride-sharing/services/trip-service/internal/infrastructure/events"
+	"ride-sharing/services/trip-service/internal/infrastructure/repository"
+	"ride-sharing/services/trip-service/internal/service"
+	"ride-sharing/shared/contracts"
+	"ride-sharing/shared/messaging"
+	pbd "ride-sharing/shared/proto/driver"
+	pb "ride-sharing/shared/proto/trip"
+
+	"github.com/rabbitmq/amqp091-go"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// The trip flows run on the in-process broker. The test plays the driver service and the payment
+// service: it offers the trips, accepts them, and reads the commands sent to the payment service.
+
+// testDrivers are the profiles of the driver service
+type testDrivers struct{}
+
+func (testDrivers) GetDriver(ctx context.Context, driverID string) (*domain.TripDriver, error) {
+	return &domain.TripDriver{ID: driverID, Name: "Driver " + driverID, CarPlate: "7ABC123"}, nil
+}
+
+// testHolds authorizes the holds, or refuses them with reason when it is set
+type testHolds struct {
+	reason string
+	mutex  sync.Mutex
+	held   []string
+}
+
+func (h *testHolds) AuthorizeHold(ctx context.Context, trip *domain.TripModel) error {
+	if h.reason != "" {
+		return fmt.Errorf("%w: %s", domain.ErrHoldRefused, h.reason)
+	}
+
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	h.held = append(h.held, trip.ID.Hex())
+	return nil
+}
+
+type testTripService struct {
+	handler *gRPCHandler
+	service domain.TripService
+	broker  *messaging.InmemBroker
+}
+
+// startTestTripService wires the trip service on a new broker like cmd/main.go, the routes come
+// from a fake OSRM server
+func startTestTripService(t *testing.T, holds domain.FareHolds) *testTripService {
+	t.Helper()
+
+	osrm := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"routes": [{"distance": 5200, "duration": 720, "geometry": {"coordinates": [[37.7749, -122.4194], [37.8044, -122.2712]]}}]}`))
+	}))
+	t.Cleanup(osrm.Close)
+	t.Setenv("OSRM_API", osrm.URL)
+
+	broker := messaging.NewInmemBroker()
+	t.Cleanup(func() { broker.Close() })
+
+	repo := repository.NewInmemRepository()
+	svc := service.NewService(repo, testDrivers{}, holds)
+	splitSvc := service.NewSplitService(repo)
+	publisher := events.NewTripEventPublisher(broker)
+
+	if err := events.NewDriverConsumer(broker, svc, splitSvc, publisher).Listen(); err != nil {
+		t.Fatal(err)
+	}
+	if err := events.NewOfferConsumer(broker, svc).Listen(); err != nil {
+		t.Fatal(err)
+	}
+	paymentConsumer := events.NewPaymentConsumer(broker, svc, publisher)
+	if err := paymentConsumer.Listen(); err != nil {
+		t.Fatal(err)
+	}
+	if err := paymentConsumer.ListenDisputes(); err != nil {
+		t.Fatal(err)
+	}
+
+	return &testTripService{
+		handler: NewGRPCHandler(grpc.NewServer(), svc, service.NewChatService(repo, repo), splitSvc, publisher),
+		service: svc,
+		broker:  broker,
+	}
+}
+
+// consume collects the messages routed to the queue
+func (s *testTripService) consume(t *testing.T, queue string) <-chan amqp091.Delivery {
+	t.Helper()
+
+	deliveries := make(chan amqp091.Delivery, 16)
+	err := s.broker.ConsumeMessages(queue, func(ctx context.Context, msg amqp091.Delivery) error {
+		deliveries <- msg
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("failed to consume %s: %v", queue, err)
+	}
+	return deliveries
+}
+
+// publish sends a message the way the other services do
+func (s *testTripService) publish(t *testing.T, routingKey, ownerID string, data any) {
+	t.Helper()
+
+	payload, err := json.Marshal(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.broker.PublishMessage(context.Background(), routingKey, contracts.AmqpMessage{OwnerID: ownerID, Data: payload}); err != nil {
+		t.Fatalf("failed to publish %s: %v", routingKey, err)
+	}
+}
+
+// expectMessage waits for the next message of the queue and decodes its data into data
+func expectMessage(t *testing.T, deliveries <-chan amqp091.Delivery, routingKey string, data any) contracts.AmqpMessage {
+	t.Helper()
+
+	select {
+	case msg := <-deliveries:
+		if msg.RoutingKey != routingKey {
+			t.Fatalf("got message %s, want %s", msg.RoutingKey, routingKey)
+		}
+
+		var message contracts.AmqpMessage
+		if err := json.Unmarshal(msg.Body, &message); err != nil {
+			t.Fatal(err)
+		}
+		if err := json.Unmarshal(message.Data, data); err != nil {
+			t.Fatal(err)
+		}
+		return message
+	case <-time.After(2 * time.Second):
+		t.Fatalf("timed out waiting for message %s", routingKey)
+		return contracts.AmqpMessage{}
+	}
+}
+
+// expectNoMessage checks that nothing is routed to the queue for a while
+func expectNoMessage(t *testing.T, deliveries <-chan amqp091.Delivery) {
+	t.Helper()
+
+	select {
+	case msg := <-deliveries:
+		t.Fatalf("got unexpected message %s", msg.RoutingKey)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+// expectTripStatus waits for the trip to reach the status, the consumers update it asynchronously
+func (s *testTripService) expectTripStatus(t *testing.T, tripID, status string) {
+	t.Helper()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		trip, err := s.service.GetTripByID(context.Background(), tripID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if trip.Status == status {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("got trip %s, want %s", trip.Status, status)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// previewTrip previews a trip in San Francisco and returns its first fare
+func (s *testTripService) previewTrip(t *testing.T, userID string) *pb.RideFare {
+	t.Helper()
+
+	preview, err := s.handler.PreviewTrip(context.Background(), &pb.PreviewTripRequest{
+		UserID:        userID,
+		StartLocation: &pb.Coordinate{Latitude: 37.7749, Longitude: -122.4194},
+		EndLocation:   &pb.Coordinate{Latitude: 37.8044, Longitude: -122.2712},
+	})
+	if err != nil {
+		t.Fatalf("failed to preview the trip: %v", err)
+	}
+	if len(preview.GetRideFares()) == 0 {
+		t.Fatal("the preview has no fare")
+	}
+	return preview.GetRideFares()[0]
+}
+
+func TestTripFlowFromPreviewToPayment(t *testing.T) {
+	ctx := context.Background()
+	holds := &testHolds{}
+	s := startTestTripService(t, holds)
+
+	dispatched := s.consume(t, messaging.FindAvailableDriversQueue)
+	requests := s.consume(t, messaging.DriverCmdTripRequestQueue)
+	rejected := s.consume(t, messaging.NotifyDriverRejectedQueue)
+	assigned := s.consume(t, messaging.NotifyDriverAssignQueue)
+	paymentCmds := s.consume(t, messaging.PaymentTripResponseQueue)
+
+	fare := s.previewTrip(t, "rider-1")
+
+	created, err := s.handler.CreateTrip(ctx, &pb.CreateTripRequest{RideFareID: fare.GetId(), UserID: "rider-1"})
+	if err != nil {
+		t.Fatalf("failed to create the trip: %v", err)
+	}
+	tripID := created.GetTripID()
+
+	if len(holds.held) != 1 || holds.held[0] != tripID {
+		t.Fatalf("got holds %v, want the hold of trip %s", holds.held, tripID)
+	}
+
+	// The driver service finds a driver for the trip and offers it to them
+	var createdEvent messaging.TripEventData
+	expectMessage(t, dispatched, contracts.TripEventCreated, &createdEvent)
+	if createdEvent.Trip.GetId() != tripID || createdEvent.Trip.GetStatus() != domain.TripStatusPending {
+		t.Fatalf("got trip %s %s, want trip %s pending", createdEvent.Trip.GetId(), createdEvent.Trip.GetStatus(), tripID)
+	}
+	s.publish(t, contracts.TripCmdOffer, "driver-1", createdEvent)
+
+	// The trip service records the offer before the driver gets the trip request
+	var offered messaging.TripEventData
+	driver := expectMessage(t, requests, contracts.DriverCmdTripRequest, &offered)
+	if driver.OwnerID != "driver-1" || offered.Trip.GetId() != tripID {
+		t.Fatalf("got the request of trip %s for %s, want trip %s for driver-1", offered.Trip.GetId(), driver.OwnerID, tripID)
+	}
+	trip, err := s.service.GetTripByID(ctx, tripID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if trip.OfferedDriverID != "driver-1" {
+		t.Fatalf("got the trip offered to %q when the request was sent, want driver-1", trip.OfferedDriverID)
+	}
+
+	// Only the driver the trip is offered to can accept it, the others are told why
+	s.publish(t, contracts.DriverCmdTripAccept, "driver-2", messaging.DriverTripResponseData{
+		Driver: &pbd.Driver{Id: "driver-2"},
+		TripID: tripID,
+	})
+	var rejection messaging.DriverResponseRejectedData
+	other := expectMessage(t, rejected, contracts.TripEventResponseRejected, &rejection)
+	if other.OwnerID != "driver-2" || rejection.Code != contracts.ErrCodeTripNotOffered || rejection.Command != contracts.DriverCmdTripAccept {
+		t.Fatalf("got rejection %+v for %s, want %s of the accept for driver-2", rejection, other.OwnerID, contracts.ErrCodeTripNotOffered)
+	}
+
+	s.publish(t, contracts.DriverCmdTripAccept, "driver-1", messaging.DriverTripResponseData{
+		Driver:  &pbd.Driver{Id: "driver-1", Name: "Sent by the client"},
+		TripID:  tripID,
+		RiderID: "rider-1",
+	})
+
+	var accepted pb.Trip
+	rider := expectMessage(t, assigned, contracts.TripEventDriverAssigned, &accepted)
+	if rider.OwnerID != "rider-1" {
+		t.Errorf("driver assigned sent to %s, want rider-1", rider.OwnerID)
+	}
+	if accepted.GetStatus() != domain.TripStatusAccepted || accepted.GetDriver().GetName() != "Driver driver-1" {
+		t.Errorf("got trip %s with driver %q, want it accepted by the profile of the driver service", accepted.GetStatus(), accepted.GetDriver().GetName())
+	}
+
+	// The payment service creates the payment session of the fare
+	var payment messaging.PaymentTripResponseData
+	expectMessage(t, paymentCmds, contracts.PaymentCmdCreateSession, &payment)
+	want := messaging.PaymentTripResponseData{
+		TripID:      tripID,
+		UserID:      "rider-1",
+		DriverID:    "driver-1",
+		PackageSlug: fare.GetPackageSlug(),
+		Amount:      fare.GetTotalPriceInCents(),
+		Currency:    "USD",
+	}
+	if !reflect.DeepEqual(payment, want) {
+		t.Errorf("got payment command %+v, want %+v", payment, want)
+	}
+
+	// A failed payment, or a dispute of a payment not made, leaves the trip waiting for the payment
+	status := messaging.PaymentStatusUpdateData{TripID: tripID, UserID: "rider-1", DriverID: "driver-1"}
+	s.publish(t, contracts.PaymentEventFailed, "rider-1", status)
+	s.publish(t, contracts.PaymentEventDisputed, "rider-1", status)
+	time.Sleep(100 * time.Millisecond)
+	s.expectTripStatus(t, tripID, domain.TripStatusAccepted)
+
+	// The rider pays, then disputes the payment with their bank
+	s.publish(t, contracts.PaymentEventSuccess, "rider-1", status)
+	s.expectTripStatus(t, tripID, domain.TripStatusPayed)
+
+	s.publish(t, contracts.PaymentEventDisputed, "rider-1", status)
+	s.expectTripStatus(t, tripID, domain.TripStatusDisputed)
+}
+
+func TestTripFlowRefusedHold(t *testing.T) {
+	ctx := context.Background()
+	s := startTestTripService(t, &testHolds{reason: messaging.HoldFailedCardDeclined})
+
+	dispatched := s.consume(t, messaging.FindAvailableDriversQueue)
+	holdCmds := s.consume(t, messaging.PaymentCmdHoldQueue)
+
+	fare := s.previewTrip(t, "rider-1")
+
+	_, err := s.handler.CreateTrip(ctx, &pb.CreateTripRequest{RideFareID: fare.GetId(), UserID: "rider-1"})
+	if status.Code(err) != codes.FailedPrecondition {
+		t.Fatalf("got error %v, want a refused hold", err)
+	}
+
+	// The trip is never dispatched, a hold placed anyway is released
+	var release messaging.PaymentHoldCmdData
+	expectMessage(t, holdCmds, contracts.PaymentCmdReleaseHold, &release)
+	expectNoMessage(t, dispatched)
+
+	trip, err := s.service.GetTripByID(ctx, release.TripID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if trip.Status != domain.TripStatusHoldFailed {
+		t.Errorf("got trip %s, want %s", trip.Status, domain.TripStatusHoldFailed)
+	}
+}
@@ -2,28 +2,35 @@ package grpc
 
 import (
 	"context"
+	"fmt"
 	"log"
 	"ride-sharing/services/trip-service/internal/domain"
 	"ride-sharing/services/trip-service/internal/infrastructure/events"
+	"ride-sharing/shared/auth"
 	pb "ride-sharing/shared/proto/trip"
 	"ride-sharing/shared/types"
+	"ride-sharing/shared/validate"
 
 	"google.golang.org/grpc"
-	"google.golang.org/grpc/codes"
-	"google.golang.org/grpc/status"
 )
 
 type gRPCHandler struct {
 	pb.UnimplementedTripServiceServer
 
 	service   domain.TripService
+	chat      domain.ChatService
+	splits    domain.SplitService
 	publisher *events.TripEventPublisher
+	limits    validate.Limits
 }
 
-func NewGRPCHandler(server *grpc.Server, service domain.TripService, publisher *events.TripEventPublisher) *gRPCHandler {
+func NewGRPCHandler(server *grpc.Server, service domain.TripService, chat domain.ChatService, splits domain.SplitService, publisher *events.TripEventPublisher) *gRPCHandler {
 	handler := &gRPCHandler{
 		service:   service,
+		chat:      chat,
+		splits:    splits,
 		publisher: publisher,
+		limits:    validate.NewDefaultLimits(),
 	}
 
 	pb.RegisterTripServiceServer(server, handler)
@@ -34,20 +41,38 @@ func (h *gRPCHandler) CreateTrip(ctx context.Context, req *pb.CreateTripRequest)
 	fareID := req.GetRideFareID()
 	userID := req.GetUserID()
 
+	var errs validate.Errors
+	errs.ObjectID("rideFareID", fareID)
+	errs.Required("userID", userID)
+	if err := errs.Err(); err != nil {
+		return nil, validationStatus(errs)
+	}
+
+	if err := authorizeUser(ctx, userID); err != nil {
+		return nil, err
+	}
+
 	rideFare, err := h.service.GetAndValidateFare(ctx, fareID, userID)
 	if err != nil {
-		log.Printf("DEBUG: CreateTrip - GetAndValidateFare failed: %v", err)
-		return nil, status.Errorf(codes.Internal, "failed to validate the fare: %v", err)
+		return nil, toStatus(err, "validate the fare")
 	}
 
 	trip, err := h.service.CreateTrip(ctx, rideFare)
 	if err != nil {
-		log.Printf("DEBUG: CreateTrip - service.CreateTrip failed: %v", err)
-		return nil, status.Errorf(codes.Internal, "failed to create the trip: %v", err)
+		// A failed hold call may have placed the hold anyway, releasing a refused one does nothing
+		if trip != nil {
+			h.releaseHold(ctx, trip)
+		}
+		return nil, toStatus(err, "create the trip")
 	}
 
 	if err := h.publisher.PublishTripCreated(ctx, trip); err != nil {
-		return nil, status.Errorf(codes.Internal, "failed to publish the trip created event: %v", err)
+		// Never offered to the drivers, the rider requests the trip again
+		if _, _, cancelErr := h.service.CancelTrip(ctx, trip.ID.Hex()); cancelErr != nil {
+			log.Printf("Failed to cancel trip %s: %v", trip.ID.Hex(), cancelErr)
+		}
+		h.releaseHold(ctx, trip)
+		return nil, toStatus(err, "publish the trip created event")
 	}
 
 	return &pb.CreateTripResponse{
@@ -55,34 +80,53 @@ func (h *gRPCHandler) CreateTrip(ctx context.Context, req *pb.CreateTripRequest)
 	}, nil
 }
 
-func (h *gRPCHandler) PreviewTrip(ctx context.Context, req *pb.PreviewTripRequest) (*pb.PreviewTripResponse, error) {
-	pickup := req.GetStartLocation()
-	destination := req.GetEndLocation()
+func (h *gRPCHandler) GetActiveTrip(ctx context.Context, req *pb.GetActiveTripRequest) (*pb.GetActiveTripResponse, error) {
+	if err := authorizeUser(ctx, req.GetUserID()); err != nil {
+		return nil, err
+	}
 
-	pickupCoord := &types.Coordinate{
-		Latitude:  pickup.Latitude,
-		Longitude: pickup.Longitude,
+	trip, err := h.service.GetActiveTrip(ctx, req.GetUserID())
+	if err != nil {
+		return nil, toStatus(err, "get the active trip")
 	}
-	destinationCoord := &types.Coordinate{
-		Latitude:  destination.Latitude,
-		Longitude: destination.Longitude,
+
+	if trip == nil {
+		return nil, toStatus(fmt.Errorf("%w for user %s", domain.ErrNoActiveTrip, req.GetUserID()), "get the active trip")
 	}
 
+	return &pb.GetActiveTripResponse{
+		Trip: trip.ToProto(),
+	}, nil
+}
+
+func (h *gRPCHandler) PreviewTrip(ctx context.Context, req *pb.PreviewTripRequest) (*pb.PreviewTripResponse, error) {
+	pickupCoord := toCoordinate(req.GetStartLocation())
+	destinationCoord := toCoordinate(req.GetEndLocation())
 	userID := req.GetUserID()
 
+	// Don't send invalid or out of area coordinates to OSRM
+	var errs validate.Errors
+	errs.Required("userID", userID)
+	errs.Trip(pickupCoord, destinationCoord, h.limits)
+	if err := errs.Err(); err != nil {
+		return nil, validationStatus(errs)
+	}
+
+	if err := authorizeUser(ctx, userID); err != nil {
+		return nil, err
+	}
+
 	// CHANGE THE LAST ARG TO "FALSE" if the OSRM API is not working right now
 	route, err := h.service.GetRoute(ctx, pickupCoord, destinationCoord, true)
 	if err != nil {
-		log.Println(err)
-		return nil, status.Errorf(codes.Internal, "failed to get route: %v", err)
+		return nil, toStatus(err, "get the route")
 	}
 
 	estimatedFares := h.service.EstimatePackagesPriceWithRoute(route)
 
 	fares, err := h.service.GenerateTripFares(ctx, estimatedFares, userID, route)
 	if err != nil {
-		log.Printf("DEBUG: PreviewTrip - GenerateTripFares failed: %v", err)
-		return nil, status.Errorf(codes.Internal, "failed to generate the ride fares: %v", err)
+		return nil, toStatus(err, "generate the ride fares")
 	}
 
 	return &pb.PreviewTripResponse{
@@ -90,3 +134,163 @@ func (h *gRPCHandler) PreviewTrip(ctx context.Context, req *pb.PreviewTripReques
 		RideFares: domain.ToRideFaresProto(fares),
 	}, nil
 }
+
+func (h *gRPCHandler) GetChatMessages(ctx context.Context, req *pb.GetChatMessagesRequest) (*pb.GetChatMessagesResponse, error) {
+	userID, err := h.chatUser(ctx, req.GetTripID(), req.GetUserID())
+	if err != nil {
+		return nil, err
+	}
+
+	messages, err := h.chat.GetChatMessages(ctx, req.GetTripID(), userID)
+	if err != nil {
+		return nil, toStatus(err, "get the chat messages")
+	}
+
+	return &pb.GetChatMessagesResponse{
+		Messages: domain.ToChatMessagesProto(messages),
+	}, nil
+}
+
+func (h *gRPCHandler) ListQuickReplies(ctx context.Context, req *pb.ListQuickRepliesRequest) (*pb.ListQuickRepliesResponse, error) {
+	userID, err := h.chatUser(ctx, req.GetTripID(), req.GetUserID())
+	if err != nil {
+		return nil, err
+	}
+
+	replies, err := h.chat.ListQuickReplies(ctx, req.GetTripID(), userID)
+	if err != nil {
+		return nil, toStatus(err, "list the quick replies")
+	}
+
+	quickReplies := make([]*pb.QuickReply, len(replies))
+	for i, r := range replies {
+		quickReplies[i] = r.ToProto()
+	}
+
+	return &pb.ListQuickRepliesResponse{
+		QuickReplies: quickReplies,
+	}, nil
+}
+
+func (h *gRPCHandler) SplitFare(ctx context.Context, req *pb.SplitFareRequest) (*pb.SplitFareResponse, error) {
+	userID := req.GetUserID()
+	if identity, ok := auth.FromContext(ctx); ok && userID == "" {
+		userID = identity.UserID
+	}
+
+	var errs validate.Errors
+	errs.ObjectID("tripID", req.GetTripID())
+	errs.Required("userID", userID)
+	errs.Required("mode", req.GetMode())
+	if err := errs.Err(); err != nil {
+		return nil, validationStatus(errs)
+	}
+
+	if err := authorizeUser(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	invites := make([]domain.SplitInvite, len(req.GetParticipants()))
+	for i, p := range req.GetParticipants() {
+		invites[i] = domain.SplitInvite{UserID: p.GetUserID(), Share: p.GetShare()}
+	}
+
+	trip, err := h.splits.SplitFare(ctx, req.GetTripID(), userID, req.GetMode(), invites)
+	if err != nil {
+		return nil, toStatus(err, "split the fare")
+	}
+
+	// The riders who didn't answer yet are invited again, a failed request can be retried
+	for _, p := range trip.Split.Participants {
+		if p.Status != domain.SplitStatusInvited {
+			continue
+		}
+		if err := h.publisher.PublishSplitInvited(ctx, trip, p); err != nil {
+			return nil, toStatus(err, "publish the split invitation")
+		}
+	}
+
+	return &pb.SplitFareResponse{
+		Trip: trip.ToProto(),
+	}, nil
+}
+
+func (h *gRPCHandler) CancelTrip(ctx context.Context, req *pb.CancelTripRequest) (*pb.CancelTripResponse, error) {
+	userID := req.GetUserID()
+	if identity, ok := auth.FromContext(ctx); ok && userID == "" {
+		userID = identity.UserID
+	}
+
+	var errs validate.Errors
+	errs.ObjectID("tripID", req.GetTripID())
+	errs.Required("userID", userID)
+	if err := errs.Err(); err != nil {
+		return nil, validationStatus(errs)
+	}
+
+	if err := authorizeUser(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	trip, err := h.service.CancelRiderTrip(ctx, req.GetTripID(), userID)
+	if err != nil {
+		return nil, toStatus(err, "cancel the trip")
+	}
+
+	// Cancelling again releases the hold again, a failed request can be retried
+	if err := h.publisher.PublishReleaseHold(ctx, trip); err != nil {
+		return nil, toStatus(err, "publish the release hold command")
+	}
+
+	return &pb.CancelTripResponse{
+		Trip: trip.ToProto(),
+	}, nil
+}
+
+// chatUser validates the request and returns the participant reading the chat, by default the authenticated user
+func (h *gRPCHandler) chatUser(ctx context.Context, tripID, userID string) (string, error) {
+	if identity, ok := auth.FromContext(ctx); ok && userID == "" {
+		userID = identity.UserID
+	}
+
+	var errs validate.Errors
+	errs.ObjectID("tripID", tripID)
+	errs.Required("userID", userID)
+	if err := errs.Err(); err != nil {
+		return "", validationStatus(errs)
+	}
+
+	if err := authorizeUser(ctx, userID); err != nil {
+		return "", err
+	}
+
+	return userID, nil
+}
+
+// releaseHold asks the payment service to release the hold of a trip that won't be dispatched
+func (h *gRPCHandler) releaseHold(ctx context.Context, trip *domain.TripModel) {
+	if err := h.publisher.PublishReleaseHold(ctx, trip); err != nil {
+		log.Printf("Failed to release the hold of trip %s: %v", trip.ID.Hex(), err)
+	}
+}
+
+func toCoordinate(c *pb.Coordinate) *types.Coordinate {
+	if c == nil {
+		return nil
+	}
+	return &types.Coordinate{
+		Latitude:  c.Latitude,
+		Longitude: c.Longitude,
+	}
+}
+
+// authorizeUser rejects the requests made on behalf of another user.
+// Requests without identity come from internal callers or from a gateway running without authentication.
+func authorizeUser(ctx context.Context, userID string) error {
+	identity, ok := auth.FromContext(ctx)
+	if !ok || identity.CanActAs(userID) {
+		return nil
+	}
+
+	return userMismatchError(identity.UserID, userID)
+}
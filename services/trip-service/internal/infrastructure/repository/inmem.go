@@ -4,63 +4,239 @@ import (
 	"context"
 	"fmt"
 	"ride-sharing/services/trip-service/internal/domain"
-	pbd "ride-sharing/shared/proto/driver"
+	"slices"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 type inmemRepository struct {
-	trips     map[string]*domain.TripModel
-	rideFares map[string]*domain.RideFareModel
+	mu           sync.RWMutex
+	trips        map[string]*domain.TripModel
+	rideFares    map[string]*domain.RideFareModel
+	chatMessages map[string][]*domain.ChatMessageModel // tripID -> messages, oldest first
 }
 
 func NewInmemRepository() *inmemRepository {
 	return &inmemRepository{
-		trips:     make(map[string]*domain.TripModel),
-		rideFares: make(map[string]*domain.RideFareModel),
+		trips:        make(map[string]*domain.TripModel),
+		rideFares:    make(map[string]*domain.RideFareModel),
+		chatMessages: make(map[string][]*domain.ChatMessageModel),
 	}
 }
 
 func (r *inmemRepository) GetTripByID(ctx context.Context, id string) (*domain.TripModel, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
 	trip, ok := r.trips[id]
 	if !ok {
 		return nil, nil
 	}
-	return trip, nil
+	found := *trip
+	return &found, nil
 }
 
-func (r *inmemRepository) UpdateTrip(ctx context.Context, tripID string, status string, driver *pbd.Driver) error {
+func (r *inmemRepository) GetActiveTripByUserID(ctx context.Context, userID string) (*domain.TripModel, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var active *domain.TripModel
+	for _, trip := range r.trips {
+		if !slices.Contains(domain.ActiveTripStatuses, trip.Status) {
+			continue
+		}
+		if trip.UserID != userID && (trip.Driver == nil || trip.Driver.ID != userID) {
+			continue
+		}
+		// ObjectIDs grow with time, keep the latest trip
+		if active == nil || trip.ID.Hex() > active.ID.Hex() {
+			active = trip
+		}
+	}
+	if active == nil {
+		return nil, nil
+	}
+	found := *active
+	return &found, nil
+}
+
+func (r *inmemRepository) UpdateTrip(ctx context.Context, tripID string, status string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
 	trip, ok := r.trips[tripID]
 	if !ok {
-		return fmt.Errorf("trip not found with ID: %s", tripID)
+		return fmt.Errorf("%w: %s", domain.ErrTripNotFound, tripID)
 	}
 
 	trip.Status = status
+	return nil
+}
+
+func (r *inmemRepository) CancelTrip(ctx context.Context, tripID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	trip, ok := r.trips[tripID]
+	if !ok || trip.Status != domain.TripStatusPending {
+		return fmt.Errorf("%w: no pending trip %s", domain.ErrTripNotCancellable, tripID)
+	}
+
+	trip.Status = domain.TripStatusCancelled
+	return nil
+}
+
+func (r *inmemRepository) AcceptTrip(ctx context.Context, tripID string, driver *domain.TripDriver) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	trip, ok := r.trips[tripID]
+	if !ok || trip.Status != domain.TripStatusPending || trip.OfferedDriverID != driver.ID {
+		return fmt.Errorf("%w: trip %s, driver %s", domain.ErrTripNotOffered, tripID, driver.ID)
+	}
+
+	trip.Status = domain.TripStatusAccepted
+	trip.Driver = driver
+	return nil
+}
+
+func (r *inmemRepository) SetOfferedDriver(ctx context.Context, tripID string, driverID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	trip, ok := r.trips[tripID]
+	if !ok || trip.Status != domain.TripStatusPending {
+		return fmt.Errorf("%w: no pending trip %s", domain.ErrTripNotFound, tripID)
+	}
+
+	trip.OfferedDriverID = driverID
+	return nil
+}
+
+func (r *inmemRepository) SetTripRefund(ctx context.Context, tripID string, refund *domain.TripRefund) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	trip, ok := r.trips[tripID]
+	if !ok {
+		return fmt.Errorf("%w: %s", domain.ErrTripNotFound, tripID)
+	}
 
-	if driver != nil {
-		trip.Driver = &domain.TripDriver{
-			ID:             driver.Id,
-			Name:           driver.Name,
-			CarPlate:       driver.CarPlate,
-			ProfilePicture: driver.ProfilePicture,
+	trip.Refund = refund
+	return nil
+}
+
+func (r *inmemRepository) SetTripSplit(ctx context.Context, tripID string, split *domain.TripSplit) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	trip, ok := r.trips[tripID]
+	if !ok {
+		return fmt.Errorf("%w: %s", domain.ErrTripNotFound, tripID)
+	}
+
+	trip.Split = split
+	return nil
+}
+
+func (r *inmemRepository) MarkSplitSharePaid(ctx context.Context, tripID string, userID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	trip, ok := r.trips[tripID]
+	if !ok {
+		return fmt.Errorf("%w: %s", domain.ErrTripNotFound, tripID)
+	}
+
+	if trip.Split == nil {
+		return nil
+	}
+	for _, share := range trip.Split.Shares {
+		if share.UserID == userID {
+			share.Paid = true
 		}
 	}
 	return nil
 }
 
 func (r *inmemRepository) GetRideFareByID(ctx context.Context, id string) (*domain.RideFareModel, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
 	fare, exist := r.rideFares[id]
 	if !exist {
-		return nil, fmt.Errorf("fare does not exist with ID: %s", id)
+		return nil, nil
 	}
 
 	return fare, nil
 }
 
 func (r *inmemRepository) CreateTrip(ctx context.Context, trip *domain.TripModel) (*domain.TripModel, error) {
-	r.trips[trip.ID.Hex()] = trip
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored := *trip
+	r.trips[trip.ID.Hex()] = &stored
 	return trip, nil
 }
 
 func (r *inmemRepository) SaveRideFare(ctx context.Context, f *domain.RideFareModel) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
 	r.rideFares[f.ID.Hex()] = f
 	return nil
 }
+
+func (r *inmemRepository) SaveChatMessage(ctx context.Context, message *domain.ChatMessageModel) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if message.ID.IsZero() {
+		message.ID = primitive.NewObjectID()
+	}
+	r.chatMessages[message.TripID] = append(r.chatMessages[message.TripID], message)
+	return nil
+}
+
+func (r *inmemRepository) GetChatMessageByClientID(ctx context.Context, tripID, senderID, clientMessageID string) (*domain.ChatMessageModel, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, m := range r.chatMessages[tripID] {
+		if m.SenderID == senderID && m.ClientMessageID == clientMessageID {
+			return m, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *inmemRepository) GetChatMessages(ctx context.Context, tripID string) ([]*domain.ChatMessageModel, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return slices.Clone(r.chatMessages[tripID]), nil
+}
+
+func (r *inmemRepository) UpdateChatStatus(ctx context.Context, tripID, recipientID string, messageIDs []string, status string, at time.Time) ([]*domain.ChatMessageModel, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var updated []*domain.ChatMessageModel
+	for _, m := range r.chatMessages[tripID] {
+		if m.RecipientID != recipientID || !slices.Contains(messageIDs, m.ID.Hex()) || !domain.ChatStatusAfter(status, m.Status) {
+			continue
+		}
+
+		m.Status = status
+		if status == domain.ChatStatusRead {
+			m.ReadAt = &at
+		} else {
+			m.DeliveredAt = &at
+		}
+		updated = append(updated, m)
+	}
+	return updated, nil
+}
@@ -2,15 +2,17 @@ package repository
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"time"
 
 	"ride-sharing/services/trip-service/internal/domain"
 	"ride-sharing/shared/db"
-	pbd "ride-sharing/shared/proto/driver"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 type mongoRepository struct {
@@ -35,10 +37,13 @@ func (r *mongoRepository) CreateTrip(ctx context.Context, trip *domain.TripModel
 func (r *mongoRepository) GetTripByID(ctx context.Context, id string) (*domain.TripModel, error) {
 	_id, err := primitive.ObjectIDFromHex(id)
 	if err != nil {
-		return nil, err
+		return nil, domain.InvalidIDError(id)
 	}
 
 	result := r.db.Collection(db.TripsCollection).FindOne(ctx, bson.M{"_id": _id})
+	if errors.Is(result.Err(), mongo.ErrNoDocuments) {
+		return nil, nil
+	}
 	if result.Err() != nil {
 		return nil, result.Err()
 	}
@@ -52,32 +57,159 @@ func (r *mongoRepository) GetTripByID(ctx context.Context, id string) (*domain.T
 	return &trip, nil
 }
 
-func (r *mongoRepository) UpdateTrip(ctx context.Context, tripID string, status string, driver *pbd.Driver) error {
+func (r *mongoRepository) GetActiveTripByUserID(ctx context.Context, userID string) (*domain.TripModel, error) {
+	filter := bson.M{
+		"$or": bson.A{
+			bson.M{"userID": userID},
+			bson.M{"driver.id": userID},
+		},
+		"status": bson.M{"$in": domain.ActiveTripStatuses},
+	}
+	opts := options.FindOne().SetSort(bson.D{{Key: "_id", Value: -1}})
+
+	var trip domain.TripModel
+	err := r.db.Collection(db.TripsCollection).FindOne(ctx, filter, opts).Decode(&trip)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &trip, nil
+}
+
+func (r *mongoRepository) SetTripRefund(ctx context.Context, tripID string, refund *domain.TripRefund) error {
 	_id, err := primitive.ObjectIDFromHex(tripID)
+	if err != nil {
+		return domain.InvalidIDError(tripID)
+	}
+
+	result, err := r.db.Collection(db.TripsCollection).UpdateOne(ctx, bson.M{"_id": _id}, bson.M{"$set": bson.M{"refund": refund}})
 	if err != nil {
 		return err
 	}
 
-	update := bson.M{"$set": bson.M{"status": status}}
+	if result.MatchedCount == 0 {
+		return fmt.Errorf("%w: %s", domain.ErrTripNotFound, tripID)
+	}
 
-	if driver != nil {
-		// Convert proto driver to domain driver
-		domainDriver := &domain.TripDriver{
-			ID:             driver.Id,
-			Name:           driver.Name,
-			ProfilePicture: driver.ProfilePicture,
-			CarPlate:       driver.CarPlate,
-		}
-		update["$set"].(bson.M)["driver"] = domainDriver
+	return nil
+}
+
+func (r *mongoRepository) SetTripSplit(ctx context.Context, tripID string, split *domain.TripSplit) error {
+	_id, err := primitive.ObjectIDFromHex(tripID)
+	if err != nil {
+		return domain.InvalidIDError(tripID)
+	}
+
+	result, err := r.db.Collection(db.TripsCollection).UpdateOne(ctx, bson.M{"_id": _id}, bson.M{"$set": bson.M{"split": split}})
+	if err != nil {
+		return err
+	}
+
+	if result.MatchedCount == 0 {
+		return fmt.Errorf("%w: %s", domain.ErrTripNotFound, tripID)
+	}
+
+	return nil
+}
+
+func (r *mongoRepository) MarkSplitSharePaid(ctx context.Context, tripID string, userID string) error {
+	_id, err := primitive.ObjectIDFromHex(tripID)
+	if err != nil {
+		return domain.InvalidIDError(tripID)
 	}
 
+	// Only the share of the rider is updated, the riders may pay at the same time
+	filter := bson.M{"_id": _id, "split.shares.userID": userID}
+	update := bson.M{"$set": bson.M{"split.shares.$.paid": true}}
+
+	_, err = r.db.Collection(db.TripsCollection).UpdateOne(ctx, filter, update)
+	return err
+}
+
+func (r *mongoRepository) UpdateTrip(ctx context.Context, tripID string, status string) error {
+	_id, err := primitive.ObjectIDFromHex(tripID)
+	if err != nil {
+		return domain.InvalidIDError(tripID)
+	}
+
+	update := bson.M{"$set": bson.M{"status": status}}
+
 	result, err := r.db.Collection(db.TripsCollection).UpdateOne(ctx, bson.M{"_id": _id}, update)
 	if err != nil {
 		return err
 	}
 
-	if result.ModifiedCount == 0 {
-		return fmt.Errorf("trip not found: %s", tripID)
+	if result.MatchedCount == 0 {
+		return fmt.Errorf("%w: %s", domain.ErrTripNotFound, tripID)
+	}
+
+	return nil
+}
+
+func (r *mongoRepository) CancelTrip(ctx context.Context, tripID string) error {
+	_id, err := primitive.ObjectIDFromHex(tripID)
+	if err != nil {
+		return domain.InvalidIDError(tripID)
+	}
+
+	// A trip accepted meanwhile is not cancelled, the accept is conditional on the status as well
+	filter := bson.M{"_id": _id, "status": domain.TripStatusPending}
+	update := bson.M{"$set": bson.M{"status": domain.TripStatusCancelled}}
+
+	result, err := r.db.Collection(db.TripsCollection).UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+
+	if result.MatchedCount == 0 {
+		return fmt.Errorf("%w: no pending trip %s", domain.ErrTripNotCancellable, tripID)
+	}
+
+	return nil
+}
+
+func (r *mongoRepository) AcceptTrip(ctx context.Context, tripID string, driver *domain.TripDriver) error {
+	_id, err := primitive.ObjectIDFromHex(tripID)
+	if err != nil {
+		return domain.InvalidIDError(tripID)
+	}
+
+	// Two drivers can't both win the trip, nor a late accept take back a trip offered to another driver
+	filter := bson.M{"_id": _id, "status": domain.TripStatusPending, "offeredDriverID": driver.ID}
+	update := bson.M{"$set": bson.M{"status": domain.TripStatusAccepted, "driver": driver}}
+
+	result, err := r.db.Collection(db.TripsCollection).UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+
+	if result.MatchedCount == 0 {
+		return fmt.Errorf("%w: trip %s, driver %s", domain.ErrTripNotOffered, tripID, driver.ID)
+	}
+
+	return nil
+}
+
+func (r *mongoRepository) SetOfferedDriver(ctx context.Context, tripID string, driverID string) error {
+	_id, err := primitive.ObjectIDFromHex(tripID)
+	if err != nil {
+		return domain.InvalidIDError(tripID)
+	}
+
+	// Only a pending trip can be offered, a late offer must not reopen an accepted trip
+	filter := bson.M{"_id": _id, "status": domain.TripStatusPending}
+	update := bson.M{"$set": bson.M{"offeredDriverID": driverID}}
+
+	result, err := r.db.Collection(db.TripsCollection).UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+
+	if result.MatchedCount == 0 {
+		return fmt.Errorf("%w: no pending trip %s", domain.ErrTripNotFound, tripID)
 	}
 
 	return nil
@@ -97,10 +229,13 @@ func (r *mongoRepository) SaveRideFare(ctx context.Context, fare *domain.RideFar
 func (r *mongoRepository) GetRideFareByID(ctx context.Context, id string) (*domain.RideFareModel, error) {
 	_id, err := primitive.ObjectIDFromHex(id)
 	if err != nil {
-		return nil, err
+		return nil, domain.InvalidIDError(id)
 	}
 
 	result := r.db.Collection(db.RideFaresCollection).FindOne(ctx, bson.M{"_id": _id})
+	if errors.Is(result.Err(), mongo.ErrNoDocuments) {
+		return nil, nil
+	}
 	if result.Err() != nil {
 		return nil, result.Err()
 	}
@@ -113,3 +248,111 @@ func (r *mongoRepository) GetRideFareByID(ctx context.Context, id string) (*doma
 
 	return &fare, nil
 }
+
+func (r *mongoRepository) SaveChatMessage(ctx context.Context, message *domain.ChatMessageModel) error {
+	result, err := r.db.Collection(db.ChatMessagesCollection).InsertOne(ctx, message)
+	if err != nil {
+		return err
+	}
+
+	message.ID = result.InsertedID.(primitive.ObjectID)
+
+	return nil
+}
+
+func (r *mongoRepository) GetChatMessageByClientID(ctx context.Context, tripID, senderID, clientMessageID string) (*domain.ChatMessageModel, error) {
+	filter := bson.M{"tripID": tripID, "senderID": senderID, "clientMessageID": clientMessageID}
+
+	var message domain.ChatMessageModel
+	err := r.db.Collection(db.ChatMessagesCollection).FindOne(ctx, filter).Decode(&message)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &message, nil
+}
+
+func (r *mongoRepository) GetChatMessages(ctx context.Context, tripID string) ([]*domain.ChatMessageModel, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "_id", Value: 1}})
+
+	cursor, err := r.db.Collection(db.ChatMessagesCollection).Find(ctx, bson.M{"tripID": tripID}, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	messages := []*domain.ChatMessageModel{}
+	if err := cursor.All(ctx, &messages); err != nil {
+		return nil, err
+	}
+
+	return messages, nil
+}
+
+func (r *mongoRepository) UpdateChatStatus(ctx context.Context, tripID, recipientID string, messageIDs []string, status string, at time.Time) ([]*domain.ChatMessageModel, error) {
+	ids := make([]primitive.ObjectID, 0, len(messageIDs))
+	for _, id := range messageIDs {
+		_id, err := primitive.ObjectIDFromHex(id)
+		if err != nil {
+			return nil, domain.InvalidIDError(id)
+		}
+		ids = append(ids, _id)
+	}
+
+	// Only the recipient acknowledges a message, and never back to an earlier status
+	earlier := []string{domain.ChatStatusSent}
+	set := bson.M{"status": status}
+	if status == domain.ChatStatusRead {
+		earlier = append(earlier, domain.ChatStatusDelivered)
+		set["readAt"] = at
+	} else {
+		set["deliveredAt"] = at
+	}
+
+	filter := bson.M{
+		"_id":         bson.M{"$in": ids},
+		"tripID":      tripID,
+		"recipientID": recipientID,
+		"status":      bson.M{"$in": earlier},
+	}
+
+	collection := r.db.Collection(db.ChatMessagesCollection)
+
+	// Find the messages first to return only the ones this receipt updated
+	cursor, err := collection.Find(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	var messages []*domain.ChatMessageModel
+	if err := cursor.All(ctx, &messages); err != nil {
+		return nil, err
+	}
+
+	updated := make([]primitive.ObjectID, len(messages))
+	for i, m := range messages {
+		updated[i] = m.ID
+	}
+
+	if len(updated) == 0 {
+		return nil, nil
+	}
+
+	filter["_id"] = bson.M{"$in": updated}
+	if _, err := collection.UpdateMany(ctx, filter, bson.M{"$set": set}); err != nil {
+		return nil, err
+	}
+
+	for _, m := range messages {
+		m.Status = status
+		if status == domain.ChatStatusRead {
+			m.ReadAt = &at
+		} else {
+			m.DeliveredAt = &at
+		}
+	}
+
+	return messages, nil
+}
@@ -0,0 +1,127 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"ride-sharing/services/trip-service/internal/domain"
+)
+
+type chatService struct {
+	trips domain.TripRepository
+	chat  domain.ChatRepository
+}
+
+func NewChatService(trips domain.TripRepository, chat domain.ChatRepository) *chatService {
+	return &chatService{
+		trips: trips,
+		chat:  chat,
+	}
+}
+
+func (s *chatService) SendChatMessage(ctx context.Context, tripID, senderID, text, templateID, clientMessageID string) (*domain.ChatMessageModel, error) {
+	trip, recipientID, err := s.participantTrip(ctx, tripID, senderID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !trip.ChatOpen() {
+		return nil, fmt.Errorf("%w: trip %s is %s", domain.ErrChatClosed, tripID, trip.Status)
+	}
+
+	if templateID != "" {
+		reply, ok := domain.FindQuickReply(trip, senderID, templateID)
+		if !ok {
+			return nil, fmt.Errorf("%w: %q", domain.ErrUnknownReply, templateID)
+		}
+		text = reply.Text
+	}
+
+	text = strings.TrimSpace(text)
+	if text == "" || utf8.RuneCountInString(text) > domain.MaxChatMessageLength {
+		return nil, fmt.Errorf("%w: the text must have between 1 and %d characters", domain.ErrInvalidChatMessage, domain.MaxChatMessageLength)
+	}
+
+	// The client resends the messages it got no echo for, and the commands are retried on failures
+	if clientMessageID != "" {
+		existing, err := s.chat.GetChatMessageByClientID(ctx, tripID, senderID, clientMessageID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get the chat message: %w", err)
+		}
+		if existing != nil {
+			return existing, nil
+		}
+	}
+
+	message := &domain.ChatMessageModel{
+		TripID:          tripID,
+		SenderID:        senderID,
+		RecipientID:     recipientID,
+		Text:            text,
+		TemplateID:      templateID,
+		ClientMessageID: clientMessageID,
+		Status:          domain.ChatStatusSent,
+		SentAt:          time.Now(),
+	}
+
+	if err := s.chat.SaveChatMessage(ctx, message); err != nil {
+		return nil, fmt.Errorf("failed to save the chat message: %w", err)
+	}
+
+	return message, nil
+}
+
+func (s *chatService) RecordChatReceipt(ctx context.Context, tripID, readerID string, messageIDs []string, status string) ([]*domain.ChatMessageModel, error) {
+	if status != domain.ChatStatusDelivered && status != domain.ChatStatusRead {
+		return nil, fmt.Errorf("%w: unknown receipt status %q", domain.ErrInvalidChatMessage, status)
+	}
+
+	if _, _, err := s.participantTrip(ctx, tripID, readerID); err != nil {
+		return nil, err
+	}
+
+	if len(messageIDs) == 0 {
+		return nil, nil
+	}
+
+	return s.chat.UpdateChatStatus(ctx, tripID, readerID, messageIDs, status, time.Now())
+}
+
+func (s *chatService) GetChatMessages(ctx context.Context, tripID, userID string) ([]*domain.ChatMessageModel, error) {
+	if _, _, err := s.participantTrip(ctx, tripID, userID); err != nil {
+		return nil, err
+	}
+
+	return s.chat.GetChatMessages(ctx, tripID)
+}
+
+func (s *chatService) ListQuickReplies(ctx context.Context, tripID, userID string) ([]domain.QuickReply, error) {
+	trip, _, err := s.participantTrip(ctx, tripID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return domain.QuickReplies(trip, userID), nil
+}
+
+// participantTrip returns the trip and the other participant, or an error if the user doesn't take part in the trip
+func (s *chatService) participantTrip(ctx context.Context, tripID, userID string) (*domain.TripModel, string, error) {
+	trip, err := s.trips.GetTripByID(ctx, tripID)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if trip == nil {
+		return nil, "", fmt.Errorf("%w: %s", domain.ErrTripNotFound, tripID)
+	}
+
+	other, ok := trip.Participant(userID)
+	if !ok {
+		return nil, "", fmt.Errorf("%w: trip %s, user %s", domain.ErrNotParticipant, tripID, userID)
+	}
+
+	return trip, other, nil
+}
@@ -3,6 +3,7 @@ package service
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
@@ -10,19 +11,23 @@ import (
 	"ride-sharing/services/trip-service/internal/domain"
 	tripTypes "ride-sharing/services/trip-service/pkg/types"
 	"ride-sharing/shared/env"
-	pbd "ride-sharing/shared/proto/driver"
 	"ride-sharing/shared/types"
+	"time"
 
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 type service struct {
-	repo domain.TripRepository
+	repo    domain.TripRepository
+	drivers domain.DriverProfiles
+	holds   domain.FareHolds
 }
 
-func NewService(repo domain.TripRepository) *service {
+func NewService(repo domain.TripRepository, drivers domain.DriverProfiles, holds domain.FareHolds) *service {
 	return &service{
-		repo: repo,
+		repo:    repo,
+		drivers: drivers,
+		holds:   holds,
 	}
 }
 
@@ -30,12 +35,33 @@ func (s *service) CreateTrip(ctx context.Context, fare *domain.RideFareModel) (*
 	t := &domain.TripModel{
 		ID:       primitive.NewObjectID(),
 		UserID:   fare.UserID,
-		Status:   "pending",
+		Status:   domain.TripStatusAuthorizing,
 		RideFare: fare,
 		Driver:   nil, // Driver is initially nil until assigned
 	}
 
-	return s.repo.CreateTrip(ctx, t)
+	trip, err := s.repo.CreateTrip(ctx, t)
+	if err != nil {
+		return nil, err
+	}
+
+	// The trip is only offered to the drivers once its fare is held
+	tripID := trip.ID.Hex()
+	if err := s.holds.AuthorizeHold(ctx, trip); err != nil {
+		if updateErr := s.repo.UpdateTrip(ctx, tripID, domain.TripStatusHoldFailed); updateErr != nil {
+			log.Printf("Failed to move trip %s to %s: %v", tripID, domain.TripStatusHoldFailed, updateErr)
+		}
+		trip.Status = domain.TripStatusHoldFailed
+		return trip, err
+	}
+
+	if err := s.repo.UpdateTrip(ctx, tripID, domain.TripStatusPending); err != nil {
+		trip.Status = domain.TripStatusHoldFailed
+		return trip, err
+	}
+	trip.Status = domain.TripStatusPending
+
+	return trip, nil
 }
 
 func (s *service) GetRoute(ctx context.Context, pickup, destination *types.Coordinate, useOSRMApi bool) (*tripTypes.OsrmApiResponse, error) {
@@ -121,6 +147,7 @@ func (s *service) GenerateTripFares(ctx context.Context, rideFares []*domain.Rid
 			TotalPriceInCents: f.TotalPriceInCents,
 			PackageSlug:       f.PackageSlug,
 			Route:             route,
+			ExpiresAt:         time.Now().Add(domain.RideFareTTL),
 		}
 
 		if err := s.repo.SaveRideFare(ctx, fare); err != nil {
@@ -140,12 +167,16 @@ func (s *service) GetAndValidateFare(ctx context.Context, fareID, userID string)
 	}
 
 	if fare == nil {
-		return nil, fmt.Errorf("fare does not exist")
+		return nil, fmt.Errorf("%w: %s", domain.ErrFareNotFound, fareID)
 	}
 
 	// User fare validation (user is owner of this fare?)
 	if userID != fare.UserID {
-		return nil, fmt.Errorf("fare does not belong to the user")
+		return nil, domain.ErrFareNotOwned
+	}
+
+	if fare.Expired(time.Now()) {
+		return nil, domain.ErrFareExpired
 	}
 
 	return fare, nil
@@ -193,6 +224,189 @@ func (s *service) GetTripByID(ctx context.Context, id string) (*domain.TripModel
 	return s.repo.GetTripByID(ctx, id)
 }
 
-func (s *service) UpdateTrip(ctx context.Context, tripID string, status string, driver *pbd.Driver) error {
-	return s.repo.UpdateTrip(ctx, tripID, status, driver)
+func (s *service) GetActiveTrip(ctx context.Context, userID string) (*domain.TripModel, error) {
+	return s.repo.GetActiveTripByUserID(ctx, userID)
+}
+
+func (s *service) AcceptTrip(ctx context.Context, tripID string, driverID string) (*domain.TripModel, error) {
+	trip, err := s.getTrip(ctx, tripID)
+	if err != nil {
+		return nil, err
+	}
+
+	if acceptedBy(trip, driverID) {
+		return trip, nil
+	}
+
+	if trip.Status != domain.TripStatusPending || trip.OfferedDriverID != driverID {
+		return nil, fmt.Errorf("%w: trip %s, driver %s", domain.ErrTripNotOffered, tripID, driverID)
+	}
+
+	// The profile comes from the driver service, not from the command of the driver
+	driver, err := s.drivers.GetDriver(ctx, driverID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.AcceptTrip(ctx, tripID, driver); err != nil {
+		if !errors.Is(err, domain.ErrTripNotOffered) {
+			return nil, err
+		}
+
+		// The same accept may have been handled meanwhile
+		if trip, err = s.getTrip(ctx, tripID); err != nil {
+			return nil, err
+		}
+		if !acceptedBy(trip, driverID) {
+			return nil, fmt.Errorf("%w: trip %s, driver %s", domain.ErrTripNotOffered, tripID, driverID)
+		}
+		return trip, nil
+	}
+
+	return s.getTrip(ctx, tripID)
+}
+
+// acceptedBy reports whether the driver accepted the trip already
+func acceptedBy(trip *domain.TripModel, driverID string) bool {
+	return trip.Status == domain.TripStatusAccepted && trip.Driver != nil && trip.Driver.ID == driverID
+}
+
+func (s *service) MarkTripPayed(ctx context.Context, tripID string, userID string) (bool, error) {
+	trip, err := s.getTrip(ctx, tripID)
+	if err != nil {
+		return false, err
+	}
+
+	if trip.Status != domain.TripStatusAccepted {
+		return false, nil
+	}
+
+	if trip.Split != nil && len(trip.Split.Shares) > 0 {
+		if err := s.repo.MarkSplitSharePaid(ctx, tripID, userID); err != nil {
+			return false, err
+		}
+
+		// Read again, the other riders may have paid their share meanwhile
+		if trip, err = s.getTrip(ctx, tripID); err != nil {
+			return false, err
+		}
+		if trip.Status != domain.TripStatusAccepted || !trip.Split.Paid() {
+			return false, nil
+		}
+	}
+
+	if err := s.repo.UpdateTrip(ctx, tripID, domain.TripStatusPayed); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+func (s *service) MarkTripDisputed(ctx context.Context, tripID string) (bool, error) {
+	trip, err := s.getTrip(ctx, tripID)
+	if err != nil {
+		return false, err
+	}
+
+	if trip.Status != domain.TripStatusPayed {
+		return false, nil
+	}
+
+	if err := s.repo.UpdateTrip(ctx, tripID, domain.TripStatusDisputed); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+func (s *service) CancelTrip(ctx context.Context, tripID string) (*domain.TripModel, bool, error) {
+	trip, err := s.getTrip(ctx, tripID)
+	if err != nil {
+		return nil, false, err
+	}
+
+	switch trip.Status {
+	case domain.TripStatusCancelled:
+		return trip, true, nil
+	case domain.TripStatusPending:
+	default:
+		return trip, false, nil
+	}
+
+	// A driver may accept the trip meanwhile
+	err = s.repo.CancelTrip(ctx, tripID)
+	if errors.Is(err, domain.ErrTripNotCancellable) {
+		trip, err = s.getTrip(ctx, tripID)
+		if err != nil {
+			return nil, false, err
+		}
+		return trip, trip.Status == domain.TripStatusCancelled, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	trip.Status = domain.TripStatusCancelled
+
+	return trip, true, nil
+}
+
+func (s *service) CancelRiderTrip(ctx context.Context, tripID string, userID string) (*domain.TripModel, error) {
+	trip, err := s.getTrip(ctx, tripID)
+	if err != nil {
+		return nil, err
+	}
+
+	if trip.UserID != userID {
+		return nil, fmt.Errorf("%w: trip %s, user %s", domain.ErrNotTripOwner, tripID, userID)
+	}
+
+	trip, cancelled, err := s.CancelTrip(ctx, tripID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !cancelled {
+		return nil, fmt.Errorf("%w: trip %s is %s", domain.ErrTripNotCancellable, tripID, trip.Status)
+	}
+
+	return trip, nil
+}
+
+// getTrip returns the trip, or ErrTripNotFound
+func (s *service) getTrip(ctx context.Context, tripID string) (*domain.TripModel, error) {
+	trip, err := s.repo.GetTripByID(ctx, tripID)
+	if err != nil {
+		return nil, err
+	}
+
+	if trip == nil {
+		return nil, fmt.Errorf("%w: %s", domain.ErrTripNotFound, tripID)
+	}
+
+	return trip, nil
+}
+
+func (s *service) RecordTripRefund(ctx context.Context, tripID string, refund *domain.TripRefund) error {
+	return s.repo.SetTripRefund(ctx, tripID, refund)
+}
+
+func (s *service) RecordDriverOffer(ctx context.Context, tripID string, driverID string) error {
+	return s.repo.SetOfferedDriver(ctx, tripID, driverID)
+}
+
+func (s *service) GetOfferedTrip(ctx context.Context, tripID string, driverID string) (*domain.TripModel, error) {
+	trip, err := s.repo.GetTripByID(ctx, tripID)
+	if err != nil {
+		return nil, err
+	}
+
+	if trip == nil {
+		return nil, fmt.Errorf("%w: %s", domain.ErrTripNotFound, tripID)
+	}
+
+	if driverID == "" || trip.Status != domain.TripStatusPending || trip.OfferedDriverID != driverID {
+		return nil, fmt.Errorf("%w: trip %s, driver %s", domain.ErrTripNotOffered, tripID, driverID)
+	}
+
+	return trip, nil
 }
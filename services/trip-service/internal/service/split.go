@@ -0,0 +1,183 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"ride-sharing/services/trip-service/internal/domain"
+)
+
+type splitService struct {
+	trips domain.TripRepository
+}
+
+func NewSplitService(trips domain.TripRepository) *splitService {
+	return &splitService{
+		trips: trips,
+	}
+}
+
+func (s *splitService) SplitFare(ctx context.Context, tripID, ownerID, mode string, invites []domain.SplitInvite) (*domain.TripModel, error) {
+	trip, err := s.openSplitTrip(ctx, tripID)
+	if err != nil {
+		return nil, err
+	}
+
+	if trip.UserID != ownerID {
+		return nil, fmt.Errorf("%w: trip %s, user %s", domain.ErrNotTripOwner, tripID, ownerID)
+	}
+
+	if err := validateSplit(trip, mode, invites); err != nil {
+		return nil, err
+	}
+
+	previous := trip.Split
+	if previous == nil {
+		previous = &domain.TripSplit{}
+	}
+
+	now := time.Now()
+	split := &domain.TripSplit{Mode: mode}
+	for _, invite := range invites {
+		participant := &domain.SplitParticipant{
+			UserID:    invite.UserID,
+			Status:    domain.SplitStatusInvited,
+			InvitedAt: now,
+		}
+		if mode == domain.SplitModeCustom {
+			participant.Share = invite.Share
+		}
+
+		// A rider invited again keeps their answer, unless they were asked for another share
+		if p := previous.Participant(invite.UserID); p != nil {
+			participant.InvitedAt = p.InvitedAt
+			if previous.Mode == mode && p.Share == participant.Share {
+				participant.Status = p.Status
+				participant.RespondedAt = p.RespondedAt
+			}
+		}
+
+		split.Participants = append(split.Participants, participant)
+	}
+
+	if err := s.trips.SetTripSplit(ctx, tripID, split); err != nil {
+		return nil, fmt.Errorf("failed to save the split of trip %s: %w", tripID, err)
+	}
+	trip.Split = split
+
+	return trip, nil
+}
+
+// validateSplit checks the invitations: distinct riders other than the owner and, in custom mode,
+// positive shares that leave a part of the fare to the owner
+func validateSplit(trip *domain.TripModel, mode string, invites []domain.SplitInvite) error {
+	if mode != domain.SplitModeEqual && mode != domain.SplitModeCustom {
+		return fmt.Errorf("%w: unknown mode %q", domain.ErrInvalidSplit, mode)
+	}
+
+	if len(invites) == 0 || len(invites) > domain.MaxSplitParticipants {
+		return fmt.Errorf("%w: invite between 1 and %d riders", domain.ErrInvalidSplit, domain.MaxSplitParticipants)
+	}
+
+	var total int64
+	seen := make(map[string]bool, len(invites))
+	for _, invite := range invites {
+		switch {
+		case invite.UserID == "":
+			return fmt.Errorf("%w: the riders need an ID", domain.ErrInvalidSplit)
+		case invite.UserID == trip.UserID:
+			return fmt.Errorf("%w: the rider can't invite themselves", domain.ErrInvalidSplit)
+		case seen[invite.UserID]:
+			return fmt.Errorf("%w: rider %s is invited twice", domain.ErrInvalidSplit, invite.UserID)
+		case mode == domain.SplitModeCustom && invite.Share <= 0:
+			return fmt.Errorf("%w: the share of rider %s must be positive", domain.ErrInvalidSplit, invite.UserID)
+		}
+		seen[invite.UserID] = true
+		total += invite.Share
+	}
+
+	fare := int64(trip.RideFare.TotalPriceInCents)
+	if mode == domain.SplitModeCustom && total >= fare {
+		return fmt.Errorf("%w: the shares sum to %d, the fare is %d", domain.ErrInvalidSplit, total, fare)
+	}
+
+	return nil
+}
+
+func (s *splitService) RespondToSplit(ctx context.Context, tripID, userID string, accept bool) (*domain.TripModel, error) {
+	trip, err := s.openSplitTrip(ctx, tripID)
+	if err != nil {
+		return nil, err
+	}
+
+	var participant *domain.SplitParticipant
+	if trip.Split != nil {
+		participant = trip.Split.Participant(userID)
+	}
+	if participant == nil {
+		return nil, fmt.Errorf("%w: trip %s, user %s", domain.ErrNotInvited, tripID, userID)
+	}
+
+	status := domain.SplitStatusDeclined
+	if accept {
+		status = domain.SplitStatusAccepted
+	}
+
+	if participant.Status == status {
+		// A redelivered command
+		return trip, nil
+	}
+
+	now := time.Now()
+	participant.Status = status
+	participant.RespondedAt = &now
+
+	if err := s.trips.SetTripSplit(ctx, tripID, trip.Split); err != nil {
+		return nil, fmt.Errorf("failed to save the split of trip %s: %w", tripID, err)
+	}
+
+	return trip, nil
+}
+
+func (s *splitService) LockSplitShares(ctx context.Context, trip *domain.TripModel) ([]*domain.SplitShare, error) {
+	if trip.Split == nil {
+		return nil, nil
+	}
+
+	if len(trip.Split.Shares) > 0 {
+		return trip.Split.Shares, nil
+	}
+
+	shares := trip.Split.ComputeShares(trip.UserID, int64(trip.RideFare.TotalPriceInCents))
+	if len(shares) == 1 {
+		// Nobody accepted, the rider pays the whole fare
+		return nil, nil
+	}
+
+	trip.Split.Shares = shares
+	if err := s.trips.SetTripSplit(ctx, trip.ID.Hex(), trip.Split); err != nil {
+		return nil, fmt.Errorf("failed to lock the shares of trip %s: %w", trip.ID.Hex(), err)
+	}
+
+	return shares, nil
+}
+
+// openSplitTrip returns the trip, or ErrSplitClosed once a driver accepted it
+func (s *splitService) openSplitTrip(ctx context.Context, tripID string) (*domain.TripModel, error) {
+	trip, err := s.trips.GetTripByID(ctx, tripID)
+	if err != nil {
+		return nil, err
+	}
+
+	if trip == nil {
+		return nil, fmt.Errorf("%w: %s", domain.ErrTripNotFound, tripID)
+	}
+
+	if !slices.Contains(domain.SplitTripStatuses, trip.Status) {
+		return nil, fmt.Errorf("%w: trip %s is %s", domain.ErrSplitClosed, tripID, trip.Status)
+	}
+
+	return trip, nil
+}
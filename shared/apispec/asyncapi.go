@@ -0,0 +1,377 @@
+package apispec
+
+import (
+	"reflect"
+
+	"ride-sharing/shared/contracts"
+	"ride-sharing/shared/messaging"
+	pbd "ride-sharing/shared/proto/driver"
+	pb "ride-sharing/shared/proto/trip"
+)
+
+// message is a documented message type, Payload is the type of its data, nil when it has none
+type message struct {
+	Type     string
+	Summary  string
+	Payload  any
+	Nullable bool // The data may be null
+}
+
+var (
+	tripCreated = message{
+		Type:    contracts.TripEventCreated,
+		Summary: "A trip was created",
+		Payload: messaging.TripEventData{},
+	}
+	driverAssigned = message{
+		Type:    contracts.TripEventDriverAssigned,
+		Summary: "A driver accepted the trip",
+		Payload: pb.Trip{},
+	}
+	noDriversFound = message{
+		Type:    contracts.TripEventNoDriversFound,
+		Summary: "No driver is available for the trip, it is cancelled",
+		Payload: messaging.TripEventData{},
+	}
+	driverNotInterested = message{
+		Type:    contracts.TripEventDriverNotInterested,
+		Summary: "A driver declined the trip, another one is looked for",
+		Payload: messaging.TripEventData{},
+	}
+	paymentSessionCreated = message{
+		Type:    contracts.PaymentEventSessionCreated,
+		Summary: "The checkout session of the trip was created",
+		Payload: messaging.PaymentEventSessionCreatedData{},
+	}
+	paymentSuccess = message{
+		Type:    contracts.PaymentEventSuccess,
+		Summary: "The rider paid the trip",
+		Payload: messaging.PaymentStatusUpdateData{},
+	}
+	paymentFailed = message{
+		Type:    contracts.PaymentEventFailed,
+		Summary: "The payment of the checkout session failed",
+		Payload: messaging.PaymentStatusUpdateData{},
+	}
+	paymentCancelled = message{
+		Type:    contracts.PaymentEventCancelled,
+		Summary: "The checkout session expired without payment",
+		Payload: messaging.PaymentStatusUpdateData{},
+	}
+	paymentRefunded = message{
+		Type:    contracts.PaymentEventRefunded,
+		Summary: "The payment of the trip was refunded, fully or partially",
+		Payload: messaging.PaymentRefundedData{},
+	}
+	paymentDisputed = message{
+		Type:    contracts.PaymentEventDisputed,
+		Summary: "The rider disputed the payment with their bank",
+		Payload: messaging.PaymentStatusUpdateData{},
+	}
+	paymentCharged = message{
+		Type:    contracts.PaymentEventCharged,
+		Summary: "The saved card of the rider was charged for the trip or a tip, there is no checkout session to pay",
+		Payload: messaging.PaymentChargedData{},
+	}
+	holdFailed = message{
+		Type:    contracts.PaymentEventHoldFailed,
+		Summary: "The fare couldn't be held on the card of the rider, the trip is not dispatched",
+		Payload: messaging.PaymentHoldData{},
+	}
+	holdReleased = message{
+		Type:    contracts.PaymentEventHoldReleased,
+		Summary: "The fare held on the card of the rider was released, the trip was cancelled",
+		Payload: messaging.PaymentHoldData{},
+	}
+	tipReceived = message{
+		Type:    contracts.PaymentEventTipReceived,
+		Summary: "The rider paid a tip to the driver of the trip",
+		Payload: messaging.PaymentTipReceivedData{},
+	}
+	sessionResumed = message{
+		Type:    contracts.SessionEventResumed,
+		Summary: "The missed messages were replayed to the reconnecting client",
+		Payload: contracts.SessionResumedData{},
+	}
+	tripSnapshot = message{
+		Type:     contracts.TripEventSnapshot,
+		Summary:  "The active trip of the reconnecting client, null when there is none",
+		Payload:  pb.Trip{},
+		Nullable: true,
+	}
+	tripRequest = message{
+		Type:    contracts.DriverCmdTripRequest,
+		Summary: "A trip is offered to the driver",
+		Payload: messaging.TripEventData{},
+	}
+	tripOffer = message{
+		Type:    contracts.TripCmdOffer,
+		Summary: "Offer the trip to the driver owning the message, the trip service records the offer and sends the trip request",
+		Payload: messaging.TripEventData{},
+	}
+	responseRejected = message{
+		Type:    contracts.TripEventResponseRejected,
+		Summary: "The accept or decline of the trip was refused, code is one of the error codes of the API",
+		Payload: messaging.DriverResponseRejectedData{},
+	}
+	driverRegistered = message{
+		Type:    contracts.DriverCmdRegister,
+		Summary: "The driver was registered",
+		Payload: pbd.Driver{},
+	}
+	tripAccept = message{
+		Type:    contracts.DriverCmdTripAccept,
+		Summary: "The driver accepts the offered trip",
+		Payload: messaging.DriverTripResponseData{},
+	}
+	tripDecline = message{
+		Type:    contracts.DriverCmdTripDecline,
+		Summary: "The driver declines the offered trip",
+		Payload: messaging.DriverTripResponseData{},
+	}
+	driverLocation = message{
+		Type:    contracts.DriverCmdLocation,
+		Summary: "Location of the drivers, ignored for now",
+		Payload: []pbd.Driver{},
+	}
+	paymentCreateSession = message{
+		Type:    contracts.PaymentCmdCreateSession,
+		Summary: "Create the checkout session of an accepted trip",
+		Payload: messaging.PaymentTripResponseData{},
+	}
+	releaseHold = message{
+		Type:    contracts.PaymentCmdReleaseHold,
+		Summary: "Release the hold of a trip that won't be paid: no driver accepted it, the rider cancelled it, or the hold call failed",
+		Payload: messaging.PaymentHoldCmdData{},
+	}
+	addTip = message{
+		Type:    contracts.PaymentCmdAddTip,
+		Summary: "Tip the driver of a paid trip, amount is in cents and tipID is chosen by the client to send the tip once. The checkout session of the tip follows",
+		Payload: messaging.AddTipData{},
+	}
+	chatSend = message{
+		Type:    contracts.ChatCmdSend,
+		Summary: "Send a chat message to the other participant of the trip, text is ignored when templateID is set",
+		Payload: messaging.ChatSendData{},
+	}
+	chatReceiptCmd = message{
+		Type:    contracts.ChatCmdReceipt,
+		Summary: "Mark the received chat messages as delivered or read",
+		Payload: messaging.ChatReceiptData{},
+	}
+	splitInvited = message{
+		Type:    contracts.TripEventSplitInvited,
+		Summary: "The rider who requested the trip invites the rider to split its fare, answer with trip.cmd.split_accept or trip.cmd.split_decline",
+		Payload: messaging.SplitInvitationData{},
+	}
+	splitUpdated = message{
+		Type:    contracts.TripEventSplitUpdated,
+		Summary: "A rider invited to split the fare answered, the trip carries the split",
+		Payload: messaging.TripEventData{},
+	}
+	splitAccept = message{
+		Type:    contracts.TripCmdSplitAccept,
+		Summary: "Accept to pay a share of the fare of the trip, until a driver accepts it",
+		Payload: messaging.SplitResponseData{},
+	}
+	splitDecline = message{
+		Type:    contracts.TripCmdSplitDecline,
+		Summary: "Decline to pay a share of the fare of the trip",
+		Payload: messaging.SplitResponseData{},
+	}
+	splitFailed = message{
+		Type:    contracts.PaymentEventSplitFailed,
+		Summary: "Another rider didn't pay their share of the fare, the trip stays unpaid",
+		Payload: messaging.PaymentSplitFailedData{},
+	}
+	chatMessage = message{
+		Type:    contracts.ChatEventMessage,
+		Summary: "A chat message of the trip, sent to the recipient and echoed to the sender",
+		Payload: pb.ChatMessage{},
+	}
+	chatReceiptEvent = message{
+		Type:    contracts.ChatEventReceipt,
+		Summary: "The other participant received or read the chat messages",
+		Payload: messaging.ChatReceiptData{},
+	}
+)
+
+// wsChannel is a websocket route of the gateway. Publish lists the messages sent by the
+// gateway and Subscribe the ones sent by the client, as seen from the client.
+type wsChannel struct {
+	Path      string
+	SSE       bool // Server-Sent Events stream instead of a websocket
+	Subscribe []message
+	Publish   []message
+}
+
+// riderMessages are sent to the riders, over the websocket or the event stream
+var riderMessages = []message{
+	tripCreated, driverAssigned, noDriversFound, paymentSessionCreated, paymentCharged, paymentFailed,
+	paymentCancelled, paymentDisputed, holdFailed, holdReleased, splitInvited, splitUpdated, splitFailed, sessionResumed, tripSnapshot, chatMessage, chatReceiptEvent,
+}
+
+var wsChannels = []wsChannel{
+	{
+		Path:      "/ws/riders",
+		Subscribe: riderMessages,
+		Publish: []message{
+			chatSend, chatReceiptCmd, addTip, splitAccept, splitDecline,
+		},
+	},
+	{
+		Path:      "/events/riders",
+		SSE:       true,
+		Subscribe: riderMessages,
+	},
+	{
+		Path: "/ws/drivers",
+		Subscribe: []message{
+			driverRegistered, tripRequest, responseRejected, sessionResumed, tripSnapshot, chatMessage, chatReceiptEvent, tipReceived,
+		},
+		Publish: []message{
+			tripAccept, tripDecline, driverLocation, chatSend, chatReceiptCmd,
+		},
+	},
+}
+
+// amqpMessages are published on the trip exchange with their type as routing key
+var amqpMessages = []message{
+	tripCreated, driverAssigned, noDriversFound, driverNotInterested, tripOffer, tripRequest, tripAccept, tripDecline,
+	responseRejected,
+	paymentCreateSession, paymentSessionCreated, paymentSuccess, paymentFailed, paymentCancelled,
+	paymentRefunded, paymentDisputed, addTip, tipReceived, paymentCharged,
+	holdFailed, releaseHold, holdReleased,
+	splitInvited, splitUpdated, splitAccept, splitDecline, splitFailed,
+	chatSend, chatReceiptCmd, chatMessage, chatReceiptEvent,
+}
+
+// AsyncAPI returns the AsyncAPI 2 document of the websocket messages of the gateway
+// and of the RabbitMQ messages exchanged by the services
+func AsyncAPI() object {
+	reg := newRegistry("#/components/schemas/")
+	channels := object{}
+	messages := object{}
+
+	for _, ch := range wsChannels {
+		channel := object{
+			"description": "Websocket of the gateway, see " + ch.Path + " in /openapi.json for the connection parameters",
+			"bindings":    object{"ws": object{"method": "GET"}},
+		}
+		if ch.SSE {
+			channel = object{
+				"description": "Server-Sent Events stream of the gateway, see " + ch.Path + " in /openapi.json for the connection parameters",
+			}
+		}
+		if len(ch.Subscribe) > 0 {
+			channel["subscribe"] = wsOperation(reg, messages, ch.Subscribe)
+		}
+		if len(ch.Publish) > 0 {
+			channel["publish"] = wsOperation(reg, messages, ch.Publish)
+		}
+		channels[ch.Path] = channel
+	}
+
+	for _, msg := range amqpMessages {
+		name := "amqp." + msg.Type
+		messages[name] = object{
+			"name":        msg.Type,
+			"summary":     msg.Summary,
+			"contentType": "application/json",
+			"payload":     amqpPayload(reg, msg),
+		}
+		channels[msg.Type] = object{
+			"description": "Messages published on the " + messaging.TripExchange + " exchange with the routing key " + msg.Type,
+			"bindings": object{"amqp": object{
+				"is":       "routingKey",
+				"exchange": object{"name": messaging.TripExchange, "type": "topic", "durable": true},
+			}},
+			"subscribe": object{"message": object{"$ref": "#/components/messages/" + name}},
+		}
+	}
+
+	return object{
+		"asyncapi": "2.6.0",
+		"info": object{
+			"title":   "Ride Sharing Messages",
+			"version": Version,
+		},
+		"defaultContentType": "application/json",
+		"servers": object{
+			"gateway": object{
+				"url":         "localhost:8081",
+				"protocol":    "ws",
+				"description": "Websockets of the API gateway",
+			},
+			"rabbitmq": object{
+				"url":         "rabbitmq:5672",
+				"protocol":    "amqp",
+				"description": "Broker shared by the services",
+			},
+		},
+		"channels": channels,
+		"components": object{
+			"messages": messages,
+			"schemas":  reg.components,
+		},
+	}
+}
+
+// wsOperation registers the websocket messages and returns an operation referencing them
+func wsOperation(reg *registry, messages object, list []message) object {
+	refs := make([]object, len(list))
+	for i, msg := range list {
+		name := "ws." + msg.Type
+		messages[name] = object{
+			"name":    msg.Type,
+			"summary": msg.Summary,
+			"payload": wsPayload(reg, msg),
+		}
+		refs[i] = object{"$ref": "#/components/messages/" + name}
+	}
+
+	return object{"message": object{"oneOf": refs}}
+}
+
+// wsPayload is the WSMessage envelope of the message
+func wsPayload(reg *registry, msg message) *Schema {
+	return &Schema{
+		Type: "object",
+		Properties: map[string]*Schema{
+			"type": {Type: "string", Const: msg.Type},
+			"data": dataSchema(reg, msg),
+			"seq":  {Type: "integer", Format: "int64", Description: "Sequence number of the message, only set on the messages sent by the gateway"},
+		},
+		Required: []string{"type", "data"},
+	}
+}
+
+// amqpPayload is the AmqpMessage envelope of the message, its data is the base64 of the JSON payload
+func amqpPayload(reg *registry, msg message) *Schema {
+	envelope := reg.structSchema(reflect.TypeOf(contracts.AmqpMessage{}))
+	envelope.Properties["data"] = &Schema{
+		Type:             "string",
+		ContentEncoding:  "base64",
+		ContentMediaType: "application/json",
+		ContentSchema:    dataSchema(reg, msg),
+	}
+
+	return envelope
+}
+
+func dataSchema(reg *registry, msg message) *Schema {
+	if msg.Payload == nil {
+		return &Schema{Description: "Always null"}
+	}
+
+	s := reg.schemaOf(msg.Payload)
+	if !msg.Nullable {
+		return s
+	}
+	if s.Ref != "" {
+		// Siblings of $ref are ignored, wrap it to make it nullable
+		return &Schema{OneOf: []*Schema{s}, Nullable: true}
+	}
+	s.Nullable = true
+	return s
+}
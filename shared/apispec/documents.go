@@ -0,0 +1,25 @@
+package apispec
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Documents returns the documents committed in docs/api, encoded and keyed by file name
+func Documents() (map[string][]byte, error) {
+	docs := map[string]any{
+		"openapi.json":  OpenAPI(),
+		"asyncapi.json": AsyncAPI(),
+	}
+
+	encoded := make(map[string][]byte, len(docs))
+	for name, doc := range docs {
+		content, err := json.MarshalIndent(doc, "", "  ")
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode %s: %w", name, err)
+		}
+		encoded[name] = append(content, '\n')
+	}
+
+	return encoded, nil
+}
@@ -0,0 +1,34 @@
+package apispec
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCommittedDocumentsMatchTheTypes(t *testing.T) {
+	docs, err := Documents()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, content := range docs {
+		path := filepath.Join("..", "..", "docs", "api", name)
+		committed, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(committed, content) {
+			t.Errorf("%s is out of date with the contract types, run make generate-api-spec", path)
+		}
+	}
+}
+
+func TestRoutesAnsweredByAnRPCDocumentItsOutput(t *testing.T) {
+	for _, rt := range append(routes, restRoutes()...) {
+		if rt.RPC != "" && outputOf(rt.RPC) == nil {
+			t.Errorf("%s %s: unknown RPC %s", rt.Method, rt.Path, rt.RPC)
+		}
+	}
+}
@@ -0,0 +1,247 @@
+package apispec
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"ride-sharing/shared/contracts"
+	pb "ride-sharing/shared/proto/trip"
+)
+
+// Version of the gateway API in both documents
+const Version = "1.0.0"
+
+type object = map[string]any
+
+// route is a documented HTTP route of the gateway
+type route struct {
+	Path        string
+	Method      string
+	Summary     string
+	Request     any    // Type of the JSON body, nil for none
+	Response    any    // Type of the data of the response, nil for none
+	RPC         string // Full method name of the gRPC call answering the route, its output is the response
+	Status      int
+	Errors      []int
+	Query       []parameter
+	Headers     []parameter
+	PathParams  []parameter
+	Public      bool // Not authenticated
+	Description string
+}
+
+// parameter is a query parameter of a route
+type parameter struct {
+	Name        string
+	Description string
+	Required    bool
+}
+
+var wsQuery = []parameter{
+	{Name: "userID", Description: "Defaults to the user of the token"},
+	{Name: "lastSeq", Description: "Sequence number of the last message received, to resume the session"},
+	{Name: "token", Description: "JWT, for the clients that can't set the Authorization header"},
+}
+
+var routes = []route{
+	{
+		Path:    "/trip/preview",
+		Method:  http.MethodPost,
+		Summary: "Preview the route and the fares of a trip",
+		Request: contracts.PreviewTripRequest{},
+		RPC:     pb.TripService_PreviewTrip_FullMethodName,
+		Status:  http.StatusCreated,
+		Errors:  []int{400, 401, 403, 429, 500, 503},
+	},
+	{
+		Path:    "/trip/start",
+		Method:  http.MethodPost,
+		Summary: "Start a trip with one of the previewed fares",
+		Request: contracts.StartTripRequest{},
+		RPC:     pb.TripService_CreateTrip_FullMethodName,
+		Status:  http.StatusCreated,
+		Errors:  []int{400, 401, 403, 404, 409, 429, 500, 503},
+	},
+	{
+		Path:        "/ws/riders",
+		Method:      http.MethodGet,
+		Summary:     "Websocket of the riders",
+		Status:      http.StatusSwitchingProtocols,
+		Errors:      []int{401, 403, 429},
+		Query:       wsQuery,
+		Description: "The messages are described in /asyncapi.json. Pass ?lastSeq= to resume a session.",
+	},
+	{
+		Path:        "/events/riders",
+		Method:      http.MethodGet,
+		Summary:     "Server-Sent Events fallback of the websocket of the riders",
+		Status:      http.StatusOK,
+		Errors:      []int{400, 401, 403, 429},
+		Query:       wsQuery,
+		Headers:     []parameter{{Name: "Last-Event-ID", Description: "Sent by EventSource when it reconnects, same as lastSeq"}},
+		Description: "A text/event-stream of the messages of /ws/riders, described in /asyncapi.json. The data of every event is the JSON of a message and its id is the seq of the message.",
+	},
+	{
+		Path:        "/ws/drivers",
+		Method:      http.MethodGet,
+		Summary:     "Websocket of the drivers",
+		Status:      http.StatusSwitchingProtocols,
+		Errors:      []int{400, 401, 403, 429, 503},
+		Query:       append([]parameter{{Name: "packageSlug", Description: "Package of the car", Required: true}}, wsQuery...),
+		Description: "The messages are described in /asyncapi.json. Requires ?packageSlug=, pass ?lastSeq= to resume a session.",
+	},
+	{
+		Path:        "/webhook/stripe",
+		Method:      http.MethodPost,
+		Summary:     "Stripe webhook",
+		Status:      http.StatusOK,
+		Errors:      []int{400, 500, 502, 503},
+		Public:      true,
+		Description: "Stripe event signed with the Stripe-Signature header, proxied to payment-service which verifies and processes it.",
+		Headers:     []parameter{{Name: "Stripe-Signature", Required: true}},
+	},
+	{
+		Path:    "/openapi.json",
+		Method:  http.MethodGet,
+		Summary: "This document",
+		Status:  http.StatusOK,
+		Public:  true,
+	},
+	{
+		Path:    "/asyncapi.json",
+		Method:  http.MethodGet,
+		Summary: "AsyncAPI document of the websocket and RabbitMQ messages",
+		Status:  http.StatusOK,
+		Public:  true,
+	},
+}
+
+// OpenAPI returns the OpenAPI 3 document of the HTTP API of the gateway
+func OpenAPI() object {
+	reg := newRegistry("#/components/schemas/")
+	paths := object{}
+
+	for _, rt := range append(routes, restRoutes()...) {
+		if rt.RPC != "" {
+			rt.Response = outputOf(rt.RPC)
+		}
+
+		operation := object{
+			"summary":   rt.Summary,
+			"responses": responses(reg, rt),
+		}
+		if rt.Description != "" {
+			operation["description"] = rt.Description
+		}
+		if rt.Public {
+			operation["security"] = []object{}
+		}
+		if params := parameters(rt); len(params) > 0 {
+			operation["parameters"] = params
+		}
+		if rt.Request != nil {
+			operation["requestBody"] = object{
+				"required": true,
+				"content":  object{"application/json": object{"schema": reg.schemaOf(rt.Request)}},
+			}
+		}
+
+		item, ok := paths[rt.Path].(object)
+		if !ok {
+			item = object{}
+			paths[rt.Path] = item
+		}
+		item[strings.ToLower(rt.Method)] = operation
+	}
+
+	return object{
+		"openapi": "3.0.3",
+		"info": object{
+			"title":   "Ride Sharing API Gateway",
+			"version": Version,
+		},
+		"paths":    paths,
+		"security": []object{{"bearerAuth": []string{}}},
+		"components": object{
+			"schemas": reg.components,
+			"securitySchemes": object{
+				"bearerAuth": object{"type": "http", "scheme": "bearer", "bearerFormat": "JWT"},
+			},
+		},
+	}
+}
+
+func parameters(rt route) []object {
+	var params []object
+	add := func(in string, list []parameter) {
+		for _, p := range list {
+			param := object{"name": p.Name, "in": in, "required": p.Required, "schema": &Schema{Type: "string"}}
+			if p.Description != "" {
+				param["description"] = p.Description
+			}
+			params = append(params, param)
+		}
+	}
+
+	add("path", rt.PathParams)
+	add("query", rt.Query)
+	add("header", rt.Headers)
+	return params
+}
+
+func responses(reg *registry, rt route) object {
+	res := object{}
+
+	success := object{"description": http.StatusText(rt.Status)}
+	if rt.Response != nil {
+		success["content"] = object{"application/json": object{"schema": &Schema{
+			Type: "object",
+			Properties: map[string]*Schema{
+				"data": reg.schemaOf(rt.Response),
+			},
+			Required: []string{"data"},
+		}}}
+	}
+	if !rt.Public {
+		success["headers"] = rateLimitHeaders(false)
+	}
+	res[strconv.Itoa(rt.Status)] = success
+
+	for _, status := range rt.Errors {
+		response := object{
+			"description": http.StatusText(status),
+			"content": object{"application/json": object{"schema": &Schema{
+				Type: "object",
+				Properties: map[string]*Schema{
+					"error": reg.schemaOf(contracts.APIError{}),
+				},
+				Required: []string{"error"},
+			}}},
+		}
+		if status == http.StatusTooManyRequests {
+			response["headers"] = rateLimitHeaders(true)
+		}
+		res[strconv.Itoa(status)] = response
+	}
+
+	return res
+}
+
+// rateLimitHeaders describes the headers set by the rate limiter of the gateway
+func rateLimitHeaders(limited bool) object {
+	header := func(description string) object {
+		return object{"description": description, "schema": &Schema{Type: "integer"}}
+	}
+
+	headers := object{
+		"X-RateLimit-Limit":     header("Number of requests allowed in a burst"),
+		"X-RateLimit-Remaining": header("Number of requests left before the limit is hit"),
+		"X-RateLimit-Reset":     header("Seconds until the bucket is full again"),
+	}
+	if limited {
+		headers["Retry-After"] = header("Seconds to wait before retrying")
+	}
+
+	return headers
+}
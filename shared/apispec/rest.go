@@ -0,0 +1,124 @@
+package apispec
+
+import (
+	"net/http"
+	"regexp"
+	"strings"
+
+	pbd "ride-sharing/shared/proto/driver"
+	pbp "ride-sharing/shared/proto/payment"
+	pb "ride-sharing/shared/proto/trip"
+
+	"google.golang.org/genproto/googleapis/api/annotations"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+)
+
+// restFiles are the protos whose annotated RPCs the gateway exposes under /v1/
+var restFiles = []protoreflect.FileDescriptor{
+	pb.File_trip_proto,
+	pbd.File_driver_proto,
+	pbp.File_payment_proto,
+}
+
+var pathParamPattern = regexp.MustCompile(`\{([^}=]+)(=[^}]*)?\}`)
+
+// restRoutes lists the RPCs annotated with google.api.http, so that a new RPC is documented
+// as soon as it is exposed
+func restRoutes() []route {
+	var rts []route
+
+	for _, file := range restFiles {
+		services := file.Services()
+		for i := 0; i < services.Len(); i++ {
+			methods := services.Get(i).Methods()
+			for j := 0; j < methods.Len(); j++ {
+				if rt, ok := restRoute(methods.Get(j)); ok {
+					rts = append(rts, rt)
+				}
+			}
+		}
+	}
+
+	return rts
+}
+
+// RESTMethods returns the full method names of the RPCs exposed under /v1/
+func RESTMethods() []string {
+	var methods []string
+	for _, rt := range restRoutes() {
+		methods = append(methods, rt.RPC)
+	}
+	return methods
+}
+
+func restRoute(method protoreflect.MethodDescriptor) (route, bool) {
+	rule, ok := proto.GetExtension(method.Options(), annotations.E_Http).(*annotations.HttpRule)
+	if !ok || rule == nil {
+		return route{}, false
+	}
+
+	rt := route{
+		Summary: "Calls " + string(method.FullName()),
+		RPC:     fullMethodName(method),
+		Status:  http.StatusOK,
+		Errors:  []int{400, 401, 403, 404, 409, 429, 500, 503},
+	}
+
+	switch pattern := rule.GetPattern().(type) {
+	case *annotations.HttpRule_Get:
+		rt.Method, rt.Path = http.MethodGet, pattern.Get
+	case *annotations.HttpRule_Post:
+		rt.Method, rt.Path = http.MethodPost, pattern.Post
+	case *annotations.HttpRule_Put:
+		rt.Method, rt.Path = http.MethodPut, pattern.Put
+	case *annotations.HttpRule_Patch:
+		rt.Method, rt.Path = http.MethodPatch, pattern.Patch
+	case *annotations.HttpRule_Delete:
+		rt.Method, rt.Path = http.MethodDelete, pattern.Delete
+	default:
+		return route{}, false
+	}
+
+	// {name=pattern} becomes {name}
+	for _, match := range pathParamPattern.FindAllStringSubmatch(rt.Path, -1) {
+		rt.PathParams = append(rt.PathParams, parameter{Name: match[1], Required: true})
+	}
+	rt.Path = pathParamPattern.ReplaceAllString(rt.Path, "{$1}")
+
+	if rule.GetBody() == "*" {
+		rt.Request = messageOf(method.Input())
+	}
+
+	return rt, true
+}
+
+// fullMethodName returns the name gRPC calls the method with, e.g. /trip.TripService/PreviewTrip
+func fullMethodName(method protoreflect.MethodDescriptor) string {
+	return "/" + string(method.Parent().FullName()) + "/" + string(method.Name())
+}
+
+// outputOf returns the generated Go message answered by the gRPC method
+func outputOf(fullMethod string) any {
+	name := strings.ReplaceAll(strings.TrimPrefix(fullMethod, "/"), "/", ".")
+	desc, err := protoregistry.GlobalFiles.FindDescriptorByName(protoreflect.FullName(name))
+	if err != nil {
+		return nil
+	}
+
+	method, ok := desc.(protoreflect.MethodDescriptor)
+	if !ok {
+		return nil
+	}
+	return messageOf(method.Output())
+}
+
+// messageOf returns the generated Go message of the descriptor
+func messageOf(desc protoreflect.MessageDescriptor) any {
+	mt, err := protoregistry.GlobalTypes.FindMessageByName(desc.FullName())
+	if err != nil {
+		return nil
+	}
+	return mt.New().Interface()
+}
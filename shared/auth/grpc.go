@@ -0,0 +1,56 @@
+package auth
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// gRPC metadata keys of the identity propagated from the api gateway
+const (
+	UserIDMetadataKey = "x-user-id"
+	RoleMetadataKey   = "x-user-role"
+)
+
+// DialOptionsWithIdentity forwards the identity of the context to the called services
+func DialOptionsWithIdentity() []grpc.DialOption {
+	return []grpc.DialOption{
+		grpc.WithChainUnaryInterceptor(unaryClientInterceptor),
+	}
+}
+
+// WithIdentityInterceptors exposes the propagated identity on the context of the gRPC handlers.
+// The metadata is trusted as is, the services are only reachable from inside the cluster.
+func WithIdentityInterceptors() []grpc.ServerOption {
+	return []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(unaryServerInterceptor),
+	}
+}
+
+func unaryClientInterceptor(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	if identity, ok := FromContext(ctx); ok {
+		ctx = metadata.AppendToOutgoingContext(ctx,
+			UserIDMetadataKey, identity.UserID,
+			RoleMetadataKey, string(identity.Role),
+		)
+	}
+
+	return invoker(ctx, method, req, reply, cc, opts...)
+}
+
+func unaryServerInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		userIDs := md.Get(UserIDMetadataKey)
+		roles := md.Get(RoleMetadataKey)
+
+		if len(userIDs) > 0 && len(roles) > 0 && Role(roles[0]).Valid() {
+			ctx = NewContext(ctx, Identity{
+				UserID: userIDs[0],
+				Role:   Role(roles[0]),
+			})
+		}
+	}
+
+	return handler(ctx, req)
+}
@@ -0,0 +1,55 @@
+/*
+Package auth verifies the JWTs of the users and carries their identity across the services.
+*/
+package auth
+
+import (
+	"context"
+	"errors"
+)
+
+type Role string
+
+const (
+	RoleRider  Role = "rider"
+	RoleDriver Role = "driver"
+	RoleAdmin  Role = "admin"
+)
+
+var (
+	ErrMissingToken = errors.New("missing token")
+	ErrInvalidToken = errors.New("invalid token")
+)
+
+func (r Role) Valid() bool {
+	switch r {
+	case RoleRider, RoleDriver, RoleAdmin:
+		return true
+	default:
+		return false
+	}
+}
+
+// Identity is the authenticated user behind a request
+type Identity struct {
+	UserID string
+	Role   Role
+}
+
+// CanActAs reports whether the identity may act on behalf of the user, only admins can act for others
+func (i Identity) CanActAs(userID string) bool {
+	return i.Role == RoleAdmin || i.UserID == userID
+}
+
+type identityKey struct{}
+
+// NewContext returns a copy of ctx carrying the identity
+func NewContext(ctx context.Context, identity Identity) context.Context {
+	return context.WithValue(ctx, identityKey{}, identity)
+}
+
+// FromContext returns the identity carried by ctx, if any
+func FromContext(ctx context.Context) (Identity, bool) {
+	identity, ok := ctx.Value(identityKey{}).(Identity)
+	return identity, ok
+}
@@ -0,0 +1,179 @@
+package auth
+
+import (
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"os"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Config holds the keys and the expected claims of the JWTs
+type Config struct {
+	HMACSecret string // Shared secret of the HS256 tokens
+	JWKSFile   string // Path of a JWKS file with the public keys of the RS256 tokens
+	Issuer     string
+	Audience   string
+}
+
+// NewDefaultConfig creates a new auth configuration from environment variables
+func NewDefaultConfig() *Config {
+	return &Config{
+		HMACSecret: os.Getenv("JWT_HMAC_SECRET"),
+		JWKSFile:   os.Getenv("JWT_JWKS_FILE"),
+		Issuer:     os.Getenv("JWT_ISSUER"),
+		Audience:   os.Getenv("JWT_AUDIENCE"),
+	}
+}
+
+// Enabled reports whether any key is configured to verify the tokens
+func (c *Config) Enabled() bool {
+	return c.HMACSecret != "" || c.JWKSFile != ""
+}
+
+// Claims are the JWT claims of a user token, the subject is the user ID
+type Claims struct {
+	Role Role `json:"role"`
+	jwt.RegisteredClaims
+}
+
+type Verifier struct {
+	hmacSecret []byte
+	rsaKeys    map[string]*rsa.PublicKey // kid -> key
+	parser     *jwt.Parser
+}
+
+func NewVerifier(cfg *Config) (*Verifier, error) {
+	if !cfg.Enabled() {
+		return nil, fmt.Errorf("either a HMAC secret or a JWKS file is required")
+	}
+
+	v := &Verifier{
+		hmacSecret: []byte(cfg.HMACSecret),
+		rsaKeys:    make(map[string]*rsa.PublicKey),
+	}
+
+	if cfg.JWKSFile != "" {
+		keys, err := loadJWKS(cfg.JWKSFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load the JWKS file: %v", err)
+		}
+		v.rsaKeys = keys
+	}
+
+	methods := []string{}
+	if cfg.HMACSecret != "" {
+		methods = append(methods, jwt.SigningMethodHS256.Alg())
+	}
+	if len(v.rsaKeys) > 0 {
+		methods = append(methods, jwt.SigningMethodRS256.Alg())
+	}
+
+	opts := []jwt.ParserOption{
+		jwt.WithValidMethods(methods),
+		jwt.WithExpirationRequired(),
+	}
+	if cfg.Issuer != "" {
+		opts = append(opts, jwt.WithIssuer(cfg.Issuer))
+	}
+	if cfg.Audience != "" {
+		opts = append(opts, jwt.WithAudience(cfg.Audience))
+	}
+	v.parser = jwt.NewParser(opts...)
+
+	return v, nil
+}
+
+// Verify checks the signature and the claims of the token and returns the identity it carries
+func (v *Verifier) Verify(tokenString string) (Identity, error) {
+	if tokenString == "" {
+		return Identity{}, ErrMissingToken
+	}
+
+	var claims Claims
+	if _, err := v.parser.ParseWithClaims(tokenString, &claims, v.key); err != nil {
+		return Identity{}, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+
+	if claims.Subject == "" {
+		return Identity{}, fmt.Errorf("%w: missing subject", ErrInvalidToken)
+	}
+	if !claims.Role.Valid() {
+		return Identity{}, fmt.Errorf("%w: unknown role %q", ErrInvalidToken, claims.Role)
+	}
+
+	return Identity{
+		UserID: claims.Subject,
+		Role:   claims.Role,
+	}, nil
+}
+
+// key returns the key matching the signing method, and the kid for RS256 tokens
+func (v *Verifier) key(token *jwt.Token) (interface{}, error) {
+	switch token.Method.(type) {
+	case *jwt.SigningMethodHMAC:
+		return v.hmacSecret, nil
+	case *jwt.SigningMethodRSA:
+		kid, _ := token.Header["kid"].(string)
+		key, ok := v.rsaKeys[kid]
+		if !ok {
+			return nil, fmt.Errorf("unknown key id %q", kid)
+		}
+		return key, nil
+	default:
+		return nil, fmt.Errorf("unexpected signing method %v", token.Header["alg"])
+	}
+}
+
+type jwks struct {
+	Keys []struct {
+		Kty string `json:"kty"`
+		Kid string `json:"kid"`
+		Use string `json:"use"`
+		N   string `json:"n"`
+		E   string `json:"e"`
+	} `json:"keys"`
+}
+
+// loadJWKS reads the RSA signing keys of a JWKS file
+func loadJWKS(path string) (map[string]*rsa.PublicKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var set jwks
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, err
+	}
+
+	keys := make(map[string]*rsa.PublicKey)
+	for _, k := range set.Keys {
+		if k.Kty != "RSA" || (k.Use != "" && k.Use != "sig") {
+			continue
+		}
+
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			return nil, fmt.Errorf("invalid modulus of key %q: %v", k.Kid, err)
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			return nil, fmt.Errorf("invalid exponent of key %q: %v", k.Kid, err)
+		}
+
+		keys[k.Kid] = &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}
+	}
+
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("no RSA signing key found in %s", path)
+	}
+
+	return keys, nil
+}
@@ -0,0 +1,157 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const testSecret = "test-secret"
+
+// writeJWKS saves the public key in a JWKS file under the kid and returns its path
+func writeJWKS(t *testing.T, kid string, key *rsa.PublicKey) string {
+	t.Helper()
+
+	data, err := json.Marshal(map[string]any{
+		"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": kid,
+			"use": "sig",
+			"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// claims are valid claims of a rider, expiring in an hour
+func claims() Claims {
+	return Claims{
+		Role: RoleRider,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   "rider-1",
+			Issuer:    "ride-sharing",
+			Audience:  jwt.ClaimStrings{"gateway"},
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+		},
+	}
+}
+
+func sign(t *testing.T, method jwt.SigningMethod, kid string, key any, c Claims) string {
+	t.Helper()
+
+	token := jwt.NewWithClaims(method, c)
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+	signed, err := token.SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed
+}
+
+func TestVerify(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	jwksFile := writeJWKS(t, "key-1", &rsaKey.PublicKey)
+	publicKey, err := x509.MarshalPKIXPublicKey(&rsaKey.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	both := &Config{HMACSecret: testSecret, JWKSFile: jwksFile, Issuer: "ride-sharing", Audience: "gateway"}
+	hmacOnly := &Config{HMACSecret: testSecret}
+	rsaOnly := &Config{JWKSFile: jwksFile}
+
+	expired := claims()
+	expired.ExpiresAt = jwt.NewNumericDate(time.Now().Add(-time.Minute))
+	noExpiry := claims()
+	noExpiry.ExpiresAt = nil
+	otherIssuer := claims()
+	otherIssuer.Issuer = "someone-else"
+	otherAudience := claims()
+	otherAudience.Audience = jwt.ClaimStrings{"payments"}
+	noSubject := claims()
+	noSubject.Subject = ""
+	unknownRole := claims()
+	unknownRole.Role = "pilot"
+
+	tests := []struct {
+		name    string
+		cfg     *Config
+		token   string
+		wantErr error
+	}{
+		{"HS256", both, sign(t, jwt.SigningMethodHS256, "", []byte(testSecret), claims()), nil},
+		{"RS256 with the key of the JWKS", both, sign(t, jwt.SigningMethodRS256, "key-1", rsaKey, claims()), nil},
+		{"HS256 signed with another secret", hmacOnly, sign(t, jwt.SigningMethodHS256, "", []byte("other"), claims()), ErrInvalidToken},
+		{"RS256 signed with another key", rsaOnly, sign(t, jwt.SigningMethodRS256, "key-1", otherKey, claims()), ErrInvalidToken},
+		{"RS256 with an unknown kid", rsaOnly, sign(t, jwt.SigningMethodRS256, "key-2", rsaKey, claims()), ErrInvalidToken},
+		{"HS256 signed with the RSA public key", rsaOnly, sign(t, jwt.SigningMethodHS256, "key-1", publicKey, claims()), ErrInvalidToken},
+		{"RS256 without a JWKS", hmacOnly, sign(t, jwt.SigningMethodRS256, "key-1", rsaKey, claims()), ErrInvalidToken},
+		{"HS512", hmacOnly, sign(t, jwt.SigningMethodHS512, "", []byte(testSecret), claims()), ErrInvalidToken},
+		{"unsigned", hmacOnly, sign(t, jwt.SigningMethodNone, "", jwt.UnsafeAllowNoneSignatureType, claims()), ErrInvalidToken},
+		{"expired", hmacOnly, sign(t, jwt.SigningMethodHS256, "", []byte(testSecret), expired), ErrInvalidToken},
+		{"without expiry", hmacOnly, sign(t, jwt.SigningMethodHS256, "", []byte(testSecret), noExpiry), ErrInvalidToken},
+		{"another issuer", both, sign(t, jwt.SigningMethodHS256, "", []byte(testSecret), otherIssuer), ErrInvalidToken},
+		{"another audience", both, sign(t, jwt.SigningMethodHS256, "", []byte(testSecret), otherAudience), ErrInvalidToken},
+		{"without subject", hmacOnly, sign(t, jwt.SigningMethodHS256, "", []byte(testSecret), noSubject), ErrInvalidToken},
+		{"unknown role", hmacOnly, sign(t, jwt.SigningMethodHS256, "", []byte(testSecret), unknownRole), ErrInvalidToken},
+		{"malformed", hmacOnly, "not-a-jwt", ErrInvalidToken},
+		{"missing", hmacOnly, "", ErrMissingToken},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			verifier, err := NewVerifier(tt.cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			identity, err := verifier.Verify(tt.token)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("got error %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("got error %v, want the identity", err)
+			}
+			if identity != (Identity{UserID: "rider-1", Role: RoleRider}) {
+				t.Errorf("got identity %+v, want rider-1 as rider", identity)
+			}
+		})
+	}
+}
+
+func TestNewVerifierRequiresAKey(t *testing.T) {
+	if _, err := NewVerifier(&Config{Issuer: "ride-sharing"}); err == nil {
+		t.Fatal("got a verifier without any key")
+	}
+}
@@ -13,6 +13,17 @@ const (
 	TripEventDriverAssigned      = "trip.event.driver_assigned"
 	TripEventNoDriversFound      = "trip.event.no_drivers_found"
 	TripEventDriverNotInterested = "trip.event.driver_not_interested"
+	TripEventSplitInvited        = "trip.event.split_invited"
+	TripEventSplitUpdated        = "trip.event.split_updated"
+	// TripEventResponseRejected tells the driver their accept or decline of a trip was refused
+	TripEventResponseRejected = "trip.event.response_rejected"
+
+	// Trip commands (trip.cmd.*), sent by the riders invited to split a fare
+	TripCmdSplitAccept  = "trip.cmd.split_accept"
+	TripCmdSplitDecline = "trip.cmd.split_decline"
+	// TripCmdOffer is sent by the driver service to offer a trip to a driver, the trip service records
+	// the offer before sending the trip request to the driver
+	TripCmdOffer = "trip.cmd.offer"
 
 	// Driver commands (driver.cmd.*)
 	DriverCmdTripRequest = "driver.cmd.trip_request"
@@ -26,7 +37,24 @@ const (
 	PaymentEventSuccess        = "payment.event.success"
 	PaymentEventFailed         = "payment.event.failed"
 	PaymentEventCancelled      = "payment.event.cancelled"
+	PaymentEventRefunded       = "payment.event.refunded"
+	PaymentEventDisputed       = "payment.event.disputed"
+	PaymentEventTipReceived    = "payment.event.tip_received"
+	PaymentEventCharged        = "payment.event.charged"
+	PaymentEventHoldFailed     = "payment.event.hold_failed"
+	PaymentEventHoldReleased   = "payment.event.hold_released"
+	PaymentEventSplitFailed    = "payment.event.split_failed"
 
 	// Payment commands (payment.cmd.*)
 	PaymentCmdCreateSession = "payment.cmd.create_session"
+	PaymentCmdAddTip        = "payment.cmd.add_tip"
+	PaymentCmdReleaseHold   = "payment.cmd.release_hold"
+
+	// Chat commands (chat.cmd.*), sent by the riders and the drivers of a trip
+	ChatCmdSend    = "chat.cmd.send"
+	ChatCmdReceipt = "chat.cmd.receipt"
+
+	// Chat events (chat.event.*)
+	ChatEventMessage = "chat.event.message"
+	ChatEventReceipt = "chat.event.receipt"
 )
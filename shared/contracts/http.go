@@ -1,5 +1,7 @@
 package contracts
 
+import "ride-sharing/shared/types"
+
 // APIResponse is the response structure for the API.
 type APIResponse struct {
 	Data  any       `json:"data,omitempty"`
@@ -7,7 +9,66 @@ type APIResponse struct {
 }
 
 // APIError is the error structure for the API.
+// Code is one of the ErrCode values below, clients can rely on it unlike the message.
 type APIError struct {
-	Code    string `json:"code"`
+	Code    string       `json:"code"`
+	Message string       `json:"message"`
+	Fields  []FieldError `json:"fields,omitempty"` // Set when the code is ErrCodeValidationFailed
+}
+
+// FieldError is the validation error of a request field, Field is its JSON path e.g. "pickup.latitude".
+type FieldError struct {
+	Field   string `json:"field"`
 	Message string `json:"message"`
 }
+
+// Generic error codes, derived from the HTTP status.
+const (
+	ErrCodeBadRequest         = "BAD_REQUEST"
+	ErrCodeValidationFailed   = "VALIDATION_FAILED"
+	ErrCodeUnauthenticated    = "UNAUTHENTICATED"
+	ErrCodeForbidden          = "FORBIDDEN"
+	ErrCodeNotFound           = "NOT_FOUND"
+	ErrCodeMethodNotAllowed   = "METHOD_NOT_ALLOWED"
+	ErrCodeConflict           = "CONFLICT"
+	ErrCodeRateLimited        = "RATE_LIMITED"
+	ErrCodeInternal           = "INTERNAL"
+	ErrCodeNotImplemented     = "NOT_IMPLEMENTED"
+	ErrCodeServiceUnavailable = "SERVICE_UNAVAILABLE"
+)
+
+// Domain error codes, sent by the services as the reason of their gRPC errors.
+const (
+	ErrCodeInvalidID          = "INVALID_ID"
+	ErrCodeUserMismatch       = "USER_MISMATCH"
+	ErrCodeFareNotFound       = "FARE_NOT_FOUND"
+	ErrCodeFareNotOwned       = "FARE_NOT_OWNED"
+	ErrCodeFareExpired        = "FARE_EXPIRED"
+	ErrCodeTripNotFound       = "TRIP_NOT_FOUND"
+	ErrCodeTripNotOffered     = "TRIP_NOT_OFFERED"
+	ErrCodeDriverNotFound     = "DRIVER_NOT_FOUND"
+	ErrCodeNoActiveTrip       = "NO_ACTIVE_TRIP"
+	ErrCodeNotParticipant     = "NOT_TRIP_PARTICIPANT"
+	ErrCodeChatClosed         = "CHAT_CLOSED"
+	ErrCodeUnknownReply       = "UNKNOWN_QUICK_REPLY"
+	ErrCodeInvalidChatMessage = "INVALID_CHAT_MESSAGE"
+	ErrCodeNotTripOwner       = "NOT_TRIP_OWNER"
+	ErrCodeSplitClosed        = "SPLIT_CLOSED"
+	ErrCodeInvalidSplit       = "INVALID_SPLIT"
+	ErrCodeNotInvited         = "NOT_INVITED_TO_SPLIT"
+	ErrCodeHoldRefused        = "HOLD_REFUSED"
+	ErrCodeTripNotCancellable = "TRIP_NOT_CANCELLABLE"
+)
+
+// PreviewTripRequest is the body of POST /trip/preview.
+type PreviewTripRequest struct {
+	UserID      string           `json:"userID,omitempty"` // Defaults to the authenticated user
+	Pickup      types.Coordinate `json:"pickup"`
+	Destination types.Coordinate `json:"destination"`
+}
+
+// StartTripRequest is the body of POST /trip/start, RideFareID is one of the fares of the preview.
+type StartTripRequest struct {
+	RideFareID string `json:"rideFareID"`
+	UserID     string `json:"userID,omitempty"`
+}
@@ -6,9 +6,27 @@ import "encoding/json"
 type WSMessage struct {
 	Type string `json:"type"`
 	Data any    `json:"data"`
+	// Seq is the per-user sequence number of the message, used to resume a session with ?lastSeq=
+	Seq uint64 `json:"seq,omitempty"`
 }
 
 type WSDriverMessage struct {
 	Type string          `json:"type"`
 	Data json.RawMessage `json:"data"`
 }
+
+// WebSocket only message types, they are never published on RabbitMQ
+const (
+	// SessionEventResumed is sent to a reconnecting client after the missed messages were replayed
+	SessionEventResumed = "session.event.resumed"
+	// TripEventSnapshot is sent to a reconnecting client with its active trip
+	TripEventSnapshot = "trip.event.snapshot"
+)
+
+// SessionResumedData tells a reconnecting client what was replayed.
+// Complete is false when some of the missed messages are no longer available.
+type SessionResumedData struct {
+	LastSeq  uint64 `json:"lastSeq"`
+	Replayed int    `json:"replayed"`
+	Complete bool   `json:"complete"`
+}
@@ -13,8 +13,15 @@ import (
 )
 
 const (
-	TripsCollection     = "trips"
-	RideFaresCollection = "ride_fares"
+	TripsCollection          = "trips"
+	RideFaresCollection      = "ride_fares"
+	ChatMessagesCollection   = "chat_messages"
+	PaymentsCollection       = "payments"
+	StripeEventsCollection   = "stripe_events"
+	LedgerCollection         = "ledger_entries"
+	PayoutsCollection        = "payouts"
+	PaymentMethodsCollection = "payment_methods"
+	PresenceCollection       = "gateway_presence"
 )
 
 // MongoConfig holds MongoDB connection configuration
@@ -0,0 +1,51 @@
+// Package grpcclient creates the connections of the services to each other
+package grpcclient
+
+import (
+	"fmt"
+	"ride-sharing/shared/auth"
+	"ride-sharing/shared/tracing"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+
+	// Registers the client side health checking used by the service config
+	_ "google.golang.org/grpc/health"
+)
+
+// serviceConfig balances the calls over every resolved address that reports itself healthy,
+// with a deadline per call and retries on the calls that never reached a server.
+const serviceConfig = `{
+	"loadBalancingConfig": [{"round_robin": {}}],
+	"healthCheckConfig": {"serviceName": ""},
+	"methodConfig": [{
+		"name": [{"service": %q}],
+		"timeout": "10s",
+		"retryPolicy": {
+			"maxAttempts": 3,
+			"initialBackoff": "0.1s",
+			"maxBackoff": "1s",
+			"backoffMultiplier": 2,
+			"retryableStatusCodes": ["UNAVAILABLE"]
+		}
+	}]
+}`
+
+// NewConn creates a long-lived connection to the service. It doesn't dial,
+// the connection is established in the background and re-resolved when the pods change.
+func NewConn(target, serviceName string) (*grpc.ClientConn, error) {
+	dialOptions := append(
+		tracing.DialOptionsWithTracing(),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithDefaultServiceConfig(fmt.Sprintf(serviceConfig, serviceName)),
+	)
+	dialOptions = append(dialOptions, auth.DialOptionsWithIdentity()...)
+
+	// The DNS resolver returns every pod of a headless service, the passthrough one only the service IP
+	if !strings.HasPrefix(target, "dns:///") {
+		target = "dns:///" + target
+	}
+
+	return grpc.NewClient(target, dialOptions...)
+}
@@ -0,0 +1,154 @@
+package messaging
+
+import (
+	"context"
+	"log"
+	"strings"
+
+	"ride-sharing/shared/contracts"
+	"ride-sharing/shared/retry"
+	"ride-sharing/shared/tracing"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// InmemBrokerURI selects the in-process broker instead of RabbitMQ in NewBroker.
+const InmemBrokerURI = "memory://"
+
+// Broker is the message broker used by the services to publish and consume messages.
+// RabbitMQ is the production implementation, InmemBroker runs everything in-process.
+type Broker interface {
+	// PublishMessage publishes a message on the trip exchange with the given routing key.
+	PublishMessage(ctx context.Context, routingKey string, message contracts.AmqpMessage) error
+	// ConsumeMessages processes every message of the queue with the handler.
+	// Messages are acked when the handler succeeds and dead-lettered after the retries are exhausted.
+	ConsumeMessages(queueName string, handler MessageHandler) error
+	// Consume returns the raw deliveries of the queue. The caller is responsible for acking them.
+	Consume(queueName string) (<-chan amqp.Delivery, error)
+
+	// PublishToExchange publishes a message on any exchange, e.g. the gateway exchange.
+	PublishToExchange(ctx context.Context, exchange, routingKey string, message contracts.AmqpMessage) error
+	// DeclareInstanceQueue declares an exclusive queue that only lives as long as this broker connection.
+	DeclareInstanceQueue(queueName string) error
+	BindQueue(queueName, exchange, routingKey string) error
+	UnbindQueue(queueName, exchange, routingKey string) error
+
+	Close()
+}
+
+type MessageHandler func(context.Context, amqp.Delivery) error
+
+// NewBroker connects to RabbitMQ, or creates an in-process broker when uri is InmemBrokerURI.
+func NewBroker(uri string) (Broker, error) {
+	if uri == InmemBrokerURI {
+		log.Println("Using the in-memory message broker")
+		return NewInmemBroker(), nil
+	}
+
+	return NewRabbitMQ(uri)
+}
+
+// queueBinding describes a durable queue and the routing keys it is bound to on the trip exchange.
+type queueBinding struct {
+	queue       string
+	routingKeys []string
+}
+
+// tripQueueBindings is the topology shared by every Broker implementation.
+var tripQueueBindings = []queueBinding{
+	{FindAvailableDriversQueue, []string{contracts.TripEventCreated, contracts.TripEventDriverNotInterested}},
+	{DriverCmdTripRequestQueue, []string{contracts.DriverCmdTripRequest}},
+	{TripDriverOfferQueue, []string{contracts.TripCmdOffer}},
+	{DriverTripResponseQueue, []string{contracts.DriverCmdTripAccept, contracts.DriverCmdTripDecline}},
+	{NotifyDriverNoDriversFoundQueue, []string{contracts.TripEventNoDriversFound}},
+	{NotifyDriverAssignQueue, []string{contracts.TripEventDriverAssigned}},
+	{NotifyDriverRejectedQueue, []string{contracts.TripEventResponseRejected}},
+	{NotifyTripCreatedQueue, []string{contracts.TripEventCreated}},
+	{PaymentTripResponseQueue, []string{contracts.PaymentCmdCreateSession}},
+	{NotifyPaymentSessionCreatedQueue, []string{contracts.PaymentEventSessionCreated}},
+	{NotifyPaymentSuccessQueue, []string{contracts.PaymentEventSuccess}},
+	{NotifyPaymentRefundedQueue, []string{contracts.PaymentEventRefunded}},
+	{NotifyPaymentStatusQueue, []string{contracts.PaymentEventFailed, contracts.PaymentEventCancelled, contracts.PaymentEventDisputed}},
+	// A failed or cancelled payment leaves the trip accepted until the rider pays, only the disputes change it
+	{TripPaymentDisputedQueue, []string{contracts.PaymentEventDisputed}},
+	{PaymentCmdAddTipQueue, []string{contracts.PaymentCmdAddTip}},
+	{NotifyPaymentTipQueue, []string{contracts.PaymentEventTipReceived}},
+	{NotifyPaymentChargedQueue, []string{contracts.PaymentEventCharged}},
+	{PaymentCmdHoldQueue, []string{contracts.PaymentCmdReleaseHold}},
+	{NotifyPaymentHoldQueue, []string{contracts.PaymentEventHoldFailed, contracts.PaymentEventHoldReleased}},
+	{TripNoDriversFoundQueue, []string{contracts.TripEventNoDriversFound}},
+	{TripCmdSplitQueue, []string{contracts.TripCmdSplitAccept, contracts.TripCmdSplitDecline}},
+	{NotifySplitQueue, []string{contracts.TripEventSplitInvited, contracts.TripEventSplitUpdated}},
+	{NotifyPaymentSplitFailedQueue, []string{contracts.PaymentEventSplitFailed}},
+	{ChatCmdQueue, []string{contracts.ChatCmdSend, contracts.ChatCmdReceipt}},
+	{NotifyChatQueue, []string{contracts.ChatEventMessage, contracts.ChatEventReceipt}},
+}
+
+// handleDeliveries runs the handler for every delivery with retries.
+// Messages that still fail are rejected without requeue so they end up in the DLQ.
+func handleDeliveries(msgs <-chan amqp.Delivery, handler MessageHandler) {
+	for msg := range msgs {
+		if err := tracing.TracedConsumer(msg, func(ctx context.Context, d amqp.Delivery) error {
+			log.Printf("Received a message: %s", msg.Body)
+
+			cfg := retry.DefaultConfig()
+			err := retry.WithBackoff(ctx, cfg, func() error {
+				return handler(ctx, d)
+			})
+			if err != nil {
+				log.Printf("Message processing failed after %d retries for message ID: %s, err: %v", cfg.MaxRetries, d.MessageId, err)
+
+				// Add failure context before sending to the DLQ
+				headers := amqp.Table{}
+				if d.Headers != nil {
+					headers = d.Headers
+				}
+
+				headers["x-death-reason"] = err.Error()
+				headers["x-origin-exchange"] = d.Exchange
+				headers["x-original-routing-key"] = d.RoutingKey
+				headers["x-retry-count"] = cfg.MaxRetries
+				d.Headers = headers
+
+				// Reject without requeue - message will go to the DLQ
+				_ = d.Reject(false)
+				return err
+			}
+
+			// Only Ack if the handler succeeds
+			if ackErr := msg.Ack(false); ackErr != nil {
+				log.Printf("ERROR: Failed to Ack message: %v. Message body: %s", ackErr, msg.Body)
+			}
+
+			return nil
+		}); err != nil {
+			log.Printf("Error processing message: %v", err)
+		}
+	}
+}
+
+// topicMatches reports whether a routing key matches a topic binding pattern.
+// "*" matches exactly one word and "#" matches zero or more words.
+func topicMatches(pattern, routingKey string) bool {
+	return matchWords(strings.Split(pattern, "."), strings.Split(routingKey, "."))
+}
+
+func matchWords(pattern, words []string) bool {
+	if len(pattern) == 0 {
+		return len(words) == 0
+	}
+
+	switch pattern[0] {
+	case "#":
+		for i := 0; i <= len(words); i++ {
+			if matchWords(pattern[1:], words[i:]) {
+				return true
+			}
+		}
+		return false
+	case "*":
+		return len(words) > 0 && matchWords(pattern[1:], words[1:])
+	default:
+		return len(words) > 0 && pattern[0] == words[0] && matchWords(pattern[1:], words[1:])
+	}
+}
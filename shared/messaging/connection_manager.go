@@ -5,6 +5,7 @@ import (
 	"log"
 	"net/http"
 	"sync"
+	"time"
 
 	"ride-sharing/shared/contracts"
 
@@ -13,17 +14,67 @@ import (
 
 var (
 	ErrConnectionNotFound = errors.New("connection not found")
+	ErrSendQueueFull      = errors.New("send queue is full")
 )
 
-// connWrapper is a wrapper around the websocket connection to allow for thread-safe operations
-// This is necessary because the websocket connection is not thread-safe
-type connWrapper struct {
-	conn  *websocket.Conn
-	mutex sync.Mutex
+const (
+	// ReplayBufferSize is the max number of messages kept per user for replay
+	ReplayBufferSize = 100
+	// ReplayMessageTTL is how long a message is kept for replay
+	ReplayMessageTTL = 5 * time.Minute
+)
+
+// session holds the outbound message sequence of a user and the messages that can be replayed.
+// It outlives the websocket connections so that a user reconnecting can resume where it stopped.
+type session struct {
+	lastSeq       uint64             // Last sequence number assigned to a message of the user
+	deliveredSeq  uint64             // Last sequence number written to a connection of the user
+	buffer        []*bufferedMessage // Ordered by sequence number
+	lastMessageAt time.Time
+}
+
+type bufferedMessage struct {
+	message   contracts.WSMessage
+	expiresAt time.Time
+}
+
+// prune drops the expired messages and keeps at most ReplayBufferSize messages
+func (s *session) prune(now time.Time) {
+	i := 0
+	for i < len(s.buffer) && now.After(s.buffer[i].expiresAt) {
+		i++
+	}
+	if len(s.buffer)-i > ReplayBufferSize {
+		i = len(s.buffer) - ReplayBufferSize
+	}
+	s.buffer = s.buffer[i:]
+}
+
+// messagesAfter returns the buffered messages with a sequence number greater than seq.
+// complete is false when some of these messages were already dropped from the buffer.
+func (s *session) messagesAfter(seq uint64) (messages []contracts.WSMessage, complete bool) {
+	for _, b := range s.buffer {
+		if b.message.Seq > seq {
+			messages = append(messages, b.message)
+		}
+	}
+
+	oldest := s.lastSeq + 1
+	if len(s.buffer) > 0 {
+		oldest = s.buffer[0].message.Seq
+	}
+
+	// A client ahead of the session comes from a session that no longer exists, e.g. before a gateway restart
+	complete = seq == s.lastSeq || (seq < s.lastSeq && seq+1 >= oldest)
+
+	return messages, complete
 }
 
 type ConnectionManager struct {
 	connections map[string]*connWrapper // Local connections storage (userId -> connection)
+	sessions    map[string]*session     // Message sequences and replay buffers (userId -> session)
+	config      ConnectionConfig
+	lastSweep   time.Time
 	mutex       sync.RWMutex
 }
 
@@ -33,39 +84,94 @@ var upgrader = websocket.Upgrader{
 	},
 }
 
-// Note that on multiple instances of the API gateway, the connection manager needs to store the connections on a separate shared storage.
-func NewConnectionManager() *ConnectionManager {
+// Note that on multiple instances of the API gateway, the connections and sessions only live on the instance
+// the user is connected to, see UserRouter for the cross-instance delivery. The replay is single-instance:
+// a user reconnecting to another instance starts a new sequence there, the messages already written are not
+// replayed and the resumed session is reported incomplete, the client then relies on the trip snapshot sent
+// on reconnection. The messages never written are handed over to the new instance, see Release.
+func NewConnectionManager(config ConnectionConfig) *ConnectionManager {
 	return &ConnectionManager{
 		connections: make(map[string]*connWrapper),
+		sessions:    make(map[string]*session),
+		config:      config,
+		lastSweep:   time.Now(),
 	}
 }
 
-func (cm *ConnectionManager) Upgrade(w http.ResponseWriter, r *http.Request) (*websocket.Conn, error) {
+// Upgrade upgrades the request to a websocket connection and arms its heartbeats
+func (cm *ConnectionManager) Upgrade(w http.ResponseWriter, r *http.Request) (*WebSocketConn, error) {
 	conn, err := upgrader.Upgrade(w, r, nil)
 	if err != nil {
 		return nil, err
 	}
-	return conn, nil
+
+	wsConn := &WebSocketConn{Conn: conn}
+	wsConn.keepAlive(cm.config)
+	return wsConn, nil
 }
 
-func (cm *ConnectionManager) Add(id string, conn *websocket.Conn) {
+// Add registers the connection of the user, starts its writer and replays the messages
+// the user missed. lastSeq is the last sequence number received by the client before reconnecting,
+// when it is nil only the messages that were never written to a connection are replayed.
+// A previous connection of the user is closed.
+func (cm *ConnectionManager) Add(id string, conn Conn, lastSeq *uint64) {
+	wrapper := newConnWrapper(id, conn, cm.config.SendQueueSize)
+
+	// The replayed messages are computed and the connection registered under the same lock,
+	// so that new messages can neither overtake them nor be sent twice.
 	cm.mutex.Lock()
-	defer cm.mutex.Unlock()
-	cm.connections[id] = &connWrapper{
-		conn:  conn,
-		mutex: sync.Mutex{},
+	if previous, exists := cm.connections[id]; exists {
+		previous.close()
 	}
+	cm.connections[id] = wrapper
 
-	log.Printf("Added connection for user %s", id)
+	s := cm.getSession(id)
+	s.prune(time.Now())
+
+	from := s.deliveredSeq
+	if lastSeq != nil {
+		from = *lastSeq
+	}
+	initial, complete := s.messagesAfter(from)
+	replayed := len(initial)
+
+	// Tell a resuming client whether it got everything it missed
+	if lastSeq != nil {
+		initial = append(initial, contracts.WSMessage{
+			Type: contracts.SessionEventResumed,
+			Data: contracts.SessionResumedData{
+				LastSeq:  s.lastSeq,
+				Replayed: replayed,
+				Complete: complete,
+			},
+		})
+	}
+	cm.mutex.Unlock()
+
+	go wrapper.writeLoop(cm.config, initial, func(message contracts.WSMessage) {
+		if message.Seq > 0 {
+			cm.markDelivered(id, message.Seq)
+		}
+	})
+
+	log.Printf("Added connection for user %s, replaying %d messages", id, replayed)
 }
 
-func (cm *ConnectionManager) Remove(id string) {
+// Remove removes the connection of the user and stops its writer, unless it was already replaced
+// by a newer one. It reports whether the connection was removed.
+func (cm *ConnectionManager) Remove(id string, conn Conn) bool {
 	cm.mutex.Lock()
 	defer cm.mutex.Unlock()
-	delete(cm.connections, id)
+
+	if wrapper, exists := cm.connections[id]; exists && wrapper.conn == conn {
+		delete(cm.connections, id)
+		wrapper.close()
+		return true
+	}
+	return false
 }
 
-func (cm *ConnectionManager) Get(id string) (*websocket.Conn, bool) {
+func (cm *ConnectionManager) Get(id string) (Conn, bool) {
 	cm.mutex.RLock()
 	defer cm.mutex.RUnlock()
 	wrapper, exists := cm.connections[id]
@@ -75,17 +181,125 @@ func (cm *ConnectionManager) Get(id string) (*websocket.Conn, bool) {
 	return wrapper.conn, true
 }
 
+// SendMessage queues the message for the connection of the user, without sequencing nor buffering it.
 func (cm *ConnectionManager) SendMessage(id string, message contracts.WSMessage) error {
 	cm.mutex.RLock()
-	wrapper, exists := cm.connections[id]
-	cm.mutex.RUnlock()
+	defer cm.mutex.RUnlock()
 
+	wrapper, exists := cm.connections[id]
 	if !exists {
 		return ErrConnectionNotFound
 	}
 
-	wrapper.mutex.Lock()
-	defer wrapper.mutex.Unlock()
+	return cm.enqueue(wrapper, message)
+}
 
-	return wrapper.conn.WriteJSON(message)
+// Dispatch assigns the next sequence number of the user to the message, keeps it for replay and
+// sends it if the user is connected. Otherwise it is sent when the user connects again.
+func (cm *ConnectionManager) Dispatch(id string, message contracts.WSMessage) {
+	cm.mutex.Lock()
+	defer cm.mutex.Unlock()
+
+	message = cm.sequence(id, message)
+
+	wrapper, exists := cm.connections[id]
+	if !exists {
+		log.Printf("User %s is not connected, buffered message %s", id, message.Type)
+		return
+	}
+
+	if err := cm.enqueue(wrapper, message); err != nil {
+		log.Printf("Failed to send message to user %s: %v", id, err)
+	}
+}
+
+// enqueue queues the message on the connection. A client too slow to drain its queue is disconnected,
+// the sequenced messages it did not get are replayed when it reconnects.
+func (cm *ConnectionManager) enqueue(wrapper *connWrapper, message contracts.WSMessage) error {
+	if wrapper.enqueue(message) {
+		return nil
+	}
+
+	log.Printf("Send queue of user %s is full, closing the connection", wrapper.id)
+	wrapper.close()
+	return ErrSendQueueFull
+}
+
+// sequence assigns the next sequence number of the user to the message and buffers it for replay.
+// The caller must hold the lock.
+func (cm *ConnectionManager) sequence(id string, message contracts.WSMessage) contracts.WSMessage {
+	now := time.Now()
+	cm.sweepSessions(now)
+
+	s := cm.getSession(id)
+	s.lastSeq++
+	s.lastMessageAt = now
+	message.Seq = s.lastSeq
+
+	s.buffer = append(s.buffer, &bufferedMessage{
+		message:   message,
+		expiresAt: now.Add(ReplayMessageTTL),
+	})
+	s.prune(now)
+
+	return message
+}
+
+// Release drops the session of a disconnected user and returns the messages that were never written
+// to a connection, so that the instance the user is now connected to sends them. Nothing is released
+// while the user is connected here.
+func (cm *ConnectionManager) Release(id string) []contracts.WSMessage {
+	cm.mutex.Lock()
+	defer cm.mutex.Unlock()
+
+	if _, connected := cm.connections[id]; connected {
+		return nil
+	}
+
+	s, ok := cm.sessions[id]
+	if !ok {
+		return nil
+	}
+	delete(cm.sessions, id)
+
+	s.prune(time.Now())
+	messages, _ := s.messagesAfter(s.deliveredSeq)
+	return messages
+}
+
+func (cm *ConnectionManager) markDelivered(id string, seq uint64) {
+	cm.mutex.Lock()
+	defer cm.mutex.Unlock()
+
+	if s, ok := cm.sessions[id]; ok && seq > s.deliveredSeq {
+		s.deliveredSeq = seq
+	}
+}
+
+// getSession returns the session of the user, creating it if needed. The caller must hold the lock.
+func (cm *ConnectionManager) getSession(id string) *session {
+	s, ok := cm.sessions[id]
+	if !ok {
+		s = &session{}
+		cm.sessions[id] = s
+	}
+	return s
+}
+
+// sweepSessions drops the sessions of disconnected users that have nothing left to replay.
+// The caller must hold the lock.
+func (cm *ConnectionManager) sweepSessions(now time.Time) {
+	if now.Sub(cm.lastSweep) < ReplayMessageTTL {
+		return
+	}
+	cm.lastSweep = now
+
+	for id, s := range cm.sessions {
+		if _, connected := cm.connections[id]; connected {
+			continue
+		}
+		if now.Sub(s.lastMessageAt) > ReplayMessageTTL {
+			delete(cm.sessions, id)
+		}
+	}
 }
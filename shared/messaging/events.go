@@ -8,13 +8,29 @@ import (
 const (
 	FindAvailableDriversQueue        = "find_available_drivers"
 	DriverCmdTripRequestQueue        = "driver_cmd_trip_request"
+	TripDriverOfferQueue             = "trip_driver_offer"
 	DriverTripResponseQueue          = "driver_trip_response"
 	NotifyDriverNoDriversFoundQueue  = "notify_driver_no_drivers_found"
 	NotifyDriverAssignQueue          = "notify_driver_assign"
+	NotifyDriverRejectedQueue        = "notify_driver_rejected"
 	NotifyTripCreatedQueue           = "notify_trip_created"
 	PaymentTripResponseQueue         = "payment_trip_response"
 	NotifyPaymentSessionCreatedQueue = "notify_payment_session_created"
 	NotifyPaymentSuccessQueue        = "payment_success"
+	NotifyPaymentRefundedQueue       = "payment_refunded"
+	NotifyPaymentStatusQueue         = "notify_payment_status"
+	TripPaymentDisputedQueue         = "trip_payment_disputed"
+	PaymentCmdAddTipQueue            = "payment_cmd_add_tip"
+	NotifyPaymentTipQueue            = "notify_payment_tip"
+	NotifyPaymentChargedQueue        = "notify_payment_charged"
+	PaymentCmdHoldQueue              = "payment_cmd_hold"
+	NotifyPaymentHoldQueue           = "notify_payment_hold"
+	TripNoDriversFoundQueue          = "trip_no_drivers_found"
+	TripCmdSplitQueue                = "trip_cmd_split"
+	NotifySplitQueue                 = "notify_split"
+	NotifyPaymentSplitFailedQueue    = "notify_payment_split_failed"
+	ChatCmdQueue                     = "chat_cmd"
+	NotifyChatQueue                  = "notify_chat"
 	DeadLetterQueue                  = "dead_letter_queue"
 )
 
@@ -28,23 +44,151 @@ type DriverTripResponseData struct {
 	RiderID string      `json:"riderID"`
 }
 
+// DriverResponseRejectedData tells the driver why their response to a trip was refused. Command is
+// the routing key of the response, Code one of the error codes of contracts.
+type DriverResponseRejectedData struct {
+	TripID  string `json:"tripID"`
+	Command string `json:"command"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
 type PaymentEventSessionCreatedData struct {
 	TripID    string  `json:"tripID"`
 	SessionID string  `json:"sessionID"`
 	Amount    float64 `json:"amount"`
 	Currency  string  `json:"currency"`
+	Tip       bool    `json:"tip,omitempty"` // The session pays a tip, not the fare
 }
 
 type PaymentTripResponseData struct {
-	TripID   string  `json:"tripID"`
-	UserID   string  `json:"userID"`
-	DriverID string  `json:"driverID"`
-	Amount   float64 `json:"amount"`
-	Currency string  `json:"currency"`
+	TripID      string  `json:"tripID"`
+	UserID      string  `json:"userID"`
+	DriverID    string  `json:"driverID"`
+	PackageSlug string  `json:"packageSlug"`
+	Amount      float64 `json:"amount"`
+	Currency    string  `json:"currency"`
+	// Shares of a split fare, the rider who requested the trip included. Empty when they pay the whole fare.
+	Shares []PaymentShare `json:"shares,omitempty"`
+}
+
+// PaymentShare is the part of a split fare a rider pays, in cents
+type PaymentShare struct {
+	UserID string `json:"userID"`
+	Amount int64  `json:"amount"`
 }
 
+// PaymentStatusUpdateData is the outcome of a checkout session, the routing key tells the status
 type PaymentStatusUpdateData struct {
+	TripID    string `json:"tripID"`
+	UserID    string `json:"userID"`
+	DriverID  string `json:"driverID"`
+	SessionID string `json:"sessionID"`
+}
+
+// PaymentRefundedData is published for every refund of a trip, full or partial. The amounts are in cents.
+type PaymentRefundedData struct {
+	TripID         string `json:"tripID"`
+	UserID         string `json:"userID"`
+	DriverID       string `json:"driverID"`
+	PaymentID      string `json:"paymentID"`
+	Amount         int64  `json:"amount"`         // Amount of this refund
+	RefundedAmount int64  `json:"refundedAmount"` // Sum of the refunds of the payment
+	Currency       string `json:"currency"`
+	Reason         string `json:"reason,omitempty"`
+	FullyRefunded  bool   `json:"fullyRefunded"`
+}
+
+// AddTipData is a tip of the rider for the driver of a paid trip, the amount is in cents.
+// TipID is chosen by the client, the tip is charged once per ID.
+type AddTipData struct {
+	TripID string `json:"tripID"`
+	TipID  string `json:"tipID"`
+	Amount int64  `json:"amount"`
+}
+
+// PaymentTipReceivedData notifies the driver of a paid tip, the amount is in cents
+type PaymentTipReceivedData struct {
+	TripID    string `json:"tripID"`
+	PaymentID string `json:"paymentID"`
+	Amount    int64  `json:"amount"`
+	Currency  string `json:"currency"`
+}
+
+// PaymentChargedData tells the rider that their saved card was charged, there is no checkout session
+// to pay. The amount is in cents.
+type PaymentChargedData struct {
+	TripID    string `json:"tripID"`
+	PaymentID string `json:"paymentID"`
+	Amount    int64  `json:"amount"`
+	Currency  string `json:"currency"`
+	Tip       bool   `json:"tip,omitempty"` // The charge pays a tip, not the fare
+}
+
+// PaymentHoldCmdData asks to release the hold of the fare of a trip on the saved card of the rider
+type PaymentHoldCmdData struct {
+	TripID string `json:"tripID"`
+	UserID string `json:"userID"`
+}
+
+// Reasons of a failed hold
+const (
+	HoldFailedPaymentMethodRequired  = "payment_method_required"
+	HoldFailedCardDeclined           = "card_declined"
+	HoldFailedAuthenticationRequired = "authentication_required"
+)
+
+// PaymentHoldData is the outcome of a hold, the amount held is in cents. PaymentID is empty when no
+// hold was placed, the rider has no saved card and pays the fare at checkout.
+type PaymentHoldData struct {
+	TripID    string `json:"tripID"`
+	PaymentID string `json:"paymentID,omitempty"`
+	Amount    int64  `json:"amount,omitempty"`
+	Currency  string `json:"currency,omitempty"`
+	Reason    string `json:"reason,omitempty"` // Why the hold failed
+}
+
+// SplitInvitationData invites a rider to split the fare of a trip. Share is the part they were asked
+// to pay in custom mode, in equal mode the fare is divided between the riders who accepted. The amounts
+// are in cents.
+type SplitInvitationData struct {
 	TripID   string `json:"tripID"`
-	UserID   string `json:"userID"`
-	DriverID string `json:"driverID"`
+	OwnerID  string `json:"ownerID"`
+	Mode     string `json:"mode"`
+	Share    int64  `json:"share,omitempty"`
+	Fare     int64  `json:"fare"`
+	Currency string `json:"currency"`
+}
+
+// SplitResponseData accepts or declines, depending on the command, the invitation to split the fare of the trip
+type SplitResponseData struct {
+	TripID string `json:"tripID"`
+}
+
+// PaymentSplitFailedData tells the rider who requested a trip that another rider didn't pay their
+// share of the fare. Status is failed or cancelled, the amount is in cents.
+type PaymentSplitFailedData struct {
+	TripID    string `json:"tripID"`
+	PaymentID string `json:"paymentID"`
+	UserID    string `json:"userID"`
+	Amount    int64  `json:"amount"`
+	Currency  string `json:"currency"`
+	Status    string `json:"status"`
+}
+
+// ChatSendData is a chat message sent by a participant of a trip. Either Text or TemplateID,
+// one of the quick replies, is set. ClientMessageID lets the sender match the echoed message.
+type ChatSendData struct {
+	TripID          string `json:"tripID"`
+	Text            string `json:"text,omitempty"`
+	TemplateID      string `json:"templateID,omitempty"`
+	ClientMessageID string `json:"clientMessageID,omitempty"`
+}
+
+// ChatReceiptData acknowledges chat messages, Status is "delivered" or "read".
+// As an event it is sent to the sender of the messages.
+type ChatReceiptData struct {
+	TripID     string   `json:"tripID"`
+	MessageIDs []string `json:"messageIDs"`
+	Status     string   `json:"status"`
 }
@@ -0,0 +1,416 @@
+package messaging
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"ride-sharing/shared/contracts"
+	"ride-sharing/shared/tracing"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+var (
+	ErrBrokerClosed  = errors.New("broker is closed")
+	ErrQueueNotFound = errors.New("queue not found")
+)
+
+// InmemBroker is an in-process Broker. It declares the same exchanges, queues and dead
+// letter setup as RabbitMQ, routes messages with topic semantics and redelivers or
+// dead-letters messages on nack, so whole flows can run without a RabbitMQ server.
+type InmemBroker struct {
+	exchanges map[string]string         // exchange -> kind ("topic" or "direct")
+	bindings  map[string][]inmemBinding // exchange -> queue bindings
+	queues    map[string]*inmemQueue
+	closed    bool
+	mutex     sync.RWMutex
+}
+
+type inmemBinding struct {
+	queue   string
+	pattern string
+}
+
+func NewInmemBroker() *InmemBroker {
+	b := &InmemBroker{
+		exchanges: map[string]string{
+			TripExchange:       "topic",
+			DeadLetterExchange: "topic",
+			GatewayExchange:    "direct",
+		},
+		bindings: make(map[string][]inmemBinding),
+		queues:   make(map[string]*inmemQueue),
+	}
+
+	b.declareQueue(DeadLetterQueue, "")
+	b.bind(DeadLetterExchange, DeadLetterQueue, "#")
+
+	for _, qb := range tripQueueBindings {
+		b.declareQueue(qb.queue, DeadLetterExchange)
+		for _, key := range qb.routingKeys {
+			b.bind(TripExchange, qb.queue, key)
+		}
+	}
+
+	return b
+}
+
+func (b *InmemBroker) PublishMessage(ctx context.Context, routingKey string, message contracts.AmqpMessage) error {
+	return b.PublishToExchange(ctx, TripExchange, routingKey, message)
+}
+
+func (b *InmemBroker) PublishToExchange(ctx context.Context, exchange, routingKey string, message contracts.AmqpMessage) error {
+	log.Printf("Publishing message with routing key: %s", routingKey)
+
+	jsonMsg, err := json.Marshal(message)
+	if err != nil {
+		return fmt.Errorf("failed to marshal message: %v", err)
+	}
+
+	msg := amqp.Publishing{
+		DeliveryMode: amqp.Persistent,
+		ContentType:  "application/json",
+		Body:         jsonMsg,
+	}
+
+	return tracing.TracedPublisher(ctx, exchange, routingKey, msg, b.publish)
+}
+
+func (b *InmemBroker) publish(ctx context.Context, exchange, routingKey string, msg amqp.Publishing) error {
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+
+	if b.closed {
+		return ErrBrokerClosed
+	}
+
+	b.route(exchange, routingKey, msg)
+	return nil
+}
+
+// route copies the message into every queue bound to the exchange with a matching pattern.
+// Unroutable messages are dropped, like a non-mandatory AMQP publish. The caller must hold the lock.
+func (b *InmemBroker) route(exchange, routingKey string, msg amqp.Publishing) {
+	delivered := make(map[string]bool)
+	topic := b.exchanges[exchange] == "topic"
+
+	for _, binding := range b.bindings[exchange] {
+		if delivered[binding.queue] {
+			continue
+		}
+		if matched := binding.pattern == routingKey || (topic && topicMatches(binding.pattern, routingKey)); !matched {
+			continue
+		}
+
+		q, ok := b.queues[binding.queue]
+		if !ok {
+			continue
+		}
+
+		q.push(amqp.Delivery{
+			Headers:      copyTable(msg.Headers),
+			ContentType:  msg.ContentType,
+			DeliveryMode: msg.DeliveryMode,
+			MessageId:    msg.MessageId,
+			Timestamp:    time.Now(),
+			Exchange:     exchange,
+			RoutingKey:   routingKey,
+			Body:         msg.Body,
+		})
+		delivered[binding.queue] = true
+	}
+}
+
+func (b *InmemBroker) ConsumeMessages(queueName string, handler MessageHandler) error {
+	msgs, err := b.Consume(queueName)
+	if err != nil {
+		return err
+	}
+
+	go handleDeliveries(msgs, handler)
+
+	return nil
+}
+
+func (b *InmemBroker) Consume(queueName string) (<-chan amqp.Delivery, error) {
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+
+	if b.closed {
+		return nil, ErrBrokerClosed
+	}
+
+	q, ok := b.queues[queueName]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrQueueNotFound, queueName)
+	}
+
+	msgs := make(chan amqp.Delivery)
+
+	go func() {
+		defer close(msgs)
+
+		for {
+			d, ok := q.pop()
+			if !ok {
+				return
+			}
+			msgs <- d
+		}
+	}()
+
+	return msgs, nil
+}
+
+func (b *InmemBroker) DeclareInstanceQueue(queueName string) error {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if b.closed {
+		return ErrBrokerClosed
+	}
+
+	b.declareQueue(queueName, "")
+	return nil
+}
+
+func (b *InmemBroker) BindQueue(queueName, exchange, routingKey string) error {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if _, ok := b.queues[queueName]; !ok {
+		return fmt.Errorf("%w: %s", ErrQueueNotFound, queueName)
+	}
+
+	b.bind(exchange, queueName, routingKey)
+	return nil
+}
+
+func (b *InmemBroker) UnbindQueue(queueName, exchange, routingKey string) error {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	bindings := b.bindings[exchange]
+	for i, binding := range bindings {
+		if binding.queue == queueName && binding.pattern == routingKey {
+			b.bindings[exchange] = append(bindings[:i:i], bindings[i+1:]...)
+			return nil
+		}
+	}
+
+	return nil
+}
+
+func (b *InmemBroker) Close() {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if b.closed {
+		return
+	}
+	b.closed = true
+
+	for _, q := range b.queues {
+		q.close()
+	}
+}
+
+// deadLetter routes a rejected message to the dead letter exchange of its queue, if any.
+func (b *InmemBroker) deadLetter(q *inmemQueue, d amqp.Delivery) {
+	if q.deadLetterExchange == "" {
+		return
+	}
+
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+
+	if b.closed {
+		return
+	}
+
+	headers := copyTable(d.Headers)
+	headers["x-first-death-queue"] = q.name
+	headers["x-first-death-exchange"] = d.Exchange
+	headers["x-first-death-reason"] = "rejected"
+
+	b.route(q.deadLetterExchange, d.RoutingKey, amqp.Publishing{
+		Headers:      headers,
+		ContentType:  d.ContentType,
+		DeliveryMode: d.DeliveryMode,
+		MessageId:    d.MessageId,
+		Body:         d.Body,
+	})
+}
+
+func (b *InmemBroker) declareQueue(name, deadLetterExchange string) *inmemQueue {
+	if q, ok := b.queues[name]; ok {
+		return q
+	}
+
+	q := newInmemQueue(b, name, deadLetterExchange)
+	b.queues[name] = q
+	return q
+}
+
+func (b *InmemBroker) bind(exchange, queue, pattern string) {
+	for _, binding := range b.bindings[exchange] {
+		if binding.queue == queue && binding.pattern == pattern {
+			return
+		}
+	}
+
+	b.bindings[exchange] = append(b.bindings[exchange], inmemBinding{queue: queue, pattern: pattern})
+}
+
+// inmemQueue is a FIFO queue that tracks unacked deliveries.
+// It implements amqp.Acknowledger so handlers can Ack, Nack and Reject as with RabbitMQ.
+type inmemQueue struct {
+	broker             *InmemBroker
+	name               string
+	deadLetterExchange string
+
+	ready   []amqp.Delivery
+	unacked map[uint64]amqp.Delivery
+	nextTag uint64
+	closed  bool
+	mutex   sync.Mutex
+	cond    *sync.Cond
+}
+
+func newInmemQueue(broker *InmemBroker, name, deadLetterExchange string) *inmemQueue {
+	q := &inmemQueue{
+		broker:             broker,
+		name:               name,
+		deadLetterExchange: deadLetterExchange,
+		unacked:            make(map[uint64]amqp.Delivery),
+	}
+	q.cond = sync.NewCond(&q.mutex)
+	return q
+}
+
+func (q *inmemQueue) push(d amqp.Delivery) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	if q.closed {
+		return
+	}
+
+	q.ready = append(q.ready, d)
+	q.cond.Signal()
+}
+
+// requeue puts nacked deliveries back at the head of the queue, in their original order.
+func (q *inmemQueue) requeue(ds []amqp.Delivery) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	if q.closed {
+		return
+	}
+
+	for i := range ds {
+		ds[i].Redelivered = true
+		ds[i].Acknowledger = nil
+	}
+
+	q.ready = append(ds, q.ready...)
+	q.cond.Broadcast()
+}
+
+// pop blocks until a message is ready and hands it out as an unacked delivery.
+// It returns false once the queue is closed.
+func (q *inmemQueue) pop() (amqp.Delivery, bool) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	for len(q.ready) == 0 && !q.closed {
+		q.cond.Wait()
+	}
+	if q.closed {
+		return amqp.Delivery{}, false
+	}
+
+	d := q.ready[0]
+	q.ready = q.ready[1:]
+
+	q.nextTag++
+	d.DeliveryTag = q.nextTag
+	d.Acknowledger = q
+	q.unacked[d.DeliveryTag] = d
+
+	return d, true
+}
+
+func (q *inmemQueue) close() {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	q.closed = true
+	q.cond.Broadcast()
+}
+
+// settle removes the matching unacked deliveries and returns them.
+func (q *inmemQueue) settle(tag uint64, multiple bool) ([]amqp.Delivery, error) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	if _, ok := q.unacked[tag]; !ok {
+		return nil, fmt.Errorf("unknown delivery tag %d on queue %s", tag, q.name)
+	}
+
+	var settled []amqp.Delivery
+	for t, d := range q.unacked {
+		if t == tag || (multiple && t < tag) {
+			settled = append(settled, d)
+			delete(q.unacked, t)
+		}
+	}
+
+	sort.Slice(settled, func(i, j int) bool {
+		return settled[i].DeliveryTag < settled[j].DeliveryTag
+	})
+
+	return settled, nil
+}
+
+func (q *inmemQueue) Ack(tag uint64, multiple bool) error {
+	_, err := q.settle(tag, multiple)
+	return err
+}
+
+func (q *inmemQueue) Nack(tag uint64, multiple bool, requeue bool) error {
+	settled, err := q.settle(tag, multiple)
+	if err != nil {
+		return err
+	}
+
+	if requeue {
+		q.requeue(settled)
+		return nil
+	}
+
+	for _, d := range settled {
+		q.broker.deadLetter(q, d)
+	}
+
+	return nil
+}
+
+func (q *inmemQueue) Reject(tag uint64, requeue bool) error {
+	return q.Nack(tag, false, requeue)
+}
+
+func copyTable(t amqp.Table) amqp.Table {
+	c := make(amqp.Table, len(t))
+	for k, v := range t {
+		c[k] = v
+	}
+	return c
+}
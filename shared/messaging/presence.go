@@ -0,0 +1,77 @@
+package messaging
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+var (
+	ErrUserNotPresent = errors.New("user is not connected to any gateway instance")
+)
+
+// PresenceRegistry keeps track of the gateway instance holding the websocket connection of each user.
+// With multiple gateway instances the registry must be backed by a shared storage, see
+// NewMongoPresenceRegistry, the in-memory implementation is only shared by the instances of a process.
+type PresenceRegistry interface {
+	// Register records the instance of the user and returns the instance it replaces, if any.
+	Register(ctx context.Context, userID, instanceID string) (string, error)
+	// Claim registers the instance unless the user is already present, and returns the instance of the user.
+	Claim(ctx context.Context, userID, instanceID string) (string, error)
+	// Unregister removes the user, unless the user has moved to another instance in the meantime.
+	Unregister(ctx context.Context, userID, instanceID string) error
+	// Lookup returns the instance of the user or ErrUserNotPresent.
+	Lookup(ctx context.Context, userID string) (string, error)
+}
+
+type inmemPresenceRegistry struct {
+	instances map[string]string // userID -> instanceID
+	mutex     sync.RWMutex
+}
+
+func NewInmemPresenceRegistry() *inmemPresenceRegistry {
+	return &inmemPresenceRegistry{
+		instances: make(map[string]string),
+	}
+}
+
+func (r *inmemPresenceRegistry) Register(ctx context.Context, userID, instanceID string) (string, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	previous := r.instances[userID]
+	r.instances[userID] = instanceID
+	return previous, nil
+}
+
+func (r *inmemPresenceRegistry) Claim(ctx context.Context, userID, instanceID string) (string, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if current, ok := r.instances[userID]; ok {
+		return current, nil
+	}
+	r.instances[userID] = instanceID
+	return instanceID, nil
+}
+
+func (r *inmemPresenceRegistry) Unregister(ctx context.Context, userID, instanceID string) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if r.instances[userID] == instanceID {
+		delete(r.instances, userID)
+	}
+	return nil
+}
+
+func (r *inmemPresenceRegistry) Lookup(ctx context.Context, userID string) (string, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	instanceID, ok := r.instances[userID]
+	if !ok {
+		return "", ErrUserNotPresent
+	}
+	return instanceID, nil
+}
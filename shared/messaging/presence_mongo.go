@@ -0,0 +1,82 @@
+package messaging
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"ride-sharing/shared/db"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// presenceDocument is the instance of a user, the document ID is the user ID
+type presenceDocument struct {
+	UserID     string    `bson:"_id"`
+	InstanceID string    `bson:"instanceId"`
+	UpdatedAt  time.Time `bson:"updatedAt"`
+}
+
+type mongoPresenceRegistry struct {
+	collection *mongo.Collection
+}
+
+// NewMongoPresenceRegistry stores the presence of the users in MongoDB, shared by every gateway instance
+func NewMongoPresenceRegistry(database *mongo.Database) *mongoPresenceRegistry {
+	return &mongoPresenceRegistry{collection: database.Collection(db.PresenceCollection)}
+}
+
+func (r *mongoPresenceRegistry) Register(ctx context.Context, userID, instanceID string) (string, error) {
+	var previous presenceDocument
+	err := r.collection.FindOneAndUpdate(ctx,
+		bson.M{"_id": userID},
+		bson.M{"$set": bson.M{"instanceId": instanceID, "updatedAt": time.Now()}},
+		options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.Before),
+	).Decode(&previous)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+
+	return previous.InstanceID, nil
+}
+
+func (r *mongoPresenceRegistry) Claim(ctx context.Context, userID, instanceID string) (string, error) {
+	var current presenceDocument
+	err := r.collection.FindOneAndUpdate(ctx,
+		bson.M{"_id": userID},
+		bson.M{"$setOnInsert": bson.M{"instanceId": instanceID, "updatedAt": time.Now()}},
+		options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After),
+	).Decode(&current)
+	// Another instance inserted the user concurrently
+	if mongo.IsDuplicateKeyError(err) {
+		return r.Lookup(ctx, userID)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	return current.InstanceID, nil
+}
+
+func (r *mongoPresenceRegistry) Unregister(ctx context.Context, userID, instanceID string) error {
+	_, err := r.collection.DeleteOne(ctx, bson.M{"_id": userID, "instanceId": instanceID})
+	return err
+}
+
+func (r *mongoPresenceRegistry) Lookup(ctx context.Context, userID string) (string, error) {
+	var presence presenceDocument
+	err := r.collection.FindOne(ctx, bson.M{"_id": userID}).Decode(&presence)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return "", ErrUserNotPresent
+	}
+	if err != nil {
+		return "", err
+	}
+
+	return presence.InstanceID, nil
+}
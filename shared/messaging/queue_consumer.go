@@ -1,60 +1,43 @@
 package messaging
 
 import (
+	"context"
 	"encoding/json"
 	"log"
 
 	"ride-sharing/shared/contracts"
-
-	amqp "github.com/rabbitmq/amqp091-go"
 )
 
+// QueueConsumer dispatches the messages of a queue to the websocket connections of their owners.
+// Only one consumer per queue must be started per gateway instance, otherwise they compete for the messages.
 type QueueConsumer struct {
-	rb        *RabbitMQ
-	connMgr   *ConnectionManager
-	queueName string
-	channel   *amqp.Channel
+	broker     Broker
+	dispatcher Dispatcher
+	queueName  string
 }
 
-func NewQueueConsumer(rb *RabbitMQ, connMgr *ConnectionManager, queueName string) *QueueConsumer {
+func NewQueueConsumer(broker Broker, dispatcher Dispatcher, queueName string) *QueueConsumer {
 	return &QueueConsumer{
-		rb:        rb,
-		connMgr:   connMgr,
-		queueName: queueName,
+		broker:     broker,
+		dispatcher: dispatcher,
+		queueName:  queueName,
 	}
 }
 
 func (qc *QueueConsumer) Start() error {
-	// Create a dedicated channel for this consumer
-	// This prevents concurrent access issues with shared channels
-	ch, err := qc.rb.Conn.Channel()
-	if err != nil {
-		log.Printf("Failed to create channel for queue %s: %v", qc.queueName, err)
-		return err
-	}
-	qc.channel = ch
-
-	msgs, err := qc.channel.Consume(
-		qc.queueName,
-		"",
-		true,
-		false,
-		false,
-		false,
-		nil,
-	)
+	msgs, err := qc.broker.Consume(qc.queueName)
 	if err != nil {
-		qc.channel.Close()
+		log.Printf("Failed to consume queue %s: %v", qc.queueName, err)
 		return err
 	}
 
 	go func() {
-		defer qc.channel.Close()
-		
 		for msg := range msgs {
 			var msgBody contracts.AmqpMessage
 			if err := json.Unmarshal(msg.Body, &msgBody); err != nil {
 				log.Println("Failed to unmarshal message:", err)
+				// Malformed messages can never be delivered, send them to the DLQ
+				_ = msg.Reject(false)
 				continue
 			}
 
@@ -64,6 +47,7 @@ func (qc *QueueConsumer) Start() error {
 			if msgBody.Data != nil {
 				if err := json.Unmarshal(msgBody.Data, &payload); err != nil {
 					log.Println("Failed to unmarshal payload:", err)
+					_ = msg.Reject(false)
 					continue
 				}
 			}
@@ -73,8 +57,11 @@ func (qc *QueueConsumer) Start() error {
 				Data: payload,
 			}
 
-			if err := qc.connMgr.SendMessage(userID, clientMsg); err != nil {
-				log.Printf("Failed to send message to user %s: %v", userID, err)
+			// The message is either sent, forwarded to another instance or buffered until the user connects
+			qc.dispatcher.Dispatch(context.Background(), userID, clientMsg)
+
+			if err := msg.Ack(false); err != nil {
+				log.Printf("Failed to ack message: %v", err)
 			}
 		}
 	}()
@@ -6,7 +6,6 @@ import (
 	"fmt"
 	"log"
 	"ride-sharing/shared/contracts"
-	"ride-sharing/shared/retry"
 	"ride-sharing/shared/tracing"
 
 	amqp "github.com/rabbitmq/amqp091-go"
@@ -15,6 +14,8 @@ import (
 const (
 	TripExchange       = "trip"
 	DeadLetterExchange = "dlx"
+	// GatewayExchange routes websocket messages to the gateway instance holding the user connection
+	GatewayExchange = "gateway"
 )
 
 type RabbitMQ struct {
@@ -48,8 +49,6 @@ func NewRabbitMQ(uri string) (*RabbitMQ, error) {
 	return rmq, nil
 }
 
-type MessageHandler func(context.Context, amqp.Delivery) error
-
 func (r *RabbitMQ) ConsumeMessages(queueName string, handler MessageHandler) error {
 	// Set prefetch count to 1 for fair dispatch
 	// This tells RabbitMQ not to give more than one message to a service at a time.
@@ -76,51 +75,41 @@ func (r *RabbitMQ) ConsumeMessages(queueName string, handler MessageHandler) err
 		return err
 	}
 
-	go func() {
-		for msg := range msgs {
-			if err := tracing.TracedConsumer(msg, func(ctx context.Context, d amqp.Delivery) error {
-				log.Printf("Received a message: %s", msg.Body)
-
-				cfg := retry.DefaultConfig()
-				err := retry.WithBackoff(ctx, cfg, func() error {
-					return handler(ctx, d)
-				})
-				if err != nil {
-					log.Printf("Message processing failed after %d retries for message ID: %s, err: %v", cfg.MaxRetries, d.MessageId, err)
-
-					// Add failure context before sending to the DLQ
-					headers := amqp.Table{}
-					if d.Headers != nil {
-						headers = d.Headers
-					}
-
-					headers["x-death-reason"] = err.Error()
-					headers["x-origin-exchange"] = d.Exchange
-					headers["x-original-routing-key"] = d.RoutingKey
-					headers["x-retry-count"] = cfg.MaxRetries
-					d.Headers = headers
-
-					// Reject without requeue - message will go to the DLQ
-					_ = d.Reject(false)
-					return err
-				}
-
-				// Only Ack if the handler succeeds
-				if ackErr := msg.Ack(false); ackErr != nil {
-					log.Printf("ERROR: Failed to Ack message: %v. Message body: %s", ackErr, msg.Body)
-				}
-
-				return nil
-			}); err != nil {
-				log.Printf("Error processing message: %v", err)
-			}
-		}
-	}()
+	go handleDeliveries(msgs, handler)
 
 	return nil
 }
 
+func (r *RabbitMQ) Consume(queueName string) (<-chan amqp.Delivery, error) {
+	// Create a dedicated channel for this consumer
+	// This prevents concurrent access issues with shared channels
+	ch, err := r.Conn.Channel()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create channel for queue %s: %v", queueName, err)
+	}
+
+	msgs, err := ch.Consume(
+		queueName, // queue
+		"",        // consumer
+		false,     // auto-ack
+		false,     // exclusive
+		false,     // no-local
+		false,     // no-wait
+		nil,       // args
+	)
+	if err != nil {
+		ch.Close()
+		return nil, err
+	}
+
+	return msgs, nil
+}
+
 func (r *RabbitMQ) PublishMessage(ctx context.Context, routingKey string, message contracts.AmqpMessage) error {
+	return r.PublishToExchange(ctx, TripExchange, routingKey, message)
+}
+
+func (r *RabbitMQ) PublishToExchange(ctx context.Context, exchange, routingKey string, message contracts.AmqpMessage) error {
 	log.Printf("Publishing message with routing key: %s", routingKey)
 
 	jsonMsg, err := json.Marshal(message)
@@ -134,7 +123,39 @@ func (r *RabbitMQ) PublishMessage(ctx context.Context, routingKey string, messag
 		Body:         jsonMsg,
 	}
 
-	return tracing.TracedPublisher(ctx, TripExchange, routingKey, msg, r.publish)
+	return tracing.TracedPublisher(ctx, exchange, routingKey, msg, r.publish)
+}
+
+func (r *RabbitMQ) DeclareInstanceQueue(queueName string) error {
+	_, err := r.Channel.QueueDeclare(
+		queueName, // name
+		false,     // durable
+		true,      // delete when unused
+		true,      // exclusive
+		false,     // no-wait
+		nil,       // arguments
+	)
+	if err != nil {
+		return fmt.Errorf("failed to declare instance queue %s: %v", queueName, err)
+	}
+
+	return nil
+}
+
+func (r *RabbitMQ) BindQueue(queueName, exchange, routingKey string) error {
+	if err := r.Channel.QueueBind(queueName, routingKey, exchange, false, nil); err != nil {
+		return fmt.Errorf("failed to bind queue %s to %s: %v", queueName, routingKey, err)
+	}
+
+	return nil
+}
+
+func (r *RabbitMQ) UnbindQueue(queueName, exchange, routingKey string) error {
+	if err := r.Channel.QueueUnbind(queueName, routingKey, exchange, nil); err != nil {
+		return fmt.Errorf("failed to unbind queue %s from %s: %v", queueName, routingKey, err)
+	}
+
+	return nil
 }
 
 func (r *RabbitMQ) publish(ctx context.Context, exchange, routingKey string, msg amqp.Publishing) error {
@@ -209,79 +230,23 @@ func (r *RabbitMQ) setupExchangesAndQueues() error {
 		return fmt.Errorf("failed to declare exchange: %s: %v", TripExchange, err)
 	}
 
-	if err := r.declareAndBindQueue(
-		FindAvailableDriversQueue,
-		[]string{
-			contracts.TripEventCreated, contracts.TripEventDriverNotInterested,
-		},
-		TripExchange,
-	); err != nil {
-		return err
-	}
-
-	if err := r.declareAndBindQueue(
-		DriverCmdTripRequestQueue,
-		[]string{contracts.DriverCmdTripRequest},
-		TripExchange,
-	); err != nil {
-		return err
-	}
-
-	if err := r.declareAndBindQueue(
-		DriverTripResponseQueue,
-		[]string{contracts.DriverCmdTripAccept, contracts.DriverCmdTripDecline},
-		TripExchange,
-	); err != nil {
-		return err
-	}
-
-	if err := r.declareAndBindQueue(
-		NotifyDriverNoDriversFoundQueue,
-		[]string{contracts.TripEventNoDriversFound},
-		TripExchange,
-	); err != nil {
-		return err
-	}
-
-	if err := r.declareAndBindQueue(
-		NotifyDriverAssignQueue,
-		[]string{contracts.TripEventDriverAssigned},
-		TripExchange,
-	); err != nil {
-		return err
-	}
-
-	// Add binding for Trip Created Notification
-	if err := r.declareAndBindQueue(
-		NotifyTripCreatedQueue,
-		[]string{contracts.TripEventCreated},
-		TripExchange,
-	); err != nil {
-		return err
-	}
-
-	if err := r.declareAndBindQueue(
-		PaymentTripResponseQueue,
-		[]string{contracts.PaymentCmdCreateSession},
-		TripExchange,
-	); err != nil {
-		return err
-	}
-
-	if err := r.declareAndBindQueue(
-		NotifyPaymentSessionCreatedQueue,
-		[]string{contracts.PaymentEventSessionCreated},
-		TripExchange,
-	); err != nil {
-		return err
+	err = r.Channel.ExchangeDeclare(
+		GatewayExchange, // name
+		"direct",        // type
+		true,            // durable
+		false,           // auto-deleted
+		false,           // internal
+		false,           // no-wait
+		nil,             // arguments
+	)
+	if err != nil {
+		return fmt.Errorf("failed to declare exchange: %s: %v", GatewayExchange, err)
 	}
 
-	if err := r.declareAndBindQueue(
-		NotifyPaymentSuccessQueue,
-		[]string{contracts.PaymentEventSuccess},
-		TripExchange,
-	); err != nil {
-		return err
+	for _, b := range tripQueueBindings {
+		if err := r.declareAndBindQueue(b.queue, b.routingKeys, TripExchange); err != nil {
+			return err
+		}
 	}
 
 	return nil
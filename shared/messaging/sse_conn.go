@@ -0,0 +1,102 @@
+package messaging
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"ride-sharing/shared/contracts"
+)
+
+var errSSEConnClosed = errors.New("event stream is closed")
+
+// SSEConn is a Conn over a Server-Sent Events stream, for the clients that can't open a websocket.
+// Every message is an event whose data is the JSON of the WSMessage and whose id is its sequence
+// number, so that EventSource resumes with the Last-Event-ID header.
+type SSEConn struct {
+	w      http.ResponseWriter
+	rc     *http.ResponseController
+	done   chan struct{}
+	closed bool
+	mutex  sync.Mutex // The response can't be written once the handler returned, see Close
+}
+
+// NewSSEConn starts the event stream of the response
+func NewSSEConn(w http.ResponseWriter) (*SSEConn, error) {
+	c := &SSEConn{
+		w:    w,
+		rc:   http.NewResponseController(w),
+		done: make(chan struct{}),
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	// Don't let nginx buffer the events
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	if err := c.rc.Flush(); err != nil {
+		return nil, fmt.Errorf("the response can't be streamed: %w", err)
+	}
+
+	return c, nil
+}
+
+func (c *SSEConn) Send(message contracts.WSMessage, deadline time.Time) error {
+	data, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+
+	// The messages that are not sequenced have no id, the client keeps the last one
+	event := "data: " + string(data) + "\n\n"
+	if message.Seq > 0 {
+		event = "id: " + strconv.FormatUint(message.Seq, 10) + "\n" + event
+	}
+
+	return c.write(event, deadline)
+}
+
+// Ping writes a comment, which the clients ignore
+func (c *SSEConn) Ping(deadline time.Time) error {
+	return c.write(": ping\n\n", deadline)
+}
+
+func (c *SSEConn) write(event string, deadline time.Time) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.closed {
+		return errSSEConnClosed
+	}
+
+	if err := c.rc.SetWriteDeadline(deadline); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		return err
+	}
+	if _, err := c.w.Write([]byte(event)); err != nil {
+		return err
+	}
+	return c.rc.Flush()
+}
+
+// Close ends the stream. It waits for a write in progress, so the handler must call it before returning.
+func (c *SSEConn) Close() error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if !c.closed {
+		c.closed = true
+		close(c.done)
+	}
+	return nil
+}
+
+// Done is closed when the stream is closed by the gateway, e.g. when the user opens a new connection
+func (c *SSEConn) Done() <-chan struct{} {
+	return c.done
+}
@@ -0,0 +1,281 @@
+package messaging
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"ride-sharing/shared/contracts"
+)
+
+// Dispatcher delivers a websocket message to a user.
+type Dispatcher interface {
+	Dispatch(ctx context.Context, userID string, message contracts.WSMessage)
+}
+
+// UserRouter delivers websocket messages to users connected to any gateway instance.
+// The presence registry tells which instance holds each user. Every instance owns an exclusive
+// queue on the gateway exchange, bound with the routing key of each user it holds, so messages for
+// users held by another instance are published on the exchange and reach that instance.
+// A user stays registered and bound for unbindDelay after the connection is closed, the messages
+// the user misses meanwhile are buffered by the instance for the replay, see ConnectionManager.Add.
+// A user nobody holds is claimed by the instance dispatching a message to it, which buffers the
+// message the same way. When the user connects to another instance, the previous holder is told to
+// hand its undelivered messages over to it.
+type UserRouter struct {
+	instanceID  string
+	queueName   string
+	broker      Broker
+	registry    PresenceRegistry
+	connMgr     *ConnectionManager
+	unbindDelay time.Duration
+	unbinds     map[string]*pendingUnbind // Pending unbinds of the disconnected users (userId -> unbind)
+	mutex       sync.Mutex
+}
+
+type pendingUnbind struct {
+	timer *time.Timer
+}
+
+func NewUserRouter(instanceID string, broker Broker, registry PresenceRegistry, connMgr *ConnectionManager) *UserRouter {
+	return &UserRouter{
+		instanceID:  instanceID,
+		queueName:   "gateway_instance_" + instanceID,
+		broker:      broker,
+		registry:    registry,
+		connMgr:     connMgr,
+		unbindDelay: ReplayMessageTTL,
+		unbinds:     make(map[string]*pendingUnbind),
+	}
+}
+
+// UserRoutingKey is the gateway exchange routing key of the messages for a user.
+func UserRoutingKey(userID string) string {
+	return "user." + userID
+}
+
+// InstanceRoutingKey is the gateway exchange routing key of the handovers asked to an instance.
+func InstanceRoutingKey(instanceID string) string {
+	return "instance." + instanceID
+}
+
+// Start declares the instance queue and delivers the forwarded messages to the local connections.
+func (r *UserRouter) Start() error {
+	if err := r.broker.DeclareInstanceQueue(r.queueName); err != nil {
+		return err
+	}
+
+	if err := r.broker.BindQueue(r.queueName, GatewayExchange, InstanceRoutingKey(r.instanceID)); err != nil {
+		return err
+	}
+
+	msgs, err := r.broker.Consume(r.queueName)
+	if err != nil {
+		return err
+	}
+
+	go func() {
+		for msg := range msgs {
+			var msgBody contracts.AmqpMessage
+			var clientMsg contracts.WSMessage
+			if err := json.Unmarshal(msg.Body, &msgBody); err != nil {
+				log.Printf("Failed to unmarshal forwarded message: %v", err)
+				_ = msg.Reject(false)
+				continue
+			}
+			if msg.RoutingKey == InstanceRoutingKey(r.instanceID) {
+				r.handOver(msgBody.OwnerID)
+				_ = msg.Ack(false)
+				continue
+			}
+			if err := json.Unmarshal(msgBody.Data, &clientMsg); err != nil {
+				log.Printf("Failed to unmarshal forwarded payload: %v", err)
+				_ = msg.Reject(false)
+				continue
+			}
+
+			// Never forward again, the user was connected here when the message was routed
+			r.connMgr.Dispatch(msgBody.OwnerID, clientMsg)
+
+			if err := msg.Ack(false); err != nil {
+				log.Printf("Failed to ack forwarded message: %v", err)
+			}
+		}
+	}()
+
+	log.Printf("Gateway instance %s is consuming %s", r.instanceID, r.queueName)
+	return nil
+}
+
+// Connect adds the connection locally and routes the messages of the user to this instance.
+// The queue is bound before the user is registered, so that the instances which look the user up
+// find it bound. The messages after lastSeq are replayed, see ConnectionManager.Add, then the
+// instance that held the user before hands its undelivered messages over.
+func (r *UserRouter) Connect(ctx context.Context, userID string, conn Conn, lastSeq *uint64) error {
+	r.cancelUnbind(userID)
+
+	if err := r.broker.BindQueue(r.queueName, GatewayExchange, UserRoutingKey(userID)); err != nil {
+		return err
+	}
+
+	previous, err := r.registry.Register(ctx, userID, r.instanceID)
+	if err != nil {
+		return err
+	}
+
+	r.connMgr.Add(userID, conn, lastSeq)
+
+	if previous != "" && previous != r.instanceID {
+		err := r.broker.PublishToExchange(ctx, GatewayExchange, InstanceRoutingKey(previous), contracts.AmqpMessage{OwnerID: userID})
+		if err != nil {
+			log.Printf("Failed to ask instance %s to hand user %s over: %v", previous, userID, err)
+		}
+	}
+	return nil
+}
+
+// Disconnect removes the connection, unless the user has already reconnected with a new one.
+// The messages of the user keep being routed here for unbindDelay, so that they can be replayed.
+func (r *UserRouter) Disconnect(ctx context.Context, userID string, conn Conn) {
+	if !r.connMgr.Remove(userID, conn) {
+		return
+	}
+
+	r.scheduleUnbind(userID)
+}
+
+// scheduleUnbind unbinds the user after unbindDelay, replacing the unbind scheduled before
+func (r *UserRouter) scheduleUnbind(userID string) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if pending, ok := r.unbinds[userID]; ok {
+		pending.timer.Stop()
+	}
+
+	pending := &pendingUnbind{}
+	pending.timer = time.AfterFunc(r.unbindDelay, func() { r.unbind(userID, pending) })
+	r.unbinds[userID] = pending
+}
+
+// cancelUnbind keeps the binding of a user who reconnected before it was removed
+func (r *UserRouter) cancelUnbind(userID string) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if pending, ok := r.unbinds[userID]; ok {
+		pending.timer.Stop()
+		delete(r.unbinds, userID)
+	}
+}
+
+// unbind stops routing the messages of the user to this instance and unregisters the user, unless
+// the unbind was cancelled or scheduled again since the timer was armed
+func (r *UserRouter) unbind(userID string, pending *pendingUnbind) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if r.unbinds[userID] != pending {
+		return
+	}
+	delete(r.unbinds, userID)
+
+	// The user reconnected while the disconnection was being handled
+	if _, ok := r.connMgr.Get(userID); ok {
+		return
+	}
+
+	if err := r.registry.Unregister(context.Background(), userID, r.instanceID); err != nil {
+		log.Printf("Failed to unregister user %s: %v", userID, err)
+	}
+
+	if err := r.broker.UnbindQueue(r.queueName, GatewayExchange, UserRoutingKey(userID)); err != nil {
+		log.Printf("Failed to unbind user %s: %v", userID, err)
+	}
+}
+
+// Dispatch sends the message to the local connection of the user, buffers it when the user
+// is held by this instance but not connected, or publishes it on the gateway exchange for the
+// instance holding the user. A user nobody holds is claimed by this instance, the message is
+// buffered until the user connects here or to another instance, see handOver.
+func (r *UserRouter) Dispatch(ctx context.Context, userID string, message contracts.WSMessage) {
+	if _, ok := r.connMgr.Get(userID); !ok {
+		instanceID, err := r.registry.Lookup(ctx, userID)
+		if errors.Is(err, ErrUserNotPresent) {
+			instanceID, err = r.claim(ctx, userID)
+		}
+		if err != nil {
+			// Keep the message rather than publishing it for nobody
+			log.Printf("Failed to look up user %s, buffering the message: %v", userID, err)
+			instanceID = r.instanceID
+		}
+
+		if instanceID != r.instanceID {
+			err := r.forward(ctx, userID, message)
+			if err == nil {
+				return
+			}
+			log.Printf("Failed to forward message to user %s: %v", userID, err)
+		}
+	}
+
+	r.connMgr.Dispatch(userID, message)
+}
+
+// claim holds a user nobody holds like a user who just disconnected from this instance, and returns
+// the instance holding the user, which is another one if it claimed the user first
+func (r *UserRouter) claim(ctx context.Context, userID string) (string, error) {
+	if err := r.broker.BindQueue(r.queueName, GatewayExchange, UserRoutingKey(userID)); err != nil {
+		return "", err
+	}
+
+	instanceID, err := r.registry.Claim(ctx, userID, r.instanceID)
+	if err == nil && instanceID != r.instanceID {
+		if err := r.broker.UnbindQueue(r.queueName, GatewayExchange, UserRoutingKey(userID)); err != nil {
+			log.Printf("Failed to unbind user %s: %v", userID, err)
+		}
+		return instanceID, nil
+	}
+
+	// Held here until the user connects, the unbind leaves a connected user alone
+	r.scheduleUnbind(userID)
+	return instanceID, err
+}
+
+// handOver stops holding a user who connected to another instance and forwards the messages
+// the user never got from this instance to it
+func (r *UserRouter) handOver(userID string) {
+	if _, ok := r.connMgr.Get(userID); ok {
+		// The user came back here in the meantime
+		return
+	}
+
+	r.cancelUnbind(userID)
+	if err := r.broker.UnbindQueue(r.queueName, GatewayExchange, UserRoutingKey(userID)); err != nil {
+		log.Printf("Failed to unbind user %s: %v", userID, err)
+	}
+
+	messages := r.connMgr.Release(userID)
+	for _, message := range messages {
+		if err := r.forward(context.Background(), userID, message); err != nil {
+			log.Printf("Failed to hand message %s of user %s over: %v", message.Type, userID, err)
+		}
+	}
+
+	log.Printf("Handed user %s over with %d messages", userID, len(messages))
+}
+
+func (r *UserRouter) forward(ctx context.Context, userID string, message contracts.WSMessage) error {
+	data, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+
+	return r.broker.PublishToExchange(ctx, GatewayExchange, UserRoutingKey(userID), contracts.AmqpMessage{
+		OwnerID: userID,
+		Data:    data,
+	})
+}
@@ -0,0 +1,266 @@
+package messaging
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"ride-sharing/shared/contracts"
+)
+
+// testConn is a Conn recording the messages written to the client
+type testConn struct {
+	messages chan contracts.WSMessage
+}
+
+func newTestConn() *testConn {
+	return &testConn{messages: make(chan contracts.WSMessage, 16)}
+}
+
+func (c *testConn) Send(message contracts.WSMessage, deadline time.Time) error {
+	c.messages <- message
+	return nil
+}
+
+func (c *testConn) Ping(deadline time.Time) error { return nil }
+
+func (c *testConn) Close() error { return nil }
+
+// expect waits for the next message written to the client
+func (c *testConn) expect(t *testing.T, messageType string) contracts.WSMessage {
+	t.Helper()
+
+	select {
+	case message := <-c.messages:
+		if message.Type != messageType {
+			t.Fatalf("got message %s, want %s", message.Type, messageType)
+		}
+		return message
+	case <-time.After(2 * time.Second):
+		t.Fatalf("timed out waiting for message %s", messageType)
+		return contracts.WSMessage{}
+	}
+}
+
+// expectNone checks that nothing is written to the client for a while
+func (c *testConn) expectNone(t *testing.T) {
+	t.Helper()
+
+	select {
+	case message := <-c.messages:
+		t.Fatalf("got unexpected message %s", message.Type)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+// startTestRouter starts a gateway instance on the broker, the instances share the registry
+func startTestRouter(t *testing.T, instanceID string, broker Broker, registry PresenceRegistry) *UserRouter {
+	t.Helper()
+
+	router := NewUserRouter(instanceID, broker, registry, NewConnectionManager(DefaultConnectionConfig()))
+	if err := router.Start(); err != nil {
+		t.Fatalf("failed to start instance %s: %v", instanceID, err)
+	}
+	return router
+}
+
+func TestUserRouterDeliversToAnotherInstance(t *testing.T) {
+	ctx := context.Background()
+	broker := NewInmemBroker()
+	defer broker.Close()
+	registry := NewInmemPresenceRegistry()
+
+	a := startTestRouter(t, "a", broker, registry)
+	b := startTestRouter(t, "b", broker, registry)
+
+	rider, driver := newTestConn(), newTestConn()
+	if err := a.Connect(ctx, "rider-1", rider, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Connect(ctx, "driver-1", driver, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	// Each instance consumes notifications for the users connected to the other one
+	b.Dispatch(ctx, "rider-1", contracts.WSMessage{Type: contracts.TripEventDriverAssigned})
+	a.Dispatch(ctx, "driver-1", contracts.WSMessage{Type: contracts.DriverCmdTripRequest})
+
+	if got := rider.expect(t, contracts.TripEventDriverAssigned); got.Seq != 1 {
+		t.Errorf("rider got seq %d, want 1", got.Seq)
+	}
+	if got := driver.expect(t, contracts.DriverCmdTripRequest); got.Seq != 1 {
+		t.Errorf("driver got seq %d, want 1", got.Seq)
+	}
+
+	// The local users are served without the broker
+	a.Dispatch(ctx, "rider-1", contracts.WSMessage{Type: contracts.TripEventCreated})
+	if got := rider.expect(t, contracts.TripEventCreated); got.Seq != 2 {
+		t.Errorf("rider got seq %d, want 2", got.Seq)
+	}
+}
+
+func TestUserRouterKeepsRoutingToADisconnectedUser(t *testing.T) {
+	ctx := context.Background()
+	broker := NewInmemBroker()
+	defer broker.Close()
+	registry := NewInmemPresenceRegistry()
+
+	a := startTestRouter(t, "a", broker, registry)
+	b := startTestRouter(t, "b", broker, registry)
+
+	first := newTestConn()
+	if err := a.Connect(ctx, "rider-1", first, nil); err != nil {
+		t.Fatal(err)
+	}
+	a.Disconnect(ctx, "rider-1", first)
+
+	// Instance a buffers the message until the rider comes back
+	b.Dispatch(ctx, "rider-1", contracts.WSMessage{Type: contracts.TripEventDriverAssigned})
+	time.Sleep(100 * time.Millisecond)
+
+	second := newTestConn()
+	if err := a.Connect(ctx, "rider-1", second, nil); err != nil {
+		t.Fatal(err)
+	}
+	second.expect(t, contracts.TripEventDriverAssigned)
+}
+
+func TestUserRouterUnbindsAfterTheDelay(t *testing.T) {
+	ctx := context.Background()
+	broker := NewInmemBroker()
+	defer broker.Close()
+	registry := NewInmemPresenceRegistry()
+
+	a := startTestRouter(t, "a", broker, registry)
+	a.unbindDelay = 10 * time.Millisecond
+	b := startTestRouter(t, "b", broker, registry)
+
+	conn := newTestConn()
+	if err := a.Connect(ctx, "rider-1", conn, nil); err != nil {
+		t.Fatal(err)
+	}
+	if instanceID, err := registry.Lookup(ctx, "rider-1"); err != nil || instanceID != "a" {
+		t.Fatalf("got instance %q (%v), want a", instanceID, err)
+	}
+	a.Disconnect(ctx, "rider-1", conn)
+	time.Sleep(100 * time.Millisecond)
+
+	if _, err := registry.Lookup(ctx, "rider-1"); !errors.Is(err, ErrUserNotPresent) {
+		t.Fatalf("got %v, want the rider unregistered", err)
+	}
+
+	// Instance a isn't bound for the rider anymore, b holds the rider now
+	b.Dispatch(ctx, "rider-1", contracts.WSMessage{Type: contracts.TripEventDriverAssigned})
+	time.Sleep(100 * time.Millisecond)
+
+	if instanceID, err := registry.Lookup(ctx, "rider-1"); err != nil || instanceID != "b" {
+		t.Fatalf("got instance %q (%v), want b", instanceID, err)
+	}
+	if messages := a.connMgr.Release("rider-1"); len(messages) != 0 {
+		t.Errorf("got %d messages buffered on a, want none", len(messages))
+	}
+}
+
+func TestUserRouterBuffersForAUserConnectedNowhere(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("connected to the instance that dispatched", func(t *testing.T) {
+		broker := NewInmemBroker()
+		defer broker.Close()
+		registry := NewInmemPresenceRegistry()
+
+		a := startTestRouter(t, "a", broker, registry)
+
+		a.Dispatch(ctx, "rider-1", contracts.WSMessage{Type: contracts.TripEventDriverAssigned})
+
+		conn := newTestConn()
+		if err := a.Connect(ctx, "rider-1", conn, nil); err != nil {
+			t.Fatal(err)
+		}
+		conn.expect(t, contracts.TripEventDriverAssigned)
+		conn.expectNone(t)
+	})
+
+	t.Run("connected to another instance", func(t *testing.T) {
+		broker := NewInmemBroker()
+		defer broker.Close()
+		registry := NewInmemPresenceRegistry()
+
+		a := startTestRouter(t, "a", broker, registry)
+		b := startTestRouter(t, "b", broker, registry)
+		c := startTestRouter(t, "c", broker, registry)
+
+		// Nobody is bound for the rider, a claims the rider and b forwards to a
+		a.Dispatch(ctx, "rider-1", contracts.WSMessage{Type: contracts.TripEventCreated})
+		b.Dispatch(ctx, "rider-1", contracts.WSMessage{Type: contracts.TripEventDriverAssigned})
+		time.Sleep(100 * time.Millisecond)
+
+		// a hands the messages over to c
+		conn := newTestConn()
+		if err := c.Connect(ctx, "rider-1", conn, nil); err != nil {
+			t.Fatal(err)
+		}
+		if got := conn.expect(t, contracts.TripEventCreated); got.Seq != 1 {
+			t.Errorf("got seq %d, want 1", got.Seq)
+		}
+		if got := conn.expect(t, contracts.TripEventDriverAssigned); got.Seq != 2 {
+			t.Errorf("got seq %d, want 2", got.Seq)
+		}
+		conn.expectNone(t)
+
+		// The messages are routed to c from now on
+		b.Dispatch(ctx, "rider-1", contracts.WSMessage{Type: contracts.TripEventNoDriversFound})
+		conn.expect(t, contracts.TripEventNoDriversFound)
+		conn.expectNone(t)
+	})
+}
+
+func TestUserRouterReplaysOnTheSameInstanceOnly(t *testing.T) {
+	ctx := context.Background()
+	broker := NewInmemBroker()
+	defer broker.Close()
+	registry := NewInmemPresenceRegistry()
+
+	a := startTestRouter(t, "a", broker, registry)
+	b := startTestRouter(t, "b", broker, registry)
+
+	conn := newTestConn()
+	if err := a.Connect(ctx, "rider-1", conn, nil); err != nil {
+		t.Fatal(err)
+	}
+	a.Dispatch(ctx, "rider-1", contracts.WSMessage{Type: contracts.TripEventCreated})
+	lastSeq := conn.expect(t, contracts.TripEventCreated).Seq
+	a.Disconnect(ctx, "rider-1", conn)
+
+	b.Dispatch(ctx, "rider-1", contracts.WSMessage{Type: contracts.TripEventDriverAssigned})
+	time.Sleep(100 * time.Millisecond)
+
+	// Back on the same instance, the missed message is replayed
+	conn = newTestConn()
+	if err := a.Connect(ctx, "rider-1", conn, &lastSeq); err != nil {
+		t.Fatal(err)
+	}
+	lastSeq = conn.expect(t, contracts.TripEventDriverAssigned).Seq
+	resumed := conn.expect(t, contracts.SessionEventResumed).Data.(contracts.SessionResumedData)
+	if !resumed.Complete || resumed.Replayed != 1 {
+		t.Errorf("got %+v, want a complete replay of 1 message", resumed)
+	}
+	a.Disconnect(ctx, "rider-1", conn)
+
+	a.Dispatch(ctx, "rider-1", contracts.WSMessage{Type: contracts.TripEventCreated})
+	time.Sleep(100 * time.Millisecond)
+
+	// On another instance nothing is replayed, the client is told the replay is incomplete
+	conn = newTestConn()
+	if err := b.Connect(ctx, "rider-1", conn, &lastSeq); err != nil {
+		t.Fatal(err)
+	}
+	resumed = conn.expect(t, contracts.SessionEventResumed).Data.(contracts.SessionResumedData)
+	if resumed.Complete || resumed.Replayed != 0 {
+		t.Errorf("got %+v, want an incomplete replay of no message", resumed)
+	}
+
+	// The message the rider never got is handed over by a
+	conn.expect(t, contracts.TripEventCreated)
+}
@@ -0,0 +1,141 @@
+package messaging
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	"ride-sharing/shared/contracts"
+
+	"github.com/gorilla/websocket"
+)
+
+// ConnectionConfig holds the keepalive and flow control settings of the websocket connections
+type ConnectionConfig struct {
+	PingInterval  time.Duration // How often a ping is sent to the client
+	PongWait      time.Duration // How long a connection may stay silent before it is considered dead
+	WriteWait     time.Duration // Deadline of a single write to the client
+	SendQueueSize int           // Max number of outbound messages waiting to be written
+}
+
+// DefaultConnectionConfig returns a ConnectionConfig with sensible default values
+func DefaultConnectionConfig() ConnectionConfig {
+	return ConnectionConfig{
+		PingInterval:  25 * time.Second,
+		PongWait:      60 * time.Second,
+		WriteWait:     10 * time.Second,
+		SendQueueSize: 64,
+	}
+}
+
+// Conn is the transport of a user connection: a websocket or a Server-Sent Events stream
+type Conn interface {
+	// Send writes the message to the client, the write fails past the deadline
+	Send(message contracts.WSMessage, deadline time.Time) error
+	// Ping keeps the connection alive through the proxies and detects the dead clients
+	Ping(deadline time.Time) error
+	Close() error
+}
+
+// WebSocketConn is a Conn over a websocket. The embedded connection is only meant to be read from,
+// the writes go through the ConnectionManager.
+type WebSocketConn struct {
+	*websocket.Conn
+}
+
+func (c *WebSocketConn) Send(message contracts.WSMessage, deadline time.Time) error {
+	c.SetWriteDeadline(deadline)
+	return c.WriteJSON(message)
+}
+
+func (c *WebSocketConn) Ping(deadline time.Time) error {
+	return c.WriteControl(websocket.PingMessage, nil, deadline)
+}
+
+// keepAlive arms the read deadline, which every pong from the client pushes back.
+// A client that misses its heartbeats makes the next read fail.
+func (c *WebSocketConn) keepAlive(cfg ConnectionConfig) {
+	c.SetReadDeadline(time.Now().Add(cfg.PongWait))
+	c.SetPongHandler(func(string) error {
+		return c.SetReadDeadline(time.Now().Add(cfg.PongWait))
+	})
+}
+
+// connWrapper owns the write side of a connection.
+// The connections are not thread-safe, so every message goes through a bounded queue
+// drained by a single writer goroutine, which also sends the pings.
+type connWrapper struct {
+	id        string
+	conn      Conn
+	send      chan contracts.WSMessage
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+func newConnWrapper(id string, conn Conn, queueSize int) *connWrapper {
+	return &connWrapper{
+		id:   id,
+		conn: conn,
+		send: make(chan contracts.WSMessage, queueSize),
+		done: make(chan struct{}),
+	}
+}
+
+// enqueue queues the message without blocking. It returns false when the queue is full.
+func (w *connWrapper) enqueue(message contracts.WSMessage) bool {
+	select {
+	case w.send <- message:
+		return true
+	default:
+		return false
+	}
+}
+
+// close stops the writer and closes the connection, which also unblocks the reader.
+func (w *connWrapper) close() {
+	w.closeOnce.Do(func() {
+		close(w.done)
+		w.conn.Close()
+	})
+}
+
+// writeLoop writes the initial messages, then the queued messages and the pings, until the
+// connection is closed or a write fails. onWritten is called with every message written.
+func (w *connWrapper) writeLoop(cfg ConnectionConfig, initial []contracts.WSMessage, onWritten func(contracts.WSMessage)) {
+	ticker := time.NewTicker(cfg.PingInterval)
+	defer func() {
+		ticker.Stop()
+		w.close()
+	}()
+
+	write := func(message contracts.WSMessage) bool {
+		if err := w.conn.Send(message, time.Now().Add(cfg.WriteWait)); err != nil {
+			log.Printf("Failed to write message %s to user %s: %v", message.Type, w.id, err)
+			return false
+		}
+		onWritten(message)
+		return true
+	}
+
+	for _, message := range initial {
+		if !write(message) {
+			return
+		}
+	}
+
+	for {
+		select {
+		case <-w.done:
+			return
+		case message := <-w.send:
+			if !write(message) {
+				return
+			}
+		case <-ticker.C:
+			if err := w.conn.Ping(time.Now().Add(cfg.WriteWait)); err != nil {
+				log.Printf("Failed to ping user %s: %v", w.id, err)
+				return
+			}
+		}
+	}
+}
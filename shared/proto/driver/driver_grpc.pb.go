@@ -21,14 +21,20 @@ const _ = grpc.SupportPackageIsVersion9
 const (
 	DriverService_RegisterDriver_FullMethodName   = "/driver.DriverService/RegisterDriver"
 	DriverService_UnregisterDriver_FullMethodName = "/driver.DriverService/UnregisterDriver"
+	DriverService_GetDriver_FullMethodName        = "/driver.DriverService/GetDriver"
 )
 
 // DriverServiceClient is the client API for DriverService service.
 //
 // For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
 type DriverServiceClient interface {
+	// RegisterDriver and UnregisterDriver are internal, the gateway registers a driver for as long as their
+	// websocket is connected, so a driver never stays available after their connection is gone.
 	RegisterDriver(ctx context.Context, in *RegisterDriverRequest, opts ...grpc.CallOption) (*RegisterDriverResponse, error)
 	UnregisterDriver(ctx context.Context, in *RegisterDriverRequest, opts ...grpc.CallOption) (*RegisterDriverResponse, error)
+	// GetDriver returns the profile of a registered driver. Internal, the trip service records the driver
+	// who accepts a trip with it.
+	GetDriver(ctx context.Context, in *GetDriverRequest, opts ...grpc.CallOption) (*GetDriverResponse, error)
 }
 
 type driverServiceClient struct {
@@ -59,12 +65,27 @@ func (c *driverServiceClient) UnregisterDriver(ctx context.Context, in *Register
 	return out, nil
 }
 
+func (c *driverServiceClient) GetDriver(ctx context.Context, in *GetDriverRequest, opts ...grpc.CallOption) (*GetDriverResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetDriverResponse)
+	err := c.cc.Invoke(ctx, DriverService_GetDriver_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // DriverServiceServer is the server API for DriverService service.
 // All implementations must embed UnimplementedDriverServiceServer
 // for forward compatibility.
 type DriverServiceServer interface {
+	// RegisterDriver and UnregisterDriver are internal, the gateway registers a driver for as long as their
+	// websocket is connected, so a driver never stays available after their connection is gone.
 	RegisterDriver(context.Context, *RegisterDriverRequest) (*RegisterDriverResponse, error)
 	UnregisterDriver(context.Context, *RegisterDriverRequest) (*RegisterDriverResponse, error)
+	// GetDriver returns the profile of a registered driver. Internal, the trip service records the driver
+	// who accepts a trip with it.
+	GetDriver(context.Context, *GetDriverRequest) (*GetDriverResponse, error)
 	mustEmbedUnimplementedDriverServiceServer()
 }
 
@@ -81,6 +102,9 @@ func (UnimplementedDriverServiceServer) RegisterDriver(context.Context, *Registe
 func (UnimplementedDriverServiceServer) UnregisterDriver(context.Context, *RegisterDriverRequest) (*RegisterDriverResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method UnregisterDriver not implemented")
 }
+func (UnimplementedDriverServiceServer) GetDriver(context.Context, *GetDriverRequest) (*GetDriverResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetDriver not implemented")
+}
 func (UnimplementedDriverServiceServer) mustEmbedUnimplementedDriverServiceServer() {}
 func (UnimplementedDriverServiceServer) testEmbeddedByValue()                       {}
 
@@ -138,6 +162,24 @@ func _DriverService_UnregisterDriver_Handler(srv interface{}, ctx context.Contex
 	return interceptor(ctx, in, info, handler)
 }
 
+func _DriverService_GetDriver_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetDriverRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DriverServiceServer).GetDriver(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: DriverService_GetDriver_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DriverServiceServer).GetDriver(ctx, req.(*GetDriverRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // DriverService_ServiceDesc is the grpc.ServiceDesc for DriverService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -153,6 +195,10 @@ var DriverService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "UnregisterDriver",
 			Handler:    _DriverService_UnregisterDriver_Handler,
 		},
+		{
+			MethodName: "GetDriver",
+			Handler:    _DriverService_GetDriver_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "driver.proto",
@@ -0,0 +1,1815 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.6
+// 	protoc        v5.29.3
+// source: payment.proto
+
+package payment
+
+import (
+	_ "google.golang.org/genproto/googleapis/api/annotations"
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type GetPaymentRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	PaymentID     string                 `protobuf:"bytes,1,opt,name=paymentID,proto3" json:"paymentID,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetPaymentRequest) Reset() {
+	*x = GetPaymentRequest{}
+	mi := &file_payment_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetPaymentRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetPaymentRequest) ProtoMessage() {}
+
+func (x *GetPaymentRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_payment_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetPaymentRequest.ProtoReflect.Descriptor instead.
+func (*GetPaymentRequest) Descriptor() ([]byte, []int) {
+	return file_payment_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *GetPaymentRequest) GetPaymentID() string {
+	if x != nil {
+		return x.PaymentID
+	}
+	return ""
+}
+
+type GetPaymentResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Payment       *Payment               `protobuf:"bytes,1,opt,name=payment,proto3" json:"payment,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetPaymentResponse) Reset() {
+	*x = GetPaymentResponse{}
+	mi := &file_payment_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetPaymentResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetPaymentResponse) ProtoMessage() {}
+
+func (x *GetPaymentResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_payment_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetPaymentResponse.ProtoReflect.Descriptor instead.
+func (*GetPaymentResponse) Descriptor() ([]byte, []int) {
+	return file_payment_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *GetPaymentResponse) GetPayment() *Payment {
+	if x != nil {
+		return x.Payment
+	}
+	return nil
+}
+
+// At least one filter is set. userID matches the rider or the driver, the riders and drivers
+// only list their own payments.
+type ListPaymentsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	UserID        string                 `protobuf:"bytes,1,opt,name=userID,proto3" json:"userID,omitempty"`
+	TripID        string                 `protobuf:"bytes,2,opt,name=tripID,proto3" json:"tripID,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListPaymentsRequest) Reset() {
+	*x = ListPaymentsRequest{}
+	mi := &file_payment_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListPaymentsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListPaymentsRequest) ProtoMessage() {}
+
+func (x *ListPaymentsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_payment_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListPaymentsRequest.ProtoReflect.Descriptor instead.
+func (*ListPaymentsRequest) Descriptor() ([]byte, []int) {
+	return file_payment_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *ListPaymentsRequest) GetUserID() string {
+	if x != nil {
+		return x.UserID
+	}
+	return ""
+}
+
+func (x *ListPaymentsRequest) GetTripID() string {
+	if x != nil {
+		return x.TripID
+	}
+	return ""
+}
+
+type ListPaymentsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Payments      []*Payment             `protobuf:"bytes,1,rep,name=payments,proto3" json:"payments,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListPaymentsResponse) Reset() {
+	*x = ListPaymentsResponse{}
+	mi := &file_payment_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListPaymentsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListPaymentsResponse) ProtoMessage() {}
+
+func (x *ListPaymentsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_payment_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListPaymentsResponse.ProtoReflect.Descriptor instead.
+func (*ListPaymentsResponse) Descriptor() ([]byte, []int) {
+	return file_payment_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *ListPaymentsResponse) GetPayments() []*Payment {
+	if x != nil {
+		return x.Payments
+	}
+	return nil
+}
+
+type RefundPaymentRequest struct {
+	state     protoimpl.MessageState `protogen:"open.v1"`
+	PaymentID string                 `protobuf:"bytes,1,opt,name=paymentID,proto3" json:"paymentID,omitempty"`
+	// Amount in cents, the remaining amount of the payment when 0
+	Amount int64 `protobuf:"varint,2,opt,name=amount,proto3" json:"amount,omitempty"`
+	// duplicate, fraudulent, requested_by_customer or a free text
+	Reason        string `protobuf:"bytes,3,opt,name=reason,proto3" json:"reason,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RefundPaymentRequest) Reset() {
+	*x = RefundPaymentRequest{}
+	mi := &file_payment_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RefundPaymentRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RefundPaymentRequest) ProtoMessage() {}
+
+func (x *RefundPaymentRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_payment_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RefundPaymentRequest.ProtoReflect.Descriptor instead.
+func (*RefundPaymentRequest) Descriptor() ([]byte, []int) {
+	return file_payment_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *RefundPaymentRequest) GetPaymentID() string {
+	if x != nil {
+		return x.PaymentID
+	}
+	return ""
+}
+
+func (x *RefundPaymentRequest) GetAmount() int64 {
+	if x != nil {
+		return x.Amount
+	}
+	return 0
+}
+
+func (x *RefundPaymentRequest) GetReason() string {
+	if x != nil {
+		return x.Reason
+	}
+	return ""
+}
+
+type RefundPaymentResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Payment       *Payment               `protobuf:"bytes,1,opt,name=payment,proto3" json:"payment,omitempty"`
+	Refund        *Refund                `protobuf:"bytes,2,opt,name=refund,proto3" json:"refund,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RefundPaymentResponse) Reset() {
+	*x = RefundPaymentResponse{}
+	mi := &file_payment_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RefundPaymentResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RefundPaymentResponse) ProtoMessage() {}
+
+func (x *RefundPaymentResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_payment_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RefundPaymentResponse.ProtoReflect.Descriptor instead.
+func (*RefundPaymentResponse) Descriptor() ([]byte, []int) {
+	return file_payment_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *RefundPaymentResponse) GetPayment() *Payment {
+	if x != nil {
+		return x.Payment
+	}
+	return nil
+}
+
+func (x *RefundPaymentResponse) GetRefund() *Refund {
+	if x != nil {
+		return x.Refund
+	}
+	return nil
+}
+
+type Refund struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Id    string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	// Amount in cents
+	Amount int64  `protobuf:"varint,2,opt,name=amount,proto3" json:"amount,omitempty"`
+	Reason string `protobuf:"bytes,3,opt,name=reason,proto3" json:"reason,omitempty"`
+	// Admin who requested the refund
+	RequestedBy string `protobuf:"bytes,4,opt,name=requestedBy,proto3" json:"requestedBy,omitempty"`
+	// ID of the refund at the payment processor
+	ProcessorRefundID string `protobuf:"bytes,5,opt,name=processorRefundID,proto3" json:"processorRefundID,omitempty"`
+	// RFC 3339 timestamp
+	CreatedAt     string `protobuf:"bytes,6,opt,name=createdAt,proto3" json:"createdAt,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Refund) Reset() {
+	*x = Refund{}
+	mi := &file_payment_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Refund) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Refund) ProtoMessage() {}
+
+func (x *Refund) ProtoReflect() protoreflect.Message {
+	mi := &file_payment_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Refund.ProtoReflect.Descriptor instead.
+func (*Refund) Descriptor() ([]byte, []int) {
+	return file_payment_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *Refund) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *Refund) GetAmount() int64 {
+	if x != nil {
+		return x.Amount
+	}
+	return 0
+}
+
+func (x *Refund) GetReason() string {
+	if x != nil {
+		return x.Reason
+	}
+	return ""
+}
+
+func (x *Refund) GetRequestedBy() string {
+	if x != nil {
+		return x.RequestedBy
+	}
+	return ""
+}
+
+func (x *Refund) GetProcessorRefundID() string {
+	if x != nil {
+		return x.ProcessorRefundID
+	}
+	return ""
+}
+
+func (x *Refund) GetCreatedAt() string {
+	if x != nil {
+		return x.CreatedAt
+	}
+	return ""
+}
+
+type Payment struct {
+	state    protoimpl.MessageState `protogen:"open.v1"`
+	Id       string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	TripID   string                 `protobuf:"bytes,2,opt,name=tripID,proto3" json:"tripID,omitempty"`
+	UserID   string                 `protobuf:"bytes,3,opt,name=userID,proto3" json:"userID,omitempty"`
+	DriverID string                 `protobuf:"bytes,4,opt,name=driverID,proto3" json:"driverID,omitempty"`
+	// Amount in cents
+	Amount   int64  `protobuf:"varint,5,opt,name=amount,proto3" json:"amount,omitempty"`
+	Currency string `protobuf:"bytes,6,opt,name=currency,proto3" json:"currency,omitempty"`
+	// authorized, pending, success, failed, cancelled, partially_refunded, refunded or disputed
+	Status          string `protobuf:"bytes,7,opt,name=status,proto3" json:"status,omitempty"`
+	StripeSessionID string `protobuf:"bytes,8,opt,name=stripeSessionID,proto3" json:"stripeSessionID,omitempty"`
+	// RFC 3339 timestamps
+	CreatedAt string `protobuf:"bytes,9,opt,name=createdAt,proto3" json:"createdAt,omitempty"`
+	UpdatedAt string `protobuf:"bytes,10,opt,name=updatedAt,proto3" json:"updatedAt,omitempty"`
+	// Set once the checkout session completed
+	PaymentIntentID string `protobuf:"bytes,11,opt,name=paymentIntentID,proto3" json:"paymentIntentID,omitempty"`
+	// Sum of the refunds in cents
+	RefundedAmount int64     `protobuf:"varint,12,opt,name=refundedAmount,proto3" json:"refundedAmount,omitempty"`
+	Refunds        []*Refund `protobuf:"bytes,13,rep,name=refunds,proto3" json:"refunds,omitempty"`
+	// Package of the trip, sets the commission of the platform
+	PackageSlug string `protobuf:"bytes,14,opt,name=packageSlug,proto3" json:"packageSlug,omitempty"`
+	// fare or tip
+	Type string `protobuf:"bytes,15,opt,name=type,proto3" json:"type,omitempty"`
+	// Charged to the saved card of the rider, without a checkout session
+	OffSession bool `protobuf:"varint,16,opt,name=offSession,proto3" json:"offSession,omitempty"`
+	// Amount in cents held on the saved card of the rider when they requested the trip, the fare is
+	// captured from it
+	HeldAmount int64 `protobuf:"varint,17,opt,name=heldAmount,proto3" json:"heldAmount,omitempty"`
+	// Rider who requested the trip, set when userID pays a share of a split fare
+	TripOwnerID string `protobuf:"bytes,18,opt,name=tripOwnerID,proto3" json:"tripOwnerID,omitempty"`
+	// Set on the charge of the part of the fare above the hold of that payment
+	HoldPaymentID string `protobuf:"bytes,19,opt,name=holdPaymentID,proto3" json:"holdPaymentID,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Payment) Reset() {
+	*x = Payment{}
+	mi := &file_payment_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Payment) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Payment) ProtoMessage() {}
+
+func (x *Payment) ProtoReflect() protoreflect.Message {
+	mi := &file_payment_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Payment.ProtoReflect.Descriptor instead.
+func (*Payment) Descriptor() ([]byte, []int) {
+	return file_payment_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *Payment) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *Payment) GetTripID() string {
+	if x != nil {
+		return x.TripID
+	}
+	return ""
+}
+
+func (x *Payment) GetUserID() string {
+	if x != nil {
+		return x.UserID
+	}
+	return ""
+}
+
+func (x *Payment) GetDriverID() string {
+	if x != nil {
+		return x.DriverID
+	}
+	return ""
+}
+
+func (x *Payment) GetAmount() int64 {
+	if x != nil {
+		return x.Amount
+	}
+	return 0
+}
+
+func (x *Payment) GetCurrency() string {
+	if x != nil {
+		return x.Currency
+	}
+	return ""
+}
+
+func (x *Payment) GetStatus() string {
+	if x != nil {
+		return x.Status
+	}
+	return ""
+}
+
+func (x *Payment) GetStripeSessionID() string {
+	if x != nil {
+		return x.StripeSessionID
+	}
+	return ""
+}
+
+func (x *Payment) GetCreatedAt() string {
+	if x != nil {
+		return x.CreatedAt
+	}
+	return ""
+}
+
+func (x *Payment) GetUpdatedAt() string {
+	if x != nil {
+		return x.UpdatedAt
+	}
+	return ""
+}
+
+func (x *Payment) GetPaymentIntentID() string {
+	if x != nil {
+		return x.PaymentIntentID
+	}
+	return ""
+}
+
+func (x *Payment) GetRefundedAmount() int64 {
+	if x != nil {
+		return x.RefundedAmount
+	}
+	return 0
+}
+
+func (x *Payment) GetRefunds() []*Refund {
+	if x != nil {
+		return x.Refunds
+	}
+	return nil
+}
+
+func (x *Payment) GetPackageSlug() string {
+	if x != nil {
+		return x.PackageSlug
+	}
+	return ""
+}
+
+func (x *Payment) GetType() string {
+	if x != nil {
+		return x.Type
+	}
+	return ""
+}
+
+func (x *Payment) GetOffSession() bool {
+	if x != nil {
+		return x.OffSession
+	}
+	return false
+}
+
+func (x *Payment) GetHeldAmount() int64 {
+	if x != nil {
+		return x.HeldAmount
+	}
+	return 0
+}
+
+func (x *Payment) GetTripOwnerID() string {
+	if x != nil {
+		return x.TripOwnerID
+	}
+	return ""
+}
+
+func (x *Payment) GetHoldPaymentID() string {
+	if x != nil {
+		return x.HoldPaymentID
+	}
+	return ""
+}
+
+type AddTipRequest struct {
+	state  protoimpl.MessageState `protogen:"open.v1"`
+	TripID string                 `protobuf:"bytes,1,opt,name=tripID,proto3" json:"tripID,omitempty"`
+	// Amount in cents
+	Amount int64 `protobuf:"varint,2,opt,name=amount,proto3" json:"amount,omitempty"`
+	// Rider of the trip, the authenticated user by default
+	UserID string `protobuf:"bytes,3,opt,name=userID,proto3" json:"userID,omitempty"`
+	// Chosen by the client, a retry with the same ID returns the tip instead of charging another one
+	TipID         string `protobuf:"bytes,4,opt,name=tipID,proto3" json:"tipID,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AddTipRequest) Reset() {
+	*x = AddTipRequest{}
+	mi := &file_payment_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AddTipRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AddTipRequest) ProtoMessage() {}
+
+func (x *AddTipRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_payment_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AddTipRequest.ProtoReflect.Descriptor instead.
+func (*AddTipRequest) Descriptor() ([]byte, []int) {
+	return file_payment_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *AddTipRequest) GetTripID() string {
+	if x != nil {
+		return x.TripID
+	}
+	return ""
+}
+
+func (x *AddTipRequest) GetAmount() int64 {
+	if x != nil {
+		return x.Amount
+	}
+	return 0
+}
+
+func (x *AddTipRequest) GetUserID() string {
+	if x != nil {
+		return x.UserID
+	}
+	return ""
+}
+
+func (x *AddTipRequest) GetTipID() string {
+	if x != nil {
+		return x.TipID
+	}
+	return ""
+}
+
+// The tip is paid with the checkout session of the payment, unless it is charged off-session
+type AddTipResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Payment       *Payment               `protobuf:"bytes,1,opt,name=payment,proto3" json:"payment,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AddTipResponse) Reset() {
+	*x = AddTipResponse{}
+	mi := &file_payment_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AddTipResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AddTipResponse) ProtoMessage() {}
+
+func (x *AddTipResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_payment_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AddTipResponse.ProtoReflect.Descriptor instead.
+func (*AddTipResponse) Descriptor() ([]byte, []int) {
+	return file_payment_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *AddTipResponse) GetPayment() *Payment {
+	if x != nil {
+		return x.Payment
+	}
+	return nil
+}
+
+type AuthorizeHoldRequest struct {
+	state       protoimpl.MessageState `protogen:"open.v1"`
+	TripID      string                 `protobuf:"bytes,1,opt,name=tripID,proto3" json:"tripID,omitempty"`
+	UserID      string                 `protobuf:"bytes,2,opt,name=userID,proto3" json:"userID,omitempty"`
+	PackageSlug string                 `protobuf:"bytes,3,opt,name=packageSlug,proto3" json:"packageSlug,omitempty"`
+	// Quoted fare in cents
+	Amount        int64  `protobuf:"varint,4,opt,name=amount,proto3" json:"amount,omitempty"`
+	Currency      string `protobuf:"bytes,5,opt,name=currency,proto3" json:"currency,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AuthorizeHoldRequest) Reset() {
+	*x = AuthorizeHoldRequest{}
+	mi := &file_payment_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AuthorizeHoldRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AuthorizeHoldRequest) ProtoMessage() {}
+
+func (x *AuthorizeHoldRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_payment_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AuthorizeHoldRequest.ProtoReflect.Descriptor instead.
+func (*AuthorizeHoldRequest) Descriptor() ([]byte, []int) {
+	return file_payment_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *AuthorizeHoldRequest) GetTripID() string {
+	if x != nil {
+		return x.TripID
+	}
+	return ""
+}
+
+func (x *AuthorizeHoldRequest) GetUserID() string {
+	if x != nil {
+		return x.UserID
+	}
+	return ""
+}
+
+func (x *AuthorizeHoldRequest) GetPackageSlug() string {
+	if x != nil {
+		return x.PackageSlug
+	}
+	return ""
+}
+
+func (x *AuthorizeHoldRequest) GetAmount() int64 {
+	if x != nil {
+		return x.Amount
+	}
+	return 0
+}
+
+func (x *AuthorizeHoldRequest) GetCurrency() string {
+	if x != nil {
+		return x.Currency
+	}
+	return ""
+}
+
+// A refused hold has a failureReason: payment_method_required, card_declined or authentication_required.
+// The payment is unset when the rider has no saved card and the holds don't require one.
+type AuthorizeHoldResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Payment       *Payment               `protobuf:"bytes,1,opt,name=payment,proto3" json:"payment,omitempty"`
+	FailureReason string                 `protobuf:"bytes,2,opt,name=failureReason,proto3" json:"failureReason,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AuthorizeHoldResponse) Reset() {
+	*x = AuthorizeHoldResponse{}
+	mi := &file_payment_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AuthorizeHoldResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AuthorizeHoldResponse) ProtoMessage() {}
+
+func (x *AuthorizeHoldResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_payment_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AuthorizeHoldResponse.ProtoReflect.Descriptor instead.
+func (*AuthorizeHoldResponse) Descriptor() ([]byte, []int) {
+	return file_payment_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *AuthorizeHoldResponse) GetPayment() *Payment {
+	if x != nil {
+		return x.Payment
+	}
+	return nil
+}
+
+func (x *AuthorizeHoldResponse) GetFailureReason() string {
+	if x != nil {
+		return x.FailureReason
+	}
+	return ""
+}
+
+type CreateSetupIntentRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	UserID        string                 `protobuf:"bytes,1,opt,name=userID,proto3" json:"userID,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CreateSetupIntentRequest) Reset() {
+	*x = CreateSetupIntentRequest{}
+	mi := &file_payment_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CreateSetupIntentRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CreateSetupIntentRequest) ProtoMessage() {}
+
+func (x *CreateSetupIntentRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_payment_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CreateSetupIntentRequest.ProtoReflect.Descriptor instead.
+func (*CreateSetupIntentRequest) Descriptor() ([]byte, []int) {
+	return file_payment_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *CreateSetupIntentRequest) GetUserID() string {
+	if x != nil {
+		return x.UserID
+	}
+	return ""
+}
+
+type CreateSetupIntentResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	SetupIntentID string                 `protobuf:"bytes,1,opt,name=setupIntentID,proto3" json:"setupIntentID,omitempty"`
+	// Passed to stripe.confirmCardSetup
+	ClientSecret  string `protobuf:"bytes,2,opt,name=clientSecret,proto3" json:"clientSecret,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CreateSetupIntentResponse) Reset() {
+	*x = CreateSetupIntentResponse{}
+	mi := &file_payment_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CreateSetupIntentResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CreateSetupIntentResponse) ProtoMessage() {}
+
+func (x *CreateSetupIntentResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_payment_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CreateSetupIntentResponse.ProtoReflect.Descriptor instead.
+func (*CreateSetupIntentResponse) Descriptor() ([]byte, []int) {
+	return file_payment_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *CreateSetupIntentResponse) GetSetupIntentID() string {
+	if x != nil {
+		return x.SetupIntentID
+	}
+	return ""
+}
+
+func (x *CreateSetupIntentResponse) GetClientSecret() string {
+	if x != nil {
+		return x.ClientSecret
+	}
+	return ""
+}
+
+type GetPaymentMethodRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	UserID        string                 `protobuf:"bytes,1,opt,name=userID,proto3" json:"userID,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetPaymentMethodRequest) Reset() {
+	*x = GetPaymentMethodRequest{}
+	mi := &file_payment_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetPaymentMethodRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetPaymentMethodRequest) ProtoMessage() {}
+
+func (x *GetPaymentMethodRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_payment_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetPaymentMethodRequest.ProtoReflect.Descriptor instead.
+func (*GetPaymentMethodRequest) Descriptor() ([]byte, []int) {
+	return file_payment_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *GetPaymentMethodRequest) GetUserID() string {
+	if x != nil {
+		return x.UserID
+	}
+	return ""
+}
+
+type GetPaymentMethodResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	PaymentMethod *PaymentMethod         `protobuf:"bytes,1,opt,name=paymentMethod,proto3" json:"paymentMethod,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetPaymentMethodResponse) Reset() {
+	*x = GetPaymentMethodResponse{}
+	mi := &file_payment_proto_msgTypes[15]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetPaymentMethodResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetPaymentMethodResponse) ProtoMessage() {}
+
+func (x *GetPaymentMethodResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_payment_proto_msgTypes[15]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetPaymentMethodResponse.ProtoReflect.Descriptor instead.
+func (*GetPaymentMethodResponse) Descriptor() ([]byte, []int) {
+	return file_payment_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *GetPaymentMethodResponse) GetPaymentMethod() *PaymentMethod {
+	if x != nil {
+		return x.PaymentMethod
+	}
+	return nil
+}
+
+type DeletePaymentMethodRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	UserID        string                 `protobuf:"bytes,1,opt,name=userID,proto3" json:"userID,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeletePaymentMethodRequest) Reset() {
+	*x = DeletePaymentMethodRequest{}
+	mi := &file_payment_proto_msgTypes[16]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeletePaymentMethodRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeletePaymentMethodRequest) ProtoMessage() {}
+
+func (x *DeletePaymentMethodRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_payment_proto_msgTypes[16]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeletePaymentMethodRequest.ProtoReflect.Descriptor instead.
+func (*DeletePaymentMethodRequest) Descriptor() ([]byte, []int) {
+	return file_payment_proto_rawDescGZIP(), []int{16}
+}
+
+func (x *DeletePaymentMethodRequest) GetUserID() string {
+	if x != nil {
+		return x.UserID
+	}
+	return ""
+}
+
+type DeletePaymentMethodResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeletePaymentMethodResponse) Reset() {
+	*x = DeletePaymentMethodResponse{}
+	mi := &file_payment_proto_msgTypes[17]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeletePaymentMethodResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeletePaymentMethodResponse) ProtoMessage() {}
+
+func (x *DeletePaymentMethodResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_payment_proto_msgTypes[17]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeletePaymentMethodResponse.ProtoReflect.Descriptor instead.
+func (*DeletePaymentMethodResponse) Descriptor() ([]byte, []int) {
+	return file_payment_proto_rawDescGZIP(), []int{17}
+}
+
+// Card saved by the rider
+type PaymentMethod struct {
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	UserID          string                 `protobuf:"bytes,1,opt,name=userID,proto3" json:"userID,omitempty"`
+	PaymentMethodID string                 `protobuf:"bytes,2,opt,name=paymentMethodID,proto3" json:"paymentMethodID,omitempty"`
+	Brand           string                 `protobuf:"bytes,3,opt,name=brand,proto3" json:"brand,omitempty"`
+	Last4           string                 `protobuf:"bytes,4,opt,name=last4,proto3" json:"last4,omitempty"`
+	ExpMonth        int32                  `protobuf:"varint,5,opt,name=expMonth,proto3" json:"expMonth,omitempty"`
+	ExpYear         int32                  `protobuf:"varint,6,opt,name=expYear,proto3" json:"expYear,omitempty"`
+	// RFC 3339 timestamp
+	UpdatedAt     string `protobuf:"bytes,7,opt,name=updatedAt,proto3" json:"updatedAt,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PaymentMethod) Reset() {
+	*x = PaymentMethod{}
+	mi := &file_payment_proto_msgTypes[18]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PaymentMethod) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PaymentMethod) ProtoMessage() {}
+
+func (x *PaymentMethod) ProtoReflect() protoreflect.Message {
+	mi := &file_payment_proto_msgTypes[18]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PaymentMethod.ProtoReflect.Descriptor instead.
+func (*PaymentMethod) Descriptor() ([]byte, []int) {
+	return file_payment_proto_rawDescGZIP(), []int{18}
+}
+
+func (x *PaymentMethod) GetUserID() string {
+	if x != nil {
+		return x.UserID
+	}
+	return ""
+}
+
+func (x *PaymentMethod) GetPaymentMethodID() string {
+	if x != nil {
+		return x.PaymentMethodID
+	}
+	return ""
+}
+
+func (x *PaymentMethod) GetBrand() string {
+	if x != nil {
+		return x.Brand
+	}
+	return ""
+}
+
+func (x *PaymentMethod) GetLast4() string {
+	if x != nil {
+		return x.Last4
+	}
+	return ""
+}
+
+func (x *PaymentMethod) GetExpMonth() int32 {
+	if x != nil {
+		return x.ExpMonth
+	}
+	return 0
+}
+
+func (x *PaymentMethod) GetExpYear() int32 {
+	if x != nil {
+		return x.ExpYear
+	}
+	return 0
+}
+
+func (x *PaymentMethod) GetUpdatedAt() string {
+	if x != nil {
+		return x.UpdatedAt
+	}
+	return ""
+}
+
+// The period is [from, to), RFC 3339 timestamps. It defaults to the last 7 days.
+type GetDriverEarningsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	DriverID      string                 `protobuf:"bytes,1,opt,name=driverID,proto3" json:"driverID,omitempty"`
+	From          string                 `protobuf:"bytes,2,opt,name=from,proto3" json:"from,omitempty"`
+	To            string                 `protobuf:"bytes,3,opt,name=to,proto3" json:"to,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetDriverEarningsRequest) Reset() {
+	*x = GetDriverEarningsRequest{}
+	mi := &file_payment_proto_msgTypes[19]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetDriverEarningsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetDriverEarningsRequest) ProtoMessage() {}
+
+func (x *GetDriverEarningsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_payment_proto_msgTypes[19]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetDriverEarningsRequest.ProtoReflect.Descriptor instead.
+func (*GetDriverEarningsRequest) Descriptor() ([]byte, []int) {
+	return file_payment_proto_rawDescGZIP(), []int{19}
+}
+
+func (x *GetDriverEarningsRequest) GetDriverID() string {
+	if x != nil {
+		return x.DriverID
+	}
+	return ""
+}
+
+func (x *GetDriverEarningsRequest) GetFrom() string {
+	if x != nil {
+		return x.From
+	}
+	return ""
+}
+
+func (x *GetDriverEarningsRequest) GetTo() string {
+	if x != nil {
+		return x.To
+	}
+	return ""
+}
+
+type GetDriverEarningsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Earnings      *DriverEarnings        `protobuf:"bytes,1,opt,name=earnings,proto3" json:"earnings,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetDriverEarningsResponse) Reset() {
+	*x = GetDriverEarningsResponse{}
+	mi := &file_payment_proto_msgTypes[20]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetDriverEarningsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetDriverEarningsResponse) ProtoMessage() {}
+
+func (x *GetDriverEarningsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_payment_proto_msgTypes[20]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetDriverEarningsResponse.ProtoReflect.Descriptor instead.
+func (*GetDriverEarningsResponse) Descriptor() ([]byte, []int) {
+	return file_payment_proto_rawDescGZIP(), []int{20}
+}
+
+func (x *GetDriverEarningsResponse) GetEarnings() *DriverEarnings {
+	if x != nil {
+		return x.Earnings
+	}
+	return nil
+}
+
+// All the amounts are in cents
+type DriverEarnings struct {
+	state    protoimpl.MessageState `protogen:"open.v1"`
+	DriverID string                 `protobuf:"bytes,1,opt,name=driverID,proto3" json:"driverID,omitempty"`
+	Currency string                 `protobuf:"bytes,2,opt,name=currency,proto3" json:"currency,omitempty"`
+	// RFC 3339 timestamps
+	From string `protobuf:"bytes,3,opt,name=from,proto3" json:"from,omitempty"`
+	To   string `protobuf:"bytes,4,opt,name=to,proto3" json:"to,omitempty"`
+	// Number of trips paid in the period
+	Trips int32 `protobuf:"varint,5,opt,name=trips,proto3" json:"trips,omitempty"`
+	// Fares paid by the riders
+	Fares int64 `protobuf:"varint,6,opt,name=fares,proto3" json:"fares,omitempty"`
+	// Commission of the platform on the fares
+	Commission int64 `protobuf:"varint,7,opt,name=commission,proto3" json:"commission,omitempty"`
+	// Fares minus the commission
+	Earnings int64 `protobuf:"varint,8,opt,name=earnings,proto3" json:"earnings,omitempty"`
+	Tips     int64 `protobuf:"varint,9,opt,name=tips,proto3" json:"tips,omitempty"`
+	// Share of the refunds taken back from the driver
+	Refunds int64 `protobuf:"varint,10,opt,name=refunds,proto3" json:"refunds,omitempty"`
+	// Earnings plus tips minus refunds
+	Net     int64 `protobuf:"varint,11,opt,name=net,proto3" json:"net,omitempty"`
+	PaidOut int64 `protobuf:"varint,12,opt,name=paidOut,proto3" json:"paidOut,omitempty"`
+	// Owed to the driver and not paid out yet, whatever the period
+	Balance       int64          `protobuf:"varint,13,opt,name=balance,proto3" json:"balance,omitempty"`
+	Entries       []*LedgerEntry `protobuf:"bytes,14,rep,name=entries,proto3" json:"entries,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DriverEarnings) Reset() {
+	*x = DriverEarnings{}
+	mi := &file_payment_proto_msgTypes[21]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DriverEarnings) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DriverEarnings) ProtoMessage() {}
+
+func (x *DriverEarnings) ProtoReflect() protoreflect.Message {
+	mi := &file_payment_proto_msgTypes[21]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DriverEarnings.ProtoReflect.Descriptor instead.
+func (*DriverEarnings) Descriptor() ([]byte, []int) {
+	return file_payment_proto_rawDescGZIP(), []int{21}
+}
+
+func (x *DriverEarnings) GetDriverID() string {
+	if x != nil {
+		return x.DriverID
+	}
+	return ""
+}
+
+func (x *DriverEarnings) GetCurrency() string {
+	if x != nil {
+		return x.Currency
+	}
+	return ""
+}
+
+func (x *DriverEarnings) GetFrom() string {
+	if x != nil {
+		return x.From
+	}
+	return ""
+}
+
+func (x *DriverEarnings) GetTo() string {
+	if x != nil {
+		return x.To
+	}
+	return ""
+}
+
+func (x *DriverEarnings) GetTrips() int32 {
+	if x != nil {
+		return x.Trips
+	}
+	return 0
+}
+
+func (x *DriverEarnings) GetFares() int64 {
+	if x != nil {
+		return x.Fares
+	}
+	return 0
+}
+
+func (x *DriverEarnings) GetCommission() int64 {
+	if x != nil {
+		return x.Commission
+	}
+	return 0
+}
+
+func (x *DriverEarnings) GetEarnings() int64 {
+	if x != nil {
+		return x.Earnings
+	}
+	return 0
+}
+
+func (x *DriverEarnings) GetTips() int64 {
+	if x != nil {
+		return x.Tips
+	}
+	return 0
+}
+
+func (x *DriverEarnings) GetRefunds() int64 {
+	if x != nil {
+		return x.Refunds
+	}
+	return 0
+}
+
+func (x *DriverEarnings) GetNet() int64 {
+	if x != nil {
+		return x.Net
+	}
+	return 0
+}
+
+func (x *DriverEarnings) GetPaidOut() int64 {
+	if x != nil {
+		return x.PaidOut
+	}
+	return 0
+}
+
+func (x *DriverEarnings) GetBalance() int64 {
+	if x != nil {
+		return x.Balance
+	}
+	return 0
+}
+
+func (x *DriverEarnings) GetEntries() []*LedgerEntry {
+	if x != nil {
+		return x.Entries
+	}
+	return nil
+}
+
+// Entry of the account of the driver, the amount is positive when it credits the driver
+type LedgerEntry struct {
+	state     protoimpl.MessageState `protogen:"open.v1"`
+	Id        string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	TripID    string                 `protobuf:"bytes,2,opt,name=tripID,proto3" json:"tripID,omitempty"`
+	PaymentID string                 `protobuf:"bytes,3,opt,name=paymentID,proto3" json:"paymentID,omitempty"`
+	// earning, tip, refund or payout
+	Kind     string `protobuf:"bytes,4,opt,name=kind,proto3" json:"kind,omitempty"`
+	Amount   int64  `protobuf:"varint,5,opt,name=amount,proto3" json:"amount,omitempty"`
+	Currency string `protobuf:"bytes,6,opt,name=currency,proto3" json:"currency,omitempty"`
+	// Payout that settled the entry
+	PayoutID string `protobuf:"bytes,7,opt,name=payoutID,proto3" json:"payoutID,omitempty"`
+	// RFC 3339 timestamp
+	CreatedAt     string `protobuf:"bytes,8,opt,name=createdAt,proto3" json:"createdAt,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *LedgerEntry) Reset() {
+	*x = LedgerEntry{}
+	mi := &file_payment_proto_msgTypes[22]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *LedgerEntry) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*LedgerEntry) ProtoMessage() {}
+
+func (x *LedgerEntry) ProtoReflect() protoreflect.Message {
+	mi := &file_payment_proto_msgTypes[22]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use LedgerEntry.ProtoReflect.Descriptor instead.
+func (*LedgerEntry) Descriptor() ([]byte, []int) {
+	return file_payment_proto_rawDescGZIP(), []int{22}
+}
+
+func (x *LedgerEntry) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *LedgerEntry) GetTripID() string {
+	if x != nil {
+		return x.TripID
+	}
+	return ""
+}
+
+func (x *LedgerEntry) GetPaymentID() string {
+	if x != nil {
+		return x.PaymentID
+	}
+	return ""
+}
+
+func (x *LedgerEntry) GetKind() string {
+	if x != nil {
+		return x.Kind
+	}
+	return ""
+}
+
+func (x *LedgerEntry) GetAmount() int64 {
+	if x != nil {
+		return x.Amount
+	}
+	return 0
+}
+
+func (x *LedgerEntry) GetCurrency() string {
+	if x != nil {
+		return x.Currency
+	}
+	return ""
+}
+
+func (x *LedgerEntry) GetPayoutID() string {
+	if x != nil {
+		return x.PayoutID
+	}
+	return ""
+}
+
+func (x *LedgerEntry) GetCreatedAt() string {
+	if x != nil {
+		return x.CreatedAt
+	}
+	return ""
+}
+
+var File_payment_proto protoreflect.FileDescriptor
+
+const file_payment_proto_rawDesc = "" +
+	"\n" +
+	"\rpayment.proto\x12\apayment\x1a\x1cgoogle/api/annotations.proto\"1\n" +
+	"\x11GetPaymentRequest\x12\x1c\n" +
+	"\tpaymentID\x18\x01 \x01(\tR\tpaymentID\"@\n" +
+	"\x12GetPaymentResponse\x12*\n" +
+	"\apayment\x18\x01 \x01(\v2\x10.payment.PaymentR\apayment\"E\n" +
+	"\x13ListPaymentsRequest\x12\x16\n" +
+	"\x06userID\x18\x01 \x01(\tR\x06userID\x12\x16\n" +
+	"\x06tripID\x18\x02 \x01(\tR\x06tripID\"D\n" +
+	"\x14ListPaymentsResponse\x12,\n" +
+	"\bpayments\x18\x01 \x03(\v2\x10.payment.PaymentR\bpayments\"d\n" +
+	"\x14RefundPaymentRequest\x12\x1c\n" +
+	"\tpaymentID\x18\x01 \x01(\tR\tpaymentID\x12\x16\n" +
+	"\x06amount\x18\x02 \x01(\x03R\x06amount\x12\x16\n" +
+	"\x06reason\x18\x03 \x01(\tR\x06reason\"l\n" +
+	"\x15RefundPaymentResponse\x12*\n" +
+	"\apayment\x18\x01 \x01(\v2\x10.payment.PaymentR\apayment\x12'\n" +
+	"\x06refund\x18\x02 \x01(\v2\x0f.payment.RefundR\x06refund\"\xb6\x01\n" +
+	"\x06Refund\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x16\n" +
+	"\x06amount\x18\x02 \x01(\x03R\x06amount\x12\x16\n" +
+	"\x06reason\x18\x03 \x01(\tR\x06reason\x12 \n" +
+	"\vrequestedBy\x18\x04 \x01(\tR\vrequestedBy\x12,\n" +
+	"\x11processorRefundID\x18\x05 \x01(\tR\x11processorRefundID\x12\x1c\n" +
+	"\tcreatedAt\x18\x06 \x01(\tR\tcreatedAt\"\xd2\x04\n" +
+	"\aPayment\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x16\n" +
+	"\x06tripID\x18\x02 \x01(\tR\x06tripID\x12\x16\n" +
+	"\x06userID\x18\x03 \x01(\tR\x06userID\x12\x1a\n" +
+	"\bdriverID\x18\x04 \x01(\tR\bdriverID\x12\x16\n" +
+	"\x06amount\x18\x05 \x01(\x03R\x06amount\x12\x1a\n" +
+	"\bcurrency\x18\x06 \x01(\tR\bcurrency\x12\x16\n" +
+	"\x06status\x18\a \x01(\tR\x06status\x12(\n" +
+	"\x0fstripeSessionID\x18\b \x01(\tR\x0fstripeSessionID\x12\x1c\n" +
+	"\tcreatedAt\x18\t \x01(\tR\tcreatedAt\x12\x1c\n" +
+	"\tupdatedAt\x18\n" +
+	" \x01(\tR\tupdatedAt\x12(\n" +
+	"\x0fpaymentIntentID\x18\v \x01(\tR\x0fpaymentIntentID\x12&\n" +
+	"\x0erefundedAmount\x18\f \x01(\x03R\x0erefundedAmount\x12)\n" +
+	"\arefunds\x18\r \x03(\v2\x0f.payment.RefundR\arefunds\x12 \n" +
+	"\vpackageSlug\x18\x0e \x01(\tR\vpackageSlug\x12\x12\n" +
+	"\x04type\x18\x0f \x01(\tR\x04type\x12\x1e\n" +
+	"\n" +
+	"offSession\x18\x10 \x01(\bR\n" +
+	"offSession\x12\x1e\n" +
+	"\n" +
+	"heldAmount\x18\x11 \x01(\x03R\n" +
+	"heldAmount\x12 \n" +
+	"\vtripOwnerID\x18\x12 \x01(\tR\vtripOwnerID\x12$\n" +
+	"\rholdPaymentID\x18\x13 \x01(\tR\rholdPaymentID\"m\n" +
+	"\rAddTipRequest\x12\x16\n" +
+	"\x06tripID\x18\x01 \x01(\tR\x06tripID\x12\x16\n" +
+	"\x06amount\x18\x02 \x01(\x03R\x06amount\x12\x16\n" +
+	"\x06userID\x18\x03 \x01(\tR\x06userID\x12\x14\n" +
+	"\x05tipID\x18\x04 \x01(\tR\x05tipID\"<\n" +
+	"\x0eAddTipResponse\x12*\n" +
+	"\apayment\x18\x01 \x01(\v2\x10.payment.PaymentR\apayment\"\x9c\x01\n" +
+	"\x14AuthorizeHoldRequest\x12\x16\n" +
+	"\x06tripID\x18\x01 \x01(\tR\x06tripID\x12\x16\n" +
+	"\x06userID\x18\x02 \x01(\tR\x06userID\x12 \n" +
+	"\vpackageSlug\x18\x03 \x01(\tR\vpackageSlug\x12\x16\n" +
+	"\x06amount\x18\x04 \x01(\x03R\x06amount\x12\x1a\n" +
+	"\bcurrency\x18\x05 \x01(\tR\bcurrency\"i\n" +
+	"\x15AuthorizeHoldResponse\x12*\n" +
+	"\apayment\x18\x01 \x01(\v2\x10.payment.PaymentR\apayment\x12$\n" +
+	"\rfailureReason\x18\x02 \x01(\tR\rfailureReason\"2\n" +
+	"\x18CreateSetupIntentRequest\x12\x16\n" +
+	"\x06userID\x18\x01 \x01(\tR\x06userID\"e\n" +
+	"\x19CreateSetupIntentResponse\x12$\n" +
+	"\rsetupIntentID\x18\x01 \x01(\tR\rsetupIntentID\x12\"\n" +
+	"\fclientSecret\x18\x02 \x01(\tR\fclientSecret\"1\n" +
+	"\x17GetPaymentMethodRequest\x12\x16\n" +
+	"\x06userID\x18\x01 \x01(\tR\x06userID\"X\n" +
+	"\x18GetPaymentMethodResponse\x12<\n" +
+	"\rpaymentMethod\x18\x01 \x01(\v2\x16.payment.PaymentMethodR\rpaymentMethod\"4\n" +
+	"\x1aDeletePaymentMethodRequest\x12\x16\n" +
+	"\x06userID\x18\x01 \x01(\tR\x06userID\"\x1d\n" +
+	"\x1bDeletePaymentMethodResponse\"\xd1\x01\n" +
+	"\rPaymentMethod\x12\x16\n" +
+	"\x06userID\x18\x01 \x01(\tR\x06userID\x12(\n" +
+	"\x0fpaymentMethodID\x18\x02 \x01(\tR\x0fpaymentMethodID\x12\x14\n" +
+	"\x05brand\x18\x03 \x01(\tR\x05brand\x12\x14\n" +
+	"\x05last4\x18\x04 \x01(\tR\x05last4\x12\x1a\n" +
+	"\bexpMonth\x18\x05 \x01(\x05R\bexpMonth\x12\x18\n" +
+	"\aexpYear\x18\x06 \x01(\x05R\aexpYear\x12\x1c\n" +
+	"\tupdatedAt\x18\a \x01(\tR\tupdatedAt\"Z\n" +
+	"\x18GetDriverEarningsRequest\x12\x1a\n" +
+	"\bdriverID\x18\x01 \x01(\tR\bdriverID\x12\x12\n" +
+	"\x04from\x18\x02 \x01(\tR\x04from\x12\x0e\n" +
+	"\x02to\x18\x03 \x01(\tR\x02to\"P\n" +
+	"\x19GetDriverEarningsResponse\x123\n" +
+	"\bearnings\x18\x01 \x01(\v2\x17.payment.DriverEarningsR\bearnings\"\xf8\x02\n" +
+	"\x0eDriverEarnings\x12\x1a\n" +
+	"\bdriverID\x18\x01 \x01(\tR\bdriverID\x12\x1a\n" +
+	"\bcurrency\x18\x02 \x01(\tR\bcurrency\x12\x12\n" +
+	"\x04from\x18\x03 \x01(\tR\x04from\x12\x0e\n" +
+	"\x02to\x18\x04 \x01(\tR\x02to\x12\x14\n" +
+	"\x05trips\x18\x05 \x01(\x05R\x05trips\x12\x14\n" +
+	"\x05fares\x18\x06 \x01(\x03R\x05fares\x12\x1e\n" +
+	"\n" +
+	"commission\x18\a \x01(\x03R\n" +
+	"commission\x12\x1a\n" +
+	"\bearnings\x18\b \x01(\x03R\bearnings\x12\x12\n" +
+	"\x04tips\x18\t \x01(\x03R\x04tips\x12\x18\n" +
+	"\arefunds\x18\n" +
+	" \x01(\x03R\arefunds\x12\x10\n" +
+	"\x03net\x18\v \x01(\x03R\x03net\x12\x18\n" +
+	"\apaidOut\x18\f \x01(\x03R\apaidOut\x12\x18\n" +
+	"\abalance\x18\r \x01(\x03R\abalance\x12.\n" +
+	"\aentries\x18\x0e \x03(\v2\x14.payment.LedgerEntryR\aentries\"\xd5\x01\n" +
+	"\vLedgerEntry\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x16\n" +
+	"\x06tripID\x18\x02 \x01(\tR\x06tripID\x12\x1c\n" +
+	"\tpaymentID\x18\x03 \x01(\tR\tpaymentID\x12\x12\n" +
+	"\x04kind\x18\x04 \x01(\tR\x04kind\x12\x16\n" +
+	"\x06amount\x18\x05 \x01(\x03R\x06amount\x12\x1a\n" +
+	"\bcurrency\x18\x06 \x01(\tR\bcurrency\x12\x1a\n" +
+	"\bpayoutID\x18\a \x01(\tR\bpayoutID\x12\x1c\n" +
+	"\tcreatedAt\x18\b \x01(\tR\tcreatedAt2\xf9\a\n" +
+	"\x0ePaymentService\x12E\n" +
+	"\n" +
+	"GetPayment\x12\x1a.payment.GetPaymentRequest\x1a\x1b.payment.GetPaymentResponse\x12K\n" +
+	"\fListPayments\x12\x1c.payment.ListPaymentsRequest\x1a\x1d.payment.ListPaymentsResponse\x12z\n" +
+	"\rRefundPayment\x12\x1d.payment.RefundPaymentRequest\x1a\x1e.payment.RefundPaymentResponse\"*\x82\xd3\xe4\x93\x02$:\x01*\"\x1f/v1/payments/{paymentID}:refund\x12]\n" +
+	"\x06AddTip\x12\x16.payment.AddTipRequest\x1a\x17.payment.AddTipResponse\"\"\x82\xd3\xe4\x93\x02\x1c:\x01*\"\x17/v1/trips/{tripID}/tips\x12\x8e\x01\n" +
+	"\x11CreateSetupIntent\x12!.payment.CreateSetupIntentRequest\x1a\".payment.CreateSetupIntentResponse\"2\x82\xd3\xe4\x93\x02,:\x01*\"'/v1/users/{userID}/payment-method:setup\x12\x82\x01\n" +
+	"\x10GetPaymentMethod\x12 .payment.GetPaymentMethodRequest\x1a!.payment.GetPaymentMethodResponse\")\x82\xd3\xe4\x93\x02#\x12!/v1/users/{userID}/payment-method\x12\x8b\x01\n" +
+	"\x13DeletePaymentMethod\x12#.payment.DeletePaymentMethodRequest\x1a$.payment.DeletePaymentMethodResponse\")\x82\xd3\xe4\x93\x02#*!/v1/users/{userID}/payment-method\x12N\n" +
+	"\rAuthorizeHold\x12\x1d.payment.AuthorizeHoldRequest\x1a\x1e.payment.AuthorizeHoldResponse\x12\x83\x01\n" +
+	"\x11GetDriverEarnings\x12!.payment.GetDriverEarningsRequest\x1a\".payment.GetDriverEarningsResponse\"'\x82\xd3\xe4\x93\x02!\x12\x1f/v1/drivers/{driverID}/earningsB\x1eZ\x1cshared/proto/payment;paymentb\x06proto3"
+
+var (
+	file_payment_proto_rawDescOnce sync.Once
+	file_payment_proto_rawDescData []byte
+)
+
+func file_payment_proto_rawDescGZIP() []byte {
+	file_payment_proto_rawDescOnce.Do(func() {
+		file_payment_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_payment_proto_rawDesc), len(file_payment_proto_rawDesc)))
+	})
+	return file_payment_proto_rawDescData
+}
+
+var file_payment_proto_msgTypes = make([]protoimpl.MessageInfo, 23)
+var file_payment_proto_goTypes = []any{
+	(*GetPaymentRequest)(nil),           // 0: payment.GetPaymentRequest
+	(*GetPaymentResponse)(nil),          // 1: payment.GetPaymentResponse
+	(*ListPaymentsRequest)(nil),         // 2: payment.ListPaymentsRequest
+	(*ListPaymentsResponse)(nil),        // 3: payment.ListPaymentsResponse
+	(*RefundPaymentRequest)(nil),        // 4: payment.RefundPaymentRequest
+	(*RefundPaymentResponse)(nil),       // 5: payment.RefundPaymentResponse
+	(*Refund)(nil),                      // 6: payment.Refund
+	(*Payment)(nil),                     // 7: payment.Payment
+	(*AddTipRequest)(nil),               // 8: payment.AddTipRequest
+	(*AddTipResponse)(nil),              // 9: payment.AddTipResponse
+	(*AuthorizeHoldRequest)(nil),        // 10: payment.AuthorizeHoldRequest
+	(*AuthorizeHoldResponse)(nil),       // 11: payment.AuthorizeHoldResponse
+	(*CreateSetupIntentRequest)(nil),    // 12: payment.CreateSetupIntentRequest
+	(*CreateSetupIntentResponse)(nil),   // 13: payment.CreateSetupIntentResponse
+	(*GetPaymentMethodRequest)(nil),     // 14: payment.GetPaymentMethodRequest
+	(*GetPaymentMethodResponse)(nil),    // 15: payment.GetPaymentMethodResponse
+	(*DeletePaymentMethodRequest)(nil),  // 16: payment.DeletePaymentMethodRequest
+	(*DeletePaymentMethodResponse)(nil), // 17: payment.DeletePaymentMethodResponse
+	(*PaymentMethod)(nil),               // 18: payment.PaymentMethod
+	(*GetDriverEarningsRequest)(nil),    // 19: payment.GetDriverEarningsRequest
+	(*GetDriverEarningsResponse)(nil),   // 20: payment.GetDriverEarningsResponse
+	(*DriverEarnings)(nil),              // 21: payment.DriverEarnings
+	(*LedgerEntry)(nil),                 // 22: payment.LedgerEntry
+}
+var file_payment_proto_depIdxs = []int32{
+	7,  // 0: payment.GetPaymentResponse.payment:type_name -> payment.Payment
+	7,  // 1: payment.ListPaymentsResponse.payments:type_name -> payment.Payment
+	7,  // 2: payment.RefundPaymentResponse.payment:type_name -> payment.Payment
+	6,  // 3: payment.RefundPaymentResponse.refund:type_name -> payment.Refund
+	6,  // 4: payment.Payment.refunds:type_name -> payment.Refund
+	7,  // 5: payment.AddTipResponse.payment:type_name -> payment.Payment
+	7,  // 6: payment.AuthorizeHoldResponse.payment:type_name -> payment.Payment
+	18, // 7: payment.GetPaymentMethodResponse.paymentMethod:type_name -> payment.PaymentMethod
+	21, // 8: payment.GetDriverEarningsResponse.earnings:type_name -> payment.DriverEarnings
+	22, // 9: payment.DriverEarnings.entries:type_name -> payment.LedgerEntry
+	0,  // 10: payment.PaymentService.GetPayment:input_type -> payment.GetPaymentRequest
+	2,  // 11: payment.PaymentService.ListPayments:input_type -> payment.ListPaymentsRequest
+	4,  // 12: payment.PaymentService.RefundPayment:input_type -> payment.RefundPaymentRequest
+	8,  // 13: payment.PaymentService.AddTip:input_type -> payment.AddTipRequest
+	12, // 14: payment.PaymentService.CreateSetupIntent:input_type -> payment.CreateSetupIntentRequest
+	14, // 15: payment.PaymentService.GetPaymentMethod:input_type -> payment.GetPaymentMethodRequest
+	16, // 16: payment.PaymentService.DeletePaymentMethod:input_type -> payment.DeletePaymentMethodRequest
+	10, // 17: payment.PaymentService.AuthorizeHold:input_type -> payment.AuthorizeHoldRequest
+	19, // 18: payment.PaymentService.GetDriverEarnings:input_type -> payment.GetDriverEarningsRequest
+	1,  // 19: payment.PaymentService.GetPayment:output_type -> payment.GetPaymentResponse
+	3,  // 20: payment.PaymentService.ListPayments:output_type -> payment.ListPaymentsResponse
+	5,  // 21: payment.PaymentService.RefundPayment:output_type -> payment.RefundPaymentResponse
+	9,  // 22: payment.PaymentService.AddTip:output_type -> payment.AddTipResponse
+	13, // 23: payment.PaymentService.CreateSetupIntent:output_type -> payment.CreateSetupIntentResponse
+	15, // 24: payment.PaymentService.GetPaymentMethod:output_type -> payment.GetPaymentMethodResponse
+	17, // 25: payment.PaymentService.DeletePaymentMethod:output_type -> payment.DeletePaymentMethodResponse
+	11, // 26: payment.PaymentService.AuthorizeHold:output_type -> payment.AuthorizeHoldResponse
+	20, // 27: payment.PaymentService.GetDriverEarnings:output_type -> payment.GetDriverEarningsResponse
+	19, // [19:28] is the sub-list for method output_type
+	10, // [10:19] is the sub-list for method input_type
+	10, // [10:10] is the sub-list for extension type_name
+	10, // [10:10] is the sub-list for extension extendee
+	0,  // [0:10] is the sub-list for field type_name
+}
+
+func init() { file_payment_proto_init() }
+func file_payment_proto_init() {
+	if File_payment_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_payment_proto_rawDesc), len(file_payment_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   23,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_payment_proto_goTypes,
+		DependencyIndexes: file_payment_proto_depIdxs,
+		MessageInfos:      file_payment_proto_msgTypes,
+	}.Build()
+	File_payment_proto = out.File
+	file_payment_proto_goTypes = nil
+	file_payment_proto_depIdxs = nil
+}
@@ -7,6 +7,7 @@
 package trip
 
 import (
+	_ "google.golang.org/genproto/googleapis/api/annotations"
 	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
 	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
 	reflect "reflect"
@@ -469,33 +470,812 @@ func (x *CreateTripResponse) GetTrip() *Trip {
 	return nil
 }
 
+// Returns the trip in progress of a rider or a driver
+type GetActiveTripRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	UserID        string                 `protobuf:"bytes,1,opt,name=userID,proto3" json:"userID,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetActiveTripRequest) Reset() {
+	*x = GetActiveTripRequest{}
+	mi := &file_trip_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetActiveTripRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetActiveTripRequest) ProtoMessage() {}
+
+func (x *GetActiveTripRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_trip_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetActiveTripRequest.ProtoReflect.Descriptor instead.
+func (*GetActiveTripRequest) Descriptor() ([]byte, []int) {
+	return file_trip_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *GetActiveTripRequest) GetUserID() string {
+	if x != nil {
+		return x.UserID
+	}
+	return ""
+}
+
+type GetActiveTripResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Trip          *Trip                  `protobuf:"bytes,1,opt,name=trip,proto3" json:"trip,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetActiveTripResponse) Reset() {
+	*x = GetActiveTripResponse{}
+	mi := &file_trip_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetActiveTripResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetActiveTripResponse) ProtoMessage() {}
+
+func (x *GetActiveTripResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_trip_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetActiveTripResponse.ProtoReflect.Descriptor instead.
+func (*GetActiveTripResponse) Descriptor() ([]byte, []int) {
+	return file_trip_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *GetActiveTripResponse) GetTrip() *Trip {
+	if x != nil {
+		return x.Trip
+	}
+	return nil
+}
+
 type Trip struct {
+	state        protoimpl.MessageState `protogen:"open.v1"`
+	Id           string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	SelectedFare *RideFare              `protobuf:"bytes,2,opt,name=selectedFare,proto3" json:"selectedFare,omitempty"`
+	Route        *Route                 `protobuf:"bytes,3,opt,name=route,proto3" json:"route,omitempty"`
+	Status       string                 `protobuf:"bytes,4,opt,name=status,proto3" json:"status,omitempty"`
+	UserID       string                 `protobuf:"bytes,5,opt,name=userID,proto3" json:"userID,omitempty"`
+	Driver       *TripDriver            `protobuf:"bytes,6,opt,name=driver,proto3" json:"driver,omitempty"`
+	// Set once the rider was refunded
+	Refund *TripRefund `protobuf:"bytes,7,opt,name=refund,proto3" json:"refund,omitempty"`
+	// Set once the rider invited other riders to split the fare
+	Split         *TripSplit `protobuf:"bytes,8,opt,name=split,proto3" json:"split,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Trip) Reset() {
+	*x = Trip{}
+	mi := &file_trip_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Trip) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Trip) ProtoMessage() {}
+
+func (x *Trip) ProtoReflect() protoreflect.Message {
+	mi := &file_trip_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Trip.ProtoReflect.Descriptor instead.
+func (*Trip) Descriptor() ([]byte, []int) {
+	return file_trip_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *Trip) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *Trip) GetSelectedFare() *RideFare {
+	if x != nil {
+		return x.SelectedFare
+	}
+	return nil
+}
+
+func (x *Trip) GetRoute() *Route {
+	if x != nil {
+		return x.Route
+	}
+	return nil
+}
+
+func (x *Trip) GetStatus() string {
+	if x != nil {
+		return x.Status
+	}
+	return ""
+}
+
+func (x *Trip) GetUserID() string {
+	if x != nil {
+		return x.UserID
+	}
+	return ""
+}
+
+func (x *Trip) GetDriver() *TripDriver {
+	if x != nil {
+		return x.Driver
+	}
+	return nil
+}
+
+func (x *Trip) GetRefund() *TripRefund {
+	if x != nil {
+		return x.Refund
+	}
+	return nil
+}
+
+func (x *Trip) GetSplit() *TripSplit {
+	if x != nil {
+		return x.Split
+	}
+	return nil
+}
+
+type TripRefund struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Sum of the refunds in cents
+	RefundedAmount int64  `protobuf:"varint,1,opt,name=refundedAmount,proto3" json:"refundedAmount,omitempty"`
+	Currency       string `protobuf:"bytes,2,opt,name=currency,proto3" json:"currency,omitempty"`
+	// Reason of the last refund
+	Reason string `protobuf:"bytes,3,opt,name=reason,proto3" json:"reason,omitempty"`
+	Full   bool   `protobuf:"varint,4,opt,name=full,proto3" json:"full,omitempty"`
+	// RFC 3339 timestamp of the last refund
+	RefundedAt    string `protobuf:"bytes,5,opt,name=refundedAt,proto3" json:"refundedAt,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *TripRefund) Reset() {
+	*x = TripRefund{}
+	mi := &file_trip_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TripRefund) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TripRefund) ProtoMessage() {}
+
+func (x *TripRefund) ProtoReflect() protoreflect.Message {
+	mi := &file_trip_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TripRefund.ProtoReflect.Descriptor instead.
+func (*TripRefund) Descriptor() ([]byte, []int) {
+	return file_trip_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *TripRefund) GetRefundedAmount() int64 {
+	if x != nil {
+		return x.RefundedAmount
+	}
+	return 0
+}
+
+func (x *TripRefund) GetCurrency() string {
+	if x != nil {
+		return x.Currency
+	}
+	return ""
+}
+
+func (x *TripRefund) GetReason() string {
+	if x != nil {
+		return x.Reason
+	}
+	return ""
+}
+
+func (x *TripRefund) GetFull() bool {
+	if x != nil {
+		return x.Full
+	}
+	return false
+}
+
+func (x *TripRefund) GetRefundedAt() string {
+	if x != nil {
+		return x.RefundedAt
+	}
+	return ""
+}
+
+// Static driver object that is used to store the driver information
+type TripDriver struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	Id             string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Name           string                 `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
+	ProfilePicture string                 `protobuf:"bytes,3,opt,name=profilePicture,proto3" json:"profilePicture,omitempty"`
+	CarPlate       string                 `protobuf:"bytes,4,opt,name=carPlate,proto3" json:"carPlate,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *TripDriver) Reset() {
+	*x = TripDriver{}
+	mi := &file_trip_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TripDriver) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TripDriver) ProtoMessage() {}
+
+func (x *TripDriver) ProtoReflect() protoreflect.Message {
+	mi := &file_trip_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TripDriver.ProtoReflect.Descriptor instead.
+func (*TripDriver) Descriptor() ([]byte, []int) {
+	return file_trip_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *TripDriver) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *TripDriver) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *TripDriver) GetProfilePicture() string {
+	if x != nil {
+		return x.ProfilePicture
+	}
+	return ""
+}
+
+func (x *TripDriver) GetCarPlate() string {
+	if x != nil {
+		return x.CarPlate
+	}
+	return ""
+}
+
+// Returns the chat of a trip, userID is the participant reading it
+type GetChatMessagesRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	TripID        string                 `protobuf:"bytes,1,opt,name=tripID,proto3" json:"tripID,omitempty"`
+	UserID        string                 `protobuf:"bytes,2,opt,name=userID,proto3" json:"userID,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetChatMessagesRequest) Reset() {
+	*x = GetChatMessagesRequest{}
+	mi := &file_trip_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetChatMessagesRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetChatMessagesRequest) ProtoMessage() {}
+
+func (x *GetChatMessagesRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_trip_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetChatMessagesRequest.ProtoReflect.Descriptor instead.
+func (*GetChatMessagesRequest) Descriptor() ([]byte, []int) {
+	return file_trip_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *GetChatMessagesRequest) GetTripID() string {
+	if x != nil {
+		return x.TripID
+	}
+	return ""
+}
+
+func (x *GetChatMessagesRequest) GetUserID() string {
+	if x != nil {
+		return x.UserID
+	}
+	return ""
+}
+
+type GetChatMessagesResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Messages      []*ChatMessage         `protobuf:"bytes,1,rep,name=messages,proto3" json:"messages,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetChatMessagesResponse) Reset() {
+	*x = GetChatMessagesResponse{}
+	mi := &file_trip_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetChatMessagesResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetChatMessagesResponse) ProtoMessage() {}
+
+func (x *GetChatMessagesResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_trip_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetChatMessagesResponse.ProtoReflect.Descriptor instead.
+func (*GetChatMessagesResponse) Descriptor() ([]byte, []int) {
+	return file_trip_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *GetChatMessagesResponse) GetMessages() []*ChatMessage {
+	if x != nil {
+		return x.Messages
+	}
+	return nil
+}
+
+// Returns the quick replies userID can send in the chat of the trip
+type ListQuickRepliesRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	TripID        string                 `protobuf:"bytes,1,opt,name=tripID,proto3" json:"tripID,omitempty"`
+	UserID        string                 `protobuf:"bytes,2,opt,name=userID,proto3" json:"userID,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListQuickRepliesRequest) Reset() {
+	*x = ListQuickRepliesRequest{}
+	mi := &file_trip_proto_msgTypes[15]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListQuickRepliesRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListQuickRepliesRequest) ProtoMessage() {}
+
+func (x *ListQuickRepliesRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_trip_proto_msgTypes[15]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListQuickRepliesRequest.ProtoReflect.Descriptor instead.
+func (*ListQuickRepliesRequest) Descriptor() ([]byte, []int) {
+	return file_trip_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *ListQuickRepliesRequest) GetTripID() string {
+	if x != nil {
+		return x.TripID
+	}
+	return ""
+}
+
+func (x *ListQuickRepliesRequest) GetUserID() string {
+	if x != nil {
+		return x.UserID
+	}
+	return ""
+}
+
+type ListQuickRepliesResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	QuickReplies  []*QuickReply          `protobuf:"bytes,1,rep,name=quickReplies,proto3" json:"quickReplies,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListQuickRepliesResponse) Reset() {
+	*x = ListQuickRepliesResponse{}
+	mi := &file_trip_proto_msgTypes[16]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListQuickRepliesResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListQuickRepliesResponse) ProtoMessage() {}
+
+func (x *ListQuickRepliesResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_trip_proto_msgTypes[16]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListQuickRepliesResponse.ProtoReflect.Descriptor instead.
+func (*ListQuickRepliesResponse) Descriptor() ([]byte, []int) {
+	return file_trip_proto_rawDescGZIP(), []int{16}
+}
+
+func (x *ListQuickRepliesResponse) GetQuickReplies() []*QuickReply {
+	if x != nil {
+		return x.QuickReplies
+	}
+	return nil
+}
+
+type ChatMessage struct {
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	Id              string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	TripID          string                 `protobuf:"bytes,2,opt,name=tripID,proto3" json:"tripID,omitempty"`
+	SenderID        string                 `protobuf:"bytes,3,opt,name=senderID,proto3" json:"senderID,omitempty"`
+	RecipientID     string                 `protobuf:"bytes,4,opt,name=recipientID,proto3" json:"recipientID,omitempty"`
+	Text            string                 `protobuf:"bytes,5,opt,name=text,proto3" json:"text,omitempty"`
+	TemplateID      string                 `protobuf:"bytes,6,opt,name=templateID,proto3" json:"templateID,omitempty"`
+	ClientMessageID string                 `protobuf:"bytes,7,opt,name=clientMessageID,proto3" json:"clientMessageID,omitempty"`
+	// sent, delivered or read
+	Status string `protobuf:"bytes,8,opt,name=status,proto3" json:"status,omitempty"`
+	// RFC 3339 timestamps, empty until the message reaches the status
+	SentAt        string `protobuf:"bytes,9,opt,name=sentAt,proto3" json:"sentAt,omitempty"`
+	DeliveredAt   string `protobuf:"bytes,10,opt,name=deliveredAt,proto3" json:"deliveredAt,omitempty"`
+	ReadAt        string `protobuf:"bytes,11,opt,name=readAt,proto3" json:"readAt,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ChatMessage) Reset() {
+	*x = ChatMessage{}
+	mi := &file_trip_proto_msgTypes[17]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ChatMessage) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ChatMessage) ProtoMessage() {}
+
+func (x *ChatMessage) ProtoReflect() protoreflect.Message {
+	mi := &file_trip_proto_msgTypes[17]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ChatMessage.ProtoReflect.Descriptor instead.
+func (*ChatMessage) Descriptor() ([]byte, []int) {
+	return file_trip_proto_rawDescGZIP(), []int{17}
+}
+
+func (x *ChatMessage) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *ChatMessage) GetTripID() string {
+	if x != nil {
+		return x.TripID
+	}
+	return ""
+}
+
+func (x *ChatMessage) GetSenderID() string {
+	if x != nil {
+		return x.SenderID
+	}
+	return ""
+}
+
+func (x *ChatMessage) GetRecipientID() string {
+	if x != nil {
+		return x.RecipientID
+	}
+	return ""
+}
+
+func (x *ChatMessage) GetText() string {
+	if x != nil {
+		return x.Text
+	}
+	return ""
+}
+
+func (x *ChatMessage) GetTemplateID() string {
+	if x != nil {
+		return x.TemplateID
+	}
+	return ""
+}
+
+func (x *ChatMessage) GetClientMessageID() string {
+	if x != nil {
+		return x.ClientMessageID
+	}
+	return ""
+}
+
+func (x *ChatMessage) GetStatus() string {
+	if x != nil {
+		return x.Status
+	}
+	return ""
+}
+
+func (x *ChatMessage) GetSentAt() string {
+	if x != nil {
+		return x.SentAt
+	}
+	return ""
+}
+
+func (x *ChatMessage) GetDeliveredAt() string {
+	if x != nil {
+		return x.DeliveredAt
+	}
+	return ""
+}
+
+func (x *ChatMessage) GetReadAt() string {
+	if x != nil {
+		return x.ReadAt
+	}
+	return ""
+}
+
+type QuickReply struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
-	SelectedFare  *RideFare              `protobuf:"bytes,2,opt,name=selectedFare,proto3" json:"selectedFare,omitempty"`
-	Route         *Route                 `protobuf:"bytes,3,opt,name=route,proto3" json:"route,omitempty"`
-	Status        string                 `protobuf:"bytes,4,opt,name=status,proto3" json:"status,omitempty"`
-	UserID        string                 `protobuf:"bytes,5,opt,name=userID,proto3" json:"userID,omitempty"`
-	Driver        *TripDriver            `protobuf:"bytes,6,opt,name=driver,proto3" json:"driver,omitempty"`
+	Text          string                 `protobuf:"bytes,2,opt,name=text,proto3" json:"text,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *QuickReply) Reset() {
+	*x = QuickReply{}
+	mi := &file_trip_proto_msgTypes[18]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *QuickReply) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*QuickReply) ProtoMessage() {}
+
+func (x *QuickReply) ProtoReflect() protoreflect.Message {
+	mi := &file_trip_proto_msgTypes[18]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use QuickReply.ProtoReflect.Descriptor instead.
+func (*QuickReply) Descriptor() ([]byte, []int) {
+	return file_trip_proto_rawDescGZIP(), []int{18}
+}
+
+func (x *QuickReply) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *QuickReply) GetText() string {
+	if x != nil {
+		return x.Text
+	}
+	return ""
+}
+
+// Invites riders to split the fare of the trip, userID is the rider who requested it. Inviting again
+// replaces the invitations, the riders who already answered keep their answer.
+type SplitFareRequest struct {
+	state  protoimpl.MessageState `protogen:"open.v1"`
+	TripID string                 `protobuf:"bytes,1,opt,name=tripID,proto3" json:"tripID,omitempty"`
+	UserID string                 `protobuf:"bytes,2,opt,name=userID,proto3" json:"userID,omitempty"`
+	// equal or custom
+	Mode          string         `protobuf:"bytes,3,opt,name=mode,proto3" json:"mode,omitempty"`
+	Participants  []*SplitInvite `protobuf:"bytes,4,rep,name=participants,proto3" json:"participants,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SplitFareRequest) Reset() {
+	*x = SplitFareRequest{}
+	mi := &file_trip_proto_msgTypes[19]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SplitFareRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SplitFareRequest) ProtoMessage() {}
+
+func (x *SplitFareRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_trip_proto_msgTypes[19]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SplitFareRequest.ProtoReflect.Descriptor instead.
+func (*SplitFareRequest) Descriptor() ([]byte, []int) {
+	return file_trip_proto_rawDescGZIP(), []int{19}
+}
+
+func (x *SplitFareRequest) GetTripID() string {
+	if x != nil {
+		return x.TripID
+	}
+	return ""
+}
+
+func (x *SplitFareRequest) GetUserID() string {
+	if x != nil {
+		return x.UserID
+	}
+	return ""
+}
+
+func (x *SplitFareRequest) GetMode() string {
+	if x != nil {
+		return x.Mode
+	}
+	return ""
+}
+
+func (x *SplitFareRequest) GetParticipants() []*SplitInvite {
+	if x != nil {
+		return x.Participants
+	}
+	return nil
+}
+
+type SplitInvite struct {
+	state  protoimpl.MessageState `protogen:"open.v1"`
+	UserID string                 `protobuf:"bytes,1,opt,name=userID,proto3" json:"userID,omitempty"`
+	// Part of the fare in cents, required in custom mode
+	Share         int64 `protobuf:"varint,2,opt,name=share,proto3" json:"share,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *Trip) Reset() {
-	*x = Trip{}
-	mi := &file_trip_proto_msgTypes[8]
+func (x *SplitInvite) Reset() {
+	*x = SplitInvite{}
+	mi := &file_trip_proto_msgTypes[20]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *Trip) String() string {
+func (x *SplitInvite) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*Trip) ProtoMessage() {}
+func (*SplitInvite) ProtoMessage() {}
 
-func (x *Trip) ProtoReflect() protoreflect.Message {
-	mi := &file_trip_proto_msgTypes[8]
+func (x *SplitInvite) ProtoReflect() protoreflect.Message {
+	mi := &file_trip_proto_msgTypes[20]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -506,79 +1286,193 @@ func (x *Trip) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use Trip.ProtoReflect.Descriptor instead.
-func (*Trip) Descriptor() ([]byte, []int) {
-	return file_trip_proto_rawDescGZIP(), []int{8}
+// Deprecated: Use SplitInvite.ProtoReflect.Descriptor instead.
+func (*SplitInvite) Descriptor() ([]byte, []int) {
+	return file_trip_proto_rawDescGZIP(), []int{20}
 }
 
-func (x *Trip) GetId() string {
+func (x *SplitInvite) GetUserID() string {
 	if x != nil {
-		return x.Id
+		return x.UserID
 	}
 	return ""
 }
 
-func (x *Trip) GetSelectedFare() *RideFare {
+func (x *SplitInvite) GetShare() int64 {
 	if x != nil {
-		return x.SelectedFare
+		return x.Share
 	}
-	return nil
+	return 0
 }
 
-func (x *Trip) GetRoute() *Route {
+type SplitFareResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Trip          *Trip                  `protobuf:"bytes,1,opt,name=trip,proto3" json:"trip,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SplitFareResponse) Reset() {
+	*x = SplitFareResponse{}
+	mi := &file_trip_proto_msgTypes[21]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SplitFareResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SplitFareResponse) ProtoMessage() {}
+
+func (x *SplitFareResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_trip_proto_msgTypes[21]
 	if x != nil {
-		return x.Route
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SplitFareResponse.ProtoReflect.Descriptor instead.
+func (*SplitFareResponse) Descriptor() ([]byte, []int) {
+	return file_trip_proto_rawDescGZIP(), []int{21}
+}
+
+func (x *SplitFareResponse) GetTrip() *Trip {
+	if x != nil {
+		return x.Trip
 	}
 	return nil
 }
 
-func (x *Trip) GetStatus() string {
+// Cancels the trip until a driver accepts it, userID is the rider who requested it. The hold of the
+// fare is released.
+type CancelTripRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	TripID        string                 `protobuf:"bytes,1,opt,name=tripID,proto3" json:"tripID,omitempty"`
+	UserID        string                 `protobuf:"bytes,2,opt,name=userID,proto3" json:"userID,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CancelTripRequest) Reset() {
+	*x = CancelTripRequest{}
+	mi := &file_trip_proto_msgTypes[22]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CancelTripRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CancelTripRequest) ProtoMessage() {}
+
+func (x *CancelTripRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_trip_proto_msgTypes[22]
 	if x != nil {
-		return x.Status
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CancelTripRequest.ProtoReflect.Descriptor instead.
+func (*CancelTripRequest) Descriptor() ([]byte, []int) {
+	return file_trip_proto_rawDescGZIP(), []int{22}
+}
+
+func (x *CancelTripRequest) GetTripID() string {
+	if x != nil {
+		return x.TripID
 	}
 	return ""
 }
 
-func (x *Trip) GetUserID() string {
+func (x *CancelTripRequest) GetUserID() string {
 	if x != nil {
 		return x.UserID
 	}
 	return ""
 }
 
-func (x *Trip) GetDriver() *TripDriver {
+type CancelTripResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Trip          *Trip                  `protobuf:"bytes,1,opt,name=trip,proto3" json:"trip,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CancelTripResponse) Reset() {
+	*x = CancelTripResponse{}
+	mi := &file_trip_proto_msgTypes[23]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CancelTripResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CancelTripResponse) ProtoMessage() {}
+
+func (x *CancelTripResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_trip_proto_msgTypes[23]
 	if x != nil {
-		return x.Driver
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CancelTripResponse.ProtoReflect.Descriptor instead.
+func (*CancelTripResponse) Descriptor() ([]byte, []int) {
+	return file_trip_proto_rawDescGZIP(), []int{23}
+}
+
+func (x *CancelTripResponse) GetTrip() *Trip {
+	if x != nil {
+		return x.Trip
 	}
 	return nil
 }
 
-// Static driver object that is used to store the driver information
-type TripDriver struct {
-	state          protoimpl.MessageState `protogen:"open.v1"`
-	Id             string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
-	Name           string                 `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
-	ProfilePicture string                 `protobuf:"bytes,3,opt,name=profilePicture,proto3" json:"profilePicture,omitempty"`
-	CarPlate       string                 `protobuf:"bytes,4,opt,name=carPlate,proto3" json:"carPlate,omitempty"`
-	unknownFields  protoimpl.UnknownFields
-	sizeCache      protoimpl.SizeCache
+type TripSplit struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// equal or custom
+	Mode         string              `protobuf:"bytes,1,opt,name=mode,proto3" json:"mode,omitempty"`
+	Participants []*SplitParticipant `protobuf:"bytes,2,rep,name=participants,proto3" json:"participants,omitempty"`
+	// Set when a driver accepts the trip, the rider who requested it included
+	Shares        []*SplitShare `protobuf:"bytes,3,rep,name=shares,proto3" json:"shares,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
 }
 
-func (x *TripDriver) Reset() {
-	*x = TripDriver{}
-	mi := &file_trip_proto_msgTypes[9]
+func (x *TripSplit) Reset() {
+	*x = TripSplit{}
+	mi := &file_trip_proto_msgTypes[24]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *TripDriver) String() string {
+func (x *TripSplit) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*TripDriver) ProtoMessage() {}
+func (*TripSplit) ProtoMessage() {}
 
-func (x *TripDriver) ProtoReflect() protoreflect.Message {
-	mi := &file_trip_proto_msgTypes[9]
+func (x *TripSplit) ProtoReflect() protoreflect.Message {
+	mi := &file_trip_proto_msgTypes[24]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -589,45 +1483,177 @@ func (x *TripDriver) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use TripDriver.ProtoReflect.Descriptor instead.
-func (*TripDriver) Descriptor() ([]byte, []int) {
-	return file_trip_proto_rawDescGZIP(), []int{9}
+// Deprecated: Use TripSplit.ProtoReflect.Descriptor instead.
+func (*TripSplit) Descriptor() ([]byte, []int) {
+	return file_trip_proto_rawDescGZIP(), []int{24}
 }
 
-func (x *TripDriver) GetId() string {
+func (x *TripSplit) GetMode() string {
 	if x != nil {
-		return x.Id
+		return x.Mode
 	}
 	return ""
 }
 
-func (x *TripDriver) GetName() string {
+func (x *TripSplit) GetParticipants() []*SplitParticipant {
 	if x != nil {
-		return x.Name
+		return x.Participants
+	}
+	return nil
+}
+
+func (x *TripSplit) GetShares() []*SplitShare {
+	if x != nil {
+		return x.Shares
+	}
+	return nil
+}
+
+type SplitParticipant struct {
+	state  protoimpl.MessageState `protogen:"open.v1"`
+	UserID string                 `protobuf:"bytes,1,opt,name=userID,proto3" json:"userID,omitempty"`
+	Share  int64                  `protobuf:"varint,2,opt,name=share,proto3" json:"share,omitempty"`
+	// invited, accepted or declined
+	Status string `protobuf:"bytes,3,opt,name=status,proto3" json:"status,omitempty"`
+	// RFC 3339 timestamps
+	InvitedAt     string `protobuf:"bytes,4,opt,name=invitedAt,proto3" json:"invitedAt,omitempty"`
+	RespondedAt   string `protobuf:"bytes,5,opt,name=respondedAt,proto3" json:"respondedAt,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SplitParticipant) Reset() {
+	*x = SplitParticipant{}
+	mi := &file_trip_proto_msgTypes[25]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SplitParticipant) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SplitParticipant) ProtoMessage() {}
+
+func (x *SplitParticipant) ProtoReflect() protoreflect.Message {
+	mi := &file_trip_proto_msgTypes[25]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SplitParticipant.ProtoReflect.Descriptor instead.
+func (*SplitParticipant) Descriptor() ([]byte, []int) {
+	return file_trip_proto_rawDescGZIP(), []int{25}
+}
+
+func (x *SplitParticipant) GetUserID() string {
+	if x != nil {
+		return x.UserID
 	}
 	return ""
 }
 
-func (x *TripDriver) GetProfilePicture() string {
+func (x *SplitParticipant) GetShare() int64 {
 	if x != nil {
-		return x.ProfilePicture
+		return x.Share
+	}
+	return 0
+}
+
+func (x *SplitParticipant) GetStatus() string {
+	if x != nil {
+		return x.Status
 	}
 	return ""
 }
 
-func (x *TripDriver) GetCarPlate() string {
+func (x *SplitParticipant) GetInvitedAt() string {
 	if x != nil {
-		return x.CarPlate
+		return x.InvitedAt
+	}
+	return ""
+}
+
+func (x *SplitParticipant) GetRespondedAt() string {
+	if x != nil {
+		return x.RespondedAt
+	}
+	return ""
+}
+
+type SplitShare struct {
+	state  protoimpl.MessageState `protogen:"open.v1"`
+	UserID string                 `protobuf:"bytes,1,opt,name=userID,proto3" json:"userID,omitempty"`
+	// In cents
+	Amount        int64 `protobuf:"varint,2,opt,name=amount,proto3" json:"amount,omitempty"`
+	Paid          bool  `protobuf:"varint,3,opt,name=paid,proto3" json:"paid,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SplitShare) Reset() {
+	*x = SplitShare{}
+	mi := &file_trip_proto_msgTypes[26]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SplitShare) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SplitShare) ProtoMessage() {}
+
+func (x *SplitShare) ProtoReflect() protoreflect.Message {
+	mi := &file_trip_proto_msgTypes[26]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SplitShare.ProtoReflect.Descriptor instead.
+func (*SplitShare) Descriptor() ([]byte, []int) {
+	return file_trip_proto_rawDescGZIP(), []int{26}
+}
+
+func (x *SplitShare) GetUserID() string {
+	if x != nil {
+		return x.UserID
 	}
 	return ""
 }
 
+func (x *SplitShare) GetAmount() int64 {
+	if x != nil {
+		return x.Amount
+	}
+	return 0
+}
+
+func (x *SplitShare) GetPaid() bool {
+	if x != nil {
+		return x.Paid
+	}
+	return false
+}
+
 var File_trip_proto protoreflect.FileDescriptor
 
 const file_trip_proto_rawDesc = "" +
 	"\n" +
 	"\n" +
-	"trip.proto\x12\x04trip\"\x98\x01\n" +
+	"trip.proto\x12\x04trip\x1a\x1cgoogle/api/annotations.proto\"\x98\x01\n" +
 	"\x12PreviewTripRequest\x12\x16\n" +
 	"\x06userID\x18\x01 \x01(\tR\x06userID\x126\n" +
 	"\rstartLocation\x18\x02 \x01(\v2\x10.trip.CoordinateR\rstartLocation\x122\n" +
@@ -659,24 +1685,107 @@ const file_trip_proto_rawDesc = "" +
 	"\x12CreateTripResponse\x12\x16\n" +
 	"\x06tripID\x18\x01 \x01(\tR\x06tripID\x12\x1e\n" +
 	"\x04trip\x18\x02 \x01(\v2\n" +
-	".trip.TripR\x04trip\"\xc7\x01\n" +
+	".trip.TripR\x04trip\".\n" +
+	"\x14GetActiveTripRequest\x12\x16\n" +
+	"\x06userID\x18\x01 \x01(\tR\x06userID\"7\n" +
+	"\x15GetActiveTripResponse\x12\x1e\n" +
+	"\x04trip\x18\x01 \x01(\v2\n" +
+	".trip.TripR\x04trip\"\x98\x02\n" +
 	"\x04Trip\x12\x0e\n" +
 	"\x02id\x18\x01 \x01(\tR\x02id\x122\n" +
 	"\fselectedFare\x18\x02 \x01(\v2\x0e.trip.RideFareR\fselectedFare\x12!\n" +
 	"\x05route\x18\x03 \x01(\v2\v.trip.RouteR\x05route\x12\x16\n" +
 	"\x06status\x18\x04 \x01(\tR\x06status\x12\x16\n" +
 	"\x06userID\x18\x05 \x01(\tR\x06userID\x12(\n" +
-	"\x06driver\x18\x06 \x01(\v2\x10.trip.TripDriverR\x06driver\"t\n" +
+	"\x06driver\x18\x06 \x01(\v2\x10.trip.TripDriverR\x06driver\x12(\n" +
+	"\x06refund\x18\a \x01(\v2\x10.trip.TripRefundR\x06refund\x12%\n" +
+	"\x05split\x18\b \x01(\v2\x0f.trip.TripSplitR\x05split\"\x9c\x01\n" +
+	"\n" +
+	"TripRefund\x12&\n" +
+	"\x0erefundedAmount\x18\x01 \x01(\x03R\x0erefundedAmount\x12\x1a\n" +
+	"\bcurrency\x18\x02 \x01(\tR\bcurrency\x12\x16\n" +
+	"\x06reason\x18\x03 \x01(\tR\x06reason\x12\x12\n" +
+	"\x04full\x18\x04 \x01(\bR\x04full\x12\x1e\n" +
+	"\n" +
+	"refundedAt\x18\x05 \x01(\tR\n" +
+	"refundedAt\"t\n" +
 	"\n" +
 	"TripDriver\x12\x0e\n" +
 	"\x02id\x18\x01 \x01(\tR\x02id\x12\x12\n" +
 	"\x04name\x18\x02 \x01(\tR\x04name\x12&\n" +
 	"\x0eprofilePicture\x18\x03 \x01(\tR\x0eprofilePicture\x12\x1a\n" +
-	"\bcarPlate\x18\x04 \x01(\tR\bcarPlate2\x92\x01\n" +
-	"\vTripService\x12B\n" +
-	"\vPreviewTrip\x12\x18.trip.PreviewTripRequest\x1a\x19.trip.PreviewTripResponse\x12?\n" +
+	"\bcarPlate\x18\x04 \x01(\tR\bcarPlate\"H\n" +
+	"\x16GetChatMessagesRequest\x12\x16\n" +
+	"\x06tripID\x18\x01 \x01(\tR\x06tripID\x12\x16\n" +
+	"\x06userID\x18\x02 \x01(\tR\x06userID\"H\n" +
+	"\x17GetChatMessagesResponse\x12-\n" +
+	"\bmessages\x18\x01 \x03(\v2\x11.trip.ChatMessageR\bmessages\"I\n" +
+	"\x17ListQuickRepliesRequest\x12\x16\n" +
+	"\x06tripID\x18\x01 \x01(\tR\x06tripID\x12\x16\n" +
+	"\x06userID\x18\x02 \x01(\tR\x06userID\"P\n" +
+	"\x18ListQuickRepliesResponse\x124\n" +
+	"\fquickReplies\x18\x01 \x03(\v2\x10.trip.QuickReplyR\fquickReplies\"\xbb\x02\n" +
+	"\vChatMessage\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x16\n" +
+	"\x06tripID\x18\x02 \x01(\tR\x06tripID\x12\x1a\n" +
+	"\bsenderID\x18\x03 \x01(\tR\bsenderID\x12 \n" +
+	"\vrecipientID\x18\x04 \x01(\tR\vrecipientID\x12\x12\n" +
+	"\x04text\x18\x05 \x01(\tR\x04text\x12\x1e\n" +
+	"\n" +
+	"templateID\x18\x06 \x01(\tR\n" +
+	"templateID\x12(\n" +
+	"\x0fclientMessageID\x18\a \x01(\tR\x0fclientMessageID\x12\x16\n" +
+	"\x06status\x18\b \x01(\tR\x06status\x12\x16\n" +
+	"\x06sentAt\x18\t \x01(\tR\x06sentAt\x12 \n" +
+	"\vdeliveredAt\x18\n" +
+	" \x01(\tR\vdeliveredAt\x12\x16\n" +
+	"\x06readAt\x18\v \x01(\tR\x06readAt\"0\n" +
+	"\n" +
+	"QuickReply\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x12\n" +
+	"\x04text\x18\x02 \x01(\tR\x04text\"\x8d\x01\n" +
+	"\x10SplitFareRequest\x12\x16\n" +
+	"\x06tripID\x18\x01 \x01(\tR\x06tripID\x12\x16\n" +
+	"\x06userID\x18\x02 \x01(\tR\x06userID\x12\x12\n" +
+	"\x04mode\x18\x03 \x01(\tR\x04mode\x125\n" +
+	"\fparticipants\x18\x04 \x03(\v2\x11.trip.SplitInviteR\fparticipants\";\n" +
+	"\vSplitInvite\x12\x16\n" +
+	"\x06userID\x18\x01 \x01(\tR\x06userID\x12\x14\n" +
+	"\x05share\x18\x02 \x01(\x03R\x05share\"3\n" +
+	"\x11SplitFareResponse\x12\x1e\n" +
+	"\x04trip\x18\x01 \x01(\v2\n" +
+	".trip.TripR\x04trip\"C\n" +
+	"\x11CancelTripRequest\x12\x16\n" +
+	"\x06tripID\x18\x01 \x01(\tR\x06tripID\x12\x16\n" +
+	"\x06userID\x18\x02 \x01(\tR\x06userID\"4\n" +
+	"\x12CancelTripResponse\x12\x1e\n" +
+	"\x04trip\x18\x01 \x01(\v2\n" +
+	".trip.TripR\x04trip\"\x85\x01\n" +
+	"\tTripSplit\x12\x12\n" +
+	"\x04mode\x18\x01 \x01(\tR\x04mode\x12:\n" +
+	"\fparticipants\x18\x02 \x03(\v2\x16.trip.SplitParticipantR\fparticipants\x12(\n" +
+	"\x06shares\x18\x03 \x03(\v2\x10.trip.SplitShareR\x06shares\"\x98\x01\n" +
+	"\x10SplitParticipant\x12\x16\n" +
+	"\x06userID\x18\x01 \x01(\tR\x06userID\x12\x14\n" +
+	"\x05share\x18\x02 \x01(\x03R\x05share\x12\x16\n" +
+	"\x06status\x18\x03 \x01(\tR\x06status\x12\x1c\n" +
+	"\tinvitedAt\x18\x04 \x01(\tR\tinvitedAt\x12 \n" +
+	"\vrespondedAt\x18\x05 \x01(\tR\vrespondedAt\"P\n" +
+	"\n" +
+	"SplitShare\x12\x16\n" +
+	"\x06userID\x18\x01 \x01(\tR\x06userID\x12\x16\n" +
+	"\x06amount\x18\x02 \x01(\x03R\x06amount\x12\x12\n" +
+	"\x04paid\x18\x03 \x01(\bR\x04paid2\xf4\x05\n" +
+	"\vTripService\x12`\n" +
+	"\vPreviewTrip\x12\x18.trip.PreviewTripRequest\x1a\x19.trip.PreviewTripResponse\"\x1c\x82\xd3\xe4\x93\x02\x16:\x01*\"\x11/v1/trips:preview\x12U\n" +
+	"\n" +
+	"CreateTrip\x12\x17.trip.CreateTripRequest\x1a\x18.trip.CreateTripResponse\"\x14\x82\xd3\xe4\x93\x02\x0e:\x01*\"\t/v1/trips\x12p\n" +
+	"\rGetActiveTrip\x12\x1a.trip.GetActiveTripRequest\x1a\x1b.trip.GetActiveTripResponse\"&\x82\xd3\xe4\x93\x02 \x12\x1e/v1/users/{userID}/active-trip\x12s\n" +
+	"\x0fGetChatMessages\x12\x1c.trip.GetChatMessagesRequest\x1a\x1d.trip.GetChatMessagesResponse\"#\x82\xd3\xe4\x93\x02\x1d\x12\x1b/v1/trips/{tripID}/messages\x12{\n" +
+	"\x10ListQuickReplies\x12\x1d.trip.ListQuickRepliesRequest\x1a\x1e.trip.ListQuickRepliesResponse\"(\x82\xd3\xe4\x93\x02\"\x12 /v1/trips/{tripID}/quick-replies\x12a\n" +
+	"\tSplitFare\x12\x16.trip.SplitFareRequest\x1a\x17.trip.SplitFareResponse\"#\x82\xd3\xe4\x93\x02\x1d:\x01*\"\x18/v1/trips/{tripID}/split\x12e\n" +
 	"\n" +
-	"CreateTrip\x12\x17.trip.CreateTripRequest\x1a\x18.trip.CreateTripResponseB\x18Z\x16shared/proto/trip;tripb\x06proto3"
+	"CancelTrip\x12\x17.trip.CancelTripRequest\x1a\x18.trip.CancelTripResponse\"$\x82\xd3\xe4\x93\x02\x1e:\x01*\"\x19/v1/trips/{tripID}:cancelB\x18Z\x16shared/proto/trip;tripb\x06proto3"
 
 var (
 	file_trip_proto_rawDescOnce sync.Once
@@ -690,18 +1799,35 @@ func file_trip_proto_rawDescGZIP() []byte {
 	return file_trip_proto_rawDescData
 }
 
-var file_trip_proto_msgTypes = make([]protoimpl.MessageInfo, 10)
+var file_trip_proto_msgTypes = make([]protoimpl.MessageInfo, 27)
 var file_trip_proto_goTypes = []any{
-	(*PreviewTripRequest)(nil),  // 0: trip.PreviewTripRequest
-	(*PreviewTripResponse)(nil), // 1: trip.PreviewTripResponse
-	(*Coordinate)(nil),          // 2: trip.Coordinate
-	(*Geometry)(nil),            // 3: trip.Geometry
-	(*Route)(nil),               // 4: trip.Route
-	(*RideFare)(nil),            // 5: trip.RideFare
-	(*CreateTripRequest)(nil),   // 6: trip.CreateTripRequest
-	(*CreateTripResponse)(nil),  // 7: trip.CreateTripResponse
-	(*Trip)(nil),                // 8: trip.Trip
-	(*TripDriver)(nil),          // 9: trip.TripDriver
+	(*PreviewTripRequest)(nil),       // 0: trip.PreviewTripRequest
+	(*PreviewTripResponse)(nil),      // 1: trip.PreviewTripResponse
+	(*Coordinate)(nil),               // 2: trip.Coordinate
+	(*Geometry)(nil),                 // 3: trip.Geometry
+	(*Route)(nil),                    // 4: trip.Route
+	(*RideFare)(nil),                 // 5: trip.RideFare
+	(*CreateTripRequest)(nil),        // 6: trip.CreateTripRequest
+	(*CreateTripResponse)(nil),       // 7: trip.CreateTripResponse
+	(*GetActiveTripRequest)(nil),     // 8: trip.GetActiveTripRequest
+	(*GetActiveTripResponse)(nil),    // 9: trip.GetActiveTripResponse
+	(*Trip)(nil),                     // 10: trip.Trip
+	(*TripRefund)(nil),               // 11: trip.TripRefund
+	(*TripDriver)(nil),               // 12: trip.TripDriver
+	(*GetChatMessagesRequest)(nil),   // 13: trip.GetChatMessagesRequest
+	(*GetChatMessagesResponse)(nil),  // 14: trip.GetChatMessagesResponse
+	(*ListQuickRepliesRequest)(nil),  // 15: trip.ListQuickRepliesRequest
+	(*ListQuickRepliesResponse)(nil), // 16: trip.ListQuickRepliesResponse
+	(*ChatMessage)(nil),              // 17: trip.ChatMessage
+	(*QuickReply)(nil),               // 18: trip.QuickReply
+	(*SplitFareRequest)(nil),         // 19: trip.SplitFareRequest
+	(*SplitInvite)(nil),              // 20: trip.SplitInvite
+	(*SplitFareResponse)(nil),        // 21: trip.SplitFareResponse
+	(*CancelTripRequest)(nil),        // 22: trip.CancelTripRequest
+	(*CancelTripResponse)(nil),       // 23: trip.CancelTripResponse
+	(*TripSplit)(nil),                // 24: trip.TripSplit
+	(*SplitParticipant)(nil),         // 25: trip.SplitParticipant
+	(*SplitShare)(nil),               // 26: trip.SplitShare
 }
 var file_trip_proto_depIdxs = []int32{
 	2,  // 0: trip.PreviewTripRequest.startLocation:type_name -> trip.Coordinate
@@ -710,19 +1836,39 @@ var file_trip_proto_depIdxs = []int32{
 	5,  // 3: trip.PreviewTripResponse.rideFares:type_name -> trip.RideFare
 	2,  // 4: trip.Geometry.coordinates:type_name -> trip.Coordinate
 	3,  // 5: trip.Route.geometry:type_name -> trip.Geometry
-	8,  // 6: trip.CreateTripResponse.trip:type_name -> trip.Trip
-	5,  // 7: trip.Trip.selectedFare:type_name -> trip.RideFare
-	4,  // 8: trip.Trip.route:type_name -> trip.Route
-	9,  // 9: trip.Trip.driver:type_name -> trip.TripDriver
-	0,  // 10: trip.TripService.PreviewTrip:input_type -> trip.PreviewTripRequest
-	6,  // 11: trip.TripService.CreateTrip:input_type -> trip.CreateTripRequest
-	1,  // 12: trip.TripService.PreviewTrip:output_type -> trip.PreviewTripResponse
-	7,  // 13: trip.TripService.CreateTrip:output_type -> trip.CreateTripResponse
-	12, // [12:14] is the sub-list for method output_type
-	10, // [10:12] is the sub-list for method input_type
-	10, // [10:10] is the sub-list for extension type_name
-	10, // [10:10] is the sub-list for extension extendee
-	0,  // [0:10] is the sub-list for field type_name
+	10, // 6: trip.CreateTripResponse.trip:type_name -> trip.Trip
+	10, // 7: trip.GetActiveTripResponse.trip:type_name -> trip.Trip
+	5,  // 8: trip.Trip.selectedFare:type_name -> trip.RideFare
+	4,  // 9: trip.Trip.route:type_name -> trip.Route
+	12, // 10: trip.Trip.driver:type_name -> trip.TripDriver
+	11, // 11: trip.Trip.refund:type_name -> trip.TripRefund
+	24, // 12: trip.Trip.split:type_name -> trip.TripSplit
+	17, // 13: trip.GetChatMessagesResponse.messages:type_name -> trip.ChatMessage
+	18, // 14: trip.ListQuickRepliesResponse.quickReplies:type_name -> trip.QuickReply
+	20, // 15: trip.SplitFareRequest.participants:type_name -> trip.SplitInvite
+	10, // 16: trip.SplitFareResponse.trip:type_name -> trip.Trip
+	10, // 17: trip.CancelTripResponse.trip:type_name -> trip.Trip
+	25, // 18: trip.TripSplit.participants:type_name -> trip.SplitParticipant
+	26, // 19: trip.TripSplit.shares:type_name -> trip.SplitShare
+	0,  // 20: trip.TripService.PreviewTrip:input_type -> trip.PreviewTripRequest
+	6,  // 21: trip.TripService.CreateTrip:input_type -> trip.CreateTripRequest
+	8,  // 22: trip.TripService.GetActiveTrip:input_type -> trip.GetActiveTripRequest
+	13, // 23: trip.TripService.GetChatMessages:input_type -> trip.GetChatMessagesRequest
+	15, // 24: trip.TripService.ListQuickReplies:input_type -> trip.ListQuickRepliesRequest
+	19, // 25: trip.TripService.SplitFare:input_type -> trip.SplitFareRequest
+	22, // 26: trip.TripService.CancelTrip:input_type -> trip.CancelTripRequest
+	1,  // 27: trip.TripService.PreviewTrip:output_type -> trip.PreviewTripResponse
+	7,  // 28: trip.TripService.CreateTrip:output_type -> trip.CreateTripResponse
+	9,  // 29: trip.TripService.GetActiveTrip:output_type -> trip.GetActiveTripResponse
+	14, // 30: trip.TripService.GetChatMessages:output_type -> trip.GetChatMessagesResponse
+	16, // 31: trip.TripService.ListQuickReplies:output_type -> trip.ListQuickRepliesResponse
+	21, // 32: trip.TripService.SplitFare:output_type -> trip.SplitFareResponse
+	23, // 33: trip.TripService.CancelTrip:output_type -> trip.CancelTripResponse
+	27, // [27:34] is the sub-list for method output_type
+	20, // [20:27] is the sub-list for method input_type
+	20, // [20:20] is the sub-list for extension type_name
+	20, // [20:20] is the sub-list for extension extendee
+	0,  // [0:20] is the sub-list for field type_name
 }
 
 func init() { file_trip_proto_init() }
@@ -736,7 +1882,7 @@ func file_trip_proto_init() {
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_trip_proto_rawDesc), len(file_trip_proto_rawDesc)),
 			NumEnums:      0,
-			NumMessages:   10,
+			NumMessages:   27,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
@@ -19,8 +19,13 @@ import (
 const _ = grpc.SupportPackageIsVersion9
 
 const (
-	TripService_PreviewTrip_FullMethodName = "/trip.TripService/PreviewTrip"
-	TripService_CreateTrip_FullMethodName  = "/trip.TripService/CreateTrip"
+	TripService_PreviewTrip_FullMethodName      = "/trip.TripService/PreviewTrip"
+	TripService_CreateTrip_FullMethodName       = "/trip.TripService/CreateTrip"
+	TripService_GetActiveTrip_FullMethodName    = "/trip.TripService/GetActiveTrip"
+	TripService_GetChatMessages_FullMethodName  = "/trip.TripService/GetChatMessages"
+	TripService_ListQuickReplies_FullMethodName = "/trip.TripService/ListQuickReplies"
+	TripService_SplitFare_FullMethodName        = "/trip.TripService/SplitFare"
+	TripService_CancelTrip_FullMethodName       = "/trip.TripService/CancelTrip"
 )
 
 // TripServiceClient is the client API for TripService service.
@@ -29,6 +34,11 @@ const (
 type TripServiceClient interface {
 	PreviewTrip(ctx context.Context, in *PreviewTripRequest, opts ...grpc.CallOption) (*PreviewTripResponse, error)
 	CreateTrip(ctx context.Context, in *CreateTripRequest, opts ...grpc.CallOption) (*CreateTripResponse, error)
+	GetActiveTrip(ctx context.Context, in *GetActiveTripRequest, opts ...grpc.CallOption) (*GetActiveTripResponse, error)
+	GetChatMessages(ctx context.Context, in *GetChatMessagesRequest, opts ...grpc.CallOption) (*GetChatMessagesResponse, error)
+	ListQuickReplies(ctx context.Context, in *ListQuickRepliesRequest, opts ...grpc.CallOption) (*ListQuickRepliesResponse, error)
+	SplitFare(ctx context.Context, in *SplitFareRequest, opts ...grpc.CallOption) (*SplitFareResponse, error)
+	CancelTrip(ctx context.Context, in *CancelTripRequest, opts ...grpc.CallOption) (*CancelTripResponse, error)
 }
 
 type tripServiceClient struct {
@@ -59,12 +69,67 @@ func (c *tripServiceClient) CreateTrip(ctx context.Context, in *CreateTripReques
 	return out, nil
 }
 
+func (c *tripServiceClient) GetActiveTrip(ctx context.Context, in *GetActiveTripRequest, opts ...grpc.CallOption) (*GetActiveTripResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetActiveTripResponse)
+	err := c.cc.Invoke(ctx, TripService_GetActiveTrip_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *tripServiceClient) GetChatMessages(ctx context.Context, in *GetChatMessagesRequest, opts ...grpc.CallOption) (*GetChatMessagesResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetChatMessagesResponse)
+	err := c.cc.Invoke(ctx, TripService_GetChatMessages_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *tripServiceClient) ListQuickReplies(ctx context.Context, in *ListQuickRepliesRequest, opts ...grpc.CallOption) (*ListQuickRepliesResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListQuickRepliesResponse)
+	err := c.cc.Invoke(ctx, TripService_ListQuickReplies_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *tripServiceClient) SplitFare(ctx context.Context, in *SplitFareRequest, opts ...grpc.CallOption) (*SplitFareResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(SplitFareResponse)
+	err := c.cc.Invoke(ctx, TripService_SplitFare_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *tripServiceClient) CancelTrip(ctx context.Context, in *CancelTripRequest, opts ...grpc.CallOption) (*CancelTripResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(CancelTripResponse)
+	err := c.cc.Invoke(ctx, TripService_CancelTrip_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // TripServiceServer is the server API for TripService service.
 // All implementations must embed UnimplementedTripServiceServer
 // for forward compatibility.
 type TripServiceServer interface {
 	PreviewTrip(context.Context, *PreviewTripRequest) (*PreviewTripResponse, error)
 	CreateTrip(context.Context, *CreateTripRequest) (*CreateTripResponse, error)
+	GetActiveTrip(context.Context, *GetActiveTripRequest) (*GetActiveTripResponse, error)
+	GetChatMessages(context.Context, *GetChatMessagesRequest) (*GetChatMessagesResponse, error)
+	ListQuickReplies(context.Context, *ListQuickRepliesRequest) (*ListQuickRepliesResponse, error)
+	SplitFare(context.Context, *SplitFareRequest) (*SplitFareResponse, error)
+	CancelTrip(context.Context, *CancelTripRequest) (*CancelTripResponse, error)
 	mustEmbedUnimplementedTripServiceServer()
 }
 
@@ -81,6 +146,21 @@ func (UnimplementedTripServiceServer) PreviewTrip(context.Context, *PreviewTripR
 func (UnimplementedTripServiceServer) CreateTrip(context.Context, *CreateTripRequest) (*CreateTripResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method CreateTrip not implemented")
 }
+func (UnimplementedTripServiceServer) GetActiveTrip(context.Context, *GetActiveTripRequest) (*GetActiveTripResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetActiveTrip not implemented")
+}
+func (UnimplementedTripServiceServer) GetChatMessages(context.Context, *GetChatMessagesRequest) (*GetChatMessagesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetChatMessages not implemented")
+}
+func (UnimplementedTripServiceServer) ListQuickReplies(context.Context, *ListQuickRepliesRequest) (*ListQuickRepliesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListQuickReplies not implemented")
+}
+func (UnimplementedTripServiceServer) SplitFare(context.Context, *SplitFareRequest) (*SplitFareResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SplitFare not implemented")
+}
+func (UnimplementedTripServiceServer) CancelTrip(context.Context, *CancelTripRequest) (*CancelTripResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CancelTrip not implemented")
+}
 func (UnimplementedTripServiceServer) mustEmbedUnimplementedTripServiceServer() {}
 func (UnimplementedTripServiceServer) testEmbeddedByValue()                     {}
 
@@ -138,6 +218,96 @@ func _TripService_CreateTrip_Handler(srv interface{}, ctx context.Context, dec f
 	return interceptor(ctx, in, info, handler)
 }
 
+func _TripService_GetActiveTrip_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetActiveTripRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TripServiceServer).GetActiveTrip(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: TripService_GetActiveTrip_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TripServiceServer).GetActiveTrip(ctx, req.(*GetActiveTripRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _TripService_GetChatMessages_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetChatMessagesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TripServiceServer).GetChatMessages(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: TripService_GetChatMessages_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TripServiceServer).GetChatMessages(ctx, req.(*GetChatMessagesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _TripService_ListQuickReplies_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListQuickRepliesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TripServiceServer).ListQuickReplies(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: TripService_ListQuickReplies_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TripServiceServer).ListQuickReplies(ctx, req.(*ListQuickRepliesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _TripService_SplitFare_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SplitFareRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TripServiceServer).SplitFare(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: TripService_SplitFare_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TripServiceServer).SplitFare(ctx, req.(*SplitFareRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _TripService_CancelTrip_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CancelTripRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TripServiceServer).CancelTrip(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: TripService_CancelTrip_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TripServiceServer).CancelTrip(ctx, req.(*CancelTripRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // TripService_ServiceDesc is the grpc.ServiceDesc for TripService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -153,6 +323,26 @@ var TripService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "CreateTrip",
 			Handler:    _TripService_CreateTrip_Handler,
 		},
+		{
+			MethodName: "GetActiveTrip",
+			Handler:    _TripService_GetActiveTrip_Handler,
+		},
+		{
+			MethodName: "GetChatMessages",
+			Handler:    _TripService_GetChatMessages_Handler,
+		},
+		{
+			MethodName: "ListQuickReplies",
+			Handler:    _TripService_ListQuickReplies_Handler,
+		},
+		{
+			MethodName: "SplitFare",
+			Handler:    _TripService_SplitFare_Handler,
+		},
+		{
+			MethodName: "CancelTrip",
+			Handler:    _TripService_CancelTrip_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "trip.proto",
@@ -0,0 +1,63 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// sweepInterval is how often the buckets that are full again are dropped
+const sweepInterval = time.Minute
+
+type inmemBucket struct {
+	bucket
+	limit Limit
+}
+
+// InmemStore keeps the token buckets in memory
+type InmemStore struct {
+	buckets   map[string]*inmemBucket
+	lastSweep time.Time
+	mutex     sync.Mutex
+}
+
+func NewInmemStore() *InmemStore {
+	return &InmemStore{
+		buckets:   make(map[string]*inmemBucket),
+		lastSweep: time.Now(),
+	}
+}
+
+func (s *InmemStore) Take(ctx context.Context, key string, limit Limit) (Result, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	now := time.Now()
+	s.sweep(now)
+
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &inmemBucket{
+			bucket: bucket{tokens: float64(limit.Burst), last: now},
+		}
+		s.buckets[key] = b
+	}
+	b.limit = limit
+
+	return b.take(now, limit), nil
+}
+
+// sweep drops the buckets that are full again, they are recreated full on the next request.
+// The caller must hold the lock.
+func (s *InmemStore) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < sweepInterval {
+		return
+	}
+	s.lastSweep = now
+
+	for key, b := range s.buckets {
+		if b.tokens+now.Sub(b.last).Seconds()*b.limit.Rate >= float64(b.limit.Burst) {
+			delete(s.buckets, key)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+/*
+Package ratelimit provides token bucket rate limiting with pluggable stores for the buckets.
+*/
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"time"
+)
+
+// Limit is a token bucket refilled with Rate tokens per second, holding at most Burst tokens
+type Limit struct {
+	Rate  float64
+	Burst int
+}
+
+// PerMinute returns a Limit of n requests per minute with the given burst
+func PerMinute(n, burst int) Limit {
+	return Limit{
+		Rate:  float64(n) / 60,
+		Burst: burst,
+	}
+}
+
+// Result is the outcome of taking a token from a bucket
+type Result struct {
+	Allowed    bool
+	Limit      int           // Size of the bucket
+	Remaining  int           // Tokens left in the bucket
+	RetryAfter time.Duration // When the next token is available, if not allowed
+	ResetAfter time.Duration // When the bucket is full again
+}
+
+// Store keeps the token buckets. The in-memory store is enough for a single gateway instance,
+// a shared store is needed for the limits to hold across instances.
+type Store interface {
+	// Take takes a token from the bucket of the key, creating it full if needed
+	Take(ctx context.Context, key string, limit Limit) (Result, error)
+}
+
+// bucket is the state of a token bucket at a point in time
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// take refills the bucket up to now and takes a token if there is one
+func (b *bucket) take(now time.Time, limit Limit) Result {
+	burst := float64(limit.Burst)
+
+	b.tokens = math.Min(burst, b.tokens+now.Sub(b.last).Seconds()*limit.Rate)
+	b.last = now
+
+	result := Result{Limit: limit.Burst}
+	if b.tokens >= 1 {
+		b.tokens--
+		result.Allowed = true
+	} else {
+		result.RetryAfter = durationFor(1-b.tokens, limit.Rate)
+	}
+
+	result.Remaining = int(b.tokens)
+	result.ResetAfter = durationFor(burst-b.tokens, limit.Rate)
+
+	return result
+}
+
+// durationFor returns how long it takes to refill the tokens
+func durationFor(tokens, rate float64) time.Duration {
+	if rate <= 0 {
+		return time.Duration(math.MaxInt64)
+	}
+	return time.Duration(tokens / rate * float64(time.Second))
+}
@@ -0,0 +1,82 @@
+package validate
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	"ride-sharing/shared/env"
+	"ride-sharing/shared/types"
+)
+
+// Limits are the business limits of a trip
+type Limits struct {
+	MaxTripDistanceKm float64 // 0 disables the check
+	ServiceArea       *Area   // nil disables the check
+}
+
+// Area is a latitude/longitude bounding box
+type Area struct {
+	MinLatitude  float64
+	MinLongitude float64
+	MaxLatitude  float64
+	MaxLongitude float64
+}
+
+// Contains reports whether the coordinate is inside the area, a nil area contains everything
+func (a *Area) Contains(c *types.Coordinate) bool {
+	if a == nil {
+		return true
+	}
+	return c.Latitude >= a.MinLatitude && c.Latitude <= a.MaxLatitude &&
+		c.Longitude >= a.MinLongitude && c.Longitude <= a.MaxLongitude
+}
+
+// defaultServiceArea is the San Francisco Bay Area, where the app runs by default
+const defaultServiceArea = "37.1,-122.8,38.3,-121.5"
+
+// NewDefaultLimits creates the trip limits from environment variables.
+// SERVICE_AREA is "minLat,minLng,maxLat,maxLng", an empty value disables the service area check.
+func NewDefaultLimits() Limits {
+	limits := Limits{
+		MaxTripDistanceKm: float64(env.GetInt("MAX_TRIP_DISTANCE_KM", 100)),
+	}
+
+	area, err := ParseArea(env.GetString("SERVICE_AREA", defaultServiceArea))
+	if err != nil {
+		log.Printf("Invalid SERVICE_AREA, using the default one: %v", err)
+		area, _ = ParseArea(defaultServiceArea)
+	}
+	limits.ServiceArea = area
+
+	return limits
+}
+
+// ParseArea parses a "minLat,minLng,maxLat,maxLng" bounding box, an empty value is no area
+func ParseArea(value string) (*Area, error) {
+	if strings.TrimSpace(value) == "" {
+		return nil, nil
+	}
+
+	parts := strings.Split(value, ",")
+	if len(parts) != 4 {
+		return nil, fmt.Errorf("expected minLat,minLng,maxLat,maxLng, got %q", value)
+	}
+
+	var bounds [4]float64
+	for i, part := range parts {
+		bound, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid bound %q: %v", part, err)
+		}
+		bounds[i] = bound
+	}
+
+	return &Area{
+		MinLatitude:  bounds[0],
+		MinLongitude: bounds[1],
+		MaxLatitude:  bounds[2],
+		MaxLongitude: bounds[3],
+	}, nil
+}
@@ -0,0 +1,129 @@
+/*
+Package validate checks the requests at the gateway and at the service boundary and collects field errors.
+*/
+package validate
+
+import (
+	"encoding/hex"
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+
+	"ride-sharing/shared/contracts"
+	"ride-sharing/shared/types"
+)
+
+// PackageSlugs are the car packages a trip can be requested for and a driver can register with
+var PackageSlugs = []string{"suv", "sedan", "van", "luxury"}
+
+// Errors collects the field errors of a request
+type Errors []contracts.FieldError
+
+func (e *Errors) Add(field, message string) {
+	*e = append(*e, contracts.FieldError{Field: field, Message: message})
+}
+
+func (e Errors) Error() string {
+	messages := make([]string, len(e))
+	for i, fe := range e {
+		messages[i] = fe.Field + ": " + fe.Message
+	}
+	return "invalid request: " + strings.Join(messages, "; ")
+}
+
+// Err returns the errors, or nil if there are none
+func (e Errors) Err() error {
+	if len(e) == 0 {
+		return nil
+	}
+	return e
+}
+
+func (e *Errors) Required(field, value string) {
+	if strings.TrimSpace(value) == "" {
+		e.Add(field, "is required")
+	}
+}
+
+// ObjectID checks that the value is a MongoDB ObjectID, 12 bytes in hex
+func (e *Errors) ObjectID(field, value string) {
+	if value == "" {
+		e.Add(field, "is required")
+		return
+	}
+
+	if b, err := hex.DecodeString(value); err != nil || len(b) != 12 {
+		e.Add(field, "must be a 24 characters hex ID")
+	}
+}
+
+func (e *Errors) PackageSlug(field, value string) {
+	if !slices.Contains(PackageSlugs, value) {
+		e.Add(field, fmt.Sprintf("must be one of %s", strings.Join(PackageSlugs, ", ")))
+	}
+}
+
+// Coordinate checks the ranges of the coordinate. 0,0 is rejected too, it is what an unset location looks like.
+// It reports whether the coordinate is valid.
+func (e *Errors) Coordinate(field string, c *types.Coordinate) bool {
+	if c == nil {
+		e.Add(field, "is required")
+		return false
+	}
+
+	valid := true
+	if math.IsNaN(c.Latitude) || c.Latitude < -90 || c.Latitude > 90 {
+		e.Add(field+".latitude", "must be between -90 and 90")
+		valid = false
+	}
+	if math.IsNaN(c.Longitude) || c.Longitude < -180 || c.Longitude > 180 {
+		e.Add(field+".longitude", "must be between -180 and 180")
+		valid = false
+	}
+	if valid && c.Latitude == 0 && c.Longitude == 0 {
+		e.Add(field, "is not set")
+		valid = false
+	}
+
+	return valid
+}
+
+// Trip checks the pickup and the destination of a trip against the limits
+func (e *Errors) Trip(pickup, destination *types.Coordinate, limits Limits) {
+	pickupValid := e.Coordinate("pickup", pickup)
+	destinationValid := e.Coordinate("destination", destination)
+
+	if pickupValid && !limits.ServiceArea.Contains(pickup) {
+		e.Add("pickup", "is outside of the service area")
+	}
+	if destinationValid && !limits.ServiceArea.Contains(destination) {
+		e.Add("destination", "is outside of the service area")
+	}
+
+	if !pickupValid || !destinationValid {
+		return
+	}
+
+	if *pickup == *destination {
+		e.Add("destination", "must be different from the pickup")
+		return
+	}
+
+	if limits.MaxTripDistanceKm > 0 && DistanceKm(pickup, destination) > limits.MaxTripDistanceKm {
+		e.Add("destination", fmt.Sprintf("must be less than %gkm away from the pickup", limits.MaxTripDistanceKm))
+	}
+}
+
+// DistanceKm is the great-circle distance between two coordinates
+func DistanceKm(a, b *types.Coordinate) float64 {
+	const earthRadiusKm = 6371
+
+	lat1 := a.Latitude * math.Pi / 180
+	lat2 := b.Latitude * math.Pi / 180
+	dLat := lat2 - lat1
+	dLng := (b.Longitude - a.Longitude) * math.Pi / 180
+
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(lat1)*math.Cos(lat2)*math.Sin(dLng/2)*math.Sin(dLng/2)
+	return 2 * earthRadiusKm * math.Asin(math.Sqrt(h))
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"ride-sharing/shared/apispec"
+)
+
+// The committed documents are checked against the Go types by the tests of shared/apispec
+func main() {
+	outDir := flag.String("out", filepath.Join("docs", "api"), "Directory of the generated documents")
+	flag.Parse()
+
+	docs, err := apispec.Documents()
+	if err != nil {
+		fmt.Printf("Error generating the documents: %v\n", err)
+		os.Exit(1)
+	}
+
+	if err := os.MkdirAll(*outDir, 0755); err != nil {
+		fmt.Printf("Error creating directory %s: %v\n", *outDir, err)
+		os.Exit(1)
+	}
+
+	for name, content := range docs {
+		path := filepath.Join(*outDir, name)
+		if err := os.WriteFile(path, content, 0644); err != nil {
+			fmt.Printf("Error writing %s: %v\n", path, err)
+			os.Exit(1)
+		}
+		fmt.Printf("Generated %s\n", path)
+	}
+}
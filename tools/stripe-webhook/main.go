@@ -0,0 +1,194 @@
+// Command stripe-webhook sends signed Stripe webhook events to the gateway, to exercise the payment
+// flow without a Stripe account. The events only carry the fields payment-service reads.
+//
+//	go run ./tools/stripe-webhook -type checkout.session.completed -trip <tripID> -session <sessionID>
+//	go run ./tools/stripe-webhook -type charge.dispute.created -intent pi_123 -repeat 2
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"time"
+
+	"ride-sharing/shared/env"
+
+	"github.com/google/uuid"
+	"github.com/stripe/stripe-go/v81"
+	"github.com/stripe/stripe-go/v81/webhook"
+)
+
+func main() {
+	url := flag.String("url", "http://localhost:8081/webhook/stripe", "Webhook endpoint of the gateway")
+	secret := flag.String("secret", env.GetString("STRIPE_WEBHOOK_KEY", ""), "Webhook signing secret, STRIPE_WEBHOOK_KEY by default")
+	eventType := flag.String("type", string(stripe.EventTypeCheckoutSessionCompleted), "Type of the Stripe event")
+	eventID := flag.String("event", "", "ID of the event, random by default. Reuse one to send a duplicate")
+	tripID := flag.String("trip", "", "Trip of the payment, in the metadata")
+	userID := flag.String("user", "", "Rider of the trip, in the metadata")
+	driverID := flag.String("driver", "", "Driver of the trip, in the metadata")
+	sessionID := flag.String("session", "", "Checkout session of the payment")
+	intentID := flag.String("intent", "", "Payment intent of the payment")
+	customerID := flag.String("customer", "", "Customer of the setup intent")
+	paymentMethodID := flag.String("payment-method", "", "Card saved by the setup intent")
+	repeat := flag.Int("repeat", 1, "Number of deliveries of the event, like the Stripe retries")
+	dryRun := flag.Bool("dry-run", false, "Print the signed payload instead of sending it")
+	flag.Parse()
+
+	if *secret == "" {
+		fmt.Println("The webhook secret is required, set -secret or STRIPE_WEBHOOK_KEY")
+		os.Exit(1)
+	}
+
+	if *eventID == "" {
+		*eventID = "evt_" + uuid.NewString()
+	}
+
+	metadata := map[string]string{
+		"trip_id":   *tripID,
+		"user_id":   *userID,
+		"driver_id": *driverID,
+	}
+
+	var object map[string]any
+	var err error
+	if stripe.EventType(*eventType) == stripe.EventTypeSetupIntentSucceeded {
+		object, err = setupIntentObject(*customerID, *paymentMethodID, *userID)
+	} else {
+		object, err = eventObject(stripe.EventType(*eventType), *sessionID, *intentID, metadata)
+	}
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	payload, err := eventPayload(*eventID, stripe.EventType(*eventType), object)
+	if err != nil {
+		fmt.Printf("Error encoding the event: %v\n", err)
+		os.Exit(1)
+	}
+
+	for i := 0; i < *repeat; i++ {
+		// Signed for every delivery, like Stripe does, the timestamp is part of the signature
+		signed := webhook.GenerateTestSignedPayload(&webhook.UnsignedPayload{
+			Payload:   payload,
+			Secret:    *secret,
+			Timestamp: time.Now(),
+		})
+
+		if *dryRun {
+			fmt.Printf("Stripe-Signature: %s\n%s\n", signed.Header, signed.Payload)
+			continue
+		}
+
+		status, body, err := send(*url, signed)
+		if err != nil {
+			fmt.Printf("Error sending event %s: %v\n", *eventID, err)
+			os.Exit(1)
+		}
+		fmt.Printf("%s %s: %d %s\n", *eventType, *eventID, status, body)
+	}
+}
+
+// eventPayload returns the JSON of the event, with the fields payment-service reads
+func eventPayload(eventID string, eventType stripe.EventType, object map[string]any) ([]byte, error) {
+	return json.Marshal(map[string]any{
+		"id":          eventID,
+		"object":      "event",
+		"type":        eventType,
+		"api_version": stripe.APIVersion,
+		"created":     time.Now().Unix(),
+		"data":        map[string]any{"object": object},
+	})
+}
+
+// eventObject returns the Stripe object of the event
+func eventObject(eventType stripe.EventType, sessionID, intentID string, metadata map[string]string) (map[string]any, error) {
+	switch eventType {
+	case stripe.EventTypeCheckoutSessionCompleted, stripe.EventTypeCheckoutSessionAsyncPaymentFailed, stripe.EventTypeCheckoutSessionExpired:
+		if sessionID == "" {
+			return nil, fmt.Errorf("-session is required for %s", eventType)
+		}
+		object := map[string]any{
+			"id":       sessionID,
+			"object":   "checkout.session",
+			"metadata": metadata,
+		}
+		if intentID != "" {
+			object["payment_intent"] = intentID
+		}
+		return object, nil
+
+	case stripe.EventTypePaymentIntentPaymentFailed, stripe.EventTypePaymentIntentSucceeded:
+		if intentID == "" {
+			return nil, fmt.Errorf("-intent is required for %s", eventType)
+		}
+		// The payment service only handles the success of the off-session charges
+		if eventType == stripe.EventTypePaymentIntentSucceeded {
+			metadata["off_session"] = "true"
+		}
+		return map[string]any{
+			"id":       intentID,
+			"object":   "payment_intent",
+			"metadata": metadata,
+		}, nil
+
+	case stripe.EventTypeChargeRefunded:
+		return map[string]any{
+			"id":             "ch_" + uuid.NewString(),
+			"object":         "charge",
+			"refunded":       true,
+			"payment_intent": intentID,
+			"metadata":       metadata,
+		}, nil
+
+	case stripe.EventTypeChargeDisputeCreated:
+		if intentID == "" {
+			return nil, fmt.Errorf("-intent is required for %s", eventType)
+		}
+		return map[string]any{
+			"id":             "dp_" + uuid.NewString(),
+			"object":         "dispute",
+			"payment_intent": intentID,
+		}, nil
+
+	default:
+		return nil, fmt.Errorf("unsupported event type %s", eventType)
+	}
+}
+
+// setupIntentObject returns the setup intent saving the card for the user
+func setupIntentObject(customerID, paymentMethodID, userID string) (map[string]any, error) {
+	if userID == "" || paymentMethodID == "" {
+		return nil, fmt.Errorf("-user and -payment-method are required for %s", stripe.EventTypeSetupIntentSucceeded)
+	}
+
+	return map[string]any{
+		"id":             "seti_" + uuid.NewString(),
+		"object":         "setup_intent",
+		"customer":       customerID,
+		"payment_method": paymentMethodID,
+		"metadata":       map[string]string{"user_id": userID},
+	}, nil
+}
+
+func send(url string, signed *webhook.SignedPayload) (int, string, error) {
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(signed.Payload))
+	if err != nil {
+		return 0, "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Stripe-Signature", signed.Header)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return 0, "", err
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(resp.Body)
+	return resp.StatusCode, string(bytes.TrimSpace(body)), nil
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stripe/stripe-go/v81"
+	"github.com/stripe/stripe-go/v81/webhook"
+)
+
+// The events are verified the way payment-service does, see internal/infrastructure/stripe/webhook.go
+func TestEventsAreSignedForTheSecret(t *testing.T) {
+	metadata := map[string]string{"trip_id": "trip-1", "user_id": "rider-1", "driver_id": "driver-1"}
+
+	for _, eventType := range []stripe.EventType{
+		stripe.EventTypeCheckoutSessionCompleted,
+		stripe.EventTypeCheckoutSessionExpired,
+		stripe.EventTypePaymentIntentSucceeded,
+		stripe.EventTypePaymentIntentPaymentFailed,
+		stripe.EventTypeChargeRefunded,
+		stripe.EventTypeChargeDisputeCreated,
+	} {
+		object, err := eventObject(eventType, "cs_1", "pi_1", metadata)
+		if err != nil {
+			t.Fatalf("%s: %v", eventType, err)
+		}
+		payload, err := eventPayload("evt_1", eventType, object)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		signed := webhook.GenerateTestSignedPayload(&webhook.UnsignedPayload{Payload: payload, Secret: "whsec_test", Timestamp: time.Now()})
+		options := webhook.ConstructEventOptions{IgnoreAPIVersionMismatch: true}
+
+		event, err := webhook.ConstructEventWithOptions(signed.Payload, signed.Header, "whsec_test", options)
+		if err != nil {
+			t.Fatalf("%s: the signature is refused: %v", eventType, err)
+		}
+		if event.ID != "evt_1" || event.Type != eventType {
+			t.Errorf("got event %s %s, want evt_1 %s", event.ID, event.Type, eventType)
+		}
+
+		if _, err := webhook.ConstructEventWithOptions(signed.Payload, signed.Header, "whsec_other", options); err == nil {
+			t.Errorf("%s: the signature is accepted for another secret", eventType)
+		}
+	}
+}
+
+func TestEventObjectRequiresTheObjectIDs(t *testing.T) {
+	tests := []struct {
+		eventType           stripe.EventType
+		sessionID, intentID string
+	}{
+		{stripe.EventTypeCheckoutSessionCompleted, "", "pi_1"},
+		{stripe.EventTypePaymentIntentPaymentFailed, "cs_1", ""},
+		{stripe.EventTypeChargeDisputeCreated, "cs_1", ""},
+		{"customer.created", "cs_1", "pi_1"},
+	}
+
+	for _, tt := range tests {
+		if _, err := eventObject(tt.eventType, tt.sessionID, tt.intentID, map[string]string{}); err == nil {
+			t.Errorf("%s with session %q and intent %q: got no error", tt.eventType, tt.sessionID, tt.intentID)
+		}
+	}
+}
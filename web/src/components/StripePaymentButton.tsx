@@ -15,9 +15,9 @@ export const StripePaymentButton = ({
   isLoading = false,
 }: StripePaymentButtonProps) => {
   const handlePayment = async () => {
-    // Check if we are in mock mode
-    if (paymentSession.sessionID.startsWith("cs_test_mock_session_")) {
-      console.log("Mock session detected, simulating successful payment...");
+    // Sessions of the fake payment processor, their outcome is scripted by the payment service
+    if (paymentSession.sessionID.startsWith("cs_test_fake_")) {
+      console.log("Fake session detected, skipping the Stripe checkout...");
       window.location.href = "/?payment=success";
       return;
     }